@@ -1,7 +1,6 @@
 package main
 
 import (
-	"encoding/json"
 	"flag"
 	"fmt"
 	"net"
@@ -43,27 +42,19 @@ func messagePrinter(team marvin.Team, dumpMessages bool) func(msg slack.RTMRawMe
 			case "", "channel_leave", "channel_join", "group_join", "group_leave":
 				break subtypeswitch
 			case "message_changed":
-				type structMessage struct {
-					Edited struct {
-						TS   slack.MessageTS `json:"ts"`
-						User slack.UserID    `json:"user"`
-					}
-					Text string
-					TS   slack.MessageTS `json:"ts"`
-					User slack.UserID    `json:"user"`
-					Type string
+				ev, err := slack.DecodeEvent(msg)
+				if err != nil {
+					break typeswitch
+				}
+				edit, ok := ev.(*slack.MessageEvent)
+				if !ok || edit.Message == nil {
+					break typeswitch
 				}
-				var msgStruct struct {
-					Channel     slack.ChannelID `json:"channel"`
-					EventTS     slack.MessageTS `json:"event_ts"`
-					Message     structMessage   `json:"message"`
-					PrevMessage structMessage   `json:"previous_message"`
-					Subtype     string          `json:"subtype"`
-					Type        string          `json:"type"`
-					TS          slack.MessageTS `json:"ts"`
+				var editor slack.UserID
+				if edit.Message.Edited != nil {
+					editor = edit.Message.Edited.User
 				}
-				json.Unmarshal(msg.Original(), &msgStruct)
-				fmt.Printf("[%s%s] [EDIT BY %s] [@%s] %s\n", team.Domain(), team.ChannelName(msgStruct.Channel), team.UserName(msgStruct.Message.Edited.User), team.UserName(msgStruct.Message.User), msgStruct.Message.Text)
+				fmt.Printf("[%s%s] [EDIT BY %s] [@%s] %s\n", team.Domain(), team.ChannelName(edit.Channel), team.UserName(editor), team.UserName(edit.Message.User), edit.Message.Text)
 				if dumpMessages {
 					break typeswitch
 				}
@@ -77,25 +68,32 @@ func messagePrinter(team marvin.Team, dumpMessages bool) func(msg slack.RTMRawMe
 			}
 			return
 		case "reaction_added":
-			item, ok := msg["item"].(map[string]interface{})
-			if !ok {
+			ev, err := slack.DecodeEvent(msg)
+			if err != nil {
 				break
 			}
-			if item["type"].(string) != "message" {
+			reaction, ok := ev.(*slack.ReactionEvent)
+			if !ok || reaction.Item.Type != "message" {
 				break
 			}
-			ts := slack.MessageTS(item["ts"].(string))
-			channel := slack.ChannelID(item["channel"].(string))
-			fmt.Printf("[%s%s] :%s: @%s -> @%s %s\n", team.Domain(), team.ChannelName(channel),
-				msg.StringField("reaction"), team.UserName(msg.UserID()),
-				team.UserName(slack.UserID(msg.StringField("item_user"))), team.ArchiveURL(slack.MsgID(channel, ts)))
+			fmt.Printf("[%s%s] :%s: @%s -> @%s %s\n", team.Domain(), team.ChannelName(reaction.Item.Channel),
+				reaction.Reaction, team.UserName(reaction.User),
+				team.UserName(reaction.ItemUser), team.ArchiveURL(reaction.MessageID()))
 			return
 		}
 		fmt.Println(colorDebug(fmt.Sprintf("[%s] raw message: %s", team.Domain(), msg)))
 	}
 }
 
-func readyTeam(cfg *ini.File, name string) (marvin.Team, *rtm.Client, error) {
+type options struct {
+	dumpMessages bool
+	// recordFile is the recording to write, with the team name appended
+	// when there is more than one team.
+	recordFile string
+	replay     []rtm.RecordEntry
+}
+
+func readyTeam(cfg *ini.File, name string, opts options) (marvin.Team, *rtm.Client, error) {
 	teamConfig := marvin.LoadTeamConfig(cfg.Section(name))
 	team, err := controller.NewTeam(teamConfig)
 	if err != nil {
@@ -106,8 +104,22 @@ func readyTeam(cfg *ini.File, name string) (marvin.Team, *rtm.Client, error) {
 		return nil, nil, errors.Wrap(err, "listen tcp")
 	}
 	client := rtm.NewClient(team)
-	client.RegisterRawHandler("main.go", messagePrinter(team, false),
-		rtm.MsgTypeAll, nil)
+	printer := messagePrinter(team, opts.dumpMessages)
+	client.RegisterHandler("main.go", marvin.EventFilter{Observer: true}, func(msg slack.RTMRawMessage) bool {
+		printer(msg)
+		return false
+	})
+
+	if opts.replay != nil {
+		team.SetAPIClient(rtm.NewReplayAPI(opts.replay))
+	} else if opts.recordFile != "" {
+		rec, err := rtm.NewRecorder(opts.recordFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		team.SetAPIClient(rec.WrapHTTP(team.APIClient()))
+		client.SetRecorder(rec)
+	}
 
 	team.ConnectRTM(client)
 	if !team.EnableModules() {
@@ -121,7 +133,10 @@ func readyTeam(cfg *ini.File, name string) (marvin.Team, *rtm.Client, error) {
 func main() {
 	teamNamesStr := flag.String("team", "Test", "which team to use")
 	configFile := flag.String("conf", "", "override config file")
-	// dumpMessages := flag.Bool("msgdump", false, "dump message events")
+	dumpMessages := flag.Bool("msgdump", false, "dump message events")
+	recordFile := flag.String("record", "", "record RTM frames and API calls to this JSONL file")
+	replayFile := flag.String("replay", "", "replay a recording instead of connecting to Slack")
+	replaySpeed := flag.Float64("replay-speed", 0, "speed multiplier for -replay; 0 means as fast as possible")
 	flag.Parse()
 
 	var cfg *ini.File
@@ -137,18 +152,45 @@ func main() {
 	}
 
 	teamNames := strings.Split(*teamNamesStr, ",")
+	opts := options{dumpMessages: *dumpMessages}
+	if *replayFile != "" {
+		if len(teamNames) != 1 {
+			util.LogError(errors.Errorf("-replay can only be used with one team"))
+			os.Exit(9)
+		}
+		opts.replay, err = rtm.ReadRecording(*replayFile)
+		if err != nil {
+			util.LogError(err)
+			os.Exit(9)
+		}
+	}
 	teams := make([]marvin.Team, len(teamNames))
 	rtmClients := make([]*rtm.Client, len(teamNames))
 	for i, name := range teamNames {
-		teams[i], rtmClients[i], err = readyTeam(cfg, name)
+		teamOpts := opts
+		if *recordFile != "" && len(teamNames) > 1 {
+			teamOpts.recordFile = *recordFile + "." + name
+		} else {
+			teamOpts.recordFile = *recordFile
+		}
+		teams[i], rtmClients[i], err = readyTeam(cfg, name, teamOpts)
 		if err != nil {
 			util.LogError(err)
 			os.Exit(9)
 		}
 	}
 
-	for _, v := range rtmClients {
-		go v.Start()
+	if opts.replay != nil {
+		err = rtmClients[0].Replay(opts.replay, *replaySpeed)
+		if err != nil {
+			util.LogError(err)
+			os.Exit(9)
+		}
+		util.LogGood("Replay finished, press Ctrl-C to exit")
+	} else {
+		for _, v := range rtmClients {
+			go v.Start()
+		}
 	}
 
 	signalCh := make(chan os.Signal)
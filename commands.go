@@ -2,9 +2,12 @@ package marvin
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"reflect"
 
 	"github.com/pkg/errors"
+	"github.com/riking/marvin/slack"
 	"github.com/riking/marvin/util"
 )
 
@@ -36,11 +39,33 @@ func (args *CommandArguments) PreArgs() []string {
 	return args.OriginalArguments[:len(args.OriginalArguments)-len(args.Arguments)]
 }
 
-// SetModuleData is useful for editing.
+// SetModuleData is useful for editing. The value should survive a round trip
+// through encoding/json, as it is saved to the database between edits.
 func (args *CommandArguments) SetModuleData(v interface{}) {
 	args.ModuleData = v
 }
 
+// LoadModuleData copies the ModuleData of a previous command into v, which
+// must be a pointer to the type that was passed to SetModuleData.
+//
+// ModuleData restored from the database is JSON, and is decoded into v.
+func (args *CommandArguments) LoadModuleData(v interface{}) error {
+	switch data := args.ModuleData.(type) {
+	case nil:
+		return errors.Errorf("no module data")
+	case json.RawMessage:
+		return json.Unmarshal(data, v)
+	default:
+		dst := reflect.ValueOf(v)
+		src := reflect.ValueOf(data)
+		if dst.Kind() != reflect.Ptr || !src.Type().AssignableTo(dst.Elem().Type()) {
+			return errors.Errorf("module data is a %T, not a %s", data, dst.Type())
+		}
+		dst.Elem().Set(src)
+		return nil
+	}
+}
+
 type CommandResultCode int
 
 const (
@@ -50,6 +75,7 @@ const (
 	CmdResultNoSuchCommand
 	CmdResultPrintUsage
 	CmdResultPrintHelp
+	CmdResultConfirm
 )
 
 const UndoSimple = 2
@@ -71,8 +97,22 @@ type CommandResult struct {
 
 	CanEdit util.TriValue
 	CanUndo util.TriValue
+
+	// Suggestions is set on CmdResultNoSuchCommand results when the
+	// mistyped command looked like a known one.
+	Suggestions []CommandSuggestion
+	// Confirm is the continuation of a CmdResultConfirm result.
+	Confirm ConfirmFunc
+	// Blocks replace Message in the channel and DM replies. Message is
+	// still used as the plain-text fallback for notifications and logs, and
+	// is filled in from the blocks if empty.
+	Blocks []slack.Block
 }
 
+// A ConfirmFunc is the rest of a command that asked for confirmation. It is
+// called at most once, after the user confirms.
+type ConfirmFunc func() CommandResult
+
 // CmdError includes the Err field for the CmdResultError code.
 // An error is something that shouldn't normally happen - access violations go under Failure.
 func CmdError(args *CommandArguments, err error, msg string) CommandResult {
@@ -99,6 +139,20 @@ func CmdUsage(args *CommandArguments, usage string) CommandResult {
 	return CommandResult{Args: args, Message: usage, Code: CmdResultPrintUsage}
 }
 
+// CmdConfirm asks the user who ran the command to confirm the action
+// described by summary. The continuation is only run if they react with the
+// confirm emoji before the request expires, and its result is then delivered
+// like any other command result.
+func CmdConfirm(args *CommandArguments, summary string, cont ConfirmFunc) CommandResult {
+	return CommandResult{Args: args, Message: summary, Code: CmdResultConfirm, Confirm: cont}.WithNoEdit().WithNoUndo()
+}
+
+// WithBlocks attaches Block Kit blocks to the reply.
+func (r CommandResult) WithBlocks(blocks []slack.Block) CommandResult {
+	r.Blocks = blocks
+	return r
+}
+
 func (r CommandResult) WithEdit() CommandResult {
 	r.CanEdit = util.TriYes
 	return r
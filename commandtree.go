@@ -0,0 +1,235 @@
+package marvin
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/riking/marvin/util"
+)
+
+// CommandInfo describes one command in the command tree, for generating the
+// command reference.
+type CommandInfo struct {
+	// Path is the full command, e.g. ["factoid", "remember"].
+	Path []string
+	// Aliases are the other names of the command under the same parent.
+	Aliases []string
+	// AlsoAt lists the other places in the tree the command is registered.
+	AlsoAt [][]string
+	Help   string
+	// Level is the access level required to run the command, if it was
+	// registered with RequireLevel.
+	Level       AccessLevel
+	Subcommands []*CommandInfo
+}
+
+// Name returns the full command as it would be typed.
+func (ci *CommandInfo) Name() string {
+	return strings.Join(ci.Path, " ")
+}
+
+// Anchor returns an identifier for the command that can be used in a URL.
+func (ci *CommandInfo) Anchor() string {
+	return "cmd-" + strings.Join(ci.Path, "-")
+}
+
+// AlsoAtNames returns AlsoAt as full command names.
+func (ci *CommandInfo) AlsoAtNames() []string {
+	names := make([]string, len(ci.AlsoAt))
+	for i, p := range ci.AlsoAt {
+		names[i] = strings.Join(p, " ")
+	}
+	return names
+}
+
+// Restricted returns whether the command declares an access level above
+// AccessLevelNormal.
+func (ci *CommandInfo) Restricted() bool {
+	return ci.Level > AccessLevelNormal
+}
+
+// Summary returns the first line of the help text.
+func (ci *CommandInfo) Summary() string {
+	if idx := strings.IndexByte(ci.Help, '\n'); idx != -1 {
+		return ci.Help[:idx]
+	}
+	return ci.Help
+}
+
+// CommandTree walks the commands registered in pc and all nested
+// ParentCommands. prefix is the path of pc itself.
+func (pc *ParentCommand) CommandTree(t Team, prefix []string) []*CommandInfo {
+	w := &treeWalker{
+		t:        t,
+		visiting: map[*ParentCommand]bool{pc: true},
+		paths:    make(map[SubCommand][][]string),
+		keys:     make(map[*CommandInfo]SubCommand),
+	}
+	list := w.walk(pc, prefix)
+
+	// Note commands that are registered in more than one place
+	for ci, key := range w.keys {
+		for _, p := range w.paths[key] {
+			if strings.Join(p, " ") != ci.Name() {
+				ci.AlsoAt = append(ci.AlsoAt, p)
+			}
+		}
+	}
+	return list
+}
+
+type treeWalker struct {
+	t        Team
+	visiting map[*ParentCommand]bool
+	paths    map[SubCommand][][]string
+	keys     map[*CommandInfo]SubCommand
+}
+
+// commandKey returns a value that is equal for the same command registered
+// under several names, or nil if commands of this type can't be compared.
+func commandKey(c SubCommand) SubCommand {
+	if !reflect.TypeOf(c).Comparable() {
+		return nil
+	}
+	return c
+}
+
+func unwrapCommand(c SubCommand) SubCommand {
+	for {
+		lc, ok := c.(*levelCommand)
+		if !ok {
+			return c
+		}
+		c = lc.SubCommand
+	}
+}
+
+// commandEntry is a command and all of its names under one parent. The
+// first name is the longest.
+type commandEntry struct {
+	names   []string
+	command SubCommand
+}
+
+// sortedEntries groups the names in the nameMap by command, sorted by their
+// primary name.
+func (pc *ParentCommand) sortedEntries() []*commandEntry {
+	pc.lock.Lock()
+	all := make([]string, 0, len(pc.nameMap))
+	byName := make(map[string]SubCommand, len(pc.nameMap))
+	for k, v := range pc.nameMap {
+		all = append(all, k)
+		byName[k] = v
+	}
+	pc.lock.Unlock()
+
+	sort.Slice(all, func(i, j int) bool {
+		if len(all[i]) != len(all[j]) {
+			return len(all[i]) > len(all[j])
+		}
+		return all[i] < all[j]
+	})
+	var entries []*commandEntry
+	groups := make(map[SubCommand]*commandEntry)
+	for _, name := range all {
+		c := byName[name]
+		key := commandKey(c)
+		if e, ok := groups[key]; ok && key != nil {
+			e.names = append(e.names, name)
+			continue
+		}
+		e := &commandEntry{names: []string{name}, command: c}
+		if key != nil {
+			groups[key] = e
+		}
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].names[0] < entries[j].names[0] })
+	return entries
+}
+
+func (w *treeWalker) walk(pc *ParentCommand, prefix []string) []*CommandInfo {
+	entries := pc.sortedEntries()
+	list := make([]*CommandInfo, len(entries))
+	for i, e := range entries {
+		c := e.command
+		path := make([]string, len(prefix)+1)
+		copy(path, prefix)
+		path[len(prefix)] = e.names[0]
+
+		ci := &CommandInfo{
+			Path:    path,
+			Aliases: e.names[1:],
+			Help:    commandHelpText(w.t, c, path),
+		}
+		sort.Strings(ci.Aliases)
+		if lc, ok := c.(LeveledCommand); ok {
+			ci.Level = lc.RequiredLevel()
+		}
+		if key := commandKey(c); key != nil {
+			w.keys[ci] = key
+			w.paths[key] = append(w.paths[key], path)
+		}
+		if child, ok := unwrapCommand(c).(*ParentCommand); ok && !w.visiting[child] {
+			w.visiting[child] = true
+			ci.Subcommands = w.walk(child, path)
+			delete(w.visiting, child)
+		}
+		list[i] = ci
+	}
+	return list
+}
+
+// commandHelpText gets the help text of a command without running it.
+func commandHelpText(t Team, c SubCommand, path []string) string {
+	if pc, ok := unwrapCommand(c).(*ParentCommand); ok {
+		return pc.extraHelp
+	}
+	var result CommandResult
+	err := util.PCall(func() error {
+		result = c.Help(t, &CommandArguments{
+			Command:           path[len(path)-1],
+			Arguments:         []string{},
+			OriginalArguments: path,
+			Ctx:               context.Background(),
+		})
+		return nil
+	})
+	if err != nil {
+		util.LogWarn(fmt.Sprintf("help for `%s`: %v", strings.Join(path, " "), err))
+		return ""
+	}
+	return result.Message
+}
+
+// FormatCommandTree formats the command tree for Slack, using the first
+// line of each help text.
+func FormatCommandTree(list []*CommandInfo) string {
+	var buf bytes.Buffer
+	var write func(list []*CommandInfo, depth int)
+	write = func(list []*CommandInfo, depth int) {
+		for _, ci := range list {
+			buf.WriteString(strings.Repeat("    ", depth))
+			fmt.Fprintf(&buf, "• `%s`", ci.Name())
+			if len(ci.Aliases) > 0 {
+				fmt.Fprintf(&buf, " (also `%s`)", strings.Join(ci.Aliases, "`, `"))
+			}
+			if ci.Restricted() {
+				fmt.Fprintf(&buf, " _[%s]_", ci.Level)
+			}
+			if summary := ci.Summary(); summary != "" {
+				fmt.Fprintf(&buf, " — %s", summary)
+			} else if len(ci.AlsoAt) > 0 {
+				fmt.Fprintf(&buf, " — same as `%s`", ci.AlsoAtNames()[0])
+			}
+			buf.WriteByte('\n')
+			write(ci.Subcommands, depth+1)
+		}
+	}
+	write(list, 0)
+	return buf.String()
+}
@@ -0,0 +1,58 @@
+package marvin
+
+import (
+	"github.com/riking/marvin/slack"
+	"github.com/riking/marvin/util"
+)
+
+// Event handler priorities. Handlers with a higher priority see an event
+// first, and can consume it so that lower priority handlers never see it.
+// Handlers with the same priority run at the same time.
+const (
+	PriorityDefault = 0
+	// PriorityCommand is used by the atcommand module, so that messages
+	// that are commands aren't also handled by modules like bang factoids.
+	PriorityCommand = 100
+)
+
+// EventFilter selects the events an event handler receives. The zero value
+// matches every event.
+type EventFilter struct {
+	// Type is the event type, or "" for all events.
+	Type string
+	// Subtypes restricts the handler to these subtypes. "" is the subtype of
+	// normal messages. Requires Type to be set.
+	Subtypes []string
+	// Channel restricts the handler to events in one channel.
+	Channel slack.ChannelID
+	// User restricts the handler to events caused by one user.
+	User slack.UserID
+	// Bots is TriYes to only receive messages from bots, TriNo to only
+	// receive messages from people, or TriDefault for both.
+	Bots util.TriValue
+
+	Priority int
+	// Observer handlers receive every matching event, even consumed ones,
+	// and do not delay the other handlers. Use this for logging.
+	Observer bool
+}
+
+// An EventHandlerFunc handles an event, and returns true if the event was
+// consumed.
+type EventHandlerFunc func(msg slack.RTMRawMessage) (consumed bool)
+
+// An EventHandle is returned when registering an event handler.
+type EventHandle interface {
+	// Unregister stops the handler from receiving any more events.
+	Unregister()
+}
+
+// EventHandles is a group of handles that are unregistered together.
+type EventHandles []EventHandle
+
+// Unregister unregisters every handle.
+func (h EventHandles) Unregister() {
+	for _, v := range h {
+		v.Unregister()
+	}
+}
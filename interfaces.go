@@ -1,9 +1,11 @@
 package marvin
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/gorilla/mux"
 
@@ -112,6 +114,17 @@ func (e ErrConfNoDefault) Error() string {
 	return fmt.Sprintf("%s had no default set.", e.Key)
 }
 
+// ErrFileTooLarge is returned when a file is over a size limit.
+type ErrFileTooLarge struct {
+	Size  int64
+	Limit int64
+}
+
+// Error implements the error interface.
+func (e ErrFileTooLarge) Error() string {
+	return fmt.Sprintf("file is too large (%d bytes, limit %d)", e.Size, e.Limit)
+}
+
 // AccessLevel represents the level of rights a user has.
 type AccessLevel int
 
@@ -124,6 +137,22 @@ const (
 	AccessLevelController
 )
 
+func (l AccessLevel) String() string {
+	switch l {
+	case AccessLevelBlacklisted:
+		return "blacklisted"
+	case AccessLevelNormal:
+		return "user"
+	case AccessLevelChannelAdmin:
+		return "channel admin"
+	case AccessLevelAdmin:
+		return "admin"
+	case AccessLevelController:
+		return "controller"
+	}
+	return "invalid"
+}
+
 // ActionSource represents the cause of actions or commands.
 type ActionSource interface {
 	UserID() slack.UserID
@@ -140,6 +169,11 @@ type SubCommand interface {
 
 type SubCommandFunc func(t Team, args *CommandArguments) CommandResult
 
+// A CommandObserver is told about every command that finishes running. The
+// elapsed time is zero for commands that were handled without being
+// dispatched, such as simple undos.
+type CommandObserver func(args *CommandArguments, result CommandResult, elapsed time.Duration)
+
 type CommandRegistration interface {
 	RegisterCommand(name string, c SubCommand)
 	RegisterCommandFunc(name string, c SubCommandFunc, help string) SubCommand
@@ -150,9 +184,16 @@ type HTTPDoer interface {
 	Do(*http.Request) (*http.Response, error)
 }
 
+// SendMessage is the set of methods for posting messages. Messages to each
+// channel are sent in order, no faster than about one per second.
 type SendMessage interface {
 	SendMessage(channelID slack.ChannelID, message string) (slack.MessageTS, slack.RTMRawMessage, error)
 	SendComplexMessage(channelID slack.ChannelID, message slack.OutgoingSlackMessage) (slack.MessageTS, slack.RTMRawMessage, error)
+	// QueueMessage sends a message without waiting for it to be posted.
+	// Queued plain text messages may be merged into one.
+	QueueMessage(channelID slack.ChannelID, message slack.OutgoingSlackMessage)
+	// QueueDepth is the number of messages waiting to be sent to a channel.
+	QueueDepth(channelID slack.ChannelID) int
 }
 
 // HasTeam is a type that references a marvin.Team.
@@ -202,7 +243,29 @@ type Team interface {
 	GetAllEnabledModules() []ModuleStatus
 
 	SendMessage
+	// SendEphemeral sends a message in the channel that only the given user
+	// can see. Ephemeral messages cannot be edited or deleted.
+	SendEphemeral(channelID slack.ChannelID, userID slack.UserID, message string) (slack.MessageTS, error)
 	ReactMessage(msgID slack.MessageID, emojiName string) error
+	RemoveReaction(msgID slack.MessageID, emojiName string) error
+	// UpdateMessage replaces the content of a message sent by the bot. The
+	// message is built the same way as SendComplexMessage. Set Blocks to a
+	// non-nil empty slice to remove the blocks of the message.
+	UpdateMessage(msgID slack.MessageID, message slack.OutgoingSlackMessage) error
+	// DeleteMessage deletes a message sent by the bot.
+	DeleteMessage(msgID slack.MessageID) error
+	PinMessage(msgID slack.MessageID) error
+	UnpinMessage(msgID slack.MessageID) error
+	// UploadSnippet uploads text as a snippet and shares it to the channel.
+	UploadSnippet(channel slack.ChannelID, title, content string) (*slack.File, error)
+	// UploadFile uploads a file and shares it to the channels. Set
+	// upload.ThreadTS to share it in a thread.
+	UploadFile(upload slack.FileUpload, channels ...slack.ChannelID) (*slack.File, error)
+	// FileInfo fetches the metadata of a file.
+	FileInfo(file slack.FileID) (*slack.File, error)
+	// DownloadFile fetches the content of a file, including private files.
+	// It returns ErrFileTooLarge if the file is larger than maxSize.
+	DownloadFile(file *slack.File, maxSize int64) ([]byte, error)
 
 	// SlackAPIPost makes a Slack API call by adding the token to the form.  If
 	// the token parameter is already defined, the existing value is used.
@@ -211,15 +274,49 @@ type Team interface {
 
 	ArchiveURL(msgID slack.MessageID) string
 
-	OnEveryEvent(mod ModuleID, f func(slack.RTMRawMessage))
-	OnEvent(mod ModuleID, event string, f func(slack.RTMRawMessage))
-	OnNormalMessage(mod ModuleID, f func(slack.RTMRawMessage))
-	OnSpecialMessage(mod ModuleID, msgSubtype []string, f func(slack.RTMRawMessage))
+	OnEveryEvent(mod ModuleID, f func(slack.RTMRawMessage)) EventHandle
+	OnEvent(mod ModuleID, event string, f func(slack.RTMRawMessage)) EventHandle
+	OnNormalMessage(mod ModuleID, f func(slack.RTMRawMessage)) EventHandle
+	OnSpecialMessage(mod ModuleID, msgSubtype []string, f func(slack.RTMRawMessage)) EventHandle
+	// OnMessage registers a handler for every message event, including
+	// edits and other subtypes.
+	OnMessage(mod ModuleID, f func(*slack.MessageEvent)) EventHandle
+	// OnReaction registers a handler for reaction_added and
+	// reaction_removed events.
+	OnReaction(mod ModuleID, f func(*slack.ReactionEvent)) EventHandle
+	// OnMemberChannel registers a handler for member_joined_channel and
+	// member_left_channel events.
+	OnMemberChannel(mod ModuleID, f func(*slack.MemberChannelEvent)) EventHandle
+	// OnChannelRename registers a handler for channel_rename and
+	// group_rename events.
+	OnChannelRename(mod ModuleID, f func(*slack.ChannelRenameEvent)) EventHandle
+	OnUserChange(mod ModuleID, f func(*slack.UserChangeEvent)) EventHandle
+	// OnPin registers a handler for pin_added and pin_removed events.
+	OnPin(mod ModuleID, f func(*slack.PinEvent)) EventHandle
+	// OnFileShared registers a handler for file_shared events. The file
+	// metadata is fetched before the handler is called.
+	OnFileShared(mod ModuleID, f func(ev *slack.FileSharedEvent, file *slack.File)) EventHandle
+	// OnFilteredEvent registers a handler for the events matching the
+	// filter. The handler can return true to hide the event from handlers
+	// with a lower priority.
+	OnFilteredEvent(mod ModuleID, filter EventFilter, f EventHandlerFunc) EventHandle
+	// OffAllEvents unregisters every event handler and command observer
+	// registered by the module.
 	OffAllEvents(mod ModuleID)
 	GetRTMClient() interface{}
 
 	CommandRegistration
 	DispatchCommand(args *CommandArguments) CommandResult
+	// OnCommand registers a function to be called after every command. The
+	// observer is removed by OffAllEvents.
+	OnCommand(mod ModuleID, f CommandObserver)
+	// ReportCommand passes a command result to the command observers. It is
+	// called by DispatchCommand, and should be called by modules that
+	// finish a command without dispatching it.
+	ReportCommand(args *CommandArguments, result CommandResult, elapsed time.Duration)
+	// CommandTree describes every registered command, for the command
+	// reference.
+	CommandTree() []*CommandInfo
 
 	// Add a new HTTP route handler.
 	HandleHTTP(path string, handler http.Handler) *mux.Route
@@ -238,16 +335,46 @@ type Team interface {
 	ChannelName(channel slack.ChannelID) string
 	FormatChannel(channel slack.ChannelID) string
 	ResolveUserName(input string) slack.UserID
+	// ResolveUserNames resolves a user or a user group to a list of users.
+	// User groups are expanded to their members.
+	ResolveUserNames(input string) []slack.UserID
+	// ResolveUsergroup parses a user group mention, ID, or handle.
+	ResolveUsergroup(input string) slack.UsergroupID
+	// UsergroupInfo returns the user group, or nil if it is not known. The
+	// returned object must not be modified.
+	UsergroupInfo(group slack.UsergroupID) *slack.Usergroup
+	UsergroupMembers(group slack.UsergroupID) []slack.UserID
+	// UserUsergroups returns the user groups the user is a member of.
+	UserUsergroups(user slack.UserID) []slack.UsergroupID
 	UserName(user slack.UserID) string
 	UserLevel(user slack.UserID) AccessLevel
 	GetIM(user slack.UserID) (slack.ChannelID, error)
 	GetIMOtherUser(channel slack.ChannelID) (slack.UserID, error)
+	// ConversationInfo returns information about any kind of conversation.
+	ConversationInfo(channel slack.ChannelID) (*slack.Conversation, error)
+	// InviteToConversation invites users to a public or private channel.
+	InviteToConversation(channel slack.ChannelID, users ...slack.UserID) error
+	// ConversationHistory fetches up to limit messages from a conversation,
+	// newest first, optionally only the ones after oldest.
+	ConversationHistory(channel slack.ChannelID, oldest slack.MessageTS, limit int) ([]json.RawMessage, error)
+	// PublicChannelInfo is ConversationInfo restricted to public channels.
 	PublicChannelInfo(channel slack.ChannelID) (*slack.Channel, error)
+	// PrivateChannelInfo is ConversationInfo restricted to private channels
+	// and MPIMs.
 	PrivateChannelInfo(channel slack.ChannelID) (*slack.Channel, error)
 	ChannelIDByName(chName string) slack.ChannelID
 	ChannelMemberCount(channel slack.ChannelID) int
 	ChannelMemberList(channel slack.ChannelID) []slack.UserID
 	UserInfo(user slack.UserID) (*slack.User, error)
-	// Only supports private channels
+	// UserPresence returns "active" or "away", or "" if the presence of the
+	// user is not known.
+	UserPresence(user slack.UserID) string
+	// LastSeen returns the recorded activity of the user.
+	LastSeen(user slack.UserID) (LastSeen, error)
+	// SetPresenceOptOut stops or resumes the tracking of a user's presence
+	// and activity. Opting out deletes the recorded activity.
+	SetPresenceOptOut(user slack.UserID, optOut bool) error
+	// UserInChannels reports whether the user is in each channel. Channels
+	// with unknown membership are left out of the result.
 	UserInChannels(user slack.UserID, channels ...slack.ChannelID) map[slack.ChannelID]bool
 }
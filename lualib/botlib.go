@@ -4,6 +4,7 @@ import (
 	"net/url"
 	"time"
 
+	"github.com/pkg/errors"
 	"github.com/yuin/gopher-lua"
 
 	"github.com/riking/marvin"
@@ -22,8 +23,44 @@ type pasteAPI interface {
 	URLForLink(id int64) string
 }
 
-func OpenBot(team marvin.Team) func(L *lua.LState) int {
+const (
+	// luaUploadMaxSize is the largest file bot.upload() will send.
+	luaUploadMaxSize = 256 * 1024
+	// luaUploadMaxCount is how many times bot.upload() can be called in one
+	// run.
+	luaUploadMaxCount = 3
+	// luaEditMaxCount is how many times bot.edit() and bot.delete() can be
+	// called in one run.
+	luaEditMaxCount = 5
+)
+
+func OpenBot(g *G) func(L *lua.LState) int {
+	team := g.team
 	pasteModule := team.GetModule("paste").(pasteAPI)
+	uploadCount := 0
+	editCount := 0
+
+	// ownMessage checks the arguments of bot.edit() and bot.delete().
+	ownMessage := func(L *lua.LState, fname string) slack.MessageID {
+		ts := L.CheckString(1)
+		if editCount >= luaEditMaxCount {
+			L.RaiseError("%s() failed: only %d edits are allowed", fname, luaEditMaxCount)
+		}
+		channel := g.actS.ChannelID()
+		if channel == "" || channel[0] == '(' {
+			L.RaiseError("%s() failed: no current channel", fname)
+		}
+		msgID := slack.MsgID(channel, slack.MessageTS(ts))
+		author, err := messageAuthor(team, msgID)
+		if err != nil {
+			L.RaiseError("%s() failed: %s", fname, err)
+		}
+		if author != team.BotUser() {
+			L.RaiseError("%s() failed: can only change my own messages in this channel", fname)
+		}
+		editCount++
+		return msgID
+	}
 
 	return func(L *lua.LState) int {
 		tab := L.NewTable()
@@ -81,8 +118,91 @@ func OpenBot(team marvin.Team) func(L *lua.LState) int {
 			L.Push(lua.LString(pasteURL))
 			return 1
 		}))
+		tab.RawSetString("upload", L.NewFunction(func(L *lua.LState) int {
+			name := L.CheckString(1)
+			content := L.CheckString(2)
+			if len(content) > luaUploadMaxSize {
+				L.RaiseError("upload() failed: %s", marvin.ErrFileTooLarge{Size: int64(len(content)), Limit: luaUploadMaxSize})
+			}
+			if uploadCount >= luaUploadMaxCount {
+				L.RaiseError("upload() failed: only %d uploads are allowed", luaUploadMaxCount)
+			}
+			channel := g.actS.ChannelID()
+			if channel == "" || channel[0] == '(' {
+				L.RaiseError("upload() failed: no channel to upload to")
+			}
+			upload := slack.FileUpload{
+				Filename: name,
+				Title:    name,
+				Content:  []byte(content),
+			}
+			upload.ThreadTS = threadTS(g.actS)
+			uploadCount++
+			file, err := team.UploadFile(upload, channel)
+			if err != nil {
+				L.RaiseError("upload() failed: %s", err)
+			}
+			L.Push(lua.LString(file.Permalink))
+			return 1
+		}))
+		tab.RawSetString("edit", L.NewFunction(func(L *lua.LState) int {
+			msgID := ownMessage(L, "edit")
+			text := L.CheckString(2)
+			err := team.UpdateMessage(msgID, slack.OutgoingSlackMessage{Text: text})
+			if err != nil {
+				L.RaiseError("edit() failed: %s", err)
+			}
+			return 0
+		}))
+		tab.RawSetString("delete", L.NewFunction(func(L *lua.LState) int {
+			msgID := ownMessage(L, "delete")
+			err := team.DeleteMessage(msgID)
+			if err != nil {
+				L.RaiseError("delete() failed: %s", err)
+			}
+			return 0
+		}))
 
 		L.SetGlobal("bot", tab)
 		return 0
 	}
 }
+
+// messageAuthor fetches the message and returns its author.
+func messageAuthor(team marvin.Team, msgID slack.MessageID) (slack.UserID, error) {
+	form := url.Values{
+		"channel":   []string{string(msgID.ChannelID)},
+		"latest":    []string{string(msgID.MessageTS)},
+		"inclusive": []string{"true"},
+		"limit":     []string{"1"},
+	}
+	var response struct {
+		Messages []struct {
+			TS   slack.MessageTS `json:"ts"`
+			User slack.UserID    `json:"user"`
+		} `json:"messages"`
+	}
+	err := team.SlackAPIPostJSON("conversations.history", form, &response)
+	if err != nil {
+		return "", err
+	}
+	if len(response.Messages) == 0 || response.Messages[0].TS != msgID.MessageTS {
+		return "", errors.Errorf("message %s not found", msgID.MessageTS)
+	}
+	return response.Messages[0].User, nil
+}
+
+// threadTS returns the thread the action source is in, if any.
+func threadTS(source marvin.ActionSource) slack.MessageTS {
+	var msg slack.SlackTextMessage
+	switch um := source.(type) {
+	case marvin.ActionSourceUserMessage:
+		msg = um.Msg
+	case *marvin.ActionSourceUserMessage:
+		msg = um.Msg
+	}
+	if raw, ok := msg.(slack.RTMRawMessage); ok {
+		return slack.MessageTS(raw.StringField("thread_ts"))
+	}
+	return ""
+}
@@ -67,7 +67,7 @@ func (g *G) OpenLibraries() {
 	lua.OpenDebug(L)
 
 	OpenBit(L)
-	OpenBot(g.team)(L)
+	OpenBot(g)(L)
 	OpenCorpus(L)
 	OpenFuncs(L)
 	OpenJson(L)
@@ -16,7 +16,8 @@ import (
 //   ch.name: string
 //   ch.creator: LUser
 //   ch.im_other: slack.UserID
-//   ch.users: []slack.UserID
+//   ch.users: []LUser
+//   ch.member_count: number
 //   ch.topic: string
 //   ch.topic_user: LUser
 //   ch.topic_changed: unixMillis
@@ -51,24 +52,20 @@ func (*LChannel) SetupMetatable(L *lua.LState) int {
 
 func LNewChannel(g *G, ch slack.ChannelID) lua.LValue {
 	v := &LChannel{g: g, ID: ch, Info: nil}
-	if ch[0] == 'C' {
+	info, err := g.Team().ConversationInfo(ch)
+	if err != nil {
+		g.L.RaiseError("could not get channel info: %s", err)
+	}
+	switch info.Type() {
+	case slack.ConversationPublic:
 		v.IsPublic = true
-		info, err := g.Team().PublicChannelInfo(ch)
-		if err != nil {
-			g.L.RaiseError("could not get public channel info: %s", err)
-		}
 		v.Info = info
-	} else if ch[0] == 'G' {
+	case slack.ConversationPrivate, slack.ConversationMPIM:
 		v.IsGroup = true
-		info, err := g.Team().PrivateChannelInfo(ch)
-		if err != nil {
-			g.L.RaiseError("could not get private channel info: %s", err)
-		}
 		v.Info = info
-	} else if ch[0] == 'D' {
+	case slack.ConversationIM:
 		v.IsIM = true
-		otherUID, _ := g.Team().GetIMOtherUser(ch)
-		u, _ := LNewUser(g, otherUID, false)
+		u, _ := LNewUser(g, info.User, false)
 		v.IMOther = u
 	}
 
@@ -135,7 +132,7 @@ func luaChannelIndex__Index(L *lua.LState) int {
 
 	// vischeck the channel
 	// this has an timing attack showing the channel exists but not what's in it
-	if chID[0] == 'G' {
+	if info, err := g.Team().ConversationInfo(slack.ChannelID(chID)); err != nil || !info.IsPublicChannel() {
 		memberMap := g.Team().UserInChannels(g.ActionSource().UserID(), slack.ChannelID(chID))
 		if !memberMap[slack.ChannelID(chID)] {
 			// vischeck failed
@@ -225,6 +222,9 @@ func luaChannel__Index(L *lua.LState) int {
 		}
 		L.Push(lc.Users)
 		return 1
+	case "member_count":
+		L.Push(lua.LNumber(lc.g.Team().ChannelMemberCount(lc.ID)))
+		return 1
 	case "mention":
 		L.Push(lua.LString(lc.g.Team().FormatChannel(lc.ID)))
 		return 1
@@ -266,7 +266,7 @@ func luaChannel__Index(L *lua.LState) int {
 		L.Push(u)
 		return 1
 	default:
-		L.RaiseError("no such member %s in Channel (have: id type name mention topic purpose)", key)
+		L.RaiseError("no such member %s in Channel (have: id type name creator users member_count mention topic purpose)", key)
 		return 0
 	}
 }
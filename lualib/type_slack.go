@@ -42,5 +42,21 @@ func LNewTeam(g *G) lua.LValue {
 		//g.Team().SendComplexMessage()
 		return 0
 	}))
+	tab.RawSetString("ephemeral", g.L.NewFunction(func(L *lua.LState) int {
+		text := L.CheckString(1)
+		source := g.ActionSource()
+		switch source.(type) {
+		case marvin.ActionSourceUserMessage, *marvin.ActionSourceUserMessage:
+		default:
+			L.RaiseError("ephemeral() can only be used from a Slack message")
+			return 0
+		}
+		_, err := g.Team().SendEphemeral(source.ChannelID(), source.UserID(), text)
+		if err != nil {
+			L.RaiseError("%s", err.Error())
+			return 0
+		}
+		return 0
+	}))
 	return tab
 }
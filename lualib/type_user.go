@@ -15,6 +15,7 @@ import (
 //   user.is_controller
 //   user.username -> "slackbot"
 //    fname, lname, name
+//   user.presence -> "active", "away", or "" if unknown
 //   user.tz -> "America/Los_Angeles"
 //   user.tz_offset -> -28800 (seconds)
 //   user.profile.real
@@ -229,6 +230,9 @@ func luaUser__Index(L *lua.LState) int {
 			L.Push(lua.LBool(u.Info.Deleted))
 		}
 		return 1
+	case "presence":
+		L.Push(lua.LString(u.g.Team().UserPresence(u.ID)))
+		return 1
 	default:
 		L.RaiseError("no such field %s in User", key)
 		return 0
@@ -251,7 +255,7 @@ func luaUser_sendMessage(L *lua.LState) int {
 	if err != nil {
 		return 0
 	}
-	go g.Team().SendMessage(imCh, msg)
+	g.Team().QueueMessage(imCh, slack.OutgoingSlackMessage{Text: msg})
 
 	L.Push(lua.LTrue)
 	return 1
@@ -3,6 +3,7 @@ package _all
 
 import (
 	_ "github.com/riking/marvin/modules/atcommand"
+	_ "github.com/riking/marvin/modules/audit"
 	_ "github.com/riking/marvin/modules/autoinvite"
 	_ "github.com/riking/marvin/modules/autoresponse"
 	_ "github.com/riking/marvin/modules/awake"
@@ -15,6 +16,8 @@ import (
 	_ "github.com/riking/marvin/modules/paste"
 	_ "github.com/riking/marvin/modules/restart"
 	_ "github.com/riking/marvin/modules/rss"
+	_ "github.com/riking/marvin/modules/schedule"
+	_ "github.com/riking/marvin/modules/seen"
 	_ "github.com/riking/marvin/modules/timedpin"
 	_ "github.com/riking/marvin/modules/weblogin"
 )
@@ -3,7 +3,6 @@ package atcommand
 import (
 	"context"
 	"fmt"
-	"net/url"
 	"reflect"
 	"regexp"
 	"strconv"
@@ -14,6 +13,8 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/riking/marvin"
+	"github.com/riking/marvin/modules/on_reaction"
+	"github.com/riking/marvin/modules/paste"
 	"github.com/riking/marvin/slack"
 	"github.com/riking/marvin/util"
 )
@@ -34,12 +35,24 @@ type AtCommandModule struct {
 
 	recentCommandsLock sync.Mutex
 	recentCommands     map[slack.MessageID]*FinishedCommandInfo
+
+	onReact  marvin.Module
+	pasteMod marvin.Module
+
+	rerunLock sync.Mutex
+	rerunDone map[slack.MessageID]time.Time
+
+	confirmLock     sync.Mutex
+	pendingConfirms map[slack.MessageID]*pendingConfirm
 }
 
 func NewAtCommandModule(t marvin.Team) marvin.Module {
 	mod := &AtCommandModule{
 		team:           t,
 		recentCommands: make(map[slack.MessageID]*FinishedCommandInfo),
+		rerunDone:      make(map[slack.MessageID]time.Time),
+
+		pendingConfirms: make(map[slack.MessageID]*pendingConfirm),
 	}
 	return mod
 }
@@ -49,6 +62,15 @@ func (mod *AtCommandModule) Identifier() marvin.ModuleID {
 }
 
 func (mod *AtCommandModule) Load(t marvin.Team) {
+	t.DependModule(mod, on_reaction.Identifier, &mod.onReact)
+	t.DependModule(mod, paste.Identifier, &mod.pasteMod)
+	t.DB().MustMigrate(Identifier, 1489276800, sqlMigrate1, sqlMigrate1b)
+	t.DB().SyntaxCheck(
+		sqlSaveCommand,
+		sqlLoadCommand,
+		sqlExpireCommands,
+	)
+
 	mod.mentionRgx1 = regexp.MustCompile(fmt.Sprintf(`<@%s>`, mod.team.BotUser()))
 	mod.mentionRgx2 = regexp.MustCompile(fmt.Sprintf(`(?m:(?:\n|^)\s*(<@%s>)\s+())`, mod.team.BotUser()))
 
@@ -60,12 +82,26 @@ func (mod *AtCommandModule) Load(t marvin.Team) {
 	c.Add(confKeyEmojiUnkCmd, "question")
 	c.Add(confKeyEmojiUsage, "confused")
 	c.Add(confKeyEmojiHelp, "memo")
+	c.Add(confKeyEmojiRerun, "repeat")
+	c.Add(confKeyEmojiPending, "hourglass_flowing_sand")
+	c.Add(confKeyEmojiConfirm, "white_check_mark")
+	c.Add(confKeyEmojiCancel, "x")
+	c.Add(confKeyConfirmTimeout, "5m")
+	c.Add(confKeyRetention, "6h")
+	c.Add(confKeyEphemeral, "true")
 }
 
 func (mod *AtCommandModule) Enable(t marvin.Team) {
 	t.OnEvent(Identifier, "hello", mod.OnHello)
-	t.OnNormalMessage(Identifier, mod.HandleMessage)
+	t.OnFilteredEvent(Identifier, marvin.EventFilter{
+		Type:     "message",
+		Subtypes: []string{""},
+		Priority: marvin.PriorityCommand,
+	}, mod.ClaimMessage)
 	t.OnSpecialMessage(Identifier, []string{"message_changed", "message_deleted"}, mod.HandleEdit)
+	if mod.onReact != nil {
+		mod.onReact.(on_reaction.API).RegisterHandler(mod, Identifier)
+	}
 	mod.enabled += 1
 	go mod.janitorRecentMessages(mod.enabled)
 }
@@ -73,6 +109,9 @@ func (mod *AtCommandModule) Enable(t marvin.Team) {
 func (mod *AtCommandModule) Disable(t marvin.Team) {
 	mod.enabled += 1
 	t.OffAllEvents(Identifier)
+	if mod.onReact != nil {
+		mod.onReact.(on_reaction.API).Unregister(Identifier)
+	}
 }
 
 // -----
@@ -85,6 +124,15 @@ const (
 	confKeyEmojiUnkCmd = "emoji-unknown"
 	confKeyEmojiUsage  = "emoji-usage"
 	confKeyEmojiHelp   = "emoji-help"
+	confKeyEmojiRerun  = "emoji-rerun"
+
+	confKeyEmojiPending   = "emoji-pending"
+	confKeyEmojiConfirm   = "emoji-confirm"
+	confKeyEmojiCancel    = "emoji-cancel"
+	confKeyConfirmTimeout = "confirm-timeout"
+
+	confKeyRetention = "edit-retention"
+	confKeyEphemeral = "ephemeral-replies"
 )
 
 func (mod *AtCommandModule) OnHello(_rtm slack.RTMRawMessage) {
@@ -100,30 +148,41 @@ type ReplyActionEmoji struct {
 }
 
 func (rae ReplyActionEmoji) Undo(mod *AtCommandModule) {
-	form := url.Values{
-		"name":      []string{rae.Emoji},
-		"channel":   []string{string(rae.MessageID.ChannelID)},
-		"timestamp": []string{string(rae.MessageID.MessageTS)},
-	}
-	util.LogIfError(mod.team.SlackAPIPostJSON("reactions.remove", form, nil))
+	util.LogIfError(mod.team.RemoveReaction(rae.MessageID, rae.Emoji))
 }
 
 type ReplyActionSentMessage struct {
 	MessageID slack.MessageID
 	Text      string
+	// HasBlocks is set if the message was sent with Block Kit blocks.
+	HasBlocks bool
 }
 
 func (rsm ReplyActionSentMessage) Update(mod *AtCommandModule, newText string) error {
+	return rsm.UpdateBlocks(mod, newText, nil)
+}
+
+// UpdateBlocks replaces the text and blocks of the message. Any blocks the
+// message had are removed if blocks is empty.
+func (rsm ReplyActionSentMessage) UpdateBlocks(mod *AtCommandModule, newText string, blocks []slack.Block) error {
 	if rsm.Text == "" {
 		return nil
 	}
-	form := url.Values{
-		"channel": []string{string(rsm.MessageID.ChannelID)},
-		"ts":      []string{string(rsm.MessageID.MessageTS)},
-		"text":    []string{newText},
-		"parse":   []string{"client"},
+	message := slack.OutgoingSlackMessage{Text: newText, Blocks: blocks}
+	if len(blocks) == 0 && rsm.HasBlocks {
+		message.Blocks = []slack.Block{}
 	}
-	return util.LogIfError(mod.team.SlackAPIPostJSON("chat.update", form, nil))
+	return util.LogIfError(mod.team.UpdateMessage(rsm.MessageID, message))
+}
+
+// sendReply sends a command reply, with blocks if there are any.
+func (mod *AtCommandModule) sendReply(channel slack.ChannelID, text string, blocks []slack.Block) (slack.MessageTS, error) {
+	if len(blocks) == 0 {
+		ts, _, err := mod.team.SendMessage(channel, text)
+		return ts, err
+	}
+	ts, _, err := mod.team.SendComplexMessage(channel, slack.OutgoingSlackMessage{Text: text, Blocks: blocks})
+	return ts, err
 }
 
 type FinishedCommandInfo struct {
@@ -186,14 +245,25 @@ func (mod *AtCommandModule) janitorRecentMessages(epoch int) {
 }
 
 func (mod *AtCommandModule) _cleanRecentMessages() {
+	mod.expireCommandInfo()
+
 	mod.recentCommandsLock.Lock()
 	defer mod.recentCommandsLock.Unlock()
-	threshold := time.Now().Add(-6 * time.Hour)
+	threshold := time.Now().Add(-mod.commandRetention())
 	for k, v := range mod.recentCommands {
 		if v.MyTimestamp.Before(threshold) {
 			delete(mod.recentCommands, k)
 		}
 	}
+
+	mod.rerunLock.Lock()
+	defer mod.rerunLock.Unlock()
+	rerunThreshold := time.Now().Add(-rerunExpiry)
+	for k, v := range mod.rerunDone {
+		if v.Before(rerunThreshold) {
+			delete(mod.rerunDone, k)
+		}
+	}
 }
 
 type parseMessageReturn struct {
@@ -242,6 +312,25 @@ func (mod *AtCommandModule) ParseMessage(rtm slack.SlackTextMessage) (result par
 	return result
 }
 
+// ClaimMessage consumes messages that are commands, so that lower priority
+// handlers like bang factoids don't also act on them. The command is run in
+// the background.
+func (mod *AtCommandModule) ClaimMessage(_rtm slack.RTMRawMessage) bool {
+	rtm := slack.SlackTextMessage(_rtm)
+	if !rtm.AssertText() {
+		return false
+	}
+	if _, isThread := _rtm["thread_ts"]; isThread {
+		return false
+	}
+	parseResult := mod.ParseMessage(rtm)
+	if !parseResult.wave && parseResult.argSplit == nil && parseResult.splitErr == nil {
+		return false
+	}
+	go mod.HandleMessage(_rtm)
+	return true
+}
+
 func (mod *AtCommandModule) HandleMessage(_rtm slack.RTMRawMessage) {
 	fciResult := &FinishedCommandInfo{MyTimestamp: time.Now()}
 	fciResult.Lock.Lock()
@@ -268,6 +357,7 @@ func (mod *AtCommandModule) HandleMessage(_rtm slack.RTMRawMessage) {
 		mod.recentCommandsLock.Lock()
 		mod.recentCommands[_rtm.MessageID()] = fciResult
 		mod.recentCommandsLock.Unlock()
+		defer mod.saveCommandInfo(fciResult)
 
 		reactEmoji, _ := mod.team.ModuleConfig(Identifier).Get(confKeyEmojiHi)
 		fciResult.AddEmojiReaction(rtm.MessageID(), reactEmoji)
@@ -277,6 +367,7 @@ func (mod *AtCommandModule) HandleMessage(_rtm slack.RTMRawMessage) {
 		mod.recentCommandsLock.Lock()
 		mod.recentCommands[_rtm.MessageID()] = fciResult
 		mod.recentCommandsLock.Unlock()
+		defer mod.saveCommandInfo(fciResult)
 
 		fciResult.FoundCommand = true
 		// continue
@@ -293,7 +384,10 @@ func (mod *AtCommandModule) HandleMessage(_rtm slack.RTMRawMessage) {
 }
 
 func (mod *AtCommandModule) HandleEdit(_rtm slack.RTMRawMessage) {
-	if _rtm.Subtype() != "message_changed" {
+	if _rtm.Subtype() == "message_deleted" {
+		mod.HandleDelete(_rtm)
+		return
+	} else if _rtm.Subtype() != "message_changed" {
 		return
 	}
 	rtm := slack.EditMessage{RTMRawMessage: _rtm}
@@ -311,17 +405,14 @@ func (mod *AtCommandModule) HandleEdit(_rtm slack.RTMRawMessage) {
 	msgID := rtm.MessageID()
 	time.Sleep(50 * time.Millisecond) // slack is out-of-order sometimes
 
-	mod.recentCommandsLock.Lock()
-	fciMeta, ok := mod.recentCommands[msgID]
-	mod.recentCommandsLock.Unlock()
-
+	fciMeta, ok := mod.getCommandInfo(msgID)
 	if !ok {
 		parseResult := mod.ParseMessage(rtm)
 		if parseResult.argSplit != nil {
 			imChannel, _ := mod.team.GetIM(rtm.EditingUserID())
 			mod.team.SendMessage(imChannel, fmt.Sprintf(
-				"Oops, I seem to have forgotten about that one. I can only cope with edits of recent messages. %s",
-				mod.team.ArchiveURL(rtm.MessageID())))
+				"Oops, I seem to have forgotten about that one. I can only cope with edits of messages from the last %s. %s",
+				mod.commandRetention(), mod.team.ArchiveURL(rtm.MessageID())))
 		}
 		return
 	}
@@ -329,6 +420,7 @@ func (mod *AtCommandModule) HandleEdit(_rtm slack.RTMRawMessage) {
 	util.LogDebug("Got edit to command message", mod.team.ArchiveURL(msgID))
 	fciMeta.Lock.Lock()
 	defer fciMeta.Lock.Unlock()
+	defer mod.saveCommandInfo(fciMeta)
 
 	if fciMeta.LatestEdit != nil {
 		// TODO something??
@@ -365,20 +457,44 @@ func (mod *AtCommandModule) HandleEdit(_rtm slack.RTMRawMessage) {
 
 	if myFoundCommand == false {
 		mod.UndoCommand(fciMeta, marvin.ActionSourceUserMessage{Team: mod.team, Msg: rtm})
+	} else if mod.canEdit(fciMeta) {
+		mod.EditCommand(fciMeta, marvin.ActionSourceUserMessage{Team: mod.team, Msg: rtm})
+	} else if canUndo, _ := mod.canUndo(fciMeta); canUndo {
+		// Edits are not supported, so undo and run the new command
+		mod.UndoCommand(fciMeta, marvin.ActionSourceUserMessage{Team: mod.team, Msg: rtm})
+		fciMeta.FoundCommand = true
+		mod.ProcessInitialCommandMessage(fciMeta, rtm)
 	} else {
-		canEdit := mod.canEdit(fciMeta)
-		if !canEdit {
-			canUndo, _ := mod.canUndo(fciMeta)
-			if canUndo {
-				mod.UndoCommand(fciMeta, marvin.ActionSourceUserMessage{Team: mod.team, Msg: rtm})
-				fciMeta.FoundCommand = true
-				mod.ProcessInitialCommandMessage(fciMeta, rtm)
-			}
-		}
+		// Tells the user that editing is not supported
 		mod.EditCommand(fciMeta, marvin.ActionSourceUserMessage{Team: mod.team, Msg: rtm})
 	}
 }
 
+// HandleDelete undoes a command when its message is deleted.
+func (mod *AtCommandModule) HandleDelete(_rtm slack.RTMRawMessage) {
+	var msg struct {
+		Channel   slack.ChannelID `json:"channel"`
+		DeletedTS slack.MessageTS `json:"deleted_ts"`
+	}
+	_rtm.ReMarshal(&msg)
+	msgID := slack.MsgID(msg.Channel, msg.DeletedTS)
+	time.Sleep(50 * time.Millisecond) // slack is out-of-order sometimes
+
+	fciMeta, ok := mod.getCommandInfo(msgID)
+	if !ok {
+		return
+	}
+	fciMeta.Lock.Lock()
+	defer fciMeta.Lock.Unlock()
+	defer mod.saveCommandInfo(fciMeta)
+
+	if !fciMeta.FoundCommand {
+		return
+	}
+	util.LogDebug("Got deletion of command message", mod.team.ArchiveURL(msgID))
+	mod.UndoCommand(fciMeta, marvin.ActionSourceUserMessage{Team: mod.team, Msg: slack.SlackTextMessage(fciMeta.OriginalMsg)})
+}
+
 func (mod *AtCommandModule) canEdit(fciMeta *FinishedCommandInfo) (canEdit bool) {
 
 	if fciMeta.CommandResult.CanEdit == util.TriYes {
@@ -396,6 +512,8 @@ func (mod *AtCommandModule) canEdit(fciMeta *FinishedCommandInfo) (canEdit bool)
 			return false // error
 		case marvin.CmdResultNoSuchCommand, marvin.CmdResultPrintUsage, marvin.CmdResultPrintHelp:
 			return true
+		case marvin.CmdResultConfirm:
+			return false
 		}
 	}
 	panic("unrecognized command result code")
@@ -406,13 +524,13 @@ func (mod *AtCommandModule) canUndo(fciMeta *FinishedCommandInfo) (canUndo, cust
 		return false, false
 	}
 
-	if fciMeta.CommandResult.CanEdit == util.TriNo {
+	if fciMeta.CommandResult.CanUndo == util.TriNo {
 		// Undo not supported
 		return false, false
-	} else if fciMeta.CommandResult.CanEdit == marvin.UndoCustom {
+	} else if fciMeta.CommandResult.CanUndo == marvin.UndoCustom {
 		// Custom undo
 		return true, true
-	} else if fciMeta.CommandResult.CanEdit == marvin.UndoSimple {
+	} else if fciMeta.CommandResult.CanUndo == marvin.UndoSimple {
 		return true, false
 	} else { // TriDefault
 		switch fciMeta.CommandResult.Code {
@@ -425,6 +543,8 @@ func (mod *AtCommandModule) canUndo(fciMeta *FinishedCommandInfo) (canUndo, cust
 			return false, false // error
 		case marvin.CmdResultNoSuchCommand, marvin.CmdResultPrintUsage, marvin.CmdResultPrintHelp:
 			return true, false
+		case marvin.CmdResultConfirm:
+			return false, false
 		}
 	}
 	panic("unrecognized command result code")
@@ -483,28 +603,30 @@ func (mod *AtCommandModule) EditCommand(fciMeta *FinishedCommandInfo, source mar
 
 	didSendMessageChannel := false
 	didSendMessageIM := false
-	sendMessageChannel := func(msg string) {
+	sendMessageChannel := func(msg string, blocks []slack.Block) {
 		didSendMessageChannel = true
 		if fciMeta.ActionChanMsg.Text != "" {
-			fciMeta.ActionChanMsg.Update(mod, msg)
+			fciMeta.ActionChanMsg.UpdateBlocks(mod, msg, blocks)
+			fciMeta.ActionChanMsg.HasBlocks = len(blocks) > 0
 		} else {
-			ts, _, err := mod.team.SendMessage(source.ChannelID(), SanitizeForChannel(msg))
+			ts, err := mod.sendReply(source.ChannelID(), SanitizeForChannel(msg), blocks)
 			if err != nil {
 				util.LogError(err)
 			}
-			fciMeta.ActionChanMsg = ReplyActionSentMessage{MessageID: slack.MsgID(imChannel, ts), Text: msg}
+			fciMeta.ActionChanMsg = ReplyActionSentMessage{MessageID: slack.MsgID(imChannel, ts), Text: msg, HasBlocks: len(blocks) > 0}
 		}
 	}
-	sendMessageIM := func(msg string) {
+	sendMessageIM := func(msg string, blocks []slack.Block) {
 		didSendMessageIM = true
 		if fciMeta.ActionPMMsg.Text != "" {
-			fciMeta.ActionPMMsg.Update(mod, msg)
+			fciMeta.ActionPMMsg.UpdateBlocks(mod, msg, blocks)
+			fciMeta.ActionPMMsg.HasBlocks = len(blocks) > 0
 		} else {
-			ts, _, err := mod.team.SendMessage(imChannel, SanitizeLoose(msg))
+			ts, err := mod.sendReply(imChannel, SanitizeLoose(msg), blocks)
 			if err != nil {
 				util.LogError(err)
 			}
-			fciMeta.ActionPMMsg = ReplyActionSentMessage{MessageID: slack.MsgID(imChannel, ts), Text: msg}
+			fciMeta.ActionPMMsg = ReplyActionSentMessage{MessageID: slack.MsgID(imChannel, ts), Text: msg, HasBlocks: len(blocks) > 0}
 		}
 	}
 	sendMessageIMLog := func(msg string) {
@@ -560,6 +682,16 @@ func (mod *AtCommandModule) UndoCommand(fciMeta *FinishedCommandInfo, source mar
 		fciMeta.ActionPMMsg.Update(mod, "(removed)")
 		newEmoji = append(newEmoji, ReplyActionEmoji{MessageID: fciMeta.OriginalMsg.MessageID(), Emoji: "leftwards_arrow_with_hook"})
 		fciMeta.ChangeEmoji(mod, newEmoji)
+
+		args := &marvin.CommandArguments{
+			OriginalArguments: fciMeta.parseResult.argSplit,
+			Arguments:         fciMeta.parseResult.argSplit,
+			Source:            source,
+
+			PreviousResult: &fciMeta.CommandResult,
+			IsUndo:         true,
+		}
+		mod.team.ReportCommand(args, marvin.CmdSuccess(args, ""), 0)
 		return
 	}
 	if !customUndo {
@@ -573,17 +705,11 @@ func (mod *AtCommandModule) UndoCommand(fciMeta *FinishedCommandInfo, source mar
 	}
 
 	imChannel, _ := mod.team.GetIM(source.UserID())
-	mod.team.SendMessage(imChannel, fmt.Sprintf(
-		"TODO - custom undo support. %s", source.ArchiveLink()))
-	mod.team.ReactMessage(fciMeta.OriginalMsg.MessageID(), "x")
-	fciMeta.AddEmojiReaction(fciMeta.OriginalMsg.MessageID(), "x")
-	return // TODO
-
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
 	defer cancel()
 	args := &marvin.CommandArguments{
-		OriginalArguments: fciMeta.CommandArgs.OriginalArguments,
-		Arguments:         fciMeta.CommandArgs.OriginalArguments,
+		OriginalArguments: fciMeta.parseResult.argSplit,
+		Arguments:         fciMeta.parseResult.argSplit,
 		Command:           "",
 		Source:            source,
 		Ctx:               ctx,
@@ -601,27 +727,29 @@ func (mod *AtCommandModule) UndoCommand(fciMeta *FinishedCommandInfo, source mar
 
 	logChannel := mod.team.TeamConfig().LogChannel
 	didSendMessageChannel := false
-	sendMessageChannel := func(msg string) {
+	sendMessageChannel := func(msg string, blocks []slack.Block) {
 		if fciMeta.ActionChanMsg.Text != "" {
 			didSendMessageChannel = true
-			fciMeta.ActionChanMsg.Update(mod, msg)
+			fciMeta.ActionChanMsg.UpdateBlocks(mod, msg, blocks)
+			fciMeta.ActionChanMsg.HasBlocks = len(blocks) > 0
 		} else {
-			ts, _, err := mod.team.SendMessage(source.ChannelID(), SanitizeForChannel(msg))
+			ts, err := mod.sendReply(source.ChannelID(), SanitizeForChannel(msg), blocks)
 			if err != nil {
 				util.LogError(err)
 			}
-			fciMeta.ActionChanMsg = ReplyActionSentMessage{MessageID: slack.MsgID(imChannel, ts), Text: msg}
+			fciMeta.ActionChanMsg = ReplyActionSentMessage{MessageID: slack.MsgID(imChannel, ts), Text: msg, HasBlocks: len(blocks) > 0}
 		}
 	}
-	sendMessageIM := func(msg string) {
+	sendMessageIM := func(msg string, blocks []slack.Block) {
 		if fciMeta.ActionPMMsg.Text != "" {
-			fciMeta.ActionPMMsg.Update(mod, msg)
+			fciMeta.ActionPMMsg.UpdateBlocks(mod, msg, blocks)
+			fciMeta.ActionPMMsg.HasBlocks = len(blocks) > 0
 		} else {
-			ts, _, err := mod.team.SendMessage(imChannel, SanitizeLoose(msg))
+			ts, err := mod.sendReply(imChannel, SanitizeLoose(msg), blocks)
 			if err != nil {
 				util.LogError(err)
 			}
-			fciMeta.ActionPMMsg = ReplyActionSentMessage{MessageID: slack.MsgID(imChannel, ts), Text: msg}
+			fciMeta.ActionPMMsg = ReplyActionSentMessage{MessageID: slack.MsgID(imChannel, ts), Text: msg, HasBlocks: len(blocks) > 0}
 		}
 	}
 	sendMessageIMLog := func(msg string) {
@@ -679,20 +807,20 @@ func (mod *AtCommandModule) ProcessInitialCommandMessage(fciResult *FinishedComm
 
 	logChannel := mod.team.TeamConfig().LogChannel
 	imChannel, _ := mod.team.GetIM(rtm.UserID())
-	sendMessageChannel := func(msg string) {
-		ts, _, err := mod.team.SendMessage(rtm.ChannelID(), SanitizeForChannel(msg))
+	sendMessageChannel := func(msg string, blocks []slack.Block) {
+		ts, err := mod.sendReply(rtm.ChannelID(), SanitizeForChannel(msg), blocks)
 		if err != nil {
 			util.LogError(err)
 		} else {
-			fciResult.ActionChanMsg = ReplyActionSentMessage{Text: msg, MessageID: slack.MessageID{ChannelID: rtm.ChannelID(), MessageTS: ts}}
+			fciResult.ActionChanMsg = ReplyActionSentMessage{Text: msg, MessageID: slack.MessageID{ChannelID: rtm.ChannelID(), MessageTS: ts}, HasBlocks: len(blocks) > 0}
 		}
 	}
-	sendMessageIM := func(msg string) {
-		ts, _, err := mod.team.SendMessage(imChannel, SanitizeLoose(msg))
+	sendMessageIM := func(msg string, blocks []slack.Block) {
+		ts, err := mod.sendReply(imChannel, SanitizeLoose(msg), blocks)
 		if err != nil {
 			util.LogError(err)
 		} else {
-			fciResult.ActionPMMsg = ReplyActionSentMessage{MessageID: slack.MsgID(imChannel, ts), Text: msg}
+			fciResult.ActionPMMsg = ReplyActionSentMessage{MessageID: slack.MsgID(imChannel, ts), Text: msg, HasBlocks: len(blocks) > 0}
 		}
 	}
 	sendMessageIMLog := func(msg string) {
@@ -713,13 +841,61 @@ func (mod *AtCommandModule) ProcessInitialCommandMessage(fciResult *FinishedComm
 	}
 
 	mod.SendReplyMessages(result, source, rtm.ChannelID() == imChannel, sendMessageChannel, sendMessageIM, sendMessageIMLog, sendMessageLog)
+
+	if result.Code == marvin.CmdResultNoSuchCommand && len(result.Suggestions) > 0 &&
+		fciResult.ActionPMLogMsg.MessageID.MessageTS != "" {
+		mod.offerSuggestion(fciResult.ActionPMLogMsg.MessageID, source, result.Suggestions[0])
+	}
+	if result.Code == marvin.CmdResultConfirm {
+		prompt := fciResult.ActionChanMsg
+		if prompt.MessageID.MessageTS == "" {
+			prompt = fciResult.ActionPMMsg
+		}
+		mod.awaitConfirmation(prompt, source, result)
+	}
+}
+
+// SendReplies sends the replies for a command result that was not triggered
+// by a message, such as a scheduled or re-run command.
+func (mod *AtCommandModule) SendReplies(result marvin.CommandResult, source marvin.ActionSource) {
+	logChannel := mod.team.TeamConfig().LogChannel
+	imChannel, _ := mod.team.GetIM(source.UserID())
+	var chanMsg, pmMsg ReplyActionSentMessage
+	send := func(channel slack.ChannelID, sanitize func(string) string, sent *ReplyActionSentMessage) func(string, []slack.Block) {
+		return func(msg string, blocks []slack.Block) {
+			ts, err := mod.sendReply(channel, sanitize(msg), blocks)
+			if err != nil {
+				util.LogError(err)
+			} else if sent != nil {
+				*sent = ReplyActionSentMessage{MessageID: slack.MsgID(channel, ts), Text: msg, HasBlocks: len(blocks) > 0}
+			}
+		}
+	}
+	sendText := func(channel slack.ChannelID, sanitize func(string) string) func(string) {
+		f := send(channel, sanitize, nil)
+		return func(msg string) { f(msg, nil) }
+	}
+
+	mod.SendReplyMessages(result, source, source.ChannelID() == imChannel,
+		send(source.ChannelID(), SanitizeForChannel, &chanMsg),
+		send(imChannel, SanitizeLoose, &pmMsg),
+		sendText(imChannel, SanitizeLoose),
+		sendText(logChannel, SanitizeForChannel))
+
+	if result.Code == marvin.CmdResultConfirm {
+		if chanMsg.MessageID.MessageTS == "" {
+			chanMsg = pmMsg
+		}
+		mod.awaitConfirmation(chanMsg, source, result)
+	}
 }
 
 func (mod *AtCommandModule) SendReplyMessages(
 	result marvin.CommandResult,
 	source marvin.ActionSource,
 	isChannelIMChannel bool,
-	sendMessageChannel, sendMessageIM, sendMessageIMLog, sendMessageLog func(string),
+	sendMessageChannel, sendMessageIM func(string, []slack.Block),
+	sendMessageIMLog, sendMessageLog func(string),
 ) {
 	replyType := marvin.ReplyTypeInvalid
 	switch result.Code {
@@ -727,14 +903,29 @@ func (mod *AtCommandModule) SendReplyMessages(
 		replyType = marvin.ReplyTypeInChannel
 	case marvin.CmdResultFailure:
 		replyType = marvin.ReplyTypeShortProblem
+		if mod.ephemeralEnabled() && len(result.Message) <= marvin.LongReplyThreshold {
+			replyType = marvin.ReplyTypeEphemeral | marvin.ReplyTypeLog
+		}
 	case marvin.CmdResultError:
 		replyType = marvin.ReplyTypeShortProblem
 	case marvin.CmdResultNoSuchCommand:
 		replyType = marvin.ReplyTypePM
+		// Reactions can't be added to ephemeral messages, so keep the PM
+		// when the suggestion can be re-run with a reaction.
+		_, canRerun := source.(marvin.ActionSourceUserMessage)
+		canRerun = canRerun && mod.onReact != nil && len(result.Suggestions) > 0
+		if mod.ephemeralEnabled() && !canRerun {
+			replyType = marvin.ReplyTypeEphemeral
+		}
 	case marvin.CmdResultPrintUsage:
 		replyType = marvin.ReplyTypePM
+		if mod.ephemeralEnabled() {
+			replyType = marvin.ReplyTypeEphemeral
+		}
 	case marvin.CmdResultPrintHelp:
 		replyType = marvin.ReplyTypeInChannel
+	case marvin.CmdResultConfirm:
+		replyType = marvin.ReplyTypeInChannel
 	default:
 		replyType = marvin.ReplyTypeShortProblem
 	}
@@ -749,14 +940,29 @@ func (mod *AtCommandModule) SendReplyMessages(
 	replyIM := result.ReplyType&marvin.ReplyTypePM != 0
 	// Post in the logging channel
 	replyLog := result.ReplyType&marvin.ReplyTypeLog != 0
+	// Show in the channel to the sender only
+	replyEphemeral := result.ReplyType&marvin.ReplyTypeEphemeral != 0
 
 	// Message was sent from a DM; do not include archive link
 	replyIMPrimary := false
 
-	if (replyChannel || replyIM) && isChannelIMChannel {
+	if result.Code == marvin.CmdResultConfirm {
+		result.Message = result.Message + "\n" + mod.confirmHint()
+	}
+	if result.Message == "" && len(result.Blocks) > 0 {
+		result.Message = slack.BlocksFallback(result.Blocks)
+	}
+
+	if (replyChannel || replyIM || replyEphemeral) && isChannelIMChannel {
 		replyIMPrimary = true
 		replyIM = false
 		replyChannel = false
+		replyEphemeral = false
+	}
+	sendEphemeral := func(msg string) {
+		if !mod.sendEphemeral(source, msg) {
+			sendMessageIM(msg, nil)
+		}
 	}
 
 	switch result.Code {
@@ -769,20 +975,28 @@ func (mod *AtCommandModule) SendReplyMessages(
 		// Prefer Channel > PM > Log
 		if replyChannel {
 			channelMsg := result.Message
-			if len(result.Message) > marvin.LongReplyThreshold {
-				channelMsg = "[Reply truncated]\n" + util.PreviewString(result.Message, marvin.LongReplyCut) + "…\n"
-				replyIM = true
+			shared := false
+			if len(result.Blocks) == 0 && len(result.Message) > marvin.LongReplyThreshold {
+				var stored bool
+				channelMsg, shared, stored = mod.longReply(source.ChannelID(), result)
+				if !stored {
+					replyIM = true
+				}
 			}
 			if result.ReplyType&marvin.ReplyTypeFlagOmitUsername == 0 {
 				channelMsg = fmt.Sprintf("%v: %s", source.UserID(), channelMsg)
 			}
-			sendMessageChannel(channelMsg)
+			if !shared {
+				sendMessageChannel(channelMsg, result.Blocks)
+			}
+		} else if replyEphemeral {
+			sendEphemeral(result.Message)
 		}
 		if replyIMPrimary {
-			sendMessageIM(result.Message)
+			sendMessageIM(result.Message, result.Blocks)
 		}
 		if replyIM {
-			sendMessageIM(result.Message)
+			sendMessageIM(result.Message, result.Blocks)
 		}
 		if replyLog {
 			sendMessageLog(fmt.Sprintf("%s\n%s", result.Message, source.ArchiveLink()))
@@ -797,7 +1011,7 @@ func (mod *AtCommandModule) SendReplyMessages(
 			if len(result.Err.Error()) > marvin.ShortReplyThreshold {
 				replyIM = true
 			}
-			sendMessageChannel(fmt.Sprintf("%s: %s", result.Message, util.PreviewString(errors.Cause(result.Err).Error(), marvin.ShortReplyThreshold)))
+			sendMessageChannel(fmt.Sprintf("%s: %s", result.Message, util.PreviewString(errors.Cause(result.Err).Error(), marvin.ShortReplyThreshold)), nil)
 		}
 		if replyIMPrimary {
 			sendMessageIMLog(fmt.Sprintf("%s: %v", result.Message, result.Err))
@@ -812,9 +1026,21 @@ func (mod *AtCommandModule) SendReplyMessages(
 		if replyChannel {
 			// Nothing
 		}
-		if replyIM || replyIMPrimary {
-			sendMessageIMLog(fmt.Sprintf("I didn't quite understand that, sorry.\nYou said: [%s]",
-				strings.Join(result.Args.OriginalArguments, "] [")))
+		if replyIM || replyIMPrimary || replyEphemeral {
+			msg := fmt.Sprintf("I didn't quite understand that, sorry.\nYou said: [%s]",
+				strings.Join(result.Args.OriginalArguments, "] ["))
+			if len(result.Suggestions) > 0 {
+				msg += "\n" + marvin.SuggestionText(result.Suggestions)
+				if _, ok := source.(marvin.ActionSourceUserMessage); ok && mod.onReact != nil {
+					emoji, _ := mod.team.ModuleConfig(Identifier).Get(confKeyEmojiRerun)
+					msg += fmt.Sprintf("\nClick the :%s: reaction to run `%s`.", emoji, result.Suggestions[0].Name)
+				}
+			}
+			if replyEphemeral && !replyIM && !replyIMPrimary {
+				sendEphemeral(msg)
+			} else {
+				sendMessageIMLog(msg)
+			}
 		}
 		if replyLog {
 			sendMessageLog(fmt.Sprintf("No such command from %v\nArgs: [%s]\nLink: %s",
@@ -829,10 +1055,12 @@ func (mod *AtCommandModule) SendReplyMessages(
 			//	replyIM = true
 			//	msg = util.PreviewString(result.Message, marvin.LongReplyCut)
 			//}
-			sendMessageChannel(msg)
+			sendMessageChannel(msg, nil)
+		} else if replyEphemeral {
+			sendEphemeral(result.Message)
 		}
 		if replyIM || replyIMPrimary {
-			sendMessageIM(result.Message)
+			sendMessageIM(result.Message, nil)
 		}
 		if replyLog {
 			// err, no?
@@ -840,6 +1068,53 @@ func (mod *AtCommandModule) SendReplyMessages(
 	}
 }
 
+func (mod *AtCommandModule) ephemeralEnabled() bool {
+	val, _ := mod.team.ModuleConfig(Identifier).Get(confKeyEphemeral)
+	return val != "false"
+}
+
+// sendEphemeral shows a message to the user who ran the command, in the
+// channel it was run in. It returns false if the message could not be sent
+// that way, for example when the command did not come from Slack.
+func (mod *AtCommandModule) sendEphemeral(source marvin.ActionSource, msg string) bool {
+	if _, ok := source.(marvin.ActionSourceUserMessage); !ok {
+		return false
+	}
+	_, err := mod.team.SendEphemeral(source.ChannelID(), source.UserID(), SanitizeLoose(msg))
+	if err != nil {
+		util.LogError(errors.Wrap(err, "send ephemeral reply"))
+		return false
+	}
+	return true
+}
+
+// longReply stores a reply that is too long for the channel with the paste
+// module, and returns the message to post instead. If the reply was shared
+// to the channel as a snippet, shared is true and nothing else should be
+// posted. If the reply could not be stored, the returned message is
+// truncated and stored is false.
+func (mod *AtCommandModule) longReply(channel slack.ChannelID, result marvin.CommandResult) (msg string, shared, stored bool) {
+	var command string
+	if result.Args != nil && len(result.Args.OriginalArguments) > 0 {
+		command = result.Args.OriginalArguments[0]
+	}
+	if result.Args != nil && (result.Args.IsEdit || result.Args.IsUndo) {
+		// The earlier reply is updated in place, so it can't be a snippet
+		channel = ""
+	}
+	if mod.pasteMod != nil {
+		link, snippet, err := mod.pasteMod.(paste.API).StoreLongReply(channel, command, result.Message)
+		if err != nil {
+			util.LogError(err)
+		} else if snippet != nil {
+			return "", true, true
+		} else if link != "" {
+			return marvin.LongReplyText(result.Message, link), false, true
+		}
+	}
+	return "[Reply truncated]\n" + util.PreviewString(result.Message, marvin.LongReplyCut) + "…\n", false, false
+}
+
 var rgxTakeCodeBlock = regexp.MustCompile(`^&amp;(\d+)$`)
 var rgxCodeBlock = regexp.MustCompile("(?m:^)```\n?(?s:(.*?))\n?```()(?m:$|\\s)")
 
@@ -928,6 +1203,8 @@ func (mod *AtCommandModule) GetEmojiForResponse(result marvin.CommandResult) str
 		reactEmoji, _ = mod.team.ModuleConfig(Identifier).Get(confKeyEmojiUsage)
 	case marvin.CmdResultPrintHelp:
 		reactEmoji, _ = mod.team.ModuleConfig(Identifier).Get(confKeyEmojiHelp)
+	case marvin.CmdResultConfirm:
+		reactEmoji, _ = mod.team.ModuleConfig(Identifier).Get(confKeyEmojiPending)
 	default:
 		reactEmoji, _ = mod.team.ModuleConfig(Identifier).Get(confKeyEmojiError)
 	}
@@ -0,0 +1,183 @@
+package atcommand
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/riking/marvin"
+	"github.com/riking/marvin/modules/on_reaction"
+	"github.com/riking/marvin/slack"
+	"github.com/riking/marvin/util"
+)
+
+// Values of the "type" field in the on_reaction data for this module.
+const (
+	reactionTypeSuggestion = "suggestion"
+	reactionTypeConfirm    = "confirm"
+)
+
+// OnReaction implements on_reaction.ReactionHandler.
+func (mod *AtCommandModule) OnReaction(event *on_reaction.ReactionEvent, customData []byte) error {
+	var header struct {
+		Type string `json:"type"`
+	}
+	err := json.Unmarshal(customData, &header)
+	if err != nil {
+		return errors.Wrap(err, "atcommand: bad reaction data")
+	}
+	switch header.Type {
+	case reactionTypeConfirm:
+		return mod.onConfirmReaction(event)
+	default:
+		return mod.onSuggestionReaction(event, customData)
+	}
+}
+
+// A pendingConfirm is a CmdResultConfirm result waiting for the user to react
+// to the prompt message.
+type pendingConfirm struct {
+	prompt  ReplyActionSentMessage
+	source  marvin.ActionSource
+	result  marvin.CommandResult
+	expires time.Time
+}
+
+func (mod *AtCommandModule) confirmTimeout() time.Duration {
+	str, _ := mod.team.ModuleConfig(Identifier).Get(confKeyConfirmTimeout)
+	d, err := time.ParseDuration(str)
+	if err != nil || d <= 0 {
+		return 5 * time.Minute
+	}
+	return d
+}
+
+func (mod *AtCommandModule) confirmHint() string {
+	conf := mod.team.ModuleConfig(Identifier)
+	okEmoji, _ := conf.Get(confKeyEmojiConfirm)
+	cancelEmoji, _ := conf.Get(confKeyEmojiCancel)
+	return fmt.Sprintf("React with :%s: to confirm or :%s: to cancel. This request expires in %s.",
+		okEmoji, cancelEmoji, mod.confirmTimeout())
+}
+
+// awaitConfirmation starts watching the prompt message sent for a
+// CmdResultConfirm result.
+func (mod *AtCommandModule) awaitConfirmation(prompt ReplyActionSentMessage, source marvin.ActionSource, result marvin.CommandResult) {
+	if prompt.MessageID.MessageTS == "" || result.Confirm == nil {
+		return
+	}
+	if mod.onReact == nil {
+		prompt.Update(mod, fmt.Sprintf("_Cancelled: confirmations are not available._\n%s", result.Message))
+		return
+	}
+
+	timeout := mod.confirmTimeout()
+	id := prompt.MessageID
+	mod.confirmLock.Lock()
+	mod.pendingConfirms[id] = &pendingConfirm{
+		prompt:  prompt,
+		source:  source,
+		result:  result,
+		expires: time.Now().Add(timeout),
+	}
+	mod.confirmLock.Unlock()
+
+	err := mod.onReact.(on_reaction.API).ListenMessage(id, Identifier, []byte(`{"type":"confirm"}`))
+	if err != nil {
+		util.LogError(err)
+		if p := mod.takeConfirm(id); p != nil {
+			p.prompt.Update(mod, fmt.Sprintf("_Cancelled due to an internal error._\n%s", result.Message))
+		}
+		return
+	}
+
+	conf := mod.team.ModuleConfig(Identifier)
+	okEmoji, _ := conf.Get(confKeyEmojiConfirm)
+	cancelEmoji, _ := conf.Get(confKeyEmojiCancel)
+	mod.team.ReactMessage(id, okEmoji)
+	mod.team.ReactMessage(id, cancelEmoji)
+
+	time.AfterFunc(timeout, func() {
+		if p := mod.takeConfirm(id); p != nil {
+			p.prompt.Update(mod, fmt.Sprintf("_Expired:_ %s", p.result.Message))
+			mod.finishConfirm(p, "")
+		}
+	})
+}
+
+// takeConfirm removes and returns a pending confirmation, so that only one
+// of the reaction handler and the expiry timer acts on it.
+func (mod *AtCommandModule) takeConfirm(id slack.MessageID) *pendingConfirm {
+	mod.confirmLock.Lock()
+	defer mod.confirmLock.Unlock()
+	p := mod.pendingConfirms[id]
+	delete(mod.pendingConfirms, id)
+	return p
+}
+
+func (mod *AtCommandModule) onConfirmReaction(event *on_reaction.ReactionEvent) error {
+	if !event.IsAdded {
+		return nil
+	}
+	conf := mod.team.ModuleConfig(Identifier)
+	okEmoji, _ := conf.Get(confKeyEmojiConfirm)
+	cancelEmoji, _ := conf.Get(confKeyEmojiCancel)
+	if event.EmojiName != okEmoji && event.EmojiName != cancelEmoji {
+		return nil
+	}
+
+	mod.confirmLock.Lock()
+	p := mod.pendingConfirms[event.MessageID]
+	if p == nil || p.source.UserID() != event.UserID {
+		mod.confirmLock.Unlock()
+		return nil
+	}
+	delete(mod.pendingConfirms, event.MessageID)
+	mod.confirmLock.Unlock()
+
+	if time.Now().After(p.expires) {
+		p.prompt.Update(mod, fmt.Sprintf("_Expired:_ %s", p.result.Message))
+		mod.finishConfirm(p, "")
+		return nil
+	}
+	if event.EmojiName == cancelEmoji {
+		p.prompt.Update(mod, fmt.Sprintf("_Cancelled:_ %s", p.result.Message))
+		mod.finishConfirm(p, cancelEmoji)
+		return nil
+	}
+
+	p.prompt.Update(mod, fmt.Sprintf("_Confirmed:_ %s", p.result.Message))
+	go mod.runConfirmed(p)
+	return nil
+}
+
+func (mod *AtCommandModule) runConfirmed(p *pendingConfirm) {
+	var result marvin.CommandResult
+	start := time.Now()
+	err := util.PCall(func() error {
+		result = p.result.Confirm()
+		return nil
+	})
+	if err != nil {
+		result = marvin.CmdError(p.result.Args, err, "Runtime error")
+	}
+	mod.team.ReportCommand(p.result.Args, result, time.Since(start))
+	mod.finishConfirm(p, mod.GetEmojiForResponse(result))
+	mod.SendReplies(result, p.source)
+}
+
+// finishConfirm swaps the pending emoji on the original command message for
+// the final one.
+func (mod *AtCommandModule) finishConfirm(p *pendingConfirm, emoji string) {
+	if p.source.MsgTimestamp() == "" {
+		return
+	}
+	origMsg := slack.MsgID(p.source.ChannelID(), p.source.MsgTimestamp())
+	pendingEmoji, _ := mod.team.ModuleConfig(Identifier).Get(confKeyEmojiPending)
+	go ReplyActionEmoji{MessageID: origMsg, Emoji: pendingEmoji}.Undo(mod)
+	if emoji != "" {
+		go mod.team.ReactMessage(origMsg, emoji)
+	}
+}
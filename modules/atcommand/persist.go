@@ -0,0 +1,275 @@
+package atcommand
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/riking/marvin"
+	"github.com/riking/marvin/slack"
+	"github.com/riking/marvin/util"
+)
+
+const (
+	sqlMigrate1 = `
+	CREATE TABLE module_atcommand_commands (
+		channel    varchar(15) NOT NULL,
+		ts         varchar(20) NOT NULL,
+		user_id    varchar(15) NOT NULL,
+		created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		data       jsonb NOT NULL,
+
+		PRIMARY KEY (channel, ts)
+	)`
+	sqlMigrate1b = `CREATE INDEX idx_atcommand_commands_by_time ON module_atcommand_commands (created_at)`
+
+	// $1 = channel $2 = ts $3 = user $4 = created_at $5 = data
+	sqlSaveCommand = `
+	INSERT INTO module_atcommand_commands
+	(channel, ts, user_id, created_at, data)
+	VALUES ($1, $2, $3, $4, $5)
+	ON CONFLICT (channel, ts) DO UPDATE
+	SET data = EXCLUDED.data`
+
+	// $1 = channel $2 = ts
+	sqlLoadCommand = `
+	SELECT data
+	FROM module_atcommand_commands
+	WHERE channel = $1 AND ts = $2`
+
+	// $1 = threshold
+	sqlExpireCommands = `
+	DELETE FROM module_atcommand_commands
+	WHERE created_at < $1`
+)
+
+// persistedCommandInfo is the database form of a FinishedCommandInfo.
+type persistedCommandInfo struct {
+	MyTimestamp time.Time           `json:"time"`
+	OriginalMsg slack.RTMRawMessage `json:"msg"`
+	LatestEdit  slack.RTMRawMessage `json:"edit,omitempty"`
+
+	Wave                 bool     `json:"wave,omitempty"`
+	ArgSplit             []string `json:"args,omitempty"`
+	SplitErr             string   `json:"split_err,omitempty"`
+	LenientNoSuchCommand bool     `json:"lenient,omitempty"`
+
+	FoundCommand bool `json:"found"`
+	FailedUndo   bool `json:"failed_undo,omitempty"`
+
+	OriginalArguments []string        `json:"orig_args,omitempty"`
+	ModuleData        json.RawMessage `json:"module_data,omitempty"`
+	ResultMessage     string          `json:"result_msg"`
+	ResultErr         string          `json:"result_err,omitempty"`
+	ResultCode        int             `json:"result_code"`
+	ResultReplyType   int             `json:"result_reply_type"`
+	ResultCanEdit     int             `json:"result_can_edit"`
+	ResultCanUndo     int             `json:"result_can_undo"`
+
+	ActionEmoji    []ReplyActionEmoji     `json:"emoji,omitempty"`
+	ActionChanMsg  ReplyActionSentMessage `json:"chan_msg"`
+	ActionPMMsg    ReplyActionSentMessage `json:"pm_msg"`
+	ActionPMLogMsg ReplyActionSentMessage `json:"pm_log_msg"`
+	ActionLogMsg   ReplyActionSentMessage `json:"log_msg"`
+}
+
+// persistedError is a restored CommandResult.Err.
+type persistedError string
+
+func (e persistedError) Error() string { return string(e) }
+
+func stripRawBytes(m slack.RTMRawMessage) slack.RTMRawMessage {
+	if m == nil {
+		return nil
+	}
+	c := make(slack.RTMRawMessage, len(m))
+	for k, v := range m {
+		if k != slack.MsgFieldRawBytes {
+			c[k] = v
+		}
+	}
+	return c
+}
+
+// commandRetention is how long command messages can be edited or deleted
+// after they are sent.
+func (mod *AtCommandModule) commandRetention() time.Duration {
+	str, _ := mod.team.ModuleConfig(Identifier).Get(confKeyRetention)
+	d, err := time.ParseDuration(str)
+	if err != nil || d <= 0 {
+		return 6 * time.Hour
+	}
+	return d
+}
+
+// saveCommandInfo writes the state of a command to the database. The caller
+// must hold fci.Lock.
+func (mod *AtCommandModule) saveCommandInfo(fci *FinishedCommandInfo) {
+	p := persistedCommandInfo{
+		MyTimestamp:          fci.MyTimestamp,
+		OriginalMsg:          stripRawBytes(fci.OriginalMsg),
+		LatestEdit:           stripRawBytes(fci.LatestEdit),
+		Wave:                 fci.parseResult.wave,
+		ArgSplit:             fci.parseResult.argSplit,
+		LenientNoSuchCommand: fci.parseResult.lenientNoSuchCommand,
+		FoundCommand:         fci.FoundCommand,
+		FailedUndo:           fci.FailedUndo,
+		ResultMessage:        fci.CommandResult.Message,
+		ResultCode:           int(fci.CommandResult.Code),
+		ResultReplyType:      int(fci.CommandResult.ReplyType),
+		ResultCanEdit:        int(fci.CommandResult.CanEdit),
+		ResultCanUndo:        int(fci.CommandResult.CanUndo),
+		ActionEmoji:          fci.ActionEmoji,
+		ActionChanMsg:        fci.ActionChanMsg,
+		ActionPMMsg:          fci.ActionPMMsg,
+		ActionPMLogMsg:       fci.ActionPMLogMsg,
+		ActionLogMsg:         fci.ActionLogMsg,
+	}
+	if fci.parseResult.splitErr != nil {
+		p.SplitErr = fci.parseResult.splitErr.Error()
+	}
+	if fci.CommandResult.Err != nil {
+		p.ResultErr = fci.CommandResult.Err.Error()
+	}
+	if fci.CommandArgs != nil {
+		p.OriginalArguments = fci.CommandArgs.OriginalArguments
+	}
+	if fci.CommandResult.Args != nil && fci.CommandResult.Args.ModuleData != nil {
+		b, err := json.Marshal(fci.CommandResult.Args.ModuleData)
+		if err != nil {
+			util.LogWarn("atcommand: could not save module data:", err)
+		} else {
+			p.ModuleData = b
+		}
+	}
+
+	data, err := json.Marshal(p)
+	if err != nil {
+		util.LogError(errors.Wrap(err, "atcommand: marshal command info"))
+		return
+	}
+	stmt, err := mod.team.DB().Prepare(sqlSaveCommand)
+	if err != nil {
+		util.LogError(errors.Wrap(err, "atcommand: prepare"))
+		return
+	}
+	defer stmt.Close()
+
+	msgID := fci.OriginalMsg.MessageID()
+	_, err = stmt.Exec(string(msgID.ChannelID), string(msgID.MessageTS),
+		string(slack.SlackTextMessage(fci.OriginalMsg).UserID()), fci.MyTimestamp, data)
+	if err != nil {
+		util.LogError(errors.Wrap(err, "atcommand: save command info"))
+	}
+}
+
+// loadCommandInfo restores the state of a command from the database. It
+// returns (nil, nil) if the message is not a known command.
+func (mod *AtCommandModule) loadCommandInfo(msgID slack.MessageID) (*FinishedCommandInfo, error) {
+	stmt, err := mod.team.DB().Prepare(sqlLoadCommand)
+	if err != nil {
+		return nil, errors.Wrap(err, "atcommand: prepare")
+	}
+	defer stmt.Close()
+
+	var data []byte
+	err = stmt.QueryRow(string(msgID.ChannelID), string(msgID.MessageTS)).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "atcommand: load command info")
+	}
+
+	var p persistedCommandInfo
+	err = json.Unmarshal(data, &p)
+	if err != nil {
+		return nil, errors.Wrap(err, "atcommand: unmarshal command info")
+	}
+	if time.Since(p.MyTimestamp) > mod.commandRetention() {
+		return nil, nil
+	}
+
+	fci := &FinishedCommandInfo{
+		MyTimestamp: p.MyTimestamp,
+		OriginalMsg: p.OriginalMsg,
+		LatestEdit:  p.LatestEdit,
+		parseResult: parseMessageReturn{
+			wave:                 p.Wave,
+			argSplit:             p.ArgSplit,
+			lenientNoSuchCommand: p.LenientNoSuchCommand,
+		},
+		FoundCommand:   p.FoundCommand,
+		FailedUndo:     p.FailedUndo,
+		ActionEmoji:    p.ActionEmoji,
+		ActionChanMsg:  p.ActionChanMsg,
+		ActionPMMsg:    p.ActionPMMsg,
+		ActionPMLogMsg: p.ActionPMLogMsg,
+		ActionLogMsg:   p.ActionLogMsg,
+	}
+	if p.SplitErr != "" {
+		fci.parseResult.splitErr = errors.New(p.SplitErr)
+	}
+	args := &marvin.CommandArguments{
+		OriginalArguments: p.OriginalArguments,
+		Arguments:         p.OriginalArguments,
+	}
+	if len(p.ModuleData) > 0 {
+		args.ModuleData = p.ModuleData
+	}
+	fci.CommandArgs = args
+	fci.CommandResult = marvin.CommandResult{
+		Args:      args,
+		Message:   p.ResultMessage,
+		Code:      marvin.CommandResultCode(p.ResultCode),
+		ReplyType: marvin.ReplyType(p.ResultReplyType),
+		CanEdit:   util.TriValue(p.ResultCanEdit),
+		CanUndo:   util.TriValue(p.ResultCanUndo),
+	}
+	if p.ResultErr != "" {
+		fci.CommandResult.Err = persistedError(p.ResultErr)
+	}
+	return fci, nil
+}
+
+// getCommandInfo finds a recent command by message ID, in memory or in the
+// database.
+func (mod *AtCommandModule) getCommandInfo(msgID slack.MessageID) (*FinishedCommandInfo, bool) {
+	mod.recentCommandsLock.Lock()
+	fci, ok := mod.recentCommands[msgID]
+	mod.recentCommandsLock.Unlock()
+	if ok {
+		return fci, true
+	}
+
+	fci, err := mod.loadCommandInfo(msgID)
+	if err != nil {
+		util.LogError(err)
+		return nil, false
+	} else if fci == nil {
+		return nil, false
+	}
+	util.LogDebug("Restored command info from database", mod.team.ArchiveURL(msgID))
+
+	mod.recentCommandsLock.Lock()
+	defer mod.recentCommandsLock.Unlock()
+	// Lost a race with another event for the same message
+	if existing, ok := mod.recentCommands[msgID]; ok {
+		return existing, true
+	}
+	mod.recentCommands[msgID] = fci
+	return fci, true
+}
+
+func (mod *AtCommandModule) expireCommandInfo() {
+	stmt, err := mod.team.DB().Prepare(sqlExpireCommands)
+	if err != nil {
+		util.LogError(errors.Wrap(err, "atcommand: prepare"))
+		return
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(time.Now().Add(-mod.commandRetention()))
+	util.LogIfError(errors.Wrap(err, "atcommand: expire command info"))
+}
@@ -0,0 +1,129 @@
+package atcommand
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/riking/marvin"
+	"github.com/riking/marvin/modules/on_reaction"
+	"github.com/riking/marvin/slack"
+	"github.com/riking/marvin/util"
+)
+
+// rerunExpiry is how long a "did you mean" suggestion can be re-run with a
+// reaction.
+const rerunExpiry = 1 * time.Hour
+
+// suggestionData is saved with on_reaction so that a reaction to the "did you
+// mean" message can re-run the suggested command.
+type suggestionData struct {
+	Type    string          `json:"type"`
+	Channel slack.ChannelID `json:"channel"`
+	MsgTS   slack.MessageTS `json:"ts"`
+	User    slack.UserID    `json:"user"`
+	Args    []string        `json:"args"`
+	Created int64           `json:"created"`
+}
+
+// actionSourceRerun is the ActionSource of a suggested command that was
+// re-run with a reaction. It points at the original mistyped message.
+type actionSourceRerun struct {
+	team marvin.Team
+	data *suggestionData
+}
+
+func (as actionSourceRerun) UserID() slack.UserID          { return as.data.User }
+func (as actionSourceRerun) ChannelID() slack.ChannelID    { return as.data.Channel }
+func (as actionSourceRerun) MsgTimestamp() slack.MessageTS { return as.data.MsgTS }
+func (as actionSourceRerun) AccessLevel() marvin.AccessLevel {
+	return as.team.UserLevel(as.data.User)
+}
+func (as actionSourceRerun) ArchiveLink() string {
+	return as.team.ArchiveURL(slack.MsgID(as.data.Channel, as.data.MsgTS))
+}
+
+// offerSuggestion watches the "did you mean" message for the re-run emoji and
+// adds that reaction, so the user only has to click it.
+func (mod *AtCommandModule) offerSuggestion(replyMsg slack.MessageID, source marvin.ActionSource, suggestion marvin.CommandSuggestion) {
+	if mod.onReact == nil {
+		return
+	}
+	data := suggestionData{
+		Type:    reactionTypeSuggestion,
+		Channel: source.ChannelID(),
+		MsgTS:   source.MsgTimestamp(),
+		User:    source.UserID(),
+		Args:    suggestion.Arguments,
+		Created: time.Now().Unix(),
+	}
+	b, err := json.Marshal(data)
+	if err != nil {
+		util.LogError(errors.Wrap(err, "atcommand: marshal suggestion"))
+		return
+	}
+	err = mod.onReact.(on_reaction.API).ListenMessage(replyMsg, Identifier, b)
+	if err != nil {
+		util.LogError(err)
+		return
+	}
+	emoji, _ := mod.team.ModuleConfig(Identifier).Get(confKeyEmojiRerun)
+	mod.team.ReactMessage(replyMsg, emoji)
+}
+
+func (mod *AtCommandModule) onSuggestionReaction(event *on_reaction.ReactionEvent, customData []byte) error {
+	var data suggestionData
+	err := json.Unmarshal(customData, &data)
+	if err != nil {
+		return errors.Wrap(err, "atcommand: bad suggestion data")
+	}
+
+	emoji, _ := mod.team.ModuleConfig(Identifier).Get(confKeyEmojiRerun)
+	if !event.IsAdded || event.EmojiName != emoji || event.UserID != data.User {
+		return nil
+	}
+	if time.Since(time.Unix(data.Created, 0)) > rerunExpiry {
+		return nil
+	}
+
+	mod.rerunLock.Lock()
+	_, done := mod.rerunDone[event.MessageID]
+	if !done {
+		mod.rerunDone[event.MessageID] = time.Now()
+	}
+	mod.rerunLock.Unlock()
+	if done {
+		return nil
+	}
+
+	go mod.rerunSuggestion(&data)
+	return nil
+}
+
+func (mod *AtCommandModule) rerunSuggestion(data *suggestionData) {
+	source := actionSourceRerun{team: mod.team, data: data}
+	if source.AccessLevel() < marvin.AccessLevelNormal {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+	args := &marvin.CommandArguments{
+		OriginalArguments: data.Args,
+		Arguments:         data.Args,
+		Command:           "",
+		Ctx:               ctx,
+		Source:            source,
+	}
+	util.LogDebug("re-run args: [", strings.Join(args.OriginalArguments, "] ["), "]")
+	result := mod.team.DispatchCommand(args)
+
+	reactEmoji := mod.GetEmojiForResponse(result)
+	if reactEmoji != "" {
+		go mod.team.ReactMessage(slack.MsgID(data.Channel, data.MsgTS), reactEmoji)
+	}
+	mod.SendReplies(result, source)
+}
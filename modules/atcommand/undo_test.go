@@ -0,0 +1,48 @@
+package atcommand
+
+import (
+	"testing"
+
+	"github.com/riking/marvin"
+)
+
+func TestCanUndoCanEdit(t *testing.T) {
+	args := &marvin.CommandArguments{}
+	tests := []struct {
+		name            string
+		result          marvin.CommandResult
+		canEdit         bool
+		canUndo, custom bool
+	}{
+		{"success", marvin.CmdSuccess(args, ""), false, false, false},
+		{"failure", marvin.CmdFailuref(args, "no"), true, true, false},
+		{"error", marvin.CmdError(args, nil, "no"), false, false, false},
+		{"usage", marvin.CmdUsage(args, "usage"), true, true, false},
+		{"simple undo", marvin.CmdSuccess(args, "").WithSimpleUndo(), false, true, false},
+		{"no undo", marvin.CmdFailuref(args, "no").WithNoUndo(), true, false, false},
+		// Like remember and revert: undo is handled by the command, and an
+		// edit is an undo followed by the new command
+		{"custom undo", marvin.CmdSuccess(args, "").WithNoEdit().WithCustomUndo(), false, true, true},
+		{"custom edit", marvin.CmdSuccess(args, "").WithEdit().WithNoUndo(), true, false, false},
+		{"confirm", marvin.CmdConfirm(args, "sure?", nil), false, false, false},
+	}
+	mod := &AtCommandModule{}
+	for _, v := range tests {
+		fci := &FinishedCommandInfo{CommandResult: v.result}
+		if got := mod.canEdit(fci); got != v.canEdit {
+			t.Errorf("%s: canEdit: expected %v, got %v", v.name, v.canEdit, got)
+		}
+		canUndo, custom := mod.canUndo(fci)
+		if canUndo != v.canUndo || custom != v.custom {
+			t.Errorf("%s: canUndo: expected %v %v, got %v %v", v.name, v.canUndo, v.custom, canUndo, custom)
+		}
+	}
+
+	fci := &FinishedCommandInfo{
+		CommandResult: marvin.CmdSuccess(args, "").WithCustomUndo(),
+		FailedUndo:    true,
+	}
+	if canUndo, _ := mod.canUndo(fci); canUndo {
+		t.Error("canUndo: expected false after a failed undo")
+	}
+}
@@ -0,0 +1,403 @@
+package audit
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/riking/marvin"
+	"github.com/riking/marvin/slack"
+	"github.com/riking/marvin/util"
+)
+
+func init() {
+	marvin.RegisterModule(NewAuditModule)
+}
+
+const Identifier = "audit"
+
+type AuditModule struct {
+	team marvin.Team
+
+	insertStmt     *sql.Stmt
+	markEditedStmt *sql.Stmt
+	markUndoneStmt *sql.Stmt
+
+	// records are written by recordWorker, so that commands don't wait for
+	// the database.
+	records chan auditRecord
+	stopCh  chan struct{}
+}
+
+// recordQueueSize is the number of records that can wait to be written
+// before new ones are dropped.
+const recordQueueSize = 256
+
+func NewAuditModule(t marvin.Team) marvin.Module {
+	mod := &AuditModule{
+		team:    t,
+		records: make(chan auditRecord, recordQueueSize),
+	}
+	return mod
+}
+
+func (mod *AuditModule) Identifier() marvin.ModuleID {
+	return Identifier
+}
+
+func (mod *AuditModule) Load(t marvin.Team) {
+	t.DB().MustMigrate(Identifier, 1489536000, sqlMigrate1, sqlMigrate1b, sqlMigrate1c)
+	t.DB().SyntaxCheck(
+		sqlInsertEntry,
+		sqlMarkEdited,
+		sqlMarkUndone,
+		sqlQueryEntries,
+	)
+	mod.insertStmt = mustPrepare(t, sqlInsertEntry)
+	mod.markEditedStmt = mustPrepare(t, sqlMarkEdited)
+	mod.markUndoneStmt = mustPrepare(t, sqlMarkUndone)
+	mod.registerHTTP()
+}
+
+func mustPrepare(t marvin.Team, query string) *sql.Stmt {
+	stmt, err := t.DB().Prepare(query)
+	if err != nil {
+		panic(errors.Wrap(err, "audit: prepare"))
+	}
+	return stmt
+}
+
+func (mod *AuditModule) Enable(t marvin.Team) {
+	mod.stopCh = make(chan struct{})
+	go mod.recordWorker(mod.stopCh)
+	t.OnCommand(Identifier, mod.RecordCommand)
+	t.RegisterCommand("audit", marvin.RequireLevel(marvin.AccessLevelAdmin, marvin.CommandFunc(mod.CommandAudit, helpAudit)))
+}
+
+func (mod *AuditModule) Disable(t marvin.Team) {
+	t.OffAllEvents(Identifier)
+	t.UnregisterCommand("audit")
+	close(mod.stopCh)
+}
+
+// ---
+
+// Values of the kind column.
+const (
+	KindRun  = "run"
+	KindEdit = "edit"
+	KindUndo = "undo"
+)
+
+const (
+	sqlMigrate1 = `
+	CREATE TABLE module_audit_commands (
+		id           BIGSERIAL PRIMARY KEY,
+		user_id      varchar(15) NOT NULL,
+		channel      varchar(15) NOT NULL,
+		source_ts    varchar(20) NOT NULL,
+		arguments    text NOT NULL,
+		kind         varchar(8) NOT NULL,
+		result_code  int NOT NULL,
+		error        text NOT NULL DEFAULT '',
+		duration_ms  int NOT NULL,
+		archive_link text NOT NULL,
+		edited       boolean NOT NULL DEFAULT FALSE,
+		undone       boolean NOT NULL DEFAULT FALSE,
+		created_at   timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`
+	sqlMigrate1b = `CREATE INDEX idx_audit_by_user ON module_audit_commands (user_id, id)`
+	sqlMigrate1c = `CREATE INDEX idx_audit_by_message ON module_audit_commands (channel, source_ts)`
+
+	// $1 = user $2 = channel $3 = source ts $4 = arguments (json) $5 = kind
+	// $6 = result code $7 = error $8 = duration (ms) $9 = archive link
+	sqlInsertEntry = `
+	INSERT INTO module_audit_commands
+	(user_id, channel, source_ts, arguments, kind, result_code, error, duration_ms, archive_link)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+
+	// $1 = channel $2 = source ts
+	sqlMarkEdited = `
+	UPDATE module_audit_commands
+	SET edited = TRUE
+	WHERE channel = $1 AND source_ts = $2 AND kind = 'run'`
+
+	// $1 = channel $2 = source ts
+	sqlMarkUndone = `
+	UPDATE module_audit_commands
+	SET undone = TRUE
+	WHERE channel = $1 AND source_ts = $2 AND kind <> 'undo'`
+
+	// $1 = user ('' for any) $2 = channel ('' for any)
+	// $3 = result code (-1 for any) $4 = argument substring ('' for any)
+	// $5 = before id (0 for none) $6 = limit
+	sqlQueryEntries = `
+	SELECT id, user_id, channel, source_ts, arguments, kind, result_code,
+		error, duration_ms, archive_link, edited, undone, created_at
+	FROM module_audit_commands
+	WHERE ($1 = '' OR user_id = $1)
+	AND ($2 = '' OR channel = $2)
+	AND ($3 < 0 OR result_code = $3)
+	AND ($4 = '' OR strpos(lower(arguments), lower($4)) > 0)
+	AND ($5::bigint = 0 OR id < $5)
+	ORDER BY id DESC
+	LIMIT $6`
+)
+
+// An Entry is one row of the command audit log.
+type Entry struct {
+	ID          int64
+	User        slack.UserID
+	Channel     slack.ChannelID
+	SourceTS    slack.MessageTS
+	Args        []string
+	Kind        string
+	Code        marvin.CommandResultCode
+	Error       string
+	Duration    time.Duration
+	ArchiveLink string
+	Edited      bool
+	Undone      bool
+	Created     time.Time
+}
+
+// CodeName returns a short description of the result code.
+func (e *Entry) CodeName() string {
+	switch e.Code {
+	case marvin.CmdResultOK:
+		return "ok"
+	case marvin.CmdResultFailure:
+		return "failure"
+	case marvin.CmdResultError:
+		return "error"
+	case marvin.CmdResultNoSuchCommand:
+		return "no such command"
+	case marvin.CmdResultPrintUsage:
+		return "usage"
+	case marvin.CmdResultPrintHelp:
+		return "help"
+	case marvin.CmdResultConfirm:
+		return "confirm"
+	}
+	return fmt.Sprintf("code %d", e.Code)
+}
+
+// ArgString returns the arguments joined with spaces.
+func (e *Entry) ArgString() string {
+	return strings.Join(e.Args, " ")
+}
+
+// InSlack returns false for commands that did not come from a Slack
+// channel, such as those run from the web interface.
+func (e *Entry) InSlack() bool {
+	// Other sources use a description in parentheses, like "(via web)"
+	return e.Channel != "" && e.Channel[0] != '('
+}
+
+// A Query selects entries from the audit log. Zero values match everything.
+type Query struct {
+	User    slack.UserID
+	Channel slack.ChannelID
+	// Code is ignored unless HasCode is set.
+	Code    marvin.CommandResultCode
+	HasCode bool
+	Search  string
+	// Before selects entries with an ID lower than this one, for paging.
+	Before int64
+	Limit  int
+}
+
+// auditRecord is a row of module_audit_commands waiting to be written.
+type auditRecord struct {
+	user        slack.UserID
+	channel     slack.ChannelID
+	ts          slack.MessageTS
+	argsJSON    string
+	kind        string
+	code        marvin.CommandResultCode
+	errStr      string
+	duration    time.Duration
+	archiveLink string
+}
+
+// RecordCommand is the CommandObserver that writes the audit log. The entry
+// is queued and written in the background.
+func (mod *AuditModule) RecordCommand(args *marvin.CommandArguments, result marvin.CommandResult, elapsed time.Duration) {
+	if args.Source == nil {
+		return
+	}
+	argsJSON, err := json.Marshal(args.OriginalArguments)
+	if err != nil {
+		util.LogError(errors.Wrap(err, "audit: marshal arguments"))
+		return
+	}
+	rec := auditRecord{
+		user:        args.Source.UserID(),
+		channel:     args.Source.ChannelID(),
+		ts:          args.Source.MsgTimestamp(),
+		argsJSON:    string(argsJSON),
+		kind:        KindRun,
+		code:        result.Code,
+		duration:    elapsed,
+		archiveLink: args.Source.ArchiveLink(),
+	}
+	if args.IsEdit {
+		rec.kind = KindEdit
+	} else if args.IsUndo {
+		rec.kind = KindUndo
+	}
+	if result.Err != nil {
+		rec.errStr = result.Err.Error()
+	}
+
+	select {
+	case mod.records <- rec:
+	default:
+		util.LogWarn("audit: too many commands waiting to be recorded, dropping", rec.argsJSON)
+	}
+}
+
+func (mod *AuditModule) recordWorker(stopCh chan struct{}) {
+	for {
+		select {
+		case rec := <-mod.records:
+			mod.writeRecord(rec)
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+func (mod *AuditModule) writeRecord(rec auditRecord) {
+	_, err := mod.insertStmt.Exec(
+		string(rec.user), string(rec.channel), string(rec.ts), rec.argsJSON,
+		rec.kind, int(rec.code), rec.errStr, int64(rec.duration/time.Millisecond), rec.archiveLink)
+	if err != nil {
+		util.LogError(errors.Wrap(err, "audit: record command"))
+		return
+	}
+
+	if rec.ts == "" || rec.kind == KindRun {
+		return
+	}
+	markStmt := mod.markEditedStmt
+	if rec.kind == KindUndo {
+		markStmt = mod.markUndoneStmt
+	}
+	_, err = markStmt.Exec(string(rec.channel), string(rec.ts))
+	util.LogIfError(errors.Wrap(err, "audit: mark command"))
+}
+
+// QueryEntries returns the newest entries in the audit log that match q.
+func (mod *AuditModule) QueryEntries(q Query) ([]*Entry, error) {
+	code := -1
+	if q.HasCode {
+		code = int(q.Code)
+	}
+	limit := q.Limit
+	if limit <= 0 {
+		limit = 50
+	}
+
+	stmt, err := mod.team.DB().Prepare(sqlQueryEntries)
+	if err != nil {
+		return nil, errors.Wrap(err, "audit: prepare")
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(string(q.User), string(q.Channel), code, q.Search, q.Before, limit)
+	if err != nil {
+		return nil, errors.Wrap(err, "audit: query")
+	}
+	defer rows.Close()
+
+	var list []*Entry
+	for rows.Next() {
+		e := new(Entry)
+		var argsJSON string
+		var durationMS int64
+		err = rows.Scan(&e.ID, &e.User, &e.Channel, &e.SourceTS, &argsJSON, &e.Kind, &e.Code,
+			&e.Error, &durationMS, &e.ArchiveLink, &e.Edited, &e.Undone, &e.Created)
+		if err != nil {
+			return nil, errors.Wrap(err, "audit: scan")
+		}
+		err = json.Unmarshal([]byte(argsJSON), &e.Args)
+		if err != nil {
+			return nil, errors.Wrapf(err, "audit: entry %d has bad arguments", e.ID)
+		}
+		e.Duration = time.Duration(durationMS) * time.Millisecond
+		list = append(list, e)
+	}
+	if rows.Err() != nil && rows.Err() != sql.ErrNoRows {
+		return nil, errors.Wrap(rows.Err(), "audit: query")
+	}
+	return list, nil
+}
+
+// ---
+
+const helpAudit = "`@marvin audit <user> [count]` lists the most recent commands run by a user.\n" +
+	"The full log is available at /commands. This command is restricted to admins."
+
+const maxAuditCount = 50
+
+func (mod *AuditModule) CommandAudit(t marvin.Team, args *marvin.CommandArguments) marvin.CommandResult {
+	if len(args.Arguments) < 1 || len(args.Arguments) > 2 {
+		return marvin.CmdUsage(args, helpAudit).WithNoEdit().WithNoUndo()
+	}
+
+	userID := t.ResolveUserName(args.Arguments[0])
+	if userID == "" {
+		return marvin.CmdFailuref(args, "No such user '%s'.", args.Arguments[0]).WithNoEdit().WithNoUndo()
+	}
+	count := 10
+	if len(args.Arguments) == 2 {
+		n, err := strconv.Atoi(args.Arguments[1])
+		if err != nil || n <= 0 {
+			return marvin.CmdUsage(args, helpAudit).WithNoEdit().WithNoUndo()
+		}
+		count = n
+		if count > maxAuditCount {
+			count = maxAuditCount
+		}
+	}
+
+	list, err := mod.QueryEntries(Query{User: userID, Limit: count})
+	if err != nil {
+		return marvin.CmdError(args, err, "Could not read the audit log")
+	}
+	if len(list) == 0 {
+		return marvin.CmdSuccess(args, fmt.Sprintf("No commands recorded for %v.", userID)).
+			WithReplyType(marvin.ReplyTypePM)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "Last %d commands by %v (full log: %s):\n",
+		len(list), userID, t.AbsoluteURL(fmt.Sprintf("/commands?user=%s", userID)))
+	tz := util.TZ42USA()
+	for _, e := range list {
+		fmt.Fprintf(&buf, "• %s in %s: `%s` → %s",
+			e.Created.In(tz).Format("Jan 2 15:04"), t.FormatChannel(e.Channel),
+			util.PreviewString(e.ArgString(), 80), e.CodeName())
+		if e.Kind != KindRun {
+			fmt.Fprintf(&buf, " (%s)", e.Kind)
+		}
+		if e.Edited {
+			buf.WriteString(" (edited)")
+		}
+		if e.Undone {
+			buf.WriteString(" (undone)")
+		}
+		if e.ArchiveLink != "" {
+			fmt.Fprintf(&buf, " %s", e.ArchiveLink)
+		}
+		buf.WriteByte('\n')
+	}
+	return marvin.CmdSuccess(args, buf.String()).WithReplyType(marvin.ReplyTypePM).WithNoEdit().WithNoUndo()
+}
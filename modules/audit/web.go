@@ -0,0 +1,152 @@
+package audit
+
+import (
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/pkg/errors"
+
+	"github.com/riking/marvin"
+	"github.com/riking/marvin/modules/weblogin"
+	"github.com/riking/marvin/util"
+)
+
+func (mod *AuditModule) registerHTTP() {
+	mod.team.Router().Path("/commands").Methods(http.MethodGet).HandlerFunc(mod.HTTPCommandLog)
+}
+
+var tmplCommandLog = template.Must(weblogin.LayoutTemplateCopy().Parse(string(weblogin.MustAsset("templates/command-log.html"))))
+
+const webPageSize = 100
+
+// codeFilters are the result codes that can be picked on the web page.
+var codeFilters = []marvin.CommandResultCode{
+	marvin.CmdResultOK,
+	marvin.CmdResultFailure,
+	marvin.CmdResultError,
+	marvin.CmdResultNoSuchCommand,
+	marvin.CmdResultPrintUsage,
+	marvin.CmdResultPrintHelp,
+	marvin.CmdResultConfirm,
+}
+
+type codeOption struct {
+	Value    int
+	Name     string
+	Selected bool
+}
+
+type bodyCommandLog struct {
+	Layout      *weblogin.LayoutContent
+	NotLoggedIn bool
+	IsAdmin     bool
+
+	FilterUser    string
+	FilterChannel string
+	FilterSearch  string
+	Codes         []codeOption
+
+	Entries  []*Entry
+	NextPage string
+
+	team marvin.Team
+}
+
+func (d bodyCommandLog) Team() marvin.Team { return d.team }
+
+// HTTPCommandLog shows the audit log. Admins can see every command, while
+// other users can only see their own.
+func (mod *AuditModule) HTTPCommandLog(w http.ResponseWriter, r *http.Request) {
+	wlAPI := mod.team.GetModule(weblogin.Identifier).(weblogin.API)
+	lc, err := weblogin.NewLayoutContent(mod.team, w, r, weblogin.NavSectionCommands)
+	if err != nil {
+		wlAPI.HTTPError(w, r, err)
+		return
+	}
+	err = r.ParseForm()
+	if err != nil {
+		http.Error(w, "Bad form data: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	data := bodyCommandLog{
+		Layout: lc,
+		team:   mod.team,
+	}
+	if lc.CurrentUser == nil || lc.CurrentUser.SlackUser == "" {
+		data.NotLoggedIn = true
+		lc.BodyData = data
+		util.LogIfError(tmplCommandLog.ExecuteTemplate(w, "layout", lc))
+		return
+	}
+	data.IsAdmin = mod.team.UserLevel(lc.CurrentUser.SlackUser) >= marvin.AccessLevelAdmin
+
+	var q Query
+	data.FilterUser = r.Form.Get("user")
+	data.FilterChannel = r.Form.Get("channel")
+	data.FilterSearch = r.Form.Get("q")
+	if data.FilterUser != "" {
+		q.User = mod.team.ResolveUserName(data.FilterUser)
+		if q.User == "" {
+			wlAPI.HTTPError(w, r, errors.Errorf("No such user '%s'", data.FilterUser))
+			return
+		}
+	}
+	if !data.IsAdmin {
+		q.User = lc.CurrentUser.SlackUser
+		data.FilterUser = mod.team.UserName(q.User)
+	}
+	if data.FilterChannel != "" {
+		q.Channel = mod.team.ResolveChannelName(data.FilterChannel)
+		if q.Channel == "" {
+			wlAPI.HTTPError(w, r, errors.Errorf("No such channel '%s'", data.FilterChannel))
+			return
+		}
+	}
+	q.Search = data.FilterSearch
+	if codeStr := r.Form.Get("code"); codeStr != "" {
+		code, err := strconv.Atoi(codeStr)
+		if err != nil {
+			http.Error(w, "Bad result code", http.StatusBadRequest)
+			return
+		}
+		q.Code = marvin.CommandResultCode(code)
+		q.HasCode = true
+	}
+	if beforeStr := r.Form.Get("before"); beforeStr != "" {
+		q.Before, err = strconv.ParseInt(beforeStr, 10, 64)
+		if err != nil {
+			http.Error(w, "Bad page number", http.StatusBadRequest)
+			return
+		}
+	}
+	q.Limit = webPageSize
+
+	for _, c := range codeFilters {
+		e := Entry{Code: c}
+		data.Codes = append(data.Codes, codeOption{
+			Value:    int(c),
+			Name:     e.CodeName(),
+			Selected: q.HasCode && q.Code == c,
+		})
+	}
+
+	data.Entries, err = mod.QueryEntries(q)
+	if err != nil {
+		wlAPI.HTTPError(w, r, err)
+		return
+	}
+	if len(data.Entries) == webPageSize {
+		next := url.Values{}
+		for k, v := range r.Form {
+			next[k] = v
+		}
+		next.Set("before", strconv.FormatInt(data.Entries[len(data.Entries)-1].ID, 10))
+		data.NextPage = "/commands?" + next.Encode()
+	}
+
+	lc.BodyData = data
+	util.LogIfError(tmplCommandLog.ExecuteTemplate(w, "layout", lc))
+}
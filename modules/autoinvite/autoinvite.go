@@ -4,7 +4,6 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
-	"net/url"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -171,21 +170,13 @@ func (mod *AutoInviteModule) OnRawReaction(rtm slack.RTMRawMessage) {
 		return
 	}
 
-	var response struct {
-		AlreadyInGroup bool `json:"already_in_group"`
-	}
-	form := url.Values{
-		"channel": []string{string(targetChannelStr)},
-		"user":    []string{string(msg.User)},
-	}
-	err = mod.team.SlackAPIPostJSON("groups.invite", form, &response)
-	if err != nil {
-		util.LogError(err)
-		return
-	}
-	if response.AlreadyInGroup {
+	err = mod.team.InviteToConversation(slack.ChannelID(targetChannelStr), msg.User)
+	if isAlreadyInChannel(err) {
 		util.LogGood("Invite skipped:", mod.team.UserName(msg.User), "already in", mod.team.ChannelName(slack.ChannelID(targetChannelStr)))
 		return
+	} else if err != nil {
+		util.LogError(err)
+		return
 	}
 	util.LogGood("Invited", mod.team.UserName(msg.User), "to", mod.team.ChannelName(slack.ChannelID(targetChannelStr)))
 }
@@ -209,15 +200,8 @@ func (mod *AutoInviteModule) OnReaction(evt *on_reaction.ReactionEvent, customDa
 	if err != nil {
 		return errors.Wrap(err, "unmarshal json")
 	}
-	var response struct {
-		AlreadyInGroup bool `json:"already_in_group"`
-	}
-	form := url.Values{
-		"channel": []string{string(data.InviteTargetChannel)},
-		"user":    []string{string(evt.UserID)},
-	}
-	err = mod.team.SlackAPIPostJSON("groups.invite", form, &response)
-	if err != nil {
+	err = mod.team.InviteToConversation(data.InviteTargetChannel, evt.UserID)
+	if err != nil && !isAlreadyInChannel(err) {
 		imChannel, err := mod.team.GetIM(evt.UserID)
 		if err == nil {
 			mod.team.SendMessage(imChannel, "Sorry, an error occured. Try again later?")
@@ -248,16 +232,19 @@ func (mod *AutoInviteModule) PostInvite(t marvin.Team, args *marvin.CommandArgum
 	}
 
 	inviteTarget := args.Source.ChannelID()
-	if inviteTarget == "" || inviteTarget[0] != 'G' {
+	if inviteTarget == "" {
 		return marvin.CmdFailuref(args, "Command must be used from the private channel you want to invite people to.").WithNoEdit().WithSimpleUndo()
 	}
-	privateChannel, err := t.PrivateChannelInfo(inviteTarget)
+	privateChannel, err := t.ConversationInfo(inviteTarget)
 	if err != nil {
 		return marvin.CmdError(args, err, "Could not retrieve information about the channel")
 	}
 	if privateChannel.IsMultiIM() {
 		return marvin.CmdFailuref(args, "You cannnot invite users to a multi-party IM.").WithNoEdit().WithSimpleUndo()
 	}
+	if !privateChannel.IsPrivateChannel() {
+		return marvin.CmdFailuref(args, "Command must be used from the private channel you want to invite people to.").WithNoEdit().WithSimpleUndo()
+	}
 
 	usage := func() marvin.CommandResult {
 		return marvin.CmdUsage(args, inviteHelp)
@@ -291,55 +278,33 @@ func (mod *AutoInviteModule) PostInvite(t marvin.Team, args *marvin.CommandArgum
 	// Handle edits
 
 	if args.IsEdit {
-		prev, ok := args.PreviousResult.Args.ModuleData.(postInviteResult)
-		if !ok {
+		var prev postInviteResult
+		if args.PreviousResult.Args.LoadModuleData(&prev) != nil {
 			return marvin.CmdFailuref(args, "Bad edit data").WithNoEdit().WithNoUndo()
 		}
 		args.SetModuleData(prev)
-		form := url.Values{
-			"ts":      []string{string(prev.MsgID.MessageTS)},
-			"channel": []string{string(prev.MsgID.ChannelID)},
-			"as_user": []string{"true"},
-			"text":    []string{msg},
-			"parse":   []string{"client"},
-		}
-		err := mod.team.SlackAPIPostJSON("chat.update", form, nil)
+		err := mod.team.UpdateMessage(prev.MsgID, slack.OutgoingSlackMessage{Text: msg})
 		if err != nil {
 			return marvin.CmdError(args, err, "Error editing message")
 		}
 		if prev.Emoji != emoji {
-			form := url.Values{
-				"name":      []string{prev.Emoji},
-				"channel":   []string{string(prev.MsgID.ChannelID)},
-				"timestamp": []string{string(prev.MsgID.MessageTS)},
-			}
-			mod.team.SlackAPIPostJSON("reactions.remove", form, nil)
+			mod.team.RemoveReaction(prev.MsgID, prev.Emoji)
 			go mod.team.ReactMessage(prev.MsgID, emoji)
 			prev.Emoji = emoji
 		}
 		return marvin.CmdSuccess(args, fmt.Sprintf("Message updated: %s", t.ArchiveURL(prev.MsgID))).WithEdit().WithCustomUndo()
 	}
 	if args.IsUndo {
-		prev, ok := args.PreviousResult.Args.ModuleData.(postInviteResult)
-		if !ok {
+		var prev postInviteResult
+		if args.PreviousResult.Args.LoadModuleData(&prev) != nil {
 			return marvin.CmdFailuref(args, "Bad edit data").WithNoEdit().WithNoUndo()
 		}
 		args.SetModuleData(prev)
 
-		form := url.Values{
-			"name":      []string{prev.Emoji},
-			"channel":   []string{string(prev.MsgID.ChannelID)},
-			"timestamp": []string{string(prev.MsgID.MessageTS)},
-		}
-		util.LogIfError(mod.team.SlackAPIPostJSON("reactions.remove", form, nil))
-		form = url.Values{
-			"ts":      []string{string(prev.MsgID.MessageTS)},
-			"channel": []string{string(prev.MsgID.ChannelID)},
-			"as_user": []string{"true"},
-			"text":    []string{fmt.Sprintf("(Invite to %s retracted)", prev.TargetName)},
-			"parse":   []string{"client"},
-		}
-		err := mod.team.SlackAPIPostJSON("chat.update", form, nil)
+		util.LogIfError(mod.team.RemoveReaction(prev.MsgID, prev.Emoji))
+		err := mod.team.UpdateMessage(prev.MsgID, slack.OutgoingSlackMessage{
+			Text: fmt.Sprintf("(Invite to %s retracted)", prev.TargetName),
+		})
 		if err != nil {
 			return marvin.CmdError(args, err, "Error editing message")
 		}
@@ -366,14 +331,7 @@ func (mod *AutoInviteModule) PostInvite(t marvin.Team, args *marvin.CommandArgum
 	_ = callbackBytes
 	if err != nil {
 		// Failed to save, delete the message
-		form := url.Values{
-			"ts":      []string{string(msgID.MessageTS)},
-			"channel": []string{string(msgID.ChannelID)},
-			"as_user": []string{"true"},
-			"text":    []string{fmt.Sprintf("(Invite to %s cancelled due to internal error)", inviteTarget)},
-			"parse":   []string{"client"},
-		}
-		util.LogIfError(t.SlackAPIPostJSON("chat.delete", form, nil))
+		util.LogIfError(t.DeleteMessage(msgID))
 		return marvin.CmdError(args, err, "Error saving message")
 	}
 	err = t.ReactMessage(msgID, emoji)
@@ -395,7 +353,7 @@ func (mod *AutoInviteModule) SaveInvite(
 	defer stmt.Close()
 
 	_, err = stmt.Exec(
-		bool(sentMsgId.ChannelID[0] == 'C'),
+		mod.isPublicChannel(sentMsgId.ChannelID),
 		string(args.Source.ChannelID()), string(args.Source.UserID()), string(args.Source.MsgTimestamp()),
 		string(sentMsgId.ChannelID), string(sentMsgId.MessageTS),
 		emoji, text,
@@ -407,6 +365,13 @@ func (mod *AutoInviteModule) SaveInvite(
 }
 
 func (mod *AutoInviteModule) CmdRevokeInvite(t marvin.Team, args *marvin.CommandArguments) marvin.CommandResult {
+	return marvin.CmdConfirm(args, fmt.Sprintf("Revoke all standing invitations to %v?", t.FormatChannel(args.Source.ChannelID())),
+		func() marvin.CommandResult {
+			return mod.revokeInvites(args)
+		})
+}
+
+func (mod *AutoInviteModule) revokeInvites(args *marvin.CommandArguments) marvin.CommandResult {
 	stmt, err := mod.team.DB().Prepare(sqlRevokeInvite)
 	if err != nil {
 		return marvin.CmdError(args, err, "database error")
@@ -427,12 +392,8 @@ func (mod *AutoInviteModule) CmdRevokeInvite(t marvin.Team, args *marvin.Command
 			continue
 		}
 
-		form := url.Values{
-			"name":      []string{emoji},
-			"channel":   []string{channel},
-			"timestamp": []string{ts},
-		}
-		err = mod.team.SlackAPIPostJSON("reactions.remove", form, nil)
+		msgID := slack.MsgID(slack.ChannelID(channel), slack.MessageTS(ts))
+		err = mod.team.RemoveReaction(msgID, emoji)
 		if err, ok := errors.Cause(err).(slack.APIResponse); ok {
 			if err.SlackError == "no_reaction" {
 				continue
@@ -442,16 +403,24 @@ func (mod *AutoInviteModule) CmdRevokeInvite(t marvin.Team, args *marvin.Command
 			util.LogError(err)
 			continue
 		}
-		form = url.Values{
-			"channel": []string{channel},
-			"ts":      []string{ts},
-			"as_user": []string{"true"},
-			"text":    []string{"(Invite deleted)"},
-			"parse":   []string{"client"},
-		}
-		util.LogIfError(mod.team.SlackAPIPostJSON("chat.update", form, nil))
+		util.LogIfError(mod.team.UpdateMessage(msgID, slack.OutgoingSlackMessage{Text: "(Invite deleted)"}))
 		count++
 	}
 
 	return marvin.CmdSuccess(args, fmt.Sprintf("%d invite messages revoked.", count))
 }
+
+// isAlreadyInChannel returns whether an invite failed because the user was
+// already in the channel.
+func isAlreadyInChannel(err error) bool {
+	slErr, ok := errors.Cause(err).(slack.APIResponse)
+	return ok && slErr.SlackError == "already_in_channel"
+}
+
+func (mod *AutoInviteModule) isPublicChannel(channel slack.ChannelID) bool {
+	conv, err := mod.team.ConversationInfo(channel)
+	if err != nil {
+		return false
+	}
+	return conv.IsPublicChannel()
+}
@@ -2,7 +2,6 @@ package autoinvite
 
 import (
 	"fmt"
-	"net/url"
 	"sync"
 
 	"github.com/riking/marvin"
@@ -10,31 +9,44 @@ import (
 )
 
 const usageMass = "*`@marvin mass-invite`* will invite multiple people to a channel at once. " +
-	"Arguments: a list of user mentions or usernames. " +
+	"Arguments: a list of user mentions, usernames, or user groups. " +
 	"Use the command from the channel you want to invite users to."
 
 func CmdMassInvite(t marvin.Team, args *marvin.CommandArguments) marvin.CommandResult {
 	if len(args.Arguments) == 0 {
 		return marvin.CmdUsage(args, usageMass).WithSimpleUndo()
 	}
-	method := "channels.invite"
-	if args.Source.ChannelID()[0] == 'G' {
-		method = "groups.invite"
-	} else if args.Source.ChannelID()[0] == 'C' {
-		// ok
-	} else {
+	conv, err := t.ConversationInfo(args.Source.ChannelID())
+	if err != nil {
+		return marvin.CmdError(args, err, "Could not retrieve information about the channel")
+	}
+	switch conv.Type() {
+	case slack.ConversationIM, slack.ConversationMPIM:
 		return marvin.CmdFailuref(args, "Cannot invite to a DM.").WithNoUndo()
 	}
 
 	var userIDs []slack.UserID
+	seen := make(map[slack.UserID]bool)
 	for _, v := range args.Arguments {
-		uid := t.ResolveUserName(v)
-		if uid == "" {
-			return marvin.CmdFailuref(args, "Error: '%s' is not a Slack username", v).WithSimpleUndo()
+		uids := t.ResolveUserNames(v)
+		if len(uids) == 0 {
+			return marvin.CmdFailuref(args, "Error: '%s' is not a Slack username or user group", v).WithSimpleUndo()
+		}
+		for _, uid := range uids {
+			if !seen[uid] {
+				seen[uid] = true
+				userIDs = append(userIDs, uid)
+			}
 		}
-		userIDs = append(userIDs, uid)
 	}
 
+	return marvin.CmdConfirm(args, fmt.Sprintf("Invite %d users to %v?", len(userIDs), t.FormatChannel(args.Source.ChannelID())),
+		func() marvin.CommandResult {
+			return massInvite(t, args, userIDs)
+		})
+}
+
+func massInvite(t marvin.Team, args *marvin.CommandArguments, userIDs []slack.UserID) marvin.CommandResult {
 	workers := 3
 	if workers > len(userIDs)/2 {
 		workers = (len(userIDs) / 2) + 1
@@ -52,11 +64,8 @@ func CmdMassInvite(t marvin.Team, args *marvin.CommandArguments) marvin.CommandR
 				counts[i] = count
 			}()
 
-			var form = make(url.Values)
-			form.Set("channel", string(args.Source.ChannelID()))
 			for uid := range ch {
-				form.Set("user", string(uid))
-				err := t.SlackAPIPostJSON(method, form, nil)
+				err := t.InviteToConversation(args.Source.ChannelID(), uid)
 				if err != nil {
 					continue
 					//if slErr, ok := err.(slack.APIResponse); ok {
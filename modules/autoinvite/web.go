@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"html/template"
 	"net/http"
-	"net/url"
 	"regexp"
 	"strconv"
 	"strings"
@@ -218,15 +217,9 @@ func (mod *AutoInviteModule) HTTPInvite(w http.ResponseWriter, r *http.Request)
 
 	channelID := m[1]
 
-	var response struct {
-		AlreadyInGroup bool `json:"already_in_group"`
-	}
-	form := url.Values{
-		"channel": []string{channelID},
-		"user":    []string{string(user.SlackUser)},
-	}
-	err = mod.team.SlackAPIPostJSON("groups.invite", form, &response)
-	if err != nil {
+	err = mod.team.InviteToConversation(slack.ChannelID(channelID), user.SlackUser)
+	alreadyJoined := isAlreadyInChannel(err)
+	if err != nil && !alreadyJoined {
 		w.WriteHeader(http.StatusBadGateway)
 		json.NewEncoder(w).Encode(jsonResponse{
 			OK: false,
@@ -246,7 +239,7 @@ func (mod *AutoInviteModule) HTTPInvite(w http.ResponseWriter, r *http.Request)
 		Data: struct {
 			AlreadyJoined bool `json:"already_joined"`
 		}{
-			AlreadyJoined: response.AlreadyInGroup,
+			AlreadyJoined: alreadyJoined,
 		},
 	})
 }
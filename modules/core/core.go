@@ -28,6 +28,7 @@ func (mod *DebugModule) Identifier() marvin.ModuleID {
 }
 
 func (mod *DebugModule) Load(t marvin.Team) {
+	mod.registerHTTP()
 }
 
 const (
@@ -43,7 +44,7 @@ func (mod *DebugModule) Enable(t marvin.Team) {
 		"The `config` command manipulates team-wide configuration. Most subcommands are restricted to admins.\n" +
 			helpSet + "\n" + helpGet + "\n" + helpList,
 	)
-	parent.RegisterCommandFunc("set", mod.CommandConfigSet, helpSet)
+	parent.RegisterCommand("set", marvin.RequireLevel(marvin.AccessLevelAdmin, marvin.CommandFunc(mod.CommandConfigSet, helpSet)))
 	parent.RegisterCommandFunc("get", mod.CommandConfigGet, helpGet)
 	parent.RegisterCommandFunc("list", mod.CommandConfigList, helpList)
 	t.RegisterCommand("config", parent)
@@ -135,28 +136,28 @@ func (mod *DebugModule) CommandConfigSet(t marvin.Team, args *marvin.CommandArgu
 	case 2, 3:
 		break
 	}
-	if args.Source.AccessLevel() < marvin.AccessLevelAdmin {
-		return marvin.CmdFailuref(args, "Sorry, %v, I can't let you do that. `config set` is restricted to admins.", args.Source.UserID()).WithSimpleUndo()
-	}
-
 	module := marvin.ModuleID(args.Arguments[0])
 	key := args.Arguments[1]
 
 	conf := mod.team.ModuleConfig(module)
-	if len(args.Arguments) == 3 {
-		value := args.Arguments[2]
-		err := conf.Set(key, value)
-		if err != nil {
-			return marvin.CmdError(args, err, "Database error")
-		}
-		return marvin.CmdSuccess(args, "Configuration value set").WithNoUndo()
-	} else if conf == nil {
+	if conf == nil {
 		return marvin.CmdFailuref(args, "'%s' is not a valid module name", module).WithSimpleUndo()
+	} else if len(args.Arguments) == 3 {
+		value := args.Arguments[2]
+		return marvin.CmdConfirm(args, fmt.Sprintf("Set `%s.%s` to `%s`?", module, key, value), func() marvin.CommandResult {
+			err := conf.Set(key, value)
+			if err != nil {
+				return marvin.CmdError(args, err, "Database error")
+			}
+			return marvin.CmdSuccess(args, "Configuration value set").WithNoUndo()
+		})
 	} else {
-		err := conf.SetDefault(key)
-		if err != nil {
-			return marvin.CmdError(args, err, "Database error")
-		}
-		return marvin.CmdSuccess(args, "Configuration value reset to default").WithNoUndo()
+		return marvin.CmdConfirm(args, fmt.Sprintf("Reset `%s.%s` to the default?", module, key), func() marvin.CommandResult {
+			err := conf.SetDefault(key)
+			if err != nil {
+				return marvin.CmdError(args, err, "Database error")
+			}
+			return marvin.CmdSuccess(args, "Configuration value reset to default").WithNoUndo()
+		})
 	}
 }
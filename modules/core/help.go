@@ -0,0 +1,72 @@
+package core
+
+import (
+	"html/template"
+	"net/http"
+	"strings"
+
+	"github.com/riking/marvin"
+	"github.com/riking/marvin/modules/weblogin"
+	"github.com/riking/marvin/util"
+)
+
+func (mod *DebugModule) registerHTTP() {
+	mod.team.Router().Path("/help").Methods(http.MethodGet).HandlerFunc(mod.HTTPHelp)
+}
+
+var tmplHelp = template.Must(weblogin.LayoutTemplateCopy().Parse(string(weblogin.MustAsset("templates/help.html"))))
+
+type bodyHelp struct {
+	Layout   *weblogin.LayoutContent
+	Search   string
+	Commands []*marvin.CommandInfo
+	// Matched is false if a search found nothing.
+	Matched bool
+}
+
+// HTTPHelp shows the reference for every registered command.
+func (mod *DebugModule) HTTPHelp(w http.ResponseWriter, r *http.Request) {
+	lc, err := weblogin.NewLayoutContent(mod.team, w, r, weblogin.NavSectionHelp)
+	if err != nil {
+		mod.team.GetModule(weblogin.Identifier).(weblogin.API).HTTPError(w, r, err)
+		return
+	}
+	lc.Title = "Command Reference"
+	data := bodyHelp{
+		Layout:   lc,
+		Search:   strings.TrimSpace(r.URL.Query().Get("q")),
+		Commands: mod.team.CommandTree(),
+	}
+	if data.Search != "" {
+		data.Commands = filterCommandTree(data.Commands, strings.ToLower(data.Search))
+	}
+	data.Matched = len(data.Commands) > 0
+
+	lc.BodyData = data
+	util.LogIfError(tmplHelp.ExecuteTemplate(w, "layout", lc))
+}
+
+// filterCommandTree keeps the commands whose name, aliases or help text
+// contain the search term, along with their parents.
+func filterCommandTree(list []*marvin.CommandInfo, term string) []*marvin.CommandInfo {
+	var result []*marvin.CommandInfo
+	for _, ci := range list {
+		matched := strings.Contains(ci.Name(), term) ||
+			strings.Contains(strings.ToLower(ci.Help), term)
+		for _, alias := range ci.Aliases {
+			if strings.Contains(alias, term) {
+				matched = true
+			}
+		}
+		subs := filterCommandTree(ci.Subcommands, term)
+		if !matched && len(subs) == 0 {
+			continue
+		}
+		copied := *ci
+		if !matched {
+			copied.Subcommands = subs
+		}
+		result = append(result, &copied)
+	}
+	return result
+}
@@ -1,6 +1,7 @@
 package debug
 
 import (
+	"bytes"
 	"fmt"
 	"strings"
 	"time"
@@ -9,6 +10,7 @@ import (
 	"github.com/riking/marvin"
 	"github.com/riking/marvin/modules/paste"
 	"github.com/riking/marvin/slack"
+	"github.com/riking/marvin/slack/rtm"
 )
 
 func init() {
@@ -49,12 +51,15 @@ func (mod *DebugModule) Enable(t marvin.Team) {
 	parent.RegisterCommandFunc("do_help", mod.DebugCommandHelp, "`debug do_help` tests the behavior of commands returning help text.")
 	parent.RegisterCommandFunc("success", mod.DebugCommandSuccess, "`debug success` tests the behavior of successful commands.")
 	parent.RegisterCommandFunc("paste", mod.DebugCommandPaste, "`debug paste` tests the paste module.")
+	parent.RegisterCommand("cache", marvin.RequireLevel(marvin.AccessLevelAdmin,
+		marvin.CommandFunc(mod.CommandCache, "`debug cache [resync]` shows the state of the user and channel cache, or fetches the user and channel lists from Slack again.")))
 
 	whoami := parent.RegisterCommandFunc("whoami", mod.CommandWhoAmI, "`debug whoami [@user]` prints out your Slack user ID.")
 	whereami := parent.RegisterCommandFunc("whereami", mod.CommandWhereAmI, "`debug whereami` prints out the current channel ID.")
 
 	t.RegisterCommand("debug", parent)
-	t.RegisterCommandFunc("echo", mod.CommandEcho, "`echo` echos back the command arguments to the channel.")
+	t.RegisterCommand("echo", marvin.RequireLevel(marvin.AccessLevelAdmin,
+		marvin.CommandFunc(mod.CommandEcho, "`echo` echos back the command arguments to the channel.")))
 	t.RegisterCommand("whoami", whoami)
 	t.RegisterCommand("whereami", whereami)
 }
@@ -104,9 +109,6 @@ func (mod *DebugModule) DebugCommandPaste(t marvin.Team, args *marvin.CommandArg
 }
 
 func (mod *DebugModule) CommandEcho(t marvin.Team, args *marvin.CommandArguments) marvin.CommandResult {
-	if args.Source.AccessLevel() < marvin.AccessLevelAdmin {
-		return marvin.CmdFailuref(args, "This command is restricted to admins.")
-	}
 	return marvin.CmdSuccess(args, strings.Join(args.Arguments, " ")).WithReplyType(marvin.ReplyTypeFlagOmitUsername).WithEdit()
 }
 
@@ -135,7 +137,8 @@ func (mod *DebugModule) CommandWhereAmI(t marvin.Team, args *marvin.CommandArgum
 	//	}
 	//}
 	return marvin.CmdSuccess(args,
-		fmt.Sprintf("You sent that command in channel ID %s.", string(cid)),
+		fmt.Sprintf("You sent that command in channel ID %s. %d messages are waiting to be sent here.",
+			string(cid), t.QueueDepth(cid)),
 	)
 }
 
@@ -145,3 +148,52 @@ func mustTime(t time.Time, e error) time.Time {
 	}
 	return t
 }
+
+func (mod *DebugModule) CommandCache(t marvin.Team, args *marvin.CommandArguments) marvin.CommandResult {
+	client, ok := t.GetRTMClient().(*rtm.Client)
+	if !ok {
+		return marvin.CmdFailuref(args, "No RTM client")
+	}
+
+	if len(args.Arguments) > 0 {
+		if args.Arguments[0] != "resync" {
+			return marvin.CmdUsage(args, "`debug cache [resync]`")
+		}
+		stats, _ := client.CacheStats()
+		if stats.Refreshing {
+			return marvin.CmdFailuref(args, "A resync is already running.")
+		}
+		go func() {
+			err := client.RefreshCache()
+			if err != nil {
+				t.ReportError(errors.Wrap(err, "cache resync"), args.Source)
+			}
+		}()
+		return marvin.CmdSuccess(args, "Resync started. Run `debug cache` to check on it.")
+	}
+
+	stats, err := client.CacheStats()
+	if err != nil {
+		return marvin.CmdError(args, err, "Could not get cache stats")
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "*Users*: %d in memory, %d saved\n", stats.Users, stats.StoredUsers)
+	fmt.Fprintf(&buf, "*Channels*: %d public and %d private in memory, %d saved\n",
+		stats.Channels, stats.Groups, stats.StoredChannels)
+	fmt.Fprintf(&buf, "*Updates*: %d since startup, %d failed writes\n", stats.Updates, stats.WriteErrors)
+	if !stats.LoadedAt.IsZero() {
+		fmt.Fprintf(&buf, "Loaded from the database at %s\n", stats.LoadedAt.Format(time.RFC1123))
+	}
+	if stats.Refreshing {
+		buf.WriteString("A resync is running now.\n")
+	}
+	if !stats.LastRefresh.IsZero() {
+		fmt.Fprintf(&buf, "Last resync started at %s and took %v",
+			stats.LastRefresh.Format(time.RFC1123), stats.RefreshDuration.Round(time.Second))
+		if stats.RefreshError != nil {
+			fmt.Fprintf(&buf, ", but failed: %v", stats.RefreshError)
+		}
+		buf.WriteByte('\n')
+	}
+	return marvin.CmdSuccess(args, buf.String())
+}
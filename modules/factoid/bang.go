@@ -3,7 +3,6 @@ package factoid
 import (
 	"context"
 	"fmt"
-	"net/url"
 	"strings"
 	"sync"
 	"time"
@@ -79,10 +78,17 @@ func (mod *BangFactoidModule) OnMessage(_rtm slack.RTMRawMessage) {
 	if result == "" {
 		return
 	}
-	sentMsgID, _, err := mod.team.SendMessage(rtm.ChannelID(), " "+atcommand.SanitizeForChannel(result))
-	if err != nil {
-		util.LogError(err)
-		return
+	var sentMsgID slack.MessageTS
+	result, snippet := mod.longOutput(rtm.ChannelID(), result)
+	if snippet != nil {
+		sentMsgID = snippet.ShareTS(rtm.ChannelID())
+	} else {
+		var err error
+		sentMsgID, _, err = mod.team.SendMessage(rtm.ChannelID(), " "+atcommand.SanitizeForChannel(result))
+		if err != nil {
+			util.LogError(err)
+			return
+		}
 	}
 	record := resultInfo{Response: slack.MsgID(rtm.ChannelID(), sentMsgID), SideEffects: of.SideEffects}
 	mod.messagesLock.Lock()
@@ -116,19 +122,26 @@ func (mod *BangFactoidModule) OnEdit(_rtm slack.RTMRawMessage) {
 	if result == "" {
 		result = "(removed)"
 	}
+	// The response is edited in place, so it can't become a snippet
+	result, _ = mod.longOutput("", result)
 	if of.SideEffects {
 		record.SideEffects = true
 		mod.messagesLock.Lock()
 		mod.recentMessages[rtm.MessageID()] = record
 		mod.messagesLock.Unlock()
 	}
-	form := url.Values{
-		"channel": []string{string(record.Response.ChannelID)},
-		"ts":      []string{string(record.Response.MessageTS)},
-		"text":    []string{atcommand.SanitizeForChannel(result)},
-		"parse":   []string{"client"},
+	util.LogIfError(mod.team.UpdateMessage(record.Response, slack.OutgoingSlackMessage{
+		Text: atcommand.SanitizeForChannel(result),
+	}))
+}
+
+// longOutput shortens output that is too long to post. See
+// FactoidModule.longOutput.
+func (mod *BangFactoidModule) longOutput(channel slack.ChannelID, result string) (string, *slack.File) {
+	if len(result) <= slack.MaxMessageLength {
+		return result, nil
 	}
-	util.LogIfError(mod.team.SlackAPIPostJSON("chat.update", form, nil))
+	return mod.team.GetModule(Identifier).(*FactoidModule).longOutput(channel, result)
 }
 
 func (mod *BangFactoidModule) Process(rtm slack.SlackTextMessage) (string, OutputFlags) {
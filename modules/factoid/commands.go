@@ -2,9 +2,13 @@ package factoid
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	flag "github.com/ogier/pflag"
 	"github.com/pkg/errors"
@@ -29,6 +33,14 @@ func makeRememberArgs() *rememberArgs {
 	return obj
 }
 
+// rememberUndo is the module data of remember, for undo.
+type rememberUndo struct {
+	Name         string          `json:"name"`
+	ScopeChannel slack.ChannelID `json:"scope"`
+	Channel      slack.ChannelID `json:"channel"`
+	MessageTS    slack.MessageTS `json:"ts"`
+}
+
 const (
 	helpRemember = "`@marvin remember [--local] [name] [value]` (alias `r`) saves a factoid."
 	helpGet      = "`factoid get <name> [args...]` runs a factoid with the standard argument parsing instead of the factoid argument parsing."
@@ -36,10 +48,27 @@ const (
 	helpInfo     = "`factoid info [-f] <name>` views detailed information about a factoid."
 	helpList     = "`factoid list [pattern]` lists all factoids with `pattern` in their name."
 	helpForget   = "`factoid forget <name>` forgets the most recent version of a factoid."
-	helpUnforget = "`factoid unforget <name>` un-forgets a previously forgotten factoid."
+	helpUnforget = "`factoid unforget [--local] <name>` un-forgets a previously forgotten factoid."
+	helpLock     = "`factoid lock [--local] <name>` prevents a factoid from being changed or forgotten. Locking a global factoid requires admin; locking a local factoid requires channel admin."
+	helpUnlock   = "`factoid unlock [--local] <name>` allows a locked factoid to be changed again."
+	helpHistory  = "`factoid history [--local] <name>` lists the previous versions of a factoid."
+	helpSearch   = "`factoid search <terms>` searches the names and contents of factoids."
+	helpRevert   = "`factoid revert [--local] <name> <revision>` saves an old revision of a factoid as the current version. Revision numbers are shown by `factoid history`."
+	helpExport   = "`factoid export [--history] [--data]` sends you a JSON file with every factoid. `--history` includes old and forgotten versions, and `--data` includes the fdata maps. Requires admin."
+	helpImport   = "`factoid import [--mode skip|overwrite|rename] [--dry-run] <file>` imports factoids from a JSON file uploaded to Slack, given as a link or file ID. " +
+		"The file can be a `factoid export` file or a JSON object of factoid names to text. `--mode` chooses what to do with factoids that already exist (default skip). Requires admin."
 )
 
+// importMaxSize is the largest file `factoid import` will download.
+const importMaxSize = 10 * 1024 * 1024
+
+var rgxSlackFileID = regexp.MustCompile(`\bF[A-Z0-9]{6,}\b`)
+
 func (mod *FactoidModule) CmdRemember(t marvin.Team, args *marvin.CommandArguments) marvin.CommandResult {
+	if args.IsUndo {
+		return mod.undoRemember(args)
+	}
+
 	flags := makeRememberArgs()
 	flagErr := flags.flagSet.Parse(args.Arguments)
 	if flagErr == flag.ErrHelp {
@@ -74,25 +103,9 @@ func (mod *FactoidModule) CmdRemember(t marvin.Team, args *marvin.CommandArgumen
 		return marvin.CmdError(args, err, "Could not check existing factoid")
 	}
 
-	if prevFactoidInfo.IsLocked {
-		if flags.makeLocal {
-			// Overriding a locked global with a local is OK
-			if prevFactoidInfo.ScopeChannel != "" {
-				flags.wasLockFailure = true
-				if args.Source.AccessLevel() < marvin.AccessLevelChannelAdmin {
-					return marvin.CmdFailuref(args, "Factoid is locked (last edited by %v)", prevFactoidInfo.LastUser)
-				} else {
-					return marvin.CmdFailuref(args, "Factoid is locked; use `@marvin factoid unlock %s` to edit.", factoidName).WithEdit()
-				}
-			}
-		} else {
-			flags.wasLockFailure = true
-			if args.Source.AccessLevel() < marvin.AccessLevelAdmin {
-				return marvin.CmdFailuref(args, "Factoid is locked (last edited by %v)", prevFactoidInfo.LastUser)
-			} else {
-				return marvin.CmdFailuref(args, "Factoid is locked; use `@marvin factoid unlock %s` to edit.", factoidName).WithEdit()
-			}
-		}
+	if isLockedFor(prevFactoidInfo, scopeChannel) {
+		flags.wasLockFailure = true
+		return lockFailure(args, prevFactoidInfo, factoidName, flags.makeLocal)
 	}
 
 	fi := Factoid{
@@ -112,9 +125,79 @@ func (mod *FactoidModule) CmdRemember(t marvin.Team, args *marvin.CommandArgumen
 	if err != nil {
 		return marvin.CmdError(args, err, "Could not save factoid")
 	}
+	args.SetModuleData(rememberUndo{
+		Name:         factoidName,
+		ScopeChannel: scopeChannel,
+		Channel:      args.Source.ChannelID(),
+		MessageTS:    args.Source.MsgTimestamp(),
+	})
 	return marvin.CmdSuccess(args, "").WithCustomUndo().WithEdit()
 }
 
+// isLockedFor reports whether the lock on the current version of a factoid
+// prevents saving a new version in the scope. Overriding a locked global
+// with a local is OK.
+func isLockedFor(prevFactoidInfo *Factoid, scopeChannel slack.ChannelID) bool {
+	if !prevFactoidInfo.IsLocked {
+		return false
+	}
+	return scopeChannel == "" || prevFactoidInfo.ScopeChannel != ""
+}
+
+// lockFailure is the result of a command stopped by a factoid lock. Users
+// who can unlock the factoid are told how.
+func lockFailure(args *marvin.CommandArguments, prevFactoidInfo *Factoid, factoidName string, makeLocal bool) marvin.CommandResult {
+	unlockLevel := marvin.AccessLevelAdmin
+	unlockCmd := fmt.Sprintf("@marvin factoid unlock %s", factoidName)
+	if makeLocal {
+		unlockLevel = marvin.AccessLevelChannelAdmin
+		unlockCmd = fmt.Sprintf("@marvin factoid unlock --local %s", factoidName)
+	}
+	if args.Source.AccessLevel() < unlockLevel {
+		return marvin.CmdFailuref(args, "Factoid is locked (last edited by %v)", prevFactoidInfo.LastUser)
+	}
+	return marvin.CmdFailuref(args, "Factoid is locked; use `%s` to edit.", unlockCmd).WithEdit()
+}
+
+// undoRemember forgets the versions of a factoid that were saved by a
+// remember command and its edits.
+func (mod *FactoidModule) undoRemember(args *marvin.CommandArguments) marvin.CommandResult {
+	var prev rememberUndo
+	if args.PreviousResult.Code != marvin.CmdResultOK {
+		// Nothing was saved
+		return marvin.CmdSuccess(args, "").WithNoEdit().WithNoUndo()
+	}
+	if args.PreviousResult.Args.LoadModuleData(&prev) != nil {
+		return marvin.CmdFailuref(args, "Bad undo data").WithNoEdit().WithNoUndo()
+	}
+
+	history, err := mod.GetFactoidHistory(prev.Name, prev.ScopeChannel)
+	if err != nil {
+		return marvin.CmdError(args, err, "Error retrieving factoid history")
+	}
+	var saved []int64
+	for i := range history {
+		v := &history[i]
+		if v.ScopeChannel != prev.ScopeChannel || v.IsForgotten {
+			continue
+		}
+		if v.LastChannel != prev.Channel || v.LastMessage != prev.MessageTS {
+			continue
+		}
+		if v.IsLocked {
+			return marvin.CmdFailuref(args, "`%s` was locked after it was saved, and cannot be forgotten.", prev.Name).WithNoEdit().WithNoUndo()
+		}
+		saved = append(saved, v.DbID)
+	}
+	for _, dbID := range saved {
+		err = mod.ForgetFactoid(dbID, true)
+		if err != nil {
+			return marvin.CmdError(args, err, "Error forgetting factoid")
+		}
+	}
+	return marvin.CmdSuccess(args, fmt.Sprintf("Forgot %d saved version(s) of `%s`.", len(saved), prev.Name)).WithNoEdit().WithNoUndo()
+}
+
 func (mod *FactoidModule) CmdGet(t marvin.Team, args *marvin.CommandArguments) marvin.CommandResult {
 	if len(args.Arguments) < 1 {
 		return marvin.CmdUsage(args, helpGet)
@@ -255,6 +338,46 @@ func (mod *FactoidModule) CmdList(t marvin.Team, args *marvin.CommandArguments)
 	return marvin.CmdSuccess(args, buf.String())
 }
 
+// searchMaxResults is the number of results shown by `factoid search`.
+const searchMaxResults = 10
+
+func (mod *FactoidModule) CmdSearch(t marvin.Team, args *marvin.CommandArguments) marvin.CommandResult {
+	if len(args.Arguments) < 1 {
+		return marvin.CmdUsage(args, helpSearch)
+	}
+
+	terms := slack.UnescapeTextAll(strings.Join(args.Arguments, " "))
+	results, err := mod.SearchFactoids(terms, args.Source.ChannelID(), searchMaxResults)
+	if err != nil {
+		return marvin.CmdError(args, err, "Error searching factoids")
+	}
+	if len(results) == 0 {
+		return marvin.CmdSuccess(args, fmt.Sprintf("No factoids matching `%s`.", terms)).WithEdit().WithSimpleUndo()
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "Factoids matching `%s`:\n", terms)
+	for _, v := range results {
+		isLocal := ""
+		if v.ScopeChannel != "" {
+			isLocal = "*"
+		}
+		fmt.Fprintf(&buf, "• `%s`%s: %s\n", v.FactoidName, isLocal, slackSnippet(v.Snippet))
+	}
+	if len(results) == searchMaxResults {
+		fmt.Fprint(&buf, "(more results not shown)\n")
+	}
+	return marvin.CmdSuccess(args, buf.String()).WithEdit().WithSimpleUndo()
+}
+
+// slackSnippet formats a search snippet on one line, with the matches in
+// bold.
+func slackSnippet(snippet string) string {
+	snippet = strings.Join(strings.Fields(snippet), " ")
+	snippet = strings.Replace(snippet, SnippetMatchStart, "*", -1)
+	return strings.Replace(snippet, SnippetMatchEnd, "*", -1)
+}
+
 func (mod *FactoidModule) CmdForget(t marvin.Team, args *marvin.CommandArguments) marvin.CommandResult {
 	if len(args.Arguments) != 1 {
 		return marvin.CmdUsage(args, helpForget)
@@ -276,9 +399,378 @@ func (mod *FactoidModule) CmdForget(t marvin.Team, args *marvin.CommandArguments
 		return marvin.CmdFailuref(args, "A locked factoid cannot be forgotten.").WithEdit().WithSimpleUndo()
 	}
 
-	err = mod.ForgetFactoid(factoidInfo.DbID, true)
+	return marvin.CmdConfirm(args, fmt.Sprintf("Forget `%s` (database ID %d)?", factoidName, factoidInfo.DbID),
+		func() marvin.CommandResult {
+			err := mod.ForgetFactoid(factoidInfo.DbID, true)
+			if err != nil {
+				return marvin.CmdError(args, err, "Error forgetting factoid")
+			}
+			return marvin.CmdSuccess(args, fmt.Sprintf("Forgot `%s` with database ID %d", factoidName, factoidInfo.DbID)).WithNoEdit().WithNoUndo()
+		})
+}
+
+type scopeArgs struct {
+	flagSet   *flag.FlagSet
+	wantHelp  bool
+	makeLocal bool
+}
+
+func parseScopeArgs(command string, args *marvin.CommandArguments) (*scopeArgs, error) {
+	var obj = new(scopeArgs)
+	obj.flagSet = flag.NewFlagSet(command, flag.ContinueOnError)
+	obj.flagSet.BoolVarP(&obj.makeLocal, "local", ".", false, "use the local factoid for this channel")
+	err := obj.flagSet.Parse(args.Arguments)
+	if err == flag.ErrHelp {
+		obj.wantHelp = true
+	} else if err != nil {
+		return nil, err
+	} else if obj.flagSet.NArg() != 1 {
+		obj.wantHelp = true
+	}
+	return obj, nil
+}
+
+// scopedFactoidInfo gets the newest revision of a factoid, without falling
+// back to the global factoid if a local one was requested.
+func (mod *FactoidModule) scopedFactoidInfo(name string, scopeChannel slack.ChannelID, withForgotten bool) (*Factoid, error) {
+	factoidInfo, err := mod.GetFactoidInfo(name, scopeChannel, withForgotten)
+	if err != nil {
+		return nil, err
+	}
+	if factoidInfo.ScopeChannel != scopeChannel {
+		return nil, ErrNoSuchFactoid
+	}
+	return factoidInfo, nil
+}
+
+// canLock checks the access level needed to lock or unlock a factoid.
+func canLock(args *marvin.CommandArguments, scopeChannel slack.ChannelID) bool {
+	if scopeChannel != "" {
+		return args.Source.AccessLevel() >= marvin.AccessLevelChannelAdmin
+	}
+	return args.Source.AccessLevel() >= marvin.AccessLevelAdmin
+}
+
+// factoidFlagUndo is the module data of lock, unlock, and unforget, for
+// undo.
+type factoidFlagUndo struct {
+	DbID int64  `json:"id"`
+	Name string `json:"name"`
+	// Previous is the value of the flag before the command.
+	Previous bool `json:"prev"`
+}
+
+func (mod *FactoidModule) CmdLock(t marvin.Team, args *marvin.CommandArguments) marvin.CommandResult {
+	return mod.setLock(args, true, helpLock)
+}
+
+func (mod *FactoidModule) CmdUnlock(t marvin.Team, args *marvin.CommandArguments) marvin.CommandResult {
+	return mod.setLock(args, false, helpUnlock)
+}
+
+func (mod *FactoidModule) setLock(args *marvin.CommandArguments, isLocked bool, help string) marvin.CommandResult {
+	verb := "lock"
+	if !isLocked {
+		verb = "unlock"
+	}
+
+	if args.IsUndo {
+		var prev factoidFlagUndo
+		if args.PreviousResult.Args.LoadModuleData(&prev) != nil {
+			return marvin.CmdFailuref(args, "Bad undo data").WithNoEdit().WithNoUndo()
+		}
+		err := mod.LockFactoid(prev.DbID, prev.Previous)
+		if err != nil {
+			return marvin.CmdError(args, err, "Error changing the lock")
+		}
+		return marvin.CmdSuccess(args, fmt.Sprintf("Undid the %s of `%s`.", verb, prev.Name)).WithNoEdit().WithNoUndo()
+	}
+
+	flags, err := parseScopeArgs(verb, args)
+	if err != nil {
+		return marvin.CmdFailuref(args, "could not parse flags: %v", err)
+	}
+	if flags.wantHelp {
+		return marvin.CmdUsage(args, help).WithSimpleUndo()
+	}
+	factoidName := flags.flagSet.Arg(0)
+	if len(factoidName) > FactoidNameMaxLen {
+		return marvin.CmdFailuref(args, "Factoid name too long").WithSimpleUndo()
+	}
+	var scopeChannel slack.ChannelID
+	if flags.makeLocal {
+		scopeChannel = args.Source.ChannelID()
+	}
+
+	if !canLock(args, scopeChannel) {
+		if scopeChannel != "" {
+			return marvin.CmdFailuref(args, "Only channel admins can %s local factoids.", verb).WithSimpleUndo()
+		}
+		return marvin.CmdFailuref(args, "Only admins can %s global factoids.", verb).WithSimpleUndo()
+	}
+
+	factoidInfo, err := mod.scopedFactoidInfo(factoidName, scopeChannel, false)
+	if err == ErrNoSuchFactoid {
+		return marvin.CmdFailuref(args, "No such factoid").WithSimpleUndo()
+	} else if err != nil {
+		return marvin.CmdError(args, err, "Error retrieving factoid")
+	}
+	if factoidInfo.IsLocked == isLocked {
+		return marvin.CmdFailuref(args, "`%s` is already %sed.", factoidName, verb).WithSimpleUndo()
+	}
+
+	err = mod.LockFactoid(factoidInfo.DbID, isLocked)
+	if err != nil {
+		return marvin.CmdError(args, err, "Error changing the lock")
+	}
+	args.SetModuleData(factoidFlagUndo{DbID: factoidInfo.DbID, Name: factoidName, Previous: factoidInfo.IsLocked})
+	return marvin.CmdSuccess(args, fmt.Sprintf("%sed `%s`.", strings.Title(verb), factoidName)).WithNoEdit().WithCustomUndo()
+}
+
+func (mod *FactoidModule) CmdUnforget(t marvin.Team, args *marvin.CommandArguments) marvin.CommandResult {
+	if args.IsUndo {
+		var prev factoidFlagUndo
+		if args.PreviousResult.Args.LoadModuleData(&prev) != nil {
+			return marvin.CmdFailuref(args, "Bad undo data").WithNoEdit().WithNoUndo()
+		}
+		err := mod.ForgetFactoid(prev.DbID, prev.Previous)
+		if err != nil {
+			return marvin.CmdError(args, err, "Error forgetting factoid")
+		}
+		return marvin.CmdSuccess(args, fmt.Sprintf("Forgot `%s` again.", prev.Name)).WithNoEdit().WithNoUndo()
+	}
+
+	flags, err := parseScopeArgs("unforget", args)
+	if err != nil {
+		return marvin.CmdFailuref(args, "could not parse flags: %v", err)
+	}
+	if flags.wantHelp {
+		return marvin.CmdUsage(args, helpUnforget).WithSimpleUndo()
+	}
+	factoidName := flags.flagSet.Arg(0)
+	if len(factoidName) > FactoidNameMaxLen {
+		return marvin.CmdFailuref(args, "Factoid name too long").WithSimpleUndo()
+	}
+	var scopeChannel slack.ChannelID
+	if flags.makeLocal {
+		scopeChannel = args.Source.ChannelID()
+	}
+
+	factoidInfo, err := mod.scopedFactoidInfo(factoidName, scopeChannel, true)
+	if err == ErrNoSuchFactoid {
+		return marvin.CmdFailuref(args, "No such factoid").WithSimpleUndo()
+	} else if err != nil {
+		return marvin.CmdError(args, err, "Error retrieving factoid")
+	}
+	if !factoidInfo.IsForgotten {
+		return marvin.CmdFailuref(args, "`%s` is not forgotten.", factoidName).WithSimpleUndo()
+	}
+
+	err = mod.ForgetFactoid(factoidInfo.DbID, false)
+	if err != nil {
+		return marvin.CmdError(args, err, "Error un-forgetting factoid")
+	}
+	args.SetModuleData(factoidFlagUndo{DbID: factoidInfo.DbID, Name: factoidName, Previous: true})
+	return marvin.CmdSuccess(args, fmt.Sprintf("Un-forgot `%s` with database ID %d.", factoidName, factoidInfo.DbID)).WithNoEdit().WithCustomUndo()
+}
+
+// historyMaxEntries is the number of revisions shown by `factoid history`.
+const historyMaxEntries = 15
+
+func (mod *FactoidModule) CmdHistory(t marvin.Team, args *marvin.CommandArguments) marvin.CommandResult {
+	flags, err := parseScopeArgs("history", args)
+	if err != nil {
+		return marvin.CmdFailuref(args, "could not parse flags: %v", err)
+	}
+	if flags.wantHelp {
+		return marvin.CmdUsage(args, helpHistory).WithSimpleUndo()
+	}
+	factoidName := flags.flagSet.Arg(0)
+	if len(factoidName) > FactoidNameMaxLen {
+		return marvin.CmdFailuref(args, "Factoid name too long").WithSimpleUndo()
+	}
+	var scopeChannel slack.ChannelID
+	if flags.makeLocal {
+		scopeChannel = args.Source.ChannelID()
+	}
+
+	history, err := mod.GetFactoidHistory(factoidName, scopeChannel)
+	if err != nil {
+		return marvin.CmdError(args, err, "Error retrieving factoid history")
+	}
+	if len(history) == 0 || history[0].ScopeChannel != scopeChannel {
+		return marvin.CmdFailuref(args, "No such factoid").WithSimpleUndo()
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "History of `%s` (%d revisions):\n", factoidName, len(history))
+	for i := range history {
+		v := &history[i]
+		if i == historyMaxEntries {
+			fmt.Fprintf(&buf, "…and %d older revisions.\n", len(history)-i)
+			break
+		}
+		var status string
+		if v.IsLocked {
+			status += " (locked)"
+		}
+		if v.IsForgotten {
+			status += " (forgotten)"
+		}
+		fmt.Fprintf(&buf, "• ID %d%s by %v on <!date^%d^{date_short} {time}|%s>: %s\n",
+			v.DbID, status, v.LastUser,
+			v.LastTimestamp.Unix(), v.LastTimestamp.Format(time.RFC1123),
+			mod.team.ArchiveURL(slack.MsgID(v.LastChannel, v.LastMessage)),
+		)
+	}
+	return marvin.CmdSuccess(args, buf.String()).WithEdit().WithSimpleUndo()
+}
+
+func (mod *FactoidModule) CmdExport(t marvin.Team, args *marvin.CommandArguments) marvin.CommandResult {
+	var withHistory, withData bool
+	flagSet := flag.NewFlagSet("export", flag.ContinueOnError)
+	flagSet.BoolVar(&withHistory, "history", false, "include old and forgotten versions")
+	flagSet.BoolVar(&withData, "data", false, "include fdata maps")
+	err := flagSet.Parse(args.Arguments)
+	if err == flag.ErrHelp || (err == nil && flagSet.NArg() != 0) {
+		return marvin.CmdUsage(args, helpExport)
+	} else if err != nil {
+		return marvin.CmdFailuref(args, "could not parse flags: %v", err)
+	}
+
+	export, err := mod.ExportFactoids(withHistory, withData)
+	if err != nil {
+		return marvin.CmdError(args, err, "Error exporting factoids")
+	}
+	b, err := json.MarshalIndent(export, "", "\t")
+	if err != nil {
+		return marvin.CmdError(args, err, "Error exporting factoids")
+	}
+
+	// Local factoids can be from private channels, so only send to the admin
+	imChannel, err := t.GetIM(args.Source.UserID())
+	if err != nil {
+		return marvin.CmdError(args, err, "Could not open IM")
+	}
+	filename := fmt.Sprintf("factoids-%s.json", export.Exported.Format("2006-01-02"))
+	file, err := t.UploadFile(slack.FileUpload{
+		Filename: filename,
+		Title:    filename,
+		Filetype: "json",
+		Content:  b,
+	}, imChannel)
+	if err != nil {
+		return marvin.CmdError(args, err, "Could not upload export")
+	}
+	return marvin.CmdSuccess(args, fmt.Sprintf("Exported %d factoid revisions: %s", len(export.Factoids), file.Permalink)).
+		WithNoEdit().WithNoUndo().WithReplyType(marvin.ReplyTypePM)
+}
+
+func (mod *FactoidModule) CmdImport(t marvin.Team, args *marvin.CommandArguments) marvin.CommandResult {
+	var modeStr string
+	var dryRun bool
+	flagSet := flag.NewFlagSet("import", flag.ContinueOnError)
+	flagSet.StringVar(&modeStr, "mode", "skip", "what to do with existing factoids: skip, overwrite, or rename")
+	flagSet.BoolVarP(&dryRun, "dry-run", "n", false, "only report what would be imported")
+	err := flagSet.Parse(args.Arguments)
+	if err == flag.ErrHelp || (err == nil && flagSet.NArg() != 1) {
+		return marvin.CmdUsage(args, helpImport)
+	} else if err != nil {
+		return marvin.CmdFailuref(args, "could not parse flags: %v", err)
+	}
+	mode, err := ParseImportMode(modeStr)
+	if err != nil {
+		return marvin.CmdFailuref(args, "%v", err)
+	}
+	fileID := slack.FileID(rgxSlackFileID.FindString(flagSet.Arg(0)))
+	if fileID == "" {
+		return marvin.CmdFailuref(args, "`%s` is not a Slack file link or ID", flagSet.Arg(0))
+	}
+
+	fileInfo, err := t.FileInfo(fileID)
+	if err != nil {
+		return marvin.CmdError(args, err, "Could not get file")
+	}
+	b, err := t.DownloadFile(fileInfo, importMaxSize)
+	if _, ok := errors.Cause(err).(marvin.ErrFileTooLarge); ok {
+		return marvin.CmdFailuref(args, "%v", err)
+	} else if err != nil {
+		return marvin.CmdError(args, err, "Could not download file")
+	}
+	file, err := ParseImport(b)
+	if err != nil {
+		return marvin.CmdFailuref(args, "%v", err)
+	}
+
+	report, err := mod.ImportFactoids(file, mode, dryRun, args.Source)
+	if err != nil {
+		return marvin.CmdError(args, err, "Error importing factoids")
+	}
+	util.LogGood("Factoid import by", args.Source.UserID(), "-", report.String())
+	result := marvin.CmdSuccess(args, report.String()).WithNoEdit()
+	if dryRun {
+		return result.WithSimpleUndo()
+	}
+	return result.WithNoUndo()
+}
+
+func (mod *FactoidModule) CmdRevert(t marvin.Team, args *marvin.CommandArguments) marvin.CommandResult {
+	if args.IsUndo {
+		return mod.undoRemember(args)
+	}
+
+	var makeLocal bool
+	flagSet := flag.NewFlagSet("revert", flag.ContinueOnError)
+	flagSet.BoolVarP(&makeLocal, "local", ".", false, "revert the local factoid for this channel")
+	err := flagSet.Parse(args.Arguments)
+	if err == flag.ErrHelp || (err == nil && flagSet.NArg() != 2) {
+		return marvin.CmdUsage(args, helpRevert).WithNoEdit().WithSimpleUndo()
+	} else if err != nil {
+		return marvin.CmdFailuref(args, "could not parse flags: %v", err)
+	}
+	factoidName := flagSet.Arg(0)
+	if len(factoidName) > FactoidNameMaxLen {
+		return marvin.CmdFailuref(args, "Factoid name too long").WithEdit().WithSimpleUndo()
+	}
+	revision, err := strconv.ParseInt(strings.TrimPrefix(flagSet.Arg(1), "#"), 10, 64)
+	if err != nil {
+		return marvin.CmdFailuref(args, "`%s` is not a revision number", flagSet.Arg(1)).WithEdit().WithSimpleUndo()
+	}
+	var scopeChannel slack.ChannelID
+	if makeLocal {
+		scopeChannel = args.Source.ChannelID()
+	}
+
+	target, err := mod.GetFactoidRevision(factoidName, scopeChannel, revision)
+	if err == ErrNoSuchFactoid {
+		return marvin.CmdFailuref(args, "`%s` has no revision %d", factoidName, revision).WithEdit().WithSimpleUndo()
+	} else if err != nil {
+		return marvin.CmdError(args, err, "Error retrieving factoid history")
+	}
+
+	prevFactoidInfo, err := mod.GetFactoidInfo(factoidName, scopeChannel, false)
+	if err == ErrNoSuchFactoid {
+		prevFactoidInfo = &Factoid{IsLocked: false, ScopeChannel: ""}
+	} else if err != nil {
+		return marvin.CmdError(args, err, "Could not check existing factoid")
+	}
+	if isLockedFor(prevFactoidInfo, scopeChannel) {
+		return lockFailure(args, prevFactoidInfo, factoidName, makeLocal)
+	}
+	if prevFactoidInfo.ScopeChannel == scopeChannel && prevFactoidInfo.DbID == target.DbID {
+		return marvin.CmdFailuref(args, "Revision %d is already the current version of `%s`.", revision, factoidName).WithEdit().WithSimpleUndo()
+	}
+
+	util.LogGood("Reverting factoid", factoidName, "to revision", revision)
+	err = mod.SaveFactoid(factoidName, scopeChannel, target.RawSource, args.Source)
 	if err != nil {
-		return marvin.CmdError(args, err, "Error forgetting factoid")
+		return marvin.CmdError(args, err, "Could not save factoid")
 	}
-	return marvin.CmdSuccess(args, fmt.Sprintf("Forgot `%s` with database ID %d", factoidName, factoidInfo.DbID)).WithNoEdit().WithNoUndo()
+	args.SetModuleData(rememberUndo{
+		Name:         factoidName,
+		ScopeChannel: scopeChannel,
+		Channel:      args.Source.ChannelID(),
+		MessageTS:    args.Source.MsgTimestamp(),
+	})
+	return marvin.CmdSuccess(args, fmt.Sprintf("Reverted `%s` to revision %d.", factoidName, revision)).WithNoEdit().WithCustomUndo()
 }
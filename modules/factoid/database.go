@@ -35,6 +35,12 @@ const (
 	(name, channel_only, last_set, forgotten)
 	WHERE forgotten = FALSE`
 
+	// The expression must match the one in sqlSearchFactoids.
+	sqlMigrate4 = `
+	CREATE INDEX factoid_search ON module_factoid_factoids
+	USING GIN (to_tsvector('english', name || ' ' || rawtext))
+	WHERE forgotten = FALSE`
+
 	// $1 = name $2 = scopeChannel
 	sqlGetFactoid = `
 	SELECT rawtext, last_set_user, locked
@@ -93,6 +99,27 @@ const (
 	ORDER BY last_set DESC, name ASC, channel_only DESC
 	`
 
+	// Only the current version of each factoid is searched.
+	//
+	// $1 = search terms $2 = scopeChannel $3 = headline options $4 = limit
+	sqlSearchFactoids = `
+	SELECT f.id, f.name, f.channel_only, f.last_set_user, f.last_set, f.locked,
+		ts_rank(to_tsvector('english', f.name || ' ' || f.rawtext), query) AS rank,
+		ts_headline('english', f.rawtext, query, $3) AS snippet
+	FROM module_factoid_factoids f, plainto_tsquery('english', $1) query
+	WHERE to_tsvector('english', f.name || ' ' || f.rawtext) @@ query
+	AND f.forgotten = FALSE
+	AND (f.channel_only = $2 OR f.channel_only IS NULL)
+	AND NOT EXISTS (
+		SELECT 1 FROM module_factoid_factoids newer
+		WHERE newer.name = f.name
+		AND newer.channel_only IS NOT DISTINCT FROM f.channel_only
+		AND newer.forgotten = FALSE
+		AND newer.last_set > f.last_set
+	)
+	ORDER BY rank DESC, f.name ASC, f.channel_only DESC
+	LIMIT $4`
+
 	// $1 = isLocked $2 = dbID
 	sqlLockFactoid = `
 	UPDATE module_factoid_factoids
@@ -132,6 +159,7 @@ type Factoid struct {
 func (mod *FactoidModule) doMigrate(t marvin.Team) {
 	t.DB().MustMigrate(Identifier, 1478236994, sqlMigrate1, sqlMigrate2)
 	t.DB().MustMigrate(Identifier, 1484348222, sqlMigrate3)
+	t.DB().MustMigrate(Identifier, 1792339654, sqlMigrate4)
 }
 
 func (mod *FactoidModule) doSyntaxCheck(t marvin.Team) {
@@ -141,6 +169,7 @@ func (mod *FactoidModule) doSyntaxCheck(t marvin.Team) {
 		sqlMakeFactoid,
 		sqlListMatches,
 		sqlListMatchesWithInfo,
+		sqlSearchFactoids,
 		sqlLockFactoid,
 		sqlForgetFactoid,
 
@@ -243,14 +272,17 @@ func (mod *FactoidModule) GetFactoidHistory(name string, channel slack.ChannelID
 		return nil, errors.Wrap(err, "Database error")
 	}
 
+	defer rows.Close()
+
 	var resAry []Factoid
+	var rowChannel sql.NullString
 
 	for rows.Next() {
 		result := Factoid{
 			Mod: mod,
 		}
 		err = rows.Scan(
-			&result.DbID, &result.FactoidName, &result.RawSource, &scopeChannel,
+			&result.DbID, &result.FactoidName, &result.RawSource, &rowChannel,
 			(*string)(&result.LastUser), (*string)(&result.LastChannel), (*string)(&result.LastMessage),
 			&result.LastTimestamp,
 			&result.IsLocked, &result.IsForgotten,
@@ -258,10 +290,13 @@ func (mod *FactoidModule) GetFactoidHistory(name string, channel slack.ChannelID
 		if err != nil {
 			return nil, errors.Wrap(err, "Database error")
 		}
+		if rowChannel.Valid {
+			result.ScopeChannel = slack.ChannelID(rowChannel.String)
+		}
 		resAry = append(resAry, result)
 	}
 	if rows.Err() != nil {
-		return nil, errors.Wrap(err, "Database error")
+		return nil, errors.Wrap(rows.Err(), "Database error")
 	}
 	if scopeChannel.Valid && len(resAry) == 0 {
 		// retry without channel scope
@@ -270,6 +305,24 @@ func (mod *FactoidModule) GetFactoidHistory(name string, channel slack.ChannelID
 	return resAry, nil
 }
 
+// GetFactoidRevision returns one revision of a factoid from its history.
+// The revision must be in the given scope.
+func (mod *FactoidModule) GetFactoidRevision(name string, channel slack.ChannelID, dbID int64) (*Factoid, error) {
+	history, err := mod.GetFactoidHistory(name, channel)
+	if err != nil {
+		return nil, err
+	}
+	if channel == "_" {
+		channel = ""
+	}
+	for i := range history {
+		if history[i].DbID == dbID && history[i].ScopeChannel == channel {
+			return &history[i], nil
+		}
+	}
+	return nil, ErrNoSuchFactoid
+}
+
 // FillInfo transforms a bare FactoidInfo into a full FactoidInfo.
 func (fi *Factoid) FillInfo(channel slack.ChannelID) error {
 	if !fi.IsBareInfo {
@@ -396,6 +449,70 @@ func (mod *FactoidModule) ListFactoidsWithInfo(match string, channel slack.Chann
 	return list, nil
 }
 
+// Markers around the matched words in FactoidSearchResult.Snippet.
+const (
+	SnippetMatchStart = "\ue000"
+	SnippetMatchEnd   = "\ue001"
+)
+
+// A FactoidSearchResult is the current version of a factoid that matched a
+// search.
+type FactoidSearchResult struct {
+	DbID          int64
+	FactoidName   string
+	ScopeChannel  slack.ChannelID
+	LastUser      slack.UserID
+	LastTimestamp time.Time
+	IsLocked      bool
+
+	Rank float64
+	// Snippet is an excerpt of the factoid source, with matches surrounded
+	// by SnippetMatchStart and SnippetMatchEnd.
+	Snippet string
+}
+
+// SearchFactoids does a full-text search of the names and sources of the
+// factoids visible in the channel, best match first.
+func (mod *FactoidModule) SearchFactoids(terms string, channel slack.ChannelID, limit int) ([]FactoidSearchResult, error) {
+	stmt, err := mod.team.DB().Prepare(sqlSearchFactoids)
+	if err != nil {
+		return nil, errors.Wrap(err, "Database error")
+	}
+	defer stmt.Close()
+
+	if channel == "_" {
+		channel = ""
+	}
+	scopeChannel := sql.NullString{Valid: channel != "", String: string(channel)}
+	headlineOpts := "StartSel=" + SnippetMatchStart + ", StopSel=" + SnippetMatchEnd + ", MinWords=5, MaxWords=20"
+
+	cursor, err := stmt.Query(terms, scopeChannel, headlineOpts, limit)
+	if err != nil {
+		return nil, errors.Wrap(err, "Database error")
+	}
+	defer cursor.Close()
+
+	var list []FactoidSearchResult
+	for cursor.Next() {
+		var result FactoidSearchResult
+		err = cursor.Scan(&result.DbID, &result.FactoidName, &scopeChannel,
+			(*string)(&result.LastUser), &result.LastTimestamp, &result.IsLocked,
+			&result.Rank, &result.Snippet,
+		)
+		if err != nil {
+			return nil, errors.Wrap(err, "Database error")
+		}
+		if scopeChannel.Valid {
+			result.ScopeChannel = slack.ChannelID(scopeChannel.String)
+		}
+		list = append(list, result)
+	}
+	if cursor.Err() != nil {
+		return nil, errors.Wrap(cursor.Err(), "Database error")
+	}
+	return list, nil
+}
+
 func (mod *FactoidModule) ForgetFactoid(dbID int64, isForgotten bool) error {
 	stmt, err := mod.team.DB().Prepare(sqlForgetFactoid)
 	if err != nil {
@@ -0,0 +1,111 @@
+package factoid
+
+import "strings"
+
+// DiffOp is the kind of change to a line.
+type DiffOp int
+
+const (
+	DiffEqual DiffOp = iota
+	DiffInsert
+	DiffDelete
+)
+
+// A DiffLine is one line of a line-based diff.
+type DiffLine struct {
+	Op   DiffOp
+	Text string
+}
+
+// Prefix returns the unified diff prefix of the line.
+func (l DiffLine) Prefix() string {
+	switch l.Op {
+	case DiffInsert:
+		return "+"
+	case DiffDelete:
+		return "-"
+	}
+	return " "
+}
+
+// Class returns the CSS class for the line.
+func (l DiffLine) Class() string {
+	switch l.Op {
+	case DiffInsert:
+		return "diff-insert"
+	case DiffDelete:
+		return "diff-delete"
+	}
+	return "diff-equal"
+}
+
+// diffMaxCells limits the size of the LCS table. Larger inputs are shown as
+// a full replacement.
+const diffMaxCells = 1 << 22
+
+// DiffLines computes a line-based diff from a to b.
+func DiffLines(a, b string) []DiffLine {
+	linesA := strings.Split(a, "\n")
+	linesB := strings.Split(b, "\n")
+
+	// Trim the common prefix and suffix
+	var prefix, suffix []DiffLine
+	for len(linesA) > 0 && len(linesB) > 0 && linesA[0] == linesB[0] {
+		prefix = append(prefix, DiffLine{Op: DiffEqual, Text: linesA[0]})
+		linesA, linesB = linesA[1:], linesB[1:]
+	}
+	for len(linesA) > 0 && len(linesB) > 0 && linesA[len(linesA)-1] == linesB[len(linesB)-1] {
+		suffix = append([]DiffLine{{Op: DiffEqual, Text: linesA[len(linesA)-1]}}, suffix...)
+		linesA, linesB = linesA[:len(linesA)-1], linesB[:len(linesB)-1]
+	}
+
+	result := prefix
+	if (len(linesA)+1)*(len(linesB)+1) > diffMaxCells {
+		for _, v := range linesA {
+			result = append(result, DiffLine{Op: DiffDelete, Text: v})
+		}
+		for _, v := range linesB {
+			result = append(result, DiffLine{Op: DiffInsert, Text: v})
+		}
+		return append(result, suffix...)
+	}
+
+	// lcs[i][j] is the length of the LCS of linesA[i:] and linesB[j:]
+	lcs := make([][]int, len(linesA)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(linesB)+1)
+	}
+	for i := len(linesA) - 1; i >= 0; i-- {
+		for j := len(linesB) - 1; j >= 0; j-- {
+			if linesA[i] == linesB[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(linesA) && j < len(linesB) {
+		if linesA[i] == linesB[j] {
+			result = append(result, DiffLine{Op: DiffEqual, Text: linesA[i]})
+			i++
+			j++
+		} else if lcs[i+1][j] >= lcs[i][j+1] {
+			result = append(result, DiffLine{Op: DiffDelete, Text: linesA[i]})
+			i++
+		} else {
+			result = append(result, DiffLine{Op: DiffInsert, Text: linesB[j]})
+			j++
+		}
+	}
+	for ; i < len(linesA); i++ {
+		result = append(result, DiffLine{Op: DiffDelete, Text: linesA[i]})
+	}
+	for ; j < len(linesB); j++ {
+		result = append(result, DiffLine{Op: DiffInsert, Text: linesB[j]})
+	}
+	return append(result, suffix...)
+}
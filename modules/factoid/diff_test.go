@@ -0,0 +1,96 @@
+package factoid
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func diffString(lines []DiffLine) string {
+	var buf bytes.Buffer
+	for _, v := range lines {
+		buf.WriteString(v.Prefix())
+		buf.WriteString(v.Text)
+		buf.WriteByte('\n')
+	}
+	return buf.String()
+}
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name   string
+		a, b   string
+		expect string
+	}{
+		{"empty", "", "", " \n"},
+		{"equal", "a\nb", "a\nb", " a\n b\n"},
+		{"from empty", "", "a", "-\n+a\n"},
+		{"to empty", "a", "", "-a\n+\n"},
+		{"append", "a\nb", "a\nb\nc", " a\n b\n+c\n"},
+		{"prepend", "b\nc", "a\nb\nc", "+a\n b\n c\n"},
+		{"change middle", "a\nb\nc", "a\nx\nc", " a\n-b\n+x\n c\n"},
+		{"delete middle", "a\nb\nc\nd", "a\nd", " a\n-b\n-c\n d\n"},
+		{"keep common line", "a\nb\nc\nd\ne", "a\nx\nc\ny\ne", " a\n-b\n+x\n c\n-d\n+y\n e\n"},
+		{"replace all", "a\nb", "c\nd", "-a\n-b\n+c\n+d\n"},
+	}
+	for _, v := range tests {
+		got := diffString(DiffLines(v.a, v.b))
+		if got != v.expect {
+			t.Errorf("%s: expected\n%s\ngot\n%s", v.name, v.expect, got)
+		}
+	}
+}
+
+func TestDiffLinesTrim(t *testing.T) {
+	// The common prefix and suffix are kept even when the middle is too
+	// large for the LCS table.
+	n := 3000
+	a := make([]string, n)
+	b := make([]string, n)
+	for i := range a {
+		a[i] = "a" + strings.Repeat("x", i%7)
+		b[i] = "b" + strings.Repeat("x", i%7)
+	}
+	if (n+1)*(n+1) <= diffMaxCells {
+		t.Fatalf("test input is too small to reach diffMaxCells")
+	}
+	head, tail := "head1\nhead2", "tail"
+	result := DiffLines(head+"\n"+strings.Join(a, "\n")+"\n"+tail, head+"\n"+strings.Join(b, "\n")+"\n"+tail)
+
+	if len(result) != 2+2*n+1 {
+		t.Fatalf("expected %d lines, got %d", 2+2*n+1, len(result))
+	}
+	if result[0] != (DiffLine{DiffEqual, "head1"}) || result[1] != (DiffLine{DiffEqual, "head2"}) {
+		t.Errorf("prefix not kept: %v", result[:2])
+	}
+	if last := result[len(result)-1]; last != (DiffLine{DiffEqual, "tail"}) {
+		t.Errorf("suffix not kept: %v", last)
+	}
+	// Full replacement: every deletion comes before every insertion
+	for i, v := range result[2 : len(result)-1] {
+		expect := DiffDelete
+		if i >= n {
+			expect = DiffInsert
+		}
+		if v.Op != expect {
+			t.Fatalf("line %d: expected op %d, got %d", i+2, expect, v.Op)
+		}
+	}
+}
+
+func TestDiffLineClass(t *testing.T) {
+	tests := []struct {
+		op            DiffOp
+		prefix, class string
+	}{
+		{DiffEqual, " ", "diff-equal"},
+		{DiffInsert, "+", "diff-insert"},
+		{DiffDelete, "-", "diff-delete"},
+	}
+	for _, v := range tests {
+		l := DiffLine{Op: v.op}
+		if l.Prefix() != v.prefix || l.Class() != v.class {
+			t.Errorf("op %d: expected %q %q, got %q %q", v.op, v.prefix, v.class, l.Prefix(), l.Class())
+		}
+	}
+}
@@ -0,0 +1,544 @@
+package factoid
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/riking/marvin"
+	"github.com/riking/marvin/slack"
+	"github.com/riking/marvin/util"
+)
+
+const (
+	// $1 = includeHistory
+	sqlExportFactoids = `
+	SELECT f.name, f.channel_only, f.rawtext, f.last_set_user, f.last_set_channel, f.last_set_ts, f.last_set, f.locked, f.forgotten
+	FROM module_factoid_factoids f
+	WHERE $1 OR (f.forgotten = FALSE AND NOT EXISTS (
+		SELECT 1 FROM module_factoid_factoids newer
+		WHERE newer.name = f.name
+		AND newer.channel_only IS NOT DISTINCT FROM f.channel_only
+		AND newer.forgotten = FALSE
+		AND newer.last_set > f.last_set
+	))
+	ORDER BY f.name ASC, f.channel_only ASC NULLS FIRST, f.last_set ASC`
+
+	sqlExportFData = `
+	SELECT map, key, data FROM module_factoid_data
+	WHERE data IS NOT NULL
+	ORDER BY map, key`
+
+	// $1 = name $2 = scopeChannel $3 = source $4 = userid $5 = msg_chan $6 = msg_ts
+	// $7 = last_set $8 = locked $9 = forgotten
+	sqlImportFactoid = `
+	INSERT INTO module_factoid_factoids
+	(name, channel_only, rawtext, last_set_user, last_set_channel, last_set_ts, last_set, locked, forgotten)
+	VALUES
+	($1,   $2,           $3,      $4,            $5,               $6,          $7,       $8,     $9)`
+
+	// Keeps other writers out until the import is done, so the existence
+	// checks stay true
+	sqlImportLock = `LOCK TABLE module_factoid_factoids IN SHARE ROW EXCLUSIVE MODE`
+
+	// $1 = name $2 = scopeChannel $3 = withForgotten
+	sqlImportFactoidExists = `
+	SELECT EXISTS(
+		SELECT 1 FROM module_factoid_factoids
+		WHERE name = $1 AND channel_only IS NOT DISTINCT FROM $2
+		AND ($3 OR forgotten = FALSE)
+	)`
+)
+
+// ExportVersion is the version of the ExportFile format.
+const ExportVersion = 1
+
+// An ExportFile is the JSON format of a factoid export.
+type ExportFile struct {
+	Version  int       `json:"version"`
+	Exported time.Time `json:"exported"`
+	// History is true if the export has every revision of the factoids,
+	// instead of only the current ones.
+	History  bool            `json:"history"`
+	Factoids []ExportFactoid `json:"factoids"`
+	Data     []ExportData    `json:"data,omitempty"`
+}
+
+// ExportFactoid is one revision of a factoid.
+type ExportFactoid struct {
+	Name        string          `json:"name"`
+	Channel     slack.ChannelID `json:"channel,omitempty"`
+	Source      string          `json:"source"`
+	LastUser    slack.UserID    `json:"user,omitempty"`
+	LastChannel slack.ChannelID `json:"last_channel,omitempty"`
+	LastMessage slack.MessageTS `json:"last_ts,omitempty"`
+	Time        time.Time       `json:"time"`
+	IsLocked    bool            `json:"locked,omitempty"`
+	IsForgotten bool            `json:"forgotten,omitempty"`
+}
+
+// ExportData is one key of an fdata map.
+type ExportData struct {
+	Map  string          `json:"map"`
+	Key  string          `json:"key"`
+	Data json.RawMessage `json:"data"`
+}
+
+// ExportFactoids exports the current factoids, and optionally their full
+// history and the fdata maps.
+func (mod *FactoidModule) ExportFactoids(withHistory, withData bool) (*ExportFile, error) {
+	stmt, err := mod.team.DB().Prepare(sqlExportFactoids)
+	if err != nil {
+		return nil, errors.Wrap(err, "Database error")
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(withHistory)
+	if err != nil {
+		return nil, errors.Wrap(err, "Database error")
+	}
+	defer rows.Close()
+
+	export := &ExportFile{
+		Version:  ExportVersion,
+		Exported: time.Now(),
+		History:  withHistory,
+		Factoids: []ExportFactoid{},
+	}
+	var scopeChannel sql.NullString
+	for rows.Next() {
+		var f ExportFactoid
+		err = rows.Scan(&f.Name, &scopeChannel, &f.Source,
+			(*string)(&f.LastUser), (*string)(&f.LastChannel), (*string)(&f.LastMessage),
+			&f.Time, &f.IsLocked, &f.IsForgotten,
+		)
+		if err != nil {
+			return nil, errors.Wrap(err, "Database error")
+		}
+		if scopeChannel.Valid {
+			f.Channel = slack.ChannelID(scopeChannel.String)
+		}
+		export.Factoids = append(export.Factoids, f)
+	}
+	if rows.Err() != nil {
+		return nil, errors.Wrap(rows.Err(), "Database error")
+	}
+
+	if withData {
+		export.Data, err = mod.exportFData()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return export, nil
+}
+
+func (mod *FactoidModule) exportFData() ([]ExportData, error) {
+	stmt, err := mod.team.DB().Prepare(sqlExportFData)
+	if err != nil {
+		return nil, errors.Wrap(err, "prepare stmt")
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query()
+	if err != nil {
+		return nil, errors.Wrap(err, "fdata export query")
+	}
+	defer rows.Close()
+
+	values := make(map[fdataKey][]byte)
+	for rows.Next() {
+		var key fdataKey
+		var b []byte
+		err = rows.Scan(&key.Map, &key.Key, &b)
+		if err != nil {
+			return nil, errors.Wrap(err, "fdata export scan")
+		}
+		values[key] = b
+	}
+	if rows.Err() != nil {
+		return nil, errors.Wrap(rows.Err(), "fdata export query")
+	}
+
+	// Fill in dirty values that haven't hit the DB
+	ch := make(chan interface{})
+	mod.fdataReqChan <- fdataReq{C: ch,
+		F: func(mod *FactoidModule) interface{} {
+			for mapName, mapContent := range mod.fdataMap {
+				for keyName, val := range mapContent {
+					if val.DBSync == util.TriNo {
+						values[fdataKey{Map: mapName, Key: keyName}] = val.JSON
+					}
+				}
+			}
+			return nil
+		},
+	}
+	<-ch
+
+	var result []ExportData
+	for key, b := range values {
+		if b == nil {
+			continue
+		}
+		result = append(result, ExportData{Map: key.Map, Key: key.Key, Data: json.RawMessage(b)})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Map != result[j].Map {
+			return result[i].Map < result[j].Map
+		}
+		return result[i].Key < result[j].Key
+	})
+	return result, nil
+}
+
+// ParseImport reads an import file. It accepts an ExportFile, which is
+// recognized by its numeric "version", or a JSON object mapping factoid
+// names to their source.
+func ParseImport(b []byte) (*ExportFile, error) {
+	var probe map[string]json.RawMessage
+	err := json.Unmarshal(b, &probe)
+	if err != nil {
+		return nil, errors.Wrap(err, "import file must be a JSON object")
+	}
+
+	// A factoid named "version" has text, not a number
+	var version int
+	if raw, ok := probe["version"]; ok && json.Unmarshal(raw, &version) == nil {
+		var file ExportFile
+		err = json.Unmarshal(b, &file)
+		if err != nil {
+			return nil, errors.Wrap(err, "bad export file")
+		}
+		if file.Version > ExportVersion {
+			return nil, errors.Errorf("export file version %d is newer than this version of the bot (%d)", file.Version, ExportVersion)
+		}
+		return &file, nil
+	}
+
+	var simple map[string]string
+	err = json.Unmarshal(b, &simple)
+	if err != nil {
+		return nil, errors.Wrap(err, "import file must be an export file or an object of factoid names to text")
+	}
+	file := &ExportFile{Version: ExportVersion}
+	for name, source := range simple {
+		file.Factoids = append(file.Factoids, ExportFactoid{Name: name, Source: source})
+	}
+	sort.Slice(file.Factoids, func(i, j int) bool {
+		return file.Factoids[i].Name < file.Factoids[j].Name
+	})
+	return file, nil
+}
+
+// ImportMode says what an import does with factoids that already exist.
+type ImportMode int
+
+const (
+	// ImportSkip keeps the existing factoid.
+	ImportSkip ImportMode = iota
+	// ImportOverwrite makes the imported factoid the current version.
+	ImportOverwrite
+	// ImportRename imports the factoid under a new name.
+	ImportRename
+)
+
+// ParseImportMode parses "skip", "overwrite" or "rename".
+func ParseImportMode(s string) (ImportMode, error) {
+	switch strings.ToLower(s) {
+	case "", "skip":
+		return ImportSkip, nil
+	case "overwrite":
+		return ImportOverwrite, nil
+	case "rename":
+		return ImportRename, nil
+	}
+	return ImportSkip, errors.Errorf("bad conflict mode '%s' (must be skip, overwrite, or rename)", s)
+}
+
+// ImportReport describes what an import did, or would do in a dry run.
+type ImportReport struct {
+	DryRun      bool              `json:"dry_run"`
+	Added       []string          `json:"added"`
+	Overwritten []string          `json:"overwritten"`
+	Renamed     map[string]string `json:"renamed"`
+	Skipped     []string          `json:"skipped"`
+	Errors      []string          `json:"errors"`
+	Revisions   int               `json:"revisions"`
+	DataAdded   int               `json:"data_added"`
+	DataSkipped int               `json:"data_skipped"`
+}
+
+// String summarizes the report.
+func (r *ImportReport) String() string {
+	verb := "Imported"
+	if r.DryRun {
+		verb = "Would import"
+	}
+	s := fmt.Sprintf("%s %d new, %d overwritten, and %d renamed factoids (%d revisions), and %d fdata keys. Skipped %d factoids and %d fdata keys.",
+		verb, len(r.Added), len(r.Overwritten), len(r.Renamed), r.Revisions, r.DataAdded, len(r.Skipped), r.DataSkipped)
+	if len(r.Errors) > 0 {
+		s += fmt.Sprintf("\n%d errors:\n• %s", len(r.Errors), strings.Join(r.Errors, "\n• "))
+	}
+	return s
+}
+
+// importGroup is the revisions of one factoid in an import.
+type importGroup struct {
+	Name      string
+	Channel   slack.ChannelID
+	Revisions []ExportFactoid
+}
+
+func (g *importGroup) displayName() string {
+	if g.Channel != "" {
+		return fmt.Sprintf("%s (%s)", g.Name, g.Channel)
+	}
+	return g.Name
+}
+
+// ImportFactoids adds the factoids in the file to the database. Factoids
+// without author information are credited to the source.
+//
+// In ImportRename mode, fdata keys that already exist are skipped.
+func (mod *FactoidModule) ImportFactoids(file *ExportFile, mode ImportMode, dryRun bool, source marvin.ActionSource) (*ImportReport, error) {
+	report := &ImportReport{DryRun: dryRun, Renamed: make(map[string]string)}
+
+	now := time.Now()
+	groups := groupImport(file.Factoids, source, now)
+
+	tx, err := mod.team.DB().Begin()
+	if err != nil {
+		return nil, errors.Wrap(err, "begin transaction")
+	}
+	txOK := false
+	defer func() {
+		if !txOK {
+			tx.Rollback()
+		}
+	}()
+	_, err = tx.Exec(sqlImportLock)
+	if err != nil {
+		return nil, errors.Wrap(err, "Database error")
+	}
+	stmt, err := tx.Prepare(sqlImportFactoid)
+	if err != nil {
+		return nil, errors.Wrap(err, "Database error")
+	}
+	defer stmt.Close()
+
+	usedNames := make(map[string]bool)
+	for _, g := range groups {
+		if err := checkImportName(g.Name); err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", g.displayName(), err))
+			continue
+		}
+		if err := checkImportSyntax(mod, g.Revisions); err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", g.displayName(), err))
+			continue
+		}
+
+		name := g.Name
+		exists, err := importFactoidExists(tx, name, g.Channel, false)
+		if err != nil {
+			return nil, err
+		}
+		stampNow := false
+		if exists {
+			switch mode {
+			case ImportSkip:
+				report.Skipped = append(report.Skipped, g.displayName())
+				continue
+			case ImportOverwrite:
+				// The imported version must be newer than the existing one
+				stampNow = true
+				report.Overwritten = append(report.Overwritten, g.displayName())
+			case ImportRename:
+				name, err = importRename(tx, g, usedNames)
+				if err != nil {
+					report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", g.displayName(), err))
+					continue
+				}
+				report.Renamed[g.displayName()] = name
+			}
+		} else {
+			report.Added = append(report.Added, g.displayName())
+		}
+		usedNames[string(g.Channel)+"/"+name] = true
+		report.Revisions += len(g.Revisions)
+
+		if dryRun {
+			continue
+		}
+		for i, f := range g.Revisions {
+			ts := f.Time
+			if stampNow && i == len(g.Revisions)-1 {
+				ts = now
+			}
+			scopeChannel := sql.NullString{Valid: g.Channel != "", String: string(g.Channel)}
+			_, err = stmt.Exec(name, scopeChannel, f.Source,
+				string(f.LastUser), string(f.LastChannel), string(f.LastMessage),
+				ts, f.IsLocked, f.IsForgotten,
+			)
+			if err != nil {
+				return nil, errors.Wrapf(err, "import %s", g.displayName())
+			}
+		}
+	}
+
+	var newData []ExportData
+	for _, d := range file.Data {
+		if len(d.Key) > fdataKeyMaxLen || len(d.Data) > fdataValMaxLen || !json.Valid(d.Data) {
+			report.Errors = append(report.Errors, fmt.Sprintf("fdata %s[%s]: bad key or value", d.Map, d.Key))
+			continue
+		}
+		if mode != ImportOverwrite {
+			existing, err := mod.importFDataValue(tx, d.Map, d.Key)
+			if err != nil {
+				return nil, err
+			}
+			if existing != nil {
+				report.DataSkipped++
+				continue
+			}
+		}
+		report.DataAdded++
+		newData = append(newData, d)
+	}
+
+	if dryRun {
+		return report, nil
+	}
+	err = tx.Commit()
+	if err != nil {
+		return nil, errors.Wrap(err, "commit transaction")
+	}
+	txOK = true
+
+	for _, d := range newData {
+		mod.SetFDataValue(d.Map, d.Key, []byte(d.Data))
+	}
+	return report, nil
+}
+
+// groupImport groups the revisions by factoid, oldest first. Revisions
+// without an author are credited to the source, and ones without a time to
+// now.
+func groupImport(factoids []ExportFactoid, source marvin.ActionSource, now time.Time) []*importGroup {
+	var groups []*importGroup
+	groupIdx := make(map[string]*importGroup)
+	for _, f := range factoids {
+		k := string(f.Channel) + "/" + f.Name
+		g := groupIdx[k]
+		if g == nil {
+			g = &importGroup{Name: f.Name, Channel: f.Channel}
+			groupIdx[k] = g
+			groups = append(groups, g)
+		}
+		g.Revisions = append(g.Revisions, f)
+	}
+	for _, g := range groups {
+		sort.SliceStable(g.Revisions, func(i, j int) bool {
+			return g.Revisions[i].Time.Before(g.Revisions[j].Time)
+		})
+		for i := range g.Revisions {
+			f := &g.Revisions[i]
+			if f.LastUser == "" {
+				f.LastUser = source.UserID()
+				f.LastChannel = source.ChannelID()
+				f.LastMessage = source.MsgTimestamp()
+			}
+			if f.Time.IsZero() {
+				f.Time = now
+			}
+		}
+	}
+	return groups
+}
+
+// importFactoidExists checks for a factoid with exactly this scope.
+func importFactoidExists(tx *sql.Tx, name string, channel slack.ChannelID, withForgotten bool) (bool, error) {
+	var exists bool
+	scopeChannel := sql.NullString{Valid: channel != "", String: string(channel)}
+	err := tx.QueryRow(sqlImportFactoidExists, name, scopeChannel, withForgotten).Scan(&exists)
+	if err != nil {
+		return false, errors.Wrap(err, "Database error")
+	}
+	return exists, nil
+}
+
+// importFDataValue gets an fdata value, using the cache first because it
+// can have values that are not saved yet.
+func (mod *FactoidModule) importFDataValue(tx *sql.Tx, mapName, keyName string) ([]byte, error) {
+	fval := mod.fdataGetCachedEntry(mapName, keyName)
+	if fval.DBSync != util.TriDefault {
+		return fval.JSON, nil
+	}
+	var data []byte
+	err := tx.QueryRow(sqlFDataGetOne, mapName, keyName).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "Database error")
+	}
+	return data, nil
+}
+
+// checkImportName applies the checks of SaveFactoid.
+func checkImportName(name string) error {
+	if name == "" {
+		return errors.Errorf("Factoid name is empty")
+	}
+	if len(name) > FactoidNameMaxLen {
+		return errors.Errorf("Factoid name is too long (%d > %d)", len(name), FactoidNameMaxLen)
+	}
+	if strings.ContainsAny(name, " \n/\"") {
+		return errors.Errorf("Factoid name contains prohibited characters (space, newline, forward slash, double quote)")
+	}
+	return nil
+}
+
+// checkImportSyntax checks that the current revision of the factoid parses.
+func checkImportSyntax(mod *FactoidModule, revisions []ExportFactoid) error {
+	fi := Factoid{
+		Mod:       mod,
+		RawSource: revisions[len(revisions)-1].Source,
+	}
+	err := util.PCall(func() error {
+		fi.Tokens()
+		return nil
+	})
+	if err != nil {
+		return errors.Errorf("Bad syntax: %v", err)
+	}
+	return nil
+}
+
+// importRename finds an unused name for an imported factoid.
+func importRename(tx *sql.Tx, g *importGroup, usedNames map[string]bool) (string, error) {
+	for i := 1; i < 100; i++ {
+		suffix := "-imported"
+		if i > 1 {
+			suffix = fmt.Sprintf("-imported%d", i)
+		}
+		name := g.Name + suffix
+		if len(name) > FactoidNameMaxLen {
+			name = g.Name[:FactoidNameMaxLen-len(suffix)] + suffix
+		}
+		if usedNames[string(g.Channel)+"/"+name] {
+			continue
+		}
+		exists, err := importFactoidExists(tx, name, g.Channel, true)
+		if err != nil {
+			return "", err
+		} else if !exists {
+			return name, nil
+		}
+	}
+	return "", errors.Errorf("could not find a free name")
+}
@@ -0,0 +1,182 @@
+package factoid
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/riking/marvin/slack"
+	"github.com/riking/marvin/util/mock"
+)
+
+func TestParseImport(t *testing.T) {
+	file, err := ParseImport([]byte(`{
+		"version": 1,
+		"history": true,
+		"factoids": [
+			{"name": "hello", "source": "Hello, world!"},
+			{"name": "hello", "channel": "C1234", "source": "Hi there"}
+		],
+		"data": [{"map": "counts", "key": "a", "data": 5}]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !file.History || len(file.Factoids) != 2 || len(file.Data) != 1 {
+		t.Errorf("export format: got %+v", file)
+	} else if file.Factoids[1].Channel != "C1234" || file.Factoids[1].Source != "Hi there" {
+		t.Errorf("export format: got %+v", file.Factoids[1])
+	}
+
+	file, err = ParseImport([]byte(`{"zeta": "last", "alpha": "first", "mid": "{noop}"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file.Version != ExportVersion {
+		t.Errorf("simple format: version: expected %d, got %d", ExportVersion, file.Version)
+	}
+	var names []string
+	for _, f := range file.Factoids {
+		names = append(names, f.Name)
+	}
+	if strings.Join(names, " ") != "alpha mid zeta" {
+		t.Errorf("simple format: expected sorted names, got %v", names)
+	}
+	if file.Factoids[0].Source != "first" || file.Factoids[0].Channel != "" {
+		t.Errorf("simple format: got %+v", file.Factoids[0])
+	}
+
+	// Factoids named like the keys of the export format
+	file, err = ParseImport([]byte(`{"factoids": "All the factoids", "version": "v2", "data": "{noop}"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	names = nil
+	for _, f := range file.Factoids {
+		names = append(names, f.Name)
+	}
+	if strings.Join(names, " ") != "data factoids version" {
+		t.Errorf("simple format with export keys: expected three factoids, got %v", names)
+	} else if file.Factoids[1].Source != "All the factoids" {
+		t.Errorf("simple format with export keys: got %+v", file.Factoids[1])
+	}
+
+	errTests := []struct {
+		input    string
+		errMatch string
+	}{
+		{`{"version": 99, "factoids": []}`, "newer"},
+		{`["a", "b"]`, "must be a JSON object"},
+		{`not json`, "must be a JSON object"},
+		{`{"version": 1, "factoids": 5}`, "bad export file"},
+		{`{"name": 5}`, "object of factoid names to text"},
+	}
+	for _, v := range errTests {
+		_, err := ParseImport([]byte(v.input))
+		if err == nil {
+			t.Errorf("%s: expected an error", v.input)
+		} else if !strings.Contains(err.Error(), v.errMatch) {
+			t.Errorf("%s: expected error matching %q, got %v", v.input, v.errMatch, err)
+		}
+	}
+}
+
+func TestParseImportMode(t *testing.T) {
+	tests := []struct {
+		input  string
+		expect ImportMode
+		err    bool
+	}{
+		{"", ImportSkip, false},
+		{"skip", ImportSkip, false},
+		{"overwrite", ImportOverwrite, false},
+		{"Rename", ImportRename, false},
+		{"OVERWRITE", ImportOverwrite, false},
+		{"merge", ImportSkip, true},
+	}
+	for _, v := range tests {
+		got, err := ParseImportMode(v.input)
+		if (err != nil) != v.err {
+			t.Errorf("%q: expected error %v, got %v", v.input, v.err, err)
+		} else if got != v.expect {
+			t.Errorf("%q: expected %v, got %v", v.input, v.expect, got)
+		}
+	}
+}
+
+func TestGroupImport(t *testing.T) {
+	now := time.Date(2017, 10, 18, 12, 0, 0, 0, time.UTC)
+	old := now.Add(-48 * time.Hour)
+	older := now.Add(-72 * time.Hour)
+	source := mock.ActionSource{MUserID: "U1", MChannelID: "C1", MMessageTS: "1500000000.000001"}
+
+	groups := groupImport([]ExportFactoid{
+		{Name: "a", Source: "new", Time: old, LastUser: "U2", LastChannel: "C2", LastMessage: "1.2"},
+		{Name: "b", Source: "b"},
+		{Name: "a", Source: "old", Time: older},
+		{Name: "a", Channel: "C3", Source: "local"},
+	}, source, now)
+
+	if len(groups) != 3 {
+		t.Fatalf("expected 3 groups, got %d", len(groups))
+	}
+	expect := []struct {
+		name    string
+		channel slack.ChannelID
+		sources string
+	}{
+		{"a", "", "old new"},
+		{"b", "", "b"},
+		{"a", "C3", "local"},
+	}
+	for i, v := range expect {
+		g := groups[i]
+		var sources []string
+		for _, f := range g.Revisions {
+			sources = append(sources, f.Source)
+		}
+		if g.Name != v.name || g.Channel != v.channel || strings.Join(sources, " ") != v.sources {
+			t.Errorf("group %d: expected %s/%s %q, got %s/%s %v", i, v.channel, v.name, v.sources, g.Channel, g.Name, sources)
+		}
+	}
+
+	a := groups[0].Revisions
+	if a[0].LastUser != "U1" || a[0].LastChannel != "C1" || a[0].LastMessage != "1500000000.000001" {
+		t.Errorf("revision without author: expected the source, got %+v", a[0])
+	}
+	if !a[0].Time.Equal(older) {
+		t.Errorf("revision with a time: expected %v, got %v", older, a[0].Time)
+	}
+	if a[1].LastUser != "U2" || a[1].LastChannel != "C2" || a[1].LastMessage != "1.2" {
+		t.Errorf("revision with author: expected it kept, got %+v", a[1])
+	}
+	if !groups[1].Revisions[0].Time.Equal(now) {
+		t.Errorf("revision without a time: expected %v, got %v", now, groups[1].Revisions[0].Time)
+	}
+	if groups[2].displayName() != "a (C3)" || groups[0].displayName() != "a" {
+		t.Errorf("displayName: got %q and %q", groups[2].displayName(), groups[0].displayName())
+	}
+}
+
+func TestCheckImportName(t *testing.T) {
+	tests := []struct {
+		name string
+		ok   bool
+	}{
+		{"hello", true},
+		{"hello-world_2", true},
+		{strings.Repeat("x", FactoidNameMaxLen), true},
+		{"", false},
+		{strings.Repeat("x", FactoidNameMaxLen+1), false},
+		{"two words", false},
+		{"a/b", false},
+		{"quote\"d", false},
+		{"new\nline", false},
+	}
+	for _, v := range tests {
+		err := checkImportName(v.name)
+		if (err == nil) != v.ok {
+			t.Errorf("%q: expected ok=%v, got %v", v.name, v.ok, err)
+		}
+	}
+}
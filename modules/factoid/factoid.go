@@ -5,6 +5,7 @@ import (
 
 	"github.com/riking/marvin"
 	"github.com/riking/marvin/modules/paste"
+	"github.com/riking/marvin/slack"
 	"github.com/riking/marvin/util"
 )
 
@@ -69,6 +70,14 @@ func (mod *FactoidModule) Enable(team marvin.Team) {
 	parent.RegisterCommandFunc("source", mod.CmdSource, helpSource)
 	parent.RegisterCommandFunc("info", mod.CmdInfo, helpInfo)
 	parent.RegisterCommandFunc("list", mod.CmdList, helpList)
+	parent.RegisterCommandFunc("search", mod.CmdSearch, helpSearch)
+	parent.RegisterCommand("export", marvin.RequireLevel(marvin.AccessLevelAdmin, marvin.CommandFunc(mod.CmdExport, helpExport)))
+	parent.RegisterCommand("import", marvin.RequireLevel(marvin.AccessLevelAdmin, marvin.CommandFunc(mod.CmdImport, helpImport)))
+	parent.RegisterCommandFunc("lock", mod.CmdLock, helpLock)
+	parent.RegisterCommandFunc("unlock", mod.CmdUnlock, helpUnlock)
+	parent.RegisterCommandFunc("unforget", mod.CmdUnforget, helpUnforget)
+	parent.RegisterCommandFunc("history", mod.CmdHistory, helpHistory)
+	parent.RegisterCommandFunc("revert", mod.CmdRevert, helpRevert)
 
 	team.RegisterCommand("factoid", parent)
 	team.RegisterCommand("f", parent) // TODO RegisterAlias
@@ -98,3 +107,24 @@ func (mod *FactoidModule) Disable(t marvin.Team) {
 func (mod *FactoidModule) Team() marvin.Team {
 	return mod.team
 }
+
+// longOutput stores factoid output that is too long to post, and returns a
+// preview with a link to the full text. If the output was shared to the
+// channel as a snippet instead, the snippet is returned and nothing else
+// should be posted. Configure the method with the paste module's
+// "long-reply-method.factoid" key.
+//
+// The channel is empty when the output replaces an earlier message.
+func (mod *FactoidModule) longOutput(channel slack.ChannelID, output string) (string, *slack.File) {
+	if mod.pasteMod != nil {
+		link, snippet, err := mod.pasteMod.(paste.API).StoreLongReply(channel, "factoid", output)
+		if err != nil {
+			util.LogError(err)
+		} else if snippet != nil {
+			return "", snippet
+		} else if link != "" {
+			return marvin.LongReplyText(output, link), nil
+		}
+	}
+	return "[Output truncated]\n" + util.PreviewString(output, marvin.LongReplyCut*10) + "…", nil
+}
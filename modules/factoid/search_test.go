@@ -0,0 +1,30 @@
+package factoid
+
+import (
+	"testing"
+)
+
+func TestSnippetFormat(t *testing.T) {
+	const s, e = SnippetMatchStart, SnippetMatchEnd
+	tests := []struct {
+		snippet string
+		slack   string
+		html    string
+	}{
+		{"plain text", "plain text", "plain text"},
+		{"the " + s + "quick" + e + " fox", "the *quick* fox", "the <mark>quick</mark> fox"},
+		{s + "a" + e + " and " + s + "b" + e, "*a* and *b*", "<mark>a</mark> and <mark>b</mark>"},
+		{"line one\n  line\ttwo ", "line one line two", "line one\n  line\ttwo "},
+		{"<b>" + s + "bold" + e + "</b> & co", "<b>*bold*</b> & co", "&lt;b&gt;<mark>bold</mark>&lt;/b&gt; &amp; co"},
+	}
+	for _, v := range tests {
+		got := slackSnippet(v.snippet)
+		if got != v.slack {
+			t.Errorf("slackSnippet(%q): expected %q, got %q", v.snippet, v.slack, got)
+		}
+		gotHTML := string(FactoidSearchResult{Snippet: v.snippet}.HTMLSnippet())
+		if gotHTML != v.html {
+			t.Errorf("HTMLSnippet(%q): expected %q, got %q", v.snippet, v.html, gotHTML)
+		}
+	}
+}
@@ -1,10 +1,14 @@
 package factoid
 
 import (
+	"encoding/json"
 	"fmt"
 	"html/template"
+	"io/ioutil"
 	"net/http"
 	"regexp"
+	"strconv"
+	"strings"
 
 	"github.com/pkg/errors"
 
@@ -17,10 +21,14 @@ import (
 func (mod *FactoidModule) registerHTTP() {
 	r := mod.team.Router()
 	r.Path("/factoids").HandlerFunc(mod.HTTPListFactoids)
+	r.Methods("GET").Path("/factoids/export").HandlerFunc(mod.HTTPExportFactoids)
+	r.Methods("POST").Path("/factoids/import").HandlerFunc(mod.HTTPImportFactoids)
 	r.Path("/factoids/_/{name}").HandlerFunc(http.HandlerFunc(mod.HTTPShowFactoid))
 	r.Path("/factoids/{channel}/{name}").HandlerFunc(http.HandlerFunc(mod.HTTPShowFactoid))
 	r.Methods("POST").Path("/factoids/_/{name}/edit").HandlerFunc(mod.HTTPEditFactoid)
 	r.Methods("POST").Path("/factoids/{channel}/{name}/edit").HandlerFunc(mod.HTTPEditFactoid)
+	r.Methods("POST").Path("/factoids/_/{name}/revert").HandlerFunc(mod.HTTPRevertFactoid)
+	r.Methods("POST").Path("/factoids/{channel}/{name}/revert").HandlerFunc(mod.HTTPRevertFactoid)
 	r.Methods("POST").Path("/factoids/_/{name}/run").HandlerFunc(mod.HTTPShowFactoid)
 	r.Methods("POST").Path("/factoids/{channel}/{name}/run").HandlerFunc(mod.HTTPShowFactoid)
 }
@@ -30,6 +38,11 @@ var tmplListFactoids = template.Must(weblogin.LayoutTemplateCopy().Parse(string(
 type bodyList struct {
 	List []*Factoid
 	team marvin.Team
+
+	Query   string
+	Channel string
+	Search  string
+	Results []FactoidSearchResult
 }
 
 type bodyShow struct {
@@ -38,6 +51,11 @@ type bodyShow struct {
 	team            marvin.Team
 	ScopeChannelURL string
 	History         []Factoid
+
+	// Diff is set when comparing two revisions.
+	Diff     []DiffLine
+	DiffFrom *Factoid
+	DiffTo   *Factoid
 }
 
 func (d bodyList) Team() marvin.Team { return d.team }
@@ -56,21 +74,39 @@ func (mod *FactoidModule) HTTPListFactoids(w http.ResponseWriter, r *http.Reques
 	}
 
 	scopeChannel := r.Form.Get("channel")
+	body := bodyList{
+		team:    mod.team,
+		Query:   r.Form.Get("q"),
+		Channel: scopeChannel,
+		Search:  strings.TrimSpace(r.Form.Get("search")),
+	}
 
-	list, err := mod.ListFactoidsWithInfo(r.Form.Get("q"), slack.ChannelID(scopeChannel))
+	if body.Search != "" {
+		body.Results, err = mod.SearchFactoids(body.Search, slack.ChannelID(scopeChannel), webSearchMaxResults)
+	} else {
+		body.List, err = mod.ListFactoidsWithInfo(body.Query, slack.ChannelID(scopeChannel))
+	}
 	if err != nil {
 		mod.team.GetModule(weblogin.Identifier).(weblogin.API).HTTPError(w, r, err)
 		return
 	}
 
-	lc.BodyData = bodyList{
-		List: list,
-		team: mod.team,
-	}
+	lc.BodyData = body
 	util.LogIfError(
 		tmplListFactoids.ExecuteTemplate(w, "layout", lc))
 }
 
+// webSearchMaxResults is the number of search results shown on /factoids.
+const webSearchMaxResults = 50
+
+// HTMLSnippet returns the search snippet with the matches highlighted.
+func (r FactoidSearchResult) HTMLSnippet() template.HTML {
+	snippet := template.HTMLEscapeString(r.Snippet)
+	snippet = strings.Replace(snippet, SnippetMatchStart, "<mark>", -1)
+	snippet = strings.Replace(snippet, SnippetMatchEnd, "</mark>", -1)
+	return template.HTML(snippet)
+}
+
 var tmplShowFactoid = template.Must(weblogin.LayoutTemplateCopy().Parse(string(weblogin.MustAsset("templates/factoid-info.html"))))
 
 var rgxShowFactoid = regexp.MustCompile(`/factoids/(C[A-Z0-9]+|_)/([^/]*)`)
@@ -110,7 +146,7 @@ func (mod *FactoidModule) HTTPShowFactoid(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	lc.BodyData = bodyShow{
+	body := bodyShow{
 		Factoid:         finfo,
 		team:            mod.team,
 		History:         history,
@@ -118,6 +154,24 @@ func (mod *FactoidModule) HTTPShowFactoid(w http.ResponseWriter, r *http.Request
 		Layout:          lc,
 	}
 
+	// ?from=<revision>&to=<revision> shows a diff. to defaults to the
+	// current version.
+	r.ParseForm()
+	if r.Form.Get("from") != "" {
+		body.DiffFrom = findRevision(history, r.Form.Get("from"))
+		body.DiffTo = finfo
+		if r.Form.Get("to") != "" {
+			body.DiffTo = findRevision(history, r.Form.Get("to"))
+		}
+		if body.DiffFrom == nil || body.DiffTo == nil {
+			mod.team.GetModule(weblogin.Identifier).(weblogin.API).HTTPError(w, r, errors.Errorf("No such revision"))
+			return
+		}
+		body.Diff = DiffLines(body.DiffFrom.RawSource, body.DiffTo.RawSource)
+	}
+
+	lc.BodyData = body
+
 	util.LogIfError(
 		tmplShowFactoid.ExecuteTemplate(w, "layout", lc))
 }
@@ -167,15 +221,9 @@ func (mod *FactoidModule) HTTPEditFactoid(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	if prevFactoidInfo.IsLocked {
-		if scopeChannel != "" {
-			// Overriding a locked global with a local is OK
-			if prevFactoidInfo.ScopeChannel != "" {
-				http.Error(w, `{"ok": false, "message": "Factoid is locked"}`, 403)
-			}
-		} else {
-			http.Error(w, `{"ok": false, "message": "Factoid is locked"}`, 403)
-		}
+	if isLockedFor(prevFactoidInfo, scopeChannel) {
+		http.Error(w, `{"ok": false, "message": "Factoid is locked"}`, 403)
+		return
 	}
 
 	// Attempt parse
@@ -200,3 +248,180 @@ func (mod *FactoidModule) HTTPEditFactoid(w http.ResponseWriter, r *http.Request
 
 	fmt.Fprint(w, `{"ok": true}`)
 }
+
+// findRevision finds the revision with the ID in the history.
+func findRevision(history []Factoid, id string) *Factoid {
+	dbID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return nil
+	}
+	for i := range history {
+		if history[i].DbID == dbID {
+			return &history[i]
+		}
+	}
+	return nil
+}
+
+// HTTPRevertFactoid saves the revision in the "revision" form field as the
+// current version of the factoid.
+func (mod *FactoidModule) HTTPRevertFactoid(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	lc, err := weblogin.NewLayoutContent(mod.team, w, r, weblogin.NavSectionFactoids)
+	if err != nil {
+		http.Error(w, `{"ok": false, "message": "bad login/cookies"}`, 401)
+		return
+	}
+
+	m := rgxShowFactoid.FindStringSubmatch(r.URL.Path)
+	if m == nil {
+		http.Error(w, `{"ok": false, "message": "bad URL"}`, 404)
+		return
+	}
+
+	scopeChannel := slack.ChannelID(m[1])
+	if scopeChannel == "_" {
+		scopeChannel = ""
+	}
+	factoidName := m[2]
+
+	if lc.CurrentUser == nil {
+		http.Error(w, `{"ok": false, "message": "must log in"}`, 403)
+		return
+	}
+
+	actionSource := weblogin.ActionSourceWeb{Team: mod.Team(), User: lc.CurrentUser}
+
+	r.ParseMultipartForm(-1)
+	revision, err := strconv.ParseInt(r.Form.Get("revision"), 10, 64)
+	if err != nil {
+		http.Error(w, `{"ok": false, "message": "revision not provided"}`, 422)
+		return
+	}
+
+	target, err := mod.GetFactoidRevision(factoidName, scopeChannel, revision)
+	if err == ErrNoSuchFactoid {
+		http.Error(w, `{"ok": false, "message": "no such revision"}`, 404)
+		return
+	} else if err != nil {
+		http.Error(w, fmt.Sprintf(`{"ok": false, "message": "internal server error: %v"}`, err), 500)
+		return
+	}
+
+	prevFactoidInfo, err := mod.GetFactoidInfo(factoidName, scopeChannel, false)
+	if err == ErrNoSuchFactoid {
+		prevFactoidInfo = &Factoid{IsLocked: false, ScopeChannel: ""}
+	} else if err != nil {
+		http.Error(w, fmt.Sprintf(`{"ok": false, "message": "internal server error: %v"}`, err), 500)
+		return
+	}
+	if isLockedFor(prevFactoidInfo, scopeChannel) {
+		http.Error(w, `{"ok": false, "message": "Factoid is locked"}`, 403)
+		return
+	}
+
+	util.LogGood("Reverting factoid", factoidName, "to revision", revision, "via web by", lc.CurrentUser.IntraLogin)
+	err = mod.SaveFactoid(factoidName, scopeChannel, target.RawSource, actionSource)
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"ok": false, "message": "Could not save factoid: %v"}`, err), 500)
+		return
+	}
+
+	fmt.Fprint(w, `{"ok": true}`)
+}
+
+// webAdminSource returns the action source for the current user, or writes
+// an error if they are not an admin.
+func (mod *FactoidModule) webAdminSource(w http.ResponseWriter, r *http.Request) (weblogin.ActionSourceWeb, bool) {
+	lc, err := weblogin.NewLayoutContent(mod.team, w, r, weblogin.NavSectionFactoids)
+	if err != nil {
+		http.Error(w, `{"ok": false, "message": "bad login/cookies"}`, 401)
+		return weblogin.ActionSourceWeb{}, false
+	}
+	if lc.CurrentUser == nil {
+		http.Error(w, `{"ok": false, "message": "must log in"}`, 403)
+		return weblogin.ActionSourceWeb{}, false
+	}
+	actionSource := weblogin.ActionSourceWeb{Team: mod.Team(), User: lc.CurrentUser}
+	if actionSource.AccessLevel() < marvin.AccessLevelAdmin {
+		http.Error(w, `{"ok": false, "message": "must be an admin"}`, 403)
+		return weblogin.ActionSourceWeb{}, false
+	}
+	return actionSource, true
+}
+
+// HTTPExportFactoids downloads a factoid export. Set history=1 and data=1 to
+// include the factoid history and fdata maps.
+func (mod *FactoidModule) HTTPExportFactoids(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if _, ok := mod.webAdminSource(w, r); !ok {
+		return
+	}
+
+	r.ParseForm()
+	export, err := mod.ExportFactoids(r.Form.Get("history") == "1", r.Form.Get("data") == "1")
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"ok": false, "message": "internal server error: %v"}`, err), 500)
+		return
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="factoids-%s.json"`, export.Exported.Format("2006-01-02")))
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	util.LogIfError(enc.Encode(export))
+}
+
+// HTTPImportFactoids imports the "file" form field, or the request body if
+// there is no file. The mode and dry_run form fields have the same meaning as
+// the flags of `factoid import`.
+func (mod *FactoidModule) HTTPImportFactoids(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	actionSource, ok := mod.webAdminSource(w, r)
+	if !ok {
+		return
+	}
+
+	var b []byte
+	var err error
+	r.Body = http.MaxBytesReader(w, r.Body, importMaxSize)
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		err = r.ParseMultipartForm(importMaxSize)
+		if err == nil {
+			f, _, ferr := r.FormFile("file")
+			if ferr != nil {
+				http.Error(w, `{"ok": false, "message": "file not provided"}`, 422)
+				return
+			}
+			defer f.Close()
+			b, err = ioutil.ReadAll(f)
+		}
+	} else {
+		b, err = ioutil.ReadAll(r.Body)
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"ok": false, "message": "could not read file: %v"}`, err), 400)
+		return
+	}
+	r.ParseForm()
+
+	mode, err := ParseImportMode(r.Form.Get("mode"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"ok": false, "message": %q}`, err.Error()), 422)
+		return
+	}
+	file, err := ParseImport(b)
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"ok": false, "message": %q}`, err.Error()), 422)
+		return
+	}
+	report, err := mod.ImportFactoids(file, mode, r.Form.Get("dry_run") == "1", actionSource)
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"ok": false, "message": "internal server error: %v"}`, err), 500)
+		return
+	}
+	util.LogGood("Factoid import via web by", actionSource.User.IntraLogin, "-", report.String())
+
+	util.LogIfError(json.NewEncoder(w).Encode(struct {
+		OK     bool          `json:"ok"`
+		Report *ImportReport `json:"report"`
+	}{true, report}))
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"sync"
 	"time"
 
 	"github.com/patrickmn/go-cache"
@@ -13,6 +14,7 @@ import (
 
 	"github.com/riking/marvin"
 	"github.com/riking/marvin/slack"
+	"github.com/riking/marvin/slack/rtm"
 	"github.com/riking/marvin/util"
 )
 
@@ -25,6 +27,8 @@ const Identifier = "logger"
 type LoggerModule struct {
 	team  marvin.Team
 	cache *cache.Cache
+
+	initialBackfill sync.Once
 }
 
 func NewLoggerModule(t marvin.Team) marvin.Module {
@@ -48,11 +52,18 @@ func (mod *LoggerModule) Load(t marvin.Team) {
 }
 
 func (mod *LoggerModule) Enable(t marvin.Team) {
-	t.OnEvent(Identifier, "message", mod.OnMessage)
+	t.OnFilteredEvent(Identifier, marvin.EventFilter{Type: "message", Observer: true}, func(msg slack.RTMRawMessage) bool {
+		mod.OnMessage(msg)
+		return false
+	})
 	t.OnEvent(Identifier, "channel_joined", mod.OnJoinChannel)
 	t.OnEvent(Identifier, "group_joined", mod.OnJoinGroup)
 	t.OnEvent(Identifier, "hello", func(_ slack.RTMRawMessage) {
-		mod.BackfillAll()
+		// Later connections are caught up by the resumed event
+		mod.initialBackfill.Do(mod.BackfillAll)
+	})
+	t.OnEvent(Identifier, rtm.MsgTypeResumed, func(msg slack.RTMRawMessage) {
+		mod.BackfillSince(slack.MessageTS(msg.StringField("last_event_ts")))
 	})
 	t.HandleHTTP("/logs", http.HandlerFunc(mod.LogsIndex))
 }
@@ -147,7 +158,7 @@ func (mod *LoggerModule) OnJoinChannel(_rtm slack.RTMRawMessage) {
 		return
 	}
 	defer stmt.Close()
-	messages, err := mod.getHistory("channels.history", v, stmt)
+	messages, err := mod.getHistory(v, "", stmt)
 	if err != nil {
 		util.LogError(errors.Wrapf(err, "could not backfill logs for %s", v))
 		return
@@ -172,7 +183,7 @@ func (mod *LoggerModule) OnJoinGroup(_rtm slack.RTMRawMessage) {
 		return
 	}
 	defer stmt.Close()
-	messages, err := mod.getHistory("groups.history", v, stmt)
+	messages, err := mod.getHistory(v, "", stmt)
 	if err != nil {
 		util.LogError(errors.Wrapf(err, "could not backfill logs for %s", v))
 		return
@@ -183,11 +194,13 @@ func (mod *LoggerModule) OnJoinGroup(_rtm slack.RTMRawMessage) {
 	}
 }
 
-func (mod *LoggerModule) getHistory(method string, channel slack.ChannelID, stmt *sql.Stmt) ([]json.RawMessage, error) {
-	form := url.Values{
-		"count": []string{"40"},
+// getHistory fetches recent messages in a channel. If oldest is set, up to
+// 1000 messages after it are fetched instead of the last 40.
+func (mod *LoggerModule) getHistory(channel slack.ChannelID, oldest slack.MessageTS, stmt *sql.Stmt) ([]json.RawMessage, error) {
+	limit := 40
+	if oldest != "" {
+		limit = 1000
 	}
-	form.Set("channel", string(channel))
 
 	row := stmt.QueryRow(string(channel))
 	var lastSeenTS sql.NullString
@@ -199,34 +212,34 @@ func (mod *LoggerModule) getHistory(method string, channel slack.ChannelID, stmt
 		return nil, errors.Wrapf(err, "Backfill database err")
 	}
 
-	// PostRaw is used because we're unmarshalling a large response body into `json.RawMessage`s.
-	// Best to keep the json work to a minimum.
-	resp, err := mod.team.SlackAPIPostRaw(method, form)
+	messages, err := mod.team.ConversationHistory(channel, oldest, limit)
 	if err != nil {
-		return nil, errors.Wrapf(err, "Slack API %s error", method)
+		return nil, err
 	}
-
-	var response struct {
-		slack.APIResponse
-		Messages []json.RawMessage `json:"messages"`
-	}
-	err = json.NewDecoder(resp.Body).Decode(&response)
-	resp.Body.Close()
-	if err != nil {
-		return nil, errors.Wrapf(err, "Slack API %s json decode error", method)
-	}
-	if !response.OK {
-		return nil, errors.Wrapf(response, "Slack API %s error", method)
-	}
-	if len(response.Messages) > 0 {
-		fmt.Println("[Backfill]", channel, len(response.Messages), "recent messages")
+	if len(messages) > 0 {
+		fmt.Println("[Backfill]", channel, len(messages), "recent messages")
 	} else {
 		fmt.Println("[Backfill]", channel, "no recent messages")
 	}
-	return response.Messages, nil
+	return messages, nil
 }
 
+// BackfillAll saves the recent history of every channel.
 func (mod *LoggerModule) BackfillAll() {
+	mod.backfill("")
+}
+
+// BackfillSince saves the messages sent after the given timestamp, after
+// the RTM connection was lost and resumed.
+func (mod *LoggerModule) BackfillSince(ts slack.MessageTS) {
+	if ts == "" {
+		mod.BackfillAll()
+		return
+	}
+	mod.backfill(ts)
+}
+
+func (mod *LoggerModule) backfill(oldest slack.MessageTS) {
 	if mod.team.TeamConfig().IsDevelopment {
 		return // do not backfill in development
 	}
@@ -238,45 +251,13 @@ func (mod *LoggerModule) BackfillAll() {
 	}
 	defer stmt.Close()
 
-	publicList := mod.listChannels("channels")
-	for _, v := range publicList {
-		messages, err := mod.getHistory("channels.history", v, stmt)
-		if err != nil {
-			util.LogError(errors.Wrapf(err, "could not backfill logs for %s", v))
-			return
-		}
-		c := mod.saveBackfillData(v, messages)
-		if c != 0 {
-			util.LogGood(fmt.Sprintf("Backfilled %d messages from %s", c, v))
-		}
-	}
-	groupList := mod.listChannels("groups")
-	for _, v := range groupList {
-		messages, err := mod.getHistory("groups.history", v, stmt)
-		if err != nil {
-			util.LogError(errors.Wrapf(err, "could not backfill logs for %s", v))
-			return
-		}
-		c := mod.saveBackfillData(v, messages)
-		if c != 0 {
-			util.LogGood(fmt.Sprintf("Backfilled %d messages from %s", c, v))
-		}
-	}
-	mpimList := mod.listChannels("mpim")
-	for _, v := range mpimList {
-		messages, err := mod.getHistory("mpim.history", v, stmt)
-		if err != nil {
-			util.LogError(errors.Wrapf(err, "could not backfill logs for %s", v))
-			return
-		}
-		c := mod.saveBackfillData(v, messages)
-		if c != 0 {
-			util.LogGood(fmt.Sprintf("Backfilled %d messages from %s", c, v))
-		}
+	channels, err := mod.listChannels(oldest == "")
+	if err != nil {
+		util.LogError(errors.Wrap(err, "could not list channels to backfill"))
+		return
 	}
-	imList := mod.listChannels("im")
-	for _, v := range imList {
-		messages, err := mod.getHistory("im.history", v, stmt)
+	for _, v := range channels {
+		messages, err := mod.getHistory(v, oldest, stmt)
 		if err != nil {
 			util.LogError(errors.Wrapf(err, "could not backfill logs for %s", v))
 			return
@@ -288,46 +269,40 @@ func (mod *LoggerModule) BackfillAll() {
 	}
 }
 
-func (mod *LoggerModule) listChannels(typ string) []slack.ChannelID {
+// listChannels lists the conversations the bot is in. Archived ones are
+// left out when resuming, as they cannot have new messages.
+func (mod *LoggerModule) listChannels(withArchived bool) ([]slack.ChannelID, error) {
 	var response struct {
 		slack.APIResponse
 		Channels []struct {
-			ID string
-		}
-		Groups []struct {
-			ID string
-		}
-		Ims []struct {
-			ID string
-		}
+			ID slack.ChannelID `json:"id"`
+		} `json:"channels"`
+		PageInfo struct {
+			NextCursor string `json:"next_cursor"`
+		} `json:"response_metadata"`
 	}
-	mod.team.SlackAPIPostJSON(typ+".list", url.Values{
-		"exclude_members":  []string{"true"},
-		"exclude_archived": []string{"false"},
-	}, &response)
-
-	if len(response.Channels) != 0 {
-		ids := make([]slack.ChannelID, len(response.Channels))
-		for i := range response.Channels {
-			ids[i] = slack.ChannelID(response.Channels[i].ID)
-		}
-		return ids
+	form := url.Values{
+		"types":            []string{"public_channel,private_channel,mpim,im"},
+		"exclude_archived": []string{fmt.Sprint(!withArchived)},
+		"limit":            []string{"200"},
 	}
-	if len(response.Groups) != 0 {
-		ids := make([]slack.ChannelID, len(response.Groups))
-		for i := range response.Groups {
-			ids[i] = slack.ChannelID(response.Groups[i].ID)
+
+	var ids []slack.ChannelID
+	for {
+		// users.conversations only returns conversations the bot is in
+		err := mod.team.SlackAPIPostJSON("users.conversations", form, &response)
+		if err != nil {
+			return ids, err
 		}
-		return ids
-	}
-	if len(response.Ims) != 0 {
-		ids := make([]slack.ChannelID, len(response.Ims))
-		for i := range response.Ims {
-			ids[i] = slack.ChannelID(response.Ims[i].ID)
+		for _, v := range response.Channels {
+			ids = append(ids, v.ID)
+		}
+		if response.PageInfo.NextCursor == "" {
+			return ids, nil
 		}
-		return ids
+		form.Set("cursor", response.PageInfo.NextCursor)
+		response.PageInfo.NextCursor = ""
 	}
-	return nil
 }
 
 func (mod *LoggerModule) saveBackfillData(channel slack.ChannelID, messages []json.RawMessage) (totalAdded int64) {
@@ -20,30 +20,34 @@ func (mod *LoggerModule) getPrivateChannels(userID slack.UserID, token string) (
 	}
 
 	form := url.Values{
-		"token": []string{token},
+		"token":            []string{token},
+		"types":            []string{"private_channel"},
+		"exclude_archived": []string{"true"},
+		"limit":            []string{"1000"},
 	}
 	var resp struct {
-		Groups []*slack.Channel `json:"groups"`
+		Channels []*slack.Conversation `json:"channels"`
 	}
-	err := mod.team.SlackAPIPostJSON("groups.list", form, &resp)
+	err := mod.team.SlackAPIPostJSON("conversations.list", form, &resp)
 	if err != nil {
 		return nil, err
 	}
-	yourChannels := make([]briefChannelInfo, 0, len(resp.Groups))
-	for i, v := range resp.Groups {
+	yourChannels := make([]briefChannelInfo, 0, len(resp.Channels))
+	for _, v := range resp.Channels {
 		if v.IsMultiIM() {
 			continue
 		}
-		yourChannels = append(yourChannels, briefChannelInfo{
+		info := briefChannelInfo{
 			Name:        v.Name,
 			ID:          v.ID,
 			Purpose:     v.Purpose,
 			MemberCount: mod.team.ChannelMemberCount(v.ID),
-		})
+		}
 		g, _ := mod.team.PrivateChannelInfo(v.ID)
 		if g != nil {
-			yourChannels[i].HasMarvin = true
+			info.HasMarvin = true
 		}
+		yourChannels = append(yourChannels, info)
 	}
 
 	mod.cache.SetDefault(fmt.Sprintf("groups-%s", userID), yourChannels)
@@ -117,8 +117,7 @@ func (mod *OnReactionModule) Load(t marvin.Team) {
 }
 
 func (mod *OnReactionModule) Enable(t marvin.Team) {
-	t.OnEvent(Identifier, "reaction_added", mod.ReactionEvent)
-	t.OnEvent(Identifier, "reaction_removed", mod.ReactionEvent)
+	t.OnReaction(Identifier, mod.ReactionEvent)
 }
 
 func (mod *OnReactionModule) Disable(t marvin.Team) {
@@ -231,38 +230,24 @@ func (mod *OnReactionModule) Unregister(modID marvin.ModuleID) {
 // ---
 
 // https://api.slack.com/events/reaction_added
-func (mod *OnReactionModule) ReactionEvent(rtm slack.RTMRawMessage) {
-	var msg struct {
-		TargetUser slack.UserID `json:"item_user"`
-		Item       struct {
-			Type    string          `json:"type"`
-			Channel slack.ChannelID `json:"channel"`
-			TS      slack.MessageTS `json:"ts"`
-		}
-		EventTS slack.MessageTS `json:"event_ts"`
-	}
-
-	rtm.ReMarshal(&msg)
-	if msg.TargetUser != mod.team.BotUser() {
+func (mod *OnReactionModule) ReactionEvent(ev *slack.ReactionEvent) {
+	if ev.ItemUser != mod.team.BotUser() {
 		return
 	}
-	if msg.Item.Type != "message" {
+	if ev.Item.Type != "message" {
 		return
 	}
 	reactionEvent := ReactionEvent{
-		MessageID: slack.MessageID{
-			ChannelID: msg.Item.Channel,
-			MessageTS: msg.Item.TS,
-		},
-		UserID:    rtm.UserID(),
-		EmojiName: rtm.StringField("reaction"),
-		EventTS:   msg.EventTS,
-		IsAdded:   rtm.Type() == "reaction_added",
+		MessageID: ev.MessageID(),
+		UserID:    ev.User,
+		EmojiName: ev.Reaction,
+		EventTS:   ev.EventTS,
+		IsAdded:   !ev.IsRemoval(),
 	}
 	cbs, err := mod.getListens(reactionEvent.MessageID)
 	if err != nil {
 		// TODO not quite right
-		mod.team.ReportError(err, marvin.ActionSourceUserMessage{Msg: rtm})
+		mod.team.ReportError(err, marvin.ActionSourceUserMessage{Msg: ev.Raw()})
 		return
 	}
 	for _, v := range cbs {
@@ -6,11 +6,13 @@ import (
 	"net/http"
 	"regexp"
 	"strconv"
+	"strings"
 	"sync"
 
 	"github.com/pkg/errors"
 
 	"github.com/riking/marvin"
+	"github.com/riking/marvin/slack"
 	"github.com/riking/marvin/util"
 )
 
@@ -24,8 +26,28 @@ type API interface {
 	CreateLink(content string) (int64, error)
 	GetLink(id int64) (string, error)
 	URLForLink(id int64) string
+
+	// LongReplyMethod returns the configured way to store long replies to
+	// the given command. The command may be empty.
+	LongReplyMethod(command string) string
+	// StoreLongReply stores a message that is too long to post in the
+	// channel. With LongReplySnippet, the message is shared to the channel
+	// as a snippet, which takes the place of the reply, and the file is
+	// returned. Otherwise it returns a link to post with a preview of the
+	// message; the link is empty for LongReplyTruncate.
+	//
+	// The channel is empty when the reply has to be a message, such as an
+	// edit or an ephemeral message. Snippets are stored as pastes instead.
+	StoreLongReply(channel slack.ChannelID, command, message string) (link string, snippet *slack.File, err error)
 }
 
+// Values for the long-reply-method configuration key.
+const (
+	LongReplySnippet  = "snippet"
+	LongReplyPaste    = "paste"
+	LongReplyTruncate = "truncate"
+)
+
 var _ API = &PasteModule{}
 
 // ---
@@ -61,6 +83,25 @@ func (mod *PasteModule) Load(t marvin.Team) {
 	t.DB().MustMigrate(Identifier, 1479357009, sqlMigrate1)
 	t.DB().MustMigrate(Identifier, 1483845740, sqlMigrate2)
 	t.DB().SyntaxCheck(sqlAddPaste, sqlGetPaste, sqlAddLink, sqlGetLink)
+
+	t.ModuleConfig(Identifier).Add(confKeyLongReply, LongReplySnippet)
+	for _, v := range longReplyCommands {
+		t.ModuleConfig(Identifier).Add(longReplyKey(v), "")
+	}
+}
+
+// confKeyLongReply picks the long reply method for the team. It can be
+// overridden for the commands in longReplyCommands by setting
+// "long-reply-method.<command>", for example "long-reply-method.factoid".
+// An empty override uses the team setting.
+const confKeyLongReply = "long-reply-method"
+
+// longReplyCommands are the commands that often have long replies.
+// Factoids use "factoid" for both the command and the ! syntax.
+var longReplyCommands = []string{"factoid", "f", "help", "config", "schedule", "rss", "debug"}
+
+func longReplyKey(command string) string {
+	return fmt.Sprintf("%s.%s", confKeyLongReply, strings.ToLower(command))
 }
 
 func (mod *PasteModule) Enable(team marvin.Team) {
@@ -129,3 +170,47 @@ func (mod *PasteModule) URLForLink(id int64) string {
 	idStr := strconv.FormatInt(id, idBase)
 	return mod.team.AbsoluteURL(fmt.Sprintf("/l/%s", idStr))
 }
+
+func (mod *PasteModule) LongReplyMethod(command string) string {
+	conf := mod.team.ModuleConfig(Identifier)
+	method, _ := conf.Get(confKeyLongReply)
+	if command != "" {
+		// Commands without an override have no default, and get an error
+		override, _, err := conf.GetIsDefault(longReplyKey(command))
+		if err == nil && override != "" {
+			method = override
+		}
+	}
+	switch method {
+	case LongReplySnippet, LongReplyPaste, LongReplyTruncate:
+		return method
+	}
+	util.LogWarn("paste: unknown long-reply-method", method)
+	return LongReplySnippet
+}
+
+func (mod *PasteModule) StoreLongReply(channel slack.ChannelID, command, message string) (string, *slack.File, error) {
+	method := mod.LongReplyMethod(command)
+	if method == LongReplySnippet && channel == "" {
+		method = LongReplyPaste
+	}
+	switch method {
+	case LongReplySnippet:
+		title := "Reply"
+		if command != "" {
+			title = fmt.Sprintf("Reply to %s", command)
+		}
+		file, err := mod.team.UploadSnippet(channel, title, message)
+		if err != nil {
+			return "", nil, errors.Wrap(err, "upload snippet")
+		}
+		return "", file, nil
+	case LongReplyPaste:
+		id, err := mod.CreatePaste(message)
+		if err != nil {
+			return "", nil, errors.Wrap(err, "create paste")
+		}
+		return mod.URLForPaste(id), nil, nil
+	}
+	return "", nil, nil
+}
@@ -37,23 +37,21 @@ func (mod *RestartModule) Load(t marvin.Team) {
 }
 
 func (mod *RestartModule) Enable(team marvin.Team) {
-	team.RegisterCommandFunc("restart", mod.RestartCommand,
-		"`@marvin restart`"+
-			"This restarts the active Marvin instance.\n")
-	team.RegisterCommandFunc("recompile", mod.RecompileCommand,
-		"`@marvin recompile [restart]`"+
-			"This recompiles Marvin, pulling the latest changes.\n"+
-			"With optional parameter, restarts the server after a successful compile.\n")
+	team.RegisterCommand("restart", marvin.RequireLevel(marvin.AccessLevelController,
+		marvin.CommandFunc(mod.RestartCommand,
+			"`@marvin restart`"+
+				"This restarts the active Marvin instance.\n")))
+	team.RegisterCommand("recompile", marvin.RequireLevel(marvin.AccessLevelController,
+		marvin.CommandFunc(mod.RecompileCommand,
+			"`@marvin recompile [restart]`"+
+				"This recompiles Marvin, pulling the latest changes.\n"+
+				"With optional parameter, restarts the server after a successful compile.\n")))
 }
 
 func (mod *RestartModule) Disable(t marvin.Team) {
 }
 
 func (mod *RestartModule) RecompileCommand(t marvin.Team, args *marvin.CommandArguments) marvin.CommandResult {
-	if args.Source.AccessLevel() < marvin.AccessLevelController {
-		return marvin.CmdFailuref(args, "This command is restricted to controllers only.")
-	}
-
 	// This will check if it can take a buffer slot, if not, it means there's a recompile in progress.
 	// Otherwise it will recompile.
 	select {
@@ -80,21 +78,19 @@ func (mod *RestartModule) RecompileCommand(t marvin.Team, args *marvin.CommandAr
 }
 
 func (mod *RestartModule) RestartCommand(t marvin.Team, args *marvin.CommandArguments) marvin.CommandResult {
-	if args.Source.AccessLevel() < marvin.AccessLevelController {
-		return marvin.CmdFailuref(args, "This command is restricted to controllers only.")
-	}
-
-	select {
-	case <-recompileSemaphore:
-		break
-	default:
-		return marvin.CmdFailuref(args, "There is a recompile in progress.")
-	}
+	return marvin.CmdConfirm(args, "Restart the active Marvin instance?", func() marvin.CommandResult {
+		select {
+		case <-recompileSemaphore:
+			break
+		default:
+			return marvin.CmdFailuref(args, "There is a recompile in progress.")
+		}
 
-	defer func() { recompileSemaphore <- struct{}{} }()
+		defer func() { recompileSemaphore <- struct{}{} }()
 
-	go mod.Restart()
-	return marvin.CmdSuccess(args, "Restarting, be back soon.")
+		go mod.Restart()
+		return marvin.CmdSuccess(args, "Restarting, be back soon.")
+	})
 }
 
 // Execute the shell script located in $HOME/marvin/build (with +x perms).
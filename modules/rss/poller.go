@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/riking/marvin/slack"
 	"github.com/riking/marvin/util"
 )
 
@@ -17,7 +18,9 @@ func (p *poller) init(mod *RSSModule) {
 }
 
 func (p *poller) reportError(err error) {
-	p.mod.team.SendMessage(p.mod.team.TeamConfig().LogChannel, fmt.Sprintf("[RSS Poller] Error: %+v", err))
+	p.mod.team.QueueMessage(p.mod.team.TeamConfig().LogChannel, slack.OutgoingSlackMessage{
+		Text: fmt.Sprintf("[RSS Poller] Error: %+v", err),
+	})
 }
 
 func (p *poller) Run() {
@@ -95,7 +98,7 @@ func (p *poller) pollFeed(t FeedType, feedID string) (time.Duration, error) {
 
 		slMessage := items[i].Render(meta)
 		for _, ch := range channelList {
-			go p.mod.team.SendComplexMessage(ch.Channel, slMessage)
+			p.mod.team.QueueMessage(ch.Channel, slMessage)
 		}
 		p.mod.DB().MarkSeen(t.TypeID(), feedID, items[i].ItemID())
 	}
@@ -222,7 +222,7 @@ func (mod *RSSModule) CommandSubscribe(t marvin.Team, args *marvin.CommandArgume
 	}
 
 	if args.Source.ChannelID()[0] == 'D' {
-		go mod.team.SendComplexMessage(args.Source.ChannelID(), slackMessage)
+		mod.team.QueueMessage(args.Source.ChannelID(), slackMessage)
 		return marvin.CmdSuccess(args, "The feed given validates; however, direct messages cannot be subscribed to RSS feeds.").WithSimpleUndo()
 	} else {
 		err = mod.DB().Subscribe(feedType.TypeID(), feedID, args.Source.ChannelID(), items)
@@ -231,7 +231,7 @@ func (mod *RSSModule) CommandSubscribe(t marvin.Team, args *marvin.CommandArgume
 		}
 	}
 
-	go mod.team.SendComplexMessage(args.Source.ChannelID(), slackMessage)
+	mod.team.QueueMessage(args.Source.ChannelID(), slackMessage)
 	return marvin.CmdSuccess(args, fmt.Sprintf("%v is now subscribed to %s:%s",
 		mod.team.FormatChannel(args.Source.ChannelID()), feedType.Name(), feedID)).WithNoUndo()
 }
@@ -0,0 +1,63 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronNext(t *testing.T) {
+	loc := time.UTC
+	// Friday
+	start := time.Date(2017, 3, 3, 8, 30, 0, 0, loc)
+
+	tests := []struct {
+		spec   string
+		expect time.Time
+	}{
+		{"0 9 * * 1-5", time.Date(2017, 3, 3, 9, 0, 0, 0, loc)},
+		{"0 9 * * sat,sun", time.Date(2017, 3, 4, 9, 0, 0, 0, loc)},
+		{"*/20 * * * *", time.Date(2017, 3, 3, 8, 40, 0, 0, loc)},
+		{"30 8 * * *", time.Date(2017, 3, 4, 8, 30, 0, 0, loc)},
+		{"0 0 1 jan *", time.Date(2018, 1, 1, 0, 0, 0, 0, loc)},
+		{"0 12 15 * 7", time.Date(2017, 3, 5, 12, 0, 0, 0, loc)},
+	}
+	for _, v := range tests {
+		c, err := ParseCron(v.spec)
+		if err != nil {
+			t.Errorf("%s: parse error: %s", v.spec, err)
+			continue
+		}
+		got := c.Next(start)
+		if !got.Equal(v.expect) {
+			t.Errorf("%s: expected %v, got %v", v.spec, v.expect, got)
+		}
+	}
+}
+
+func TestCronNever(t *testing.T) {
+	c, err := ParseCron("0 0 31 2 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next := c.Next(time.Now()); !next.IsZero() {
+		t.Errorf("expected no match, got %v", next)
+	}
+}
+
+func TestCronParseErrors(t *testing.T) {
+	for _, spec := range []string{
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+	} {
+		if _, err := ParseCron(spec); err == nil {
+			t.Errorf("%s: expected error", spec)
+		}
+	}
+}
@@ -0,0 +1,640 @@
+package schedule
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/riking/marvin"
+	"github.com/riking/marvin/modules/atcommand"
+	"github.com/riking/marvin/slack"
+	"github.com/riking/marvin/util"
+)
+
+func init() {
+	marvin.RegisterModule(NewScheduleModule)
+}
+
+const Identifier = "schedule"
+
+type ScheduleModule struct {
+	team     marvin.Team
+	notifyCh chan struct{}
+	stopCh   chan struct{}
+
+	atcommandModule marvin.Module
+}
+
+func NewScheduleModule(t marvin.Team) marvin.Module {
+	mod := &ScheduleModule{
+		team:     t,
+		notifyCh: make(chan struct{}, 1),
+	}
+	return mod
+}
+
+func (mod *ScheduleModule) Identifier() marvin.ModuleID {
+	return Identifier
+}
+
+const (
+	confKeyTimezone   = "timezone"
+	confKeyMissGrace  = "missed-run-grace"
+	confKeyMaxPerUser = "max-per-user"
+)
+
+func (mod *ScheduleModule) Load(t marvin.Team) {
+	t.DependModule(mod, atcommand.Identifier, &mod.atcommandModule)
+
+	t.DB().MustMigrate(Identifier, 1488412800, sqlMigrate1, sqlMigrate1b)
+	t.DB().SyntaxCheck(
+		sqlGetNextRun,
+		sqlGetDueJobs,
+		sqlInsertJob,
+		sqlUpdateNextRun,
+		sqlDeleteJob,
+		sqlGetJob,
+		sqlListUserJobs,
+		sqlListAllJobs,
+		sqlCountUserJobs,
+	)
+
+	c := t.ModuleConfig(Identifier)
+	c.Add(confKeyTimezone, "America/Los_Angeles")
+	c.Add(confKeyMissGrace, "1h")
+	c.Add(confKeyMaxPerUser, "20")
+}
+
+func (mod *ScheduleModule) Enable(t marvin.Team) {
+	parent := marvin.NewParentCommand().WithHelp(helpSchedule)
+	parent.RegisterCommandFunc("list", mod.CommandList, helpList)
+	cancel := parent.RegisterCommandFunc("cancel", mod.CommandCancel, helpCancel)
+	parent.RegisterCommand("remove", cancel)
+	parent.RegisterCommandFunc("cron", mod.CommandCron, helpCron)
+	at := parent.RegisterCommandFunc("at", mod.CommandAt, helpAt)
+	in := parent.RegisterCommandFunc("in", mod.CommandIn, helpIn)
+
+	t.RegisterCommand("schedule", parent)
+	t.RegisterCommand("at", at)
+	t.RegisterCommand("in", in)
+
+	mod.stopCh = make(chan struct{})
+	go mod.runLoop(mod.stopCh)
+}
+
+func (mod *ScheduleModule) Disable(t marvin.Team) {
+	t.UnregisterCommand("schedule")
+	t.UnregisterCommand("at")
+	t.UnregisterCommand("in")
+	close(mod.stopCh)
+}
+
+// ---
+
+const (
+	sqlMigrate1 = `
+	CREATE TABLE module_schedule_jobs (
+		id          SERIAL PRIMARY KEY,
+		channel     varchar(15) NOT NULL,
+		user_id     varchar(15) NOT NULL,
+		source_ts   varchar(20) NOT NULL,
+		arguments   text NOT NULL,
+		cron_spec   text NOT NULL DEFAULT '',
+		description text NOT NULL,
+		next_run    timestamptz NOT NULL,
+		created_at  timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`
+	sqlMigrate1b = `CREATE INDEX idx_schedule_by_time ON module_schedule_jobs (next_run)`
+
+	sqlGetNextRun = `SELECT MIN(next_run) FROM module_schedule_jobs`
+
+	sqlGetDueJobs = `
+	SELECT id, channel, user_id, source_ts, arguments, cron_spec, description, next_run
+	FROM module_schedule_jobs
+	WHERE next_run <= CURRENT_TIMESTAMP`
+
+	// $1 = channel $2 = user $3 = source ts $4 = arguments (json)
+	// $5 = cron spec $6 = description $7 = next run
+	sqlInsertJob = `
+	INSERT INTO module_schedule_jobs
+	(channel, user_id, source_ts, arguments, cron_spec, description, next_run)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	RETURNING id`
+
+	// $1 = id $2 = next run
+	sqlUpdateNextRun = `
+	UPDATE module_schedule_jobs
+	SET next_run = $2
+	WHERE id = $1`
+
+	// $1 = id
+	sqlDeleteJob = `
+	DELETE FROM module_schedule_jobs
+	WHERE id = $1`
+
+	// $1 = id
+	sqlGetJob = `
+	SELECT id, channel, user_id, source_ts, arguments, cron_spec, description, next_run
+	FROM module_schedule_jobs
+	WHERE id = $1`
+
+	// $1 = user
+	sqlListUserJobs = `
+	SELECT id, channel, user_id, source_ts, arguments, cron_spec, description, next_run
+	FROM module_schedule_jobs
+	WHERE user_id = $1
+	ORDER BY next_run ASC`
+
+	sqlListAllJobs = `
+	SELECT id, channel, user_id, source_ts, arguments, cron_spec, description, next_run
+	FROM module_schedule_jobs
+	ORDER BY next_run ASC`
+
+	// $1 = user
+	sqlCountUserJobs = `
+	SELECT COUNT(*) FROM module_schedule_jobs
+	WHERE user_id = $1`
+)
+
+// A Job is a command that has been scheduled to run later.
+type Job struct {
+	ID       int64
+	Channel  slack.ChannelID
+	User     slack.UserID
+	SourceTS slack.MessageTS
+	Args     []string
+	// CronSpec is empty for one-shot jobs.
+	CronSpec    string
+	Description string
+	NextRun     time.Time
+}
+
+type scannable interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanJob(row scannable) (*Job, error) {
+	var j Job
+	var argsJSON string
+	err := row.Scan(&j.ID, (*string)(&j.Channel), (*string)(&j.User), (*string)(&j.SourceTS),
+		&argsJSON, &j.CronSpec, &j.Description, &j.NextRun)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal([]byte(argsJSON), &j.Args)
+	if err != nil {
+		return nil, errors.Wrapf(err, "schedule: bad arguments for job %d", j.ID)
+	}
+	return &j, nil
+}
+
+func (mod *ScheduleModule) queryJobs(query string, args ...interface{}) ([]*Job, error) {
+	stmt, err := mod.team.DB().Prepare(query)
+	if err != nil {
+		return nil, errors.Wrap(err, "schedule: prepare")
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "schedule: query")
+	}
+	defer rows.Close()
+	var result []*Job
+	for rows.Next() {
+		j, err := scanJob(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "schedule: scan")
+		}
+		result = append(result, j)
+	}
+	if rows.Err() != nil {
+		return nil, errors.Wrap(rows.Err(), "schedule: query")
+	}
+	return result, nil
+}
+
+// GetJob loads a single job from the database. It returns (nil, nil) if the
+// job does not exist.
+func (mod *ScheduleModule) GetJob(id int64) (*Job, error) {
+	stmt, err := mod.team.DB().Prepare(sqlGetJob)
+	if err != nil {
+		return nil, errors.Wrap(err, "schedule: prepare")
+	}
+	defer stmt.Close()
+
+	j, err := scanJob(stmt.QueryRow(id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "schedule: get job")
+	}
+	return j, nil
+}
+
+// AddJob saves a job to the database, filling in its ID, and wakes up the
+// worker.
+func (mod *ScheduleModule) AddJob(j *Job) error {
+	argsJSON, err := json.Marshal(j.Args)
+	if err != nil {
+		return errors.Wrap(err, "schedule: marshal arguments")
+	}
+	stmt, err := mod.team.DB().Prepare(sqlInsertJob)
+	if err != nil {
+		return errors.Wrap(err, "schedule: prepare")
+	}
+	defer stmt.Close()
+
+	err = stmt.QueryRow(string(j.Channel), string(j.User), string(j.SourceTS), string(argsJSON),
+		j.CronSpec, j.Description, j.NextRun).Scan(&j.ID)
+	if err != nil {
+		return errors.Wrap(err, "schedule: insert")
+	}
+	mod.wakeup()
+	return nil
+}
+
+// DeleteJob removes a job from the database.
+func (mod *ScheduleModule) DeleteJob(id int64) error {
+	stmt, err := mod.team.DB().Prepare(sqlDeleteJob)
+	if err != nil {
+		return errors.Wrap(err, "schedule: prepare")
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(id)
+	if err != nil {
+		return errors.Wrap(err, "schedule: delete")
+	}
+	mod.wakeup()
+	return nil
+}
+
+func (mod *ScheduleModule) countUserJobs(user slack.UserID) (int, error) {
+	stmt, err := mod.team.DB().Prepare(sqlCountUserJobs)
+	if err != nil {
+		return 0, errors.Wrap(err, "schedule: prepare")
+	}
+	defer stmt.Close()
+
+	var count int
+	err = stmt.QueryRow(string(user)).Scan(&count)
+	if err != nil {
+		return 0, errors.Wrap(err, "schedule: count")
+	}
+	return count, nil
+}
+
+func (mod *ScheduleModule) wakeup() {
+	select {
+	case mod.notifyCh <- struct{}{}:
+	default:
+	}
+}
+
+func (mod *ScheduleModule) location() *time.Location {
+	tzName, _ := mod.team.ModuleConfig(Identifier).Get(confKeyTimezone)
+	loc, err := time.LoadLocation(tzName)
+	if err != nil {
+		util.LogWarn("schedule: bad timezone", tzName, err)
+		return util.TZ42USA()
+	}
+	return loc
+}
+
+func (mod *ScheduleModule) missedRunGrace() time.Duration {
+	str, _ := mod.team.ModuleConfig(Identifier).Get(confKeyMissGrace)
+	d, err := time.ParseDuration(str)
+	if err != nil {
+		return 1 * time.Hour
+	}
+	return d
+}
+
+// ---
+
+const helpSchedule = "`@marvin at <time> [every <days>] <command...>` and `@marvin in <duration> <command...>` run a command later, in the same channel, as you.\n" +
+	"Use `@marvin schedule list` to see your scheduled commands and `@marvin schedule cancel <id>` to remove one."
+
+const helpAt = "`@marvin at <time> [every <days>] <command...>` runs a command at the given time, in the same channel, as you.\n" +
+	"_time_ is `HH:MM` (24-hour), `H[:MM]am`/`pm`, or `YYYY-MM-DD HH:MM`.\n" +
+	"_days_ is `day`, `weekday`, `weekend`, or a comma-separated list of day names (`mon,wed,fri`).\n" +
+	"Example: `@marvin at 9:00 every weekday rss list`"
+
+const helpIn = "`@marvin in <duration> <command...>` runs a command once after the duration has passed, in the same channel, as you.\n" +
+	"Example: `@marvin in 2h factoid get standup` (units: `d`, `h`, `m`, `s`)"
+
+const helpCron = "`@marvin schedule cron <minute> <hour> <day-of-month> <month> <day-of-week> <command...>` runs a command on a cron schedule.\n" +
+	"Example: `@marvin schedule cron */30 9-17 * * mon-fri echo stretch!`"
+
+const helpList = "`@marvin schedule list [all]` lists your scheduled commands. Admins can use `all` to see everyone's."
+
+const helpCancel = "`@marvin schedule cancel <id>` removes a scheduled command. You can only cancel your own, unless you are an admin."
+
+// Commands that can't be usefully scheduled.
+var noScheduleCommands = map[string]bool{
+	"schedule": true,
+	"at":       true,
+	"in":       true,
+}
+
+func (mod *ScheduleModule) checkCommand(args *marvin.CommandArguments) *marvin.CommandResult {
+	if len(args.Arguments) == 0 {
+		r := marvin.CmdFailuref(args, "You need to give a command to run.").WithSimpleUndo()
+		return &r
+	}
+	if noScheduleCommands[args.Arguments[0]] {
+		r := marvin.CmdFailuref(args, "You can't schedule a `%s` command.", args.Arguments[0]).WithSimpleUndo()
+		return &r
+	}
+	maxStr, _ := mod.team.ModuleConfig(Identifier).Get(confKeyMaxPerUser)
+	maxN, err := strconv.Atoi(maxStr)
+	if err == nil && maxN > 0 && args.Source.AccessLevel() < marvin.AccessLevelAdmin {
+		count, err := mod.countUserJobs(args.Source.UserID())
+		if err != nil {
+			r := marvin.CmdError(args, err, "Database error")
+			return &r
+		}
+		if count >= maxN {
+			r := marvin.CmdFailuref(args, "You already have %d scheduled commands. Cancel some with `@marvin schedule cancel` first.", count).WithSimpleUndo()
+			return &r
+		}
+	}
+	return nil
+}
+
+func (mod *ScheduleModule) saveJob(args *marvin.CommandArguments, cronSpec, description string, nextRun time.Time) marvin.CommandResult {
+	j := &Job{
+		Channel:     args.Source.ChannelID(),
+		User:        args.Source.UserID(),
+		SourceTS:    args.Source.MsgTimestamp(),
+		Args:        args.Arguments,
+		CronSpec:    cronSpec,
+		Description: description,
+		NextRun:     nextRun,
+	}
+	err := mod.AddJob(j)
+	if err != nil {
+		return marvin.CmdError(args, err, "Could not save scheduled command")
+	}
+	return marvin.CmdSuccess(args, fmt.Sprintf(
+		"Okay, I'll run `%s` %s (#%d, next run %s).",
+		strings.Join(j.Args, " "), description, j.ID, formatNextRun(j.NextRun, mod.location()),
+	)).WithNoUndo()
+}
+
+func formatNextRun(t time.Time, loc *time.Location) string {
+	return fmt.Sprintf("%s, in %s",
+		t.In(loc).Format("Mon Jan 2 15:04 MST"),
+		time.Until(t).Truncate(time.Minute).String())
+}
+
+var rgxDays = regexp.MustCompile(`^(\d+)d`)
+
+// parseDuration is time.ParseDuration with support for a leading day count.
+func parseDuration(s string) (time.Duration, error) {
+	var days time.Duration
+	if m := rgxDays.FindStringSubmatch(s); m != nil {
+		n, err := strconv.Atoi(m[1])
+		if err != nil {
+			return 0, err
+		}
+		days = time.Duration(n) * 24 * time.Hour
+		s = s[len(m[0]):]
+		if s == "" {
+			return days, nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	return days + d, err
+}
+
+func (mod *ScheduleModule) CommandIn(t marvin.Team, args *marvin.CommandArguments) marvin.CommandResult {
+	if len(args.Arguments) < 2 {
+		return marvin.CmdUsage(args, helpIn).WithSimpleUndo()
+	}
+	durationArg := args.Pop()
+	duration, err := parseDuration(durationArg)
+	if err != nil {
+		return marvin.CmdFailuref(args, "Bad duration '%s': %s", durationArg, err).WithSimpleUndo()
+	}
+	if duration < 1*time.Minute {
+		return marvin.CmdFailuref(args, "Duration must be at least a minute.").WithSimpleUndo()
+	}
+	if r := mod.checkCommand(args); r != nil {
+		return *r
+	}
+	return mod.saveJob(args, "", fmt.Sprintf("in %s", durationArg), time.Now().Add(duration))
+}
+
+var rgxClock = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?(am|pm)?$`)
+var rgxDate = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+
+// parseClock parses "9:00", "17:30", "9am", "5:30pm" into an hour and minute.
+func parseClock(s string) (hour, minute int, ok bool) {
+	m := rgxClock.FindStringSubmatch(strings.ToLower(s))
+	if m == nil || (m[2] == "" && m[3] == "") {
+		return 0, 0, false
+	}
+	hour, _ = strconv.Atoi(m[1])
+	if m[2] != "" {
+		minute, _ = strconv.Atoi(m[2])
+	}
+	if m[3] != "" {
+		if hour < 1 || hour > 12 {
+			return 0, 0, false
+		}
+		hour = hour % 12
+		if m[3] == "pm" {
+			hour += 12
+		}
+	}
+	if hour > 23 || minute > 59 {
+		return 0, 0, false
+	}
+	return hour, minute, true
+}
+
+var dayGroups = map[string]string{
+	"day":      "*",
+	"days":     "*",
+	"weekday":  "1-5",
+	"weekdays": "1-5",
+	"weekend":  "0,6",
+	"weekends": "0,6",
+}
+
+// parseDays turns the argument of "every" into a cron day-of-week field.
+func parseDays(s string) (string, bool) {
+	s = strings.ToLower(s)
+	if f, ok := dayGroups[s]; ok {
+		return f, true
+	}
+	var days []string
+	for _, v := range strings.Split(s, ",") {
+		if len(v) < 3 {
+			return "", false
+		}
+		if _, ok := cronDayNames[v[:3]]; !ok {
+			return "", false
+		}
+		days = append(days, v[:3])
+	}
+	return strings.Join(days, ","), true
+}
+
+func (mod *ScheduleModule) CommandAt(t marvin.Team, args *marvin.CommandArguments) marvin.CommandResult {
+	if len(args.Arguments) < 2 {
+		return marvin.CmdUsage(args, helpAt).WithSimpleUndo()
+	}
+	loc := mod.location()
+	now := time.Now().In(loc)
+
+	var date time.Time
+	hasDate := false
+	if rgxDate.MatchString(args.Arguments[0]) {
+		dateArg := args.Pop()
+		var err error
+		date, err = time.ParseInLocation("2006-01-02", dateArg, loc)
+		if err != nil {
+			return marvin.CmdFailuref(args, "Bad date '%s'", dateArg).WithSimpleUndo()
+		}
+		hasDate = true
+		if len(args.Arguments) == 0 {
+			return marvin.CmdUsage(args, helpAt).WithSimpleUndo()
+		}
+	}
+
+	timeArg := args.Pop()
+	hour, minute, ok := parseClock(timeArg)
+	if !ok {
+		return marvin.CmdFailuref(args, "Bad time '%s': use `HH:MM` or `H:MMam`.", timeArg).WithSimpleUndo()
+	}
+
+	if len(args.Arguments) >= 2 && args.Arguments[0] == "every" {
+		if hasDate {
+			return marvin.CmdFailuref(args, "You can't give both a date and `every`.").WithSimpleUndo()
+		}
+		args.Pop()
+		daysArg := args.Pop()
+		dowField, ok := parseDays(daysArg)
+		if !ok {
+			return marvin.CmdFailuref(args, "Bad day list '%s': use `day`, `weekday`, `weekend`, or names like `mon,wed,fri`.", daysArg).WithSimpleUndo()
+		}
+		if r := mod.checkCommand(args); r != nil {
+			return *r
+		}
+		cronSpec := fmt.Sprintf("%d %d * * %s", minute, hour, dowField)
+		sched, err := ParseCron(cronSpec)
+		if err != nil {
+			return marvin.CmdError(args, err, "Internal error building schedule")
+		}
+		return mod.saveJob(args, cronSpec,
+			fmt.Sprintf("every %s at %02d:%02d", daysArg, hour, minute), sched.Next(now))
+	}
+
+	if r := mod.checkCommand(args); r != nil {
+		return *r
+	}
+	var runAt time.Time
+	if hasDate {
+		runAt = time.Date(date.Year(), date.Month(), date.Day(), hour, minute, 0, 0, loc)
+		if runAt.Before(now) {
+			return marvin.CmdFailuref(args, "%s is in the past.", runAt.Format("2006-01-02 15:04 MST")).WithSimpleUndo()
+		}
+	} else {
+		runAt = time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, loc)
+		if !runAt.After(now) {
+			runAt = time.Date(now.Year(), now.Month(), now.Day()+1, hour, minute, 0, 0, loc)
+		}
+	}
+	return mod.saveJob(args, "", fmt.Sprintf("at %s", runAt.Format("2006-01-02 15:04")), runAt)
+}
+
+func (mod *ScheduleModule) CommandCron(t marvin.Team, args *marvin.CommandArguments) marvin.CommandResult {
+	if len(args.Arguments) < 6 {
+		return marvin.CmdUsage(args, helpCron).WithSimpleUndo()
+	}
+	cronSpec := strings.Join(args.Arguments[:5], " ")
+	args.Arguments = args.Arguments[5:]
+	sched, err := ParseCron(cronSpec)
+	if err != nil {
+		return marvin.CmdFailuref(args, "Bad cron expression `%s`: %s", cronSpec, err).WithSimpleUndo()
+	}
+	next := sched.Next(time.Now().In(mod.location()))
+	if next.IsZero() {
+		return marvin.CmdFailuref(args, "The cron expression `%s` never matches.", cronSpec).WithSimpleUndo()
+	}
+	if r := mod.checkCommand(args); r != nil {
+		return *r
+	}
+	return mod.saveJob(args, cronSpec, fmt.Sprintf("on cron `%s`", cronSpec), next)
+}
+
+func (mod *ScheduleModule) CommandList(t marvin.Team, args *marvin.CommandArguments) marvin.CommandResult {
+	var jobs []*Job
+	var err error
+	showAll := len(args.Arguments) > 0 && args.Arguments[0] == "all"
+	if showAll {
+		if args.Source.AccessLevel() < marvin.AccessLevelAdmin {
+			return marvin.CmdFailuref(args, "Only admins can list everyone's scheduled commands.").WithSimpleUndo()
+		}
+		jobs, err = mod.queryJobs(sqlListAllJobs)
+	} else {
+		jobs, err = mod.queryJobs(sqlListUserJobs, string(args.Source.UserID()))
+	}
+	if err != nil {
+		return marvin.CmdError(args, err, "Database error")
+	}
+	if len(jobs) == 0 {
+		return marvin.CmdSuccess(args, "No scheduled commands.").WithSimpleUndo()
+	}
+
+	loc := mod.location()
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%d scheduled commands:\n", len(jobs))
+	for _, j := range jobs {
+		fmt.Fprintf(&buf, "  `#%d` `%s` in %s %s; next run %s", j.ID,
+			strings.Join(j.Args, " "), t.FormatChannel(j.Channel), j.Description, formatNextRun(j.NextRun, loc))
+		if showAll {
+			fmt.Fprintf(&buf, " (by %s)", t.UserName(j.User))
+		}
+		buf.WriteByte('\n')
+	}
+	buf.WriteString("Use `@marvin schedule cancel <id>` to remove one.")
+	return marvin.CmdSuccess(args, buf.String()).WithSimpleUndo()
+}
+
+func (mod *ScheduleModule) CommandCancel(t marvin.Team, args *marvin.CommandArguments) marvin.CommandResult {
+	if len(args.Arguments) != 1 {
+		return marvin.CmdUsage(args, helpCancel).WithSimpleUndo()
+	}
+	idArg := strings.TrimPrefix(args.Pop(), "#")
+	id, err := strconv.ParseInt(idArg, 10, 64)
+	if err != nil {
+		return marvin.CmdFailuref(args, "'%s' is not a scheduled command ID.", idArg).WithSimpleUndo()
+	}
+	j, err := mod.GetJob(id)
+	if err != nil {
+		return marvin.CmdError(args, err, "Database error")
+	}
+	if j == nil {
+		return marvin.CmdFailuref(args, "No scheduled command `#%d`.", id).WithSimpleUndo()
+	}
+	if j.User != args.Source.UserID() && args.Source.AccessLevel() < marvin.AccessLevelAdmin {
+		return marvin.CmdFailuref(args, "Scheduled command `#%d` belongs to someone else.", id).WithSimpleUndo()
+	}
+	err = mod.DeleteJob(id)
+	if err != nil {
+		return marvin.CmdError(args, err, "Database error")
+	}
+	return marvin.CmdSuccess(args, fmt.Sprintf("Cancelled `#%d` (`%s` %s).",
+		id, strings.Join(j.Args, " "), j.Description)).WithNoUndo()
+}
@@ -0,0 +1,181 @@
+package schedule
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/riking/marvin"
+	"github.com/riking/marvin/modules/atcommand"
+	"github.com/riking/marvin/slack"
+	"github.com/riking/marvin/util"
+)
+
+// ActionSourceScheduled is the ActionSource of a command run by the
+// scheduler. It acts as the user who scheduled the command, in the channel
+// it was scheduled from.
+type ActionSourceScheduled struct {
+	Team marvin.Team
+	Job  *Job
+}
+
+func (as ActionSourceScheduled) UserID() slack.UserID          { return as.Job.User }
+func (as ActionSourceScheduled) ChannelID() slack.ChannelID    { return as.Job.Channel }
+func (as ActionSourceScheduled) MsgTimestamp() slack.MessageTS { return as.Job.SourceTS }
+func (as ActionSourceScheduled) AccessLevel() marvin.AccessLevel {
+	return as.Team.UserLevel(as.Job.User)
+}
+func (as ActionSourceScheduled) ArchiveLink() string {
+	if as.Job.SourceTS == "" {
+		return ""
+	}
+	return as.Team.ArchiveURL(slack.MsgID(as.Job.Channel, as.Job.SourceTS))
+}
+
+func (mod *ScheduleModule) runLoop(stopCh chan struct{}) {
+	for {
+		until, err := mod.nextRunTime()
+		if err != nil {
+			util.LogError(err)
+			mod.team.SendMessage(mod.team.TeamConfig().LogChannel, "<!channel> scheduler has encountered a DB error and will quit")
+			return
+		}
+		if until > 0 {
+			// interruptible sleep
+			select {
+			case <-time.After(until):
+			case <-mod.notifyCh:
+			case <-stopCh:
+				return
+			}
+			continue
+		}
+		err = mod.doRuns()
+		if err != nil {
+			util.LogError(err)
+			select {
+			case <-time.After(1 * time.Minute):
+			case <-stopCh:
+				return
+			}
+		}
+	}
+}
+
+func (mod *ScheduleModule) nextRunTime() (time.Duration, error) {
+	stmt, err := mod.team.DB().Prepare(sqlGetNextRun)
+	if err != nil {
+		return 0, errors.Wrap(err, "schedule: prepare")
+	}
+	defer stmt.Close()
+
+	var nextRun *time.Time
+	err = stmt.QueryRow().Scan(&nextRun)
+	if err == sql.ErrNoRows || nextRun == nil {
+		return 24 * time.Hour, nil
+	} else if err != nil {
+		return 0, errors.Wrap(err, "schedule: read from db")
+	}
+	return time.Until(*nextRun), nil
+}
+
+// doRuns runs every job that is due, and reschedules or deletes it.
+//
+// Jobs that were due more than missed-run-grace ago (because the bot was
+// down) are skipped and the owner is told. A recurring job that missed
+// several runs is only run once.
+func (mod *ScheduleModule) doRuns() error {
+	list, err := mod.queryJobs(sqlGetDueJobs)
+	if err != nil {
+		return err
+	}
+
+	updateStmt, err := mod.team.DB().Prepare(sqlUpdateNextRun)
+	if err != nil {
+		return errors.Wrap(err, "schedule: prepare")
+	}
+	defer updateStmt.Close()
+	deleteStmt, err := mod.team.DB().Prepare(sqlDeleteJob)
+	if err != nil {
+		return errors.Wrap(err, "schedule: prepare")
+	}
+	defer deleteStmt.Close()
+
+	now := time.Now().In(mod.location())
+	grace := mod.missedRunGrace()
+	for _, j := range list {
+		// Reschedule before running so a crashing command can't loop
+		var next time.Time
+		if j.CronSpec != "" {
+			sched, err := ParseCron(j.CronSpec)
+			if err == nil {
+				next = sched.Next(now)
+			} else {
+				util.LogError(errors.Wrapf(err, "schedule: job %d has a bad cron spec", j.ID))
+			}
+		}
+		if next.IsZero() {
+			_, err = deleteStmt.Exec(j.ID)
+		} else {
+			_, err = updateStmt.Exec(j.ID, next)
+		}
+		if err != nil {
+			// Don't run it - it would run again on the next pass
+			util.LogError(errors.Wrapf(err, "schedule: could not update job %d", j.ID))
+			continue
+		}
+
+		if late := now.Sub(j.NextRun); late > grace {
+			go mod.notifyMissed(j, late, next)
+			continue
+		}
+		go mod.runJob(j)
+	}
+	return nil
+}
+
+func (mod *ScheduleModule) notifyMissed(j *Job, late time.Duration, next time.Time) {
+	imChannel, err := mod.team.GetIM(j.User)
+	if err != nil {
+		util.LogError(err)
+		return
+	}
+	msg := fmt.Sprintf("I was offline when your scheduled command `#%d` (`%s` in %s) was due %s ago, so I skipped it.",
+		j.ID, strings.Join(j.Args, " "), mod.team.FormatChannel(j.Channel), late.Truncate(time.Minute))
+	if !next.IsZero() {
+		msg += fmt.Sprintf(" The next run is %s.", formatNextRun(next, mod.location()))
+	}
+	mod.team.SendMessage(imChannel, msg)
+}
+
+func (mod *ScheduleModule) runJob(j *Job) {
+	source := ActionSourceScheduled{Team: mod.team, Job: j}
+	if source.AccessLevel() < marvin.AccessLevelNormal {
+		util.LogWarn("schedule: not running job", j.ID, "for blacklisted user", j.User)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+	args := &marvin.CommandArguments{
+		OriginalArguments: j.Args,
+		Arguments:         j.Args,
+		Command:           "",
+		Ctx:               ctx,
+		Source:            source,
+	}
+	util.LogDebug("schedule: running job", j.ID, "args: [", strings.Join(args.OriginalArguments, "] ["), "]")
+	result := mod.team.DispatchCommand(args)
+
+	if mod.atcommandModule == nil {
+		if result.Message != "" {
+			mod.team.SendMessage(j.Channel, atcommand.SanitizeForChannel(fmt.Sprintf("%v: %s", j.User, result.Message)))
+		}
+		return
+	}
+	mod.atcommandModule.(*atcommand.AtCommandModule).SendReplies(result, source)
+}
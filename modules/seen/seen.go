@@ -0,0 +1,168 @@
+package seen
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/riking/marvin"
+	"github.com/riking/marvin/slack"
+)
+
+func init() {
+	marvin.RegisterModule(NewSeenModule)
+}
+
+const Identifier = "seen"
+
+type SeenModule struct {
+	team marvin.Team
+}
+
+func NewSeenModule(t marvin.Team) marvin.Module {
+	mod := &SeenModule{team: t}
+	return mod
+}
+
+func (mod *SeenModule) Identifier() marvin.ModuleID {
+	return Identifier
+}
+
+func (mod *SeenModule) Load(t marvin.Team) {
+}
+
+const helpSeen = "`seen @user` shows whether someone is active, and when they were last active and last spoke.\n" +
+	"`seen optout` stops recording your activity and deletes what was recorded. `seen optin` starts it again."
+
+func (mod *SeenModule) Enable(t marvin.Team) {
+	t.RegisterCommandFunc("seen", mod.CommandSeen, helpSeen)
+}
+
+func (mod *SeenModule) Disable(t marvin.Team) {
+	t.UnregisterCommand("seen")
+}
+
+// ---
+
+func (mod *SeenModule) CommandSeen(t marvin.Team, args *marvin.CommandArguments) marvin.CommandResult {
+	if len(args.Arguments) != 1 {
+		return marvin.CmdUsage(args, helpSeen).WithSimpleUndo()
+	}
+
+	switch args.Arguments[0] {
+	case "optout", "opt-out":
+		err := t.SetPresenceOptOut(args.Source.UserID(), true)
+		if err != nil {
+			return marvin.CmdError(args, err, "Could not save your choice")
+		}
+		return marvin.CmdSuccess(args, "Your activity is no longer recorded, and the records were deleted.").WithNoUndo()
+	case "optin", "opt-in":
+		err := t.SetPresenceOptOut(args.Source.UserID(), false)
+		if err != nil {
+			return marvin.CmdError(args, err, "Could not save your choice")
+		}
+		return marvin.CmdSuccess(args, "Your activity will be recorded again.").WithNoUndo()
+	}
+
+	user := t.ResolveUserName(args.Arguments[0])
+	if user == "" {
+		return marvin.CmdFailuref(args, "No such user '%s'", args.Arguments[0]).WithSimpleUndo()
+	}
+	seen, err := t.LastSeen(user)
+	if err != nil {
+		return marvin.CmdError(args, err, "Could not look up that user")
+	}
+	if seen.OptedOut {
+		return marvin.CmdFailuref(args, "%s has asked not to be tracked.", t.UserName(user)).WithSimpleUndo()
+	}
+
+	msg := fmt.Sprintf("%s is %s.", t.UserName(user), presenceText(t.UserPresence(user)))
+	if !seen.LastActive.IsZero() {
+		msg += fmt.Sprintf(" Last active %s.", formatTime(seen.LastActive))
+	}
+	if !seen.LastMessage.IsZero() {
+		msg += fmt.Sprintf(" Last spoke %s", formatTime(seen.LastMessage))
+		if mod.canShowChannel(args, seen.Channel) {
+			msg += " in " + t.FormatChannel(seen.Channel)
+		}
+		msg += "."
+	}
+	if seen.LastActive.IsZero() && seen.LastMessage.IsZero() {
+		msg += " I haven't seen them do anything yet."
+	}
+	return marvin.CmdSuccess(args, msg)
+}
+
+// canShowChannel returns whether the channel of the last message can be
+// shown to the person asking. Public channels and the current channel are
+// named. Other conversations are only named in a DM with someone who is in
+// them.
+func (mod *SeenModule) canShowChannel(args *marvin.CommandArguments, channel slack.ChannelID) bool {
+	if channel == "" {
+		return false
+	}
+	if channel == args.Source.ChannelID() {
+		return true
+	}
+	conv, err := mod.team.ConversationInfo(channel)
+	if err != nil {
+		return false
+	}
+	if conv.IsPublicChannel() {
+		return true
+	}
+	if conv.IsIM {
+		return false
+	}
+	current, err := mod.team.ConversationInfo(args.Source.ChannelID())
+	if err != nil || !current.IsIM {
+		return false
+	}
+	return mod.team.UserInChannels(args.Source.UserID(), channel)[channel]
+}
+
+func presenceText(presence string) string {
+	switch presence {
+	case marvin.PresenceActive:
+		return "active now"
+	case marvin.PresenceAway:
+		return "away"
+	default:
+		return "in an unknown state"
+	}
+}
+
+func formatTime(t time.Time) string {
+	ago := time.Since(t)
+	if ago < time.Minute {
+		return "just now"
+	}
+	return fmt.Sprintf("<!date^%d^{date_short_pretty} at {time}|%s> (%s)",
+		t.Unix(), t.UTC().Format(time.RFC1123), formatAgo(ago))
+}
+
+const day = 24 * time.Hour
+
+// formatAgo describes a duration in its largest unit, rounded down, like
+// "3 hours ago".
+func formatAgo(d time.Duration) string {
+	var n int64
+	var unit string
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		n, unit = int64(d/time.Minute), "minute"
+	case d < day:
+		n, unit = int64(d/time.Hour), "hour"
+	case d < 60*day:
+		n, unit = int64(d/day), "day"
+	case d < 2*365*day:
+		n, unit = int64(d/(30*day)), "month"
+	default:
+		n, unit = int64(d/(365*day)), "year"
+	}
+	if n != 1 {
+		unit += "s"
+	}
+	return fmt.Sprintf("%d %s ago", n, unit)
+}
@@ -0,0 +1,35 @@
+package seen
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFormatAgo(t *testing.T) {
+	tests := []struct {
+		d      time.Duration
+		expect string
+	}{
+		{0, "just now"},
+		{59 * time.Second, "just now"},
+		{time.Minute, "1 minute ago"},
+		{90 * time.Second, "1 minute ago"},
+		{59*time.Minute + 59*time.Second, "59 minutes ago"},
+		{time.Hour, "1 hour ago"},
+		{3*time.Hour + 25*time.Minute + 12500*time.Millisecond, "3 hours ago"},
+		{23 * time.Hour, "23 hours ago"},
+		{24 * time.Hour, "1 day ago"},
+		{50 * time.Hour, "2 days ago"},
+		{59 * 24 * time.Hour, "59 days ago"},
+		{60 * 24 * time.Hour, "2 months ago"},
+		{400 * 24 * time.Hour, "13 months ago"},
+		{2 * 365 * 24 * time.Hour, "2 years ago"},
+		{1000 * 24 * time.Hour, "2 years ago"},
+	}
+	for _, v := range tests {
+		got := formatAgo(v.d)
+		if got != v.expect {
+			t.Errorf("%v: expected %q, got %q", v.d, v.expect, got)
+		}
+	}
+}
@@ -144,8 +144,7 @@ func (mod *TimedPinModule) CommandTimedPin(t marvin.Team, args *marvin.CommandAr
 
 	// Pin the thing
 	if thingArg != "last" {
-		form := pinForm(channelID, thingID)
-		err = t.SlackAPIPostJSON("pins.add", form, nil)
+		err = pinThing(t, channelID, thingID, true)
 		if slErr, ok := errors.Cause(err).(slack.APIResponse); ok {
 			// Check if it's already pinned
 			if slErr.SlackError != "already_pinned" {
@@ -192,14 +191,24 @@ func mostRecentPin(t marvin.Team, channel slack.ChannelID) (thingID string, err
 	}
 }
 
-func pinForm(channel slack.ChannelID, thingID string) url.Values {
+// pinThing pins or unpins a message, file, or file comment.
+func pinThing(t marvin.Team, channel slack.ChannelID, thingID string, pin bool) error {
+	if !strings.HasPrefix(thingID, "F") {
+		msgID := slack.MsgID(channel, slack.MessageTS(thingID))
+		if pin {
+			return t.PinMessage(msgID)
+		}
+		return t.UnpinMessage(msgID)
+	}
+
 	form := url.Values{"channel": []string{string(channel)}}
 	if strings.HasPrefix(thingID, "Fc") {
 		form.Set("file_comment", thingID)
-	} else if strings.HasPrefix(thingID, "F") {
-		form.Set("file", thingID)
 	} else {
-		form.Set("timestamp", thingID)
+		form.Set("file", thingID)
+	}
+	if pin {
+		return t.SlackAPIPostJSON("pins.add", form, nil)
 	}
-	return form
+	return t.SlackAPIPostJSON("pins.remove", form, nil)
 }
@@ -77,15 +77,15 @@ func doUnpins(t marvin.Team) error {
 	defer deleteStmt.Close()
 
 	for _, v := range list {
-		form := pinForm(slack.ChannelID(v.Channel), v.ThingID)
-		err = t.SlackAPIPostJSON("pins.remove", form, nil)
+		err = pinThing(t, slack.ChannelID(v.Channel), v.ThingID, false)
 		if slErr, ok := errors.Cause(err).(slack.APIResponse); ok &&
 			slErr.SlackError == "not_pinned" {
 			// OK, delete from database
 		} else if err != nil {
 			util.LogError(errors.Wrap(err, "Failed to unpin"))
-			t.SendMessage(t.TeamConfig().LogChannel, fmt.Sprintf(
-				"<!channel> failed to unpin %s %s", v.Channel, v.ThingID))
+			t.QueueMessage(t.TeamConfig().LogChannel, slack.OutgoingSlackMessage{
+				Text: fmt.Sprintf("<!channel> failed to unpin %s %s", v.Channel, v.ThingID),
+			})
 
 			// Delete from database so it doesn't spam the log channel
 		}
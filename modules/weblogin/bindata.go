@@ -2,8 +2,10 @@
 // sources:
 // layout.html
 // assets/styles.css
+// templates/command-log.html
 // templates/factoid-info.html
 // templates/factoid-list.html
+// templates/help.html
 // templates/home.html
 // templates/invite-box.html
 // templates/invite-list.html
@@ -120,7 +122,27 @@ func assetsStylesCss() (*asset, error) {
 	return a, nil
 }
 
-var _templatesFactoidInfoHtml = "\x1f\x8b\x08\x00\x00\x09\x6e\x88\x00\xff\xbc\x56\xd1\x8f\xdb\xa8\x13\x7e\xcf\x5f\xc1\xcf\xbf\xd5\xc5\x91\x1a\x5b\xed\xe3\xae\x63\xa9\x4d\x5b\xdd\x4a\x7b\xa7\xaa\xdb\xea\x1e\x2b\x02\xe3\x18\x2d\x01\x0b\x70\xd2\x08\xf1\xbf\x9f\xb0\xb1\x43\xbc\xc9\xae\xae\x3a\x9d\x5f\x8c\xe1\x9b\x61\xe6\x9b\x8f\xc1\xd6\x52\xa8\x98\x00\x94\x68\x73\xe4\xa0\x13\xe7\x66\x45\x37\x2c\x67\xb3\x22\x0f\x23\x6b\x41\x50\xe7\x66\x27\x34\x91\xc2\x80\x30\x1d\x9c\xb2\x3d\x22\x1c\x6b\xbd\xea\xa6\x31\x13\xa0\x92\xf2\x6c\xbe\xc1\x5b\x58\xd6\x80\x69\xb7\x82\x10\x42\x45\xfd\xb6\xfc\x8c\x89\x91\x8c\xde\xa2\x82\x48\x0a\xa5\xb5\x59\x98\x19\xde\x7f\xe2\x1d\x38\x57\xe4\xdd\x32\xb2\x96\x55\x68\x84\xdc\xeb\x07\x49\x9e\x80\x3a\x57\xb0\x61\x9b\x0a\xa3\x0a\x2f\xb9\x24\x4f\x49\x59\xe4\xac\x0c\x71\x17\x79\xfd\xb6\x9c\x15\x39\x65\xfb\x72\xd6\xed\x6e\xed\x81\x99\x7a\x74\xe6\x5c\x1f\x53\x53\x3e\x60\x6d\x10\x50\x66\x6e\x91\xb5\xad\x06\xf5\x83\x33\xf1\x84\x6e\x50\xe6\x57\xbe\x6b\x50\x01\xeb\x1f\x26\x90\xb5\xa4\xc6\x42\x00\x3f\xc3\xad\xfb\xb9\x08\x6a\xad\x02\x6e\xd8\x0e\x7a\xc0\x37\xb6\x03\x6d\xf0\xae\x71\x0e\x2d\x51\x81\x51\xad\xa0\x5a\x25\xd6\x62\x45\x6a\xb6\x87\x1f\xfe\x7b\xe2\xad\xff\xf8\x03\xb4\xc6\x5b\x70\x2e\x29\x1f\x39\x26\x4f\xe8\x7d\x6f\x51\xe4\xb8\x0c\xa9\xb1\x0a\xdd\x64\x0f\xf8\x28\x5b\x93\xad\x5b\xa5\x40\x84\xc0\x0b\xdd\x60\x81\x18\x5d\x25\x3e\xc3\x65\xd5\x27\x9f\x0c\xec\x6d\x8c\x40\x1b\x23\x96\x14\x2a\xdc\x72\x93\xa0\xae\xfa\xab\xa4\xe2\x12\x9b\x5b\xc5\xb6\xb5\x49\xca\x29\xd9\x0d\x08\xc2\x78\x4f\x37\xfa\x44\x99\x29\x72\xbf\xcb\x89\xfa\x26\xd4\xdb\xab\x21\x38\x24\x1c\xb0\xba\xdd\x48\x53\x7b\xbb\x53\x55\x8a\x46\x41\x17\x5e\xa3\xd8\x0e\xab\xe3\x52\xcb\x56\x11\x18\x03\x0c\x9f\xe5\x28\x97\xaf\xf8\xf0\xd8\xcd\x8d\x22\x29\xf2\x46\xc1\x40\x44\x2f\xda\x20\xb7\x77\xe5\xef\x4c\x1b\xa9\x8e\x45\x5e\xbf\xf3\xe2\x8e\xe4\x59\xf7\x2b\xcb\x58\xbe\xd6\x2a\x2c\xb6\x80\xb2\x60\x36\x68\x24\x32\x0b\x04\x06\x61\xde\xeb\xcf\x52\x6d\xa5\x31\x20\x9c\xab\x86\x61\x88\x22\xa8\x7e\xea\x61\x07\x06\x47\x4b\x41\x84\x5f\x61\xcf\x34\x93\xc2\x6b\x30\xfb\xb8\xb9\xff\xe8\xdc\xa5\x3d\xfa\x72\x0e\x9e\x5a\x03\x34\x29\x53\x0a\x1c\x0c\xd0\xc5\x79\x19\xc6\x1d\x36\xc7\xff\x4c\xd7\xbf\x09\x8a\x75\x7d\xf7\xaf\xa9\x7b\x94\x52\xc7\x52\x2f\x9b\x13\x69\x0a\x7e\x4d\x25\xc1\xd1\xc0\x53\xf8\x1c\x5e\x9a\x28\xd6\x98\x1e\xb9\xc7\x0a\x31\xed\x25\xce\xc4\x16\xad\x50\x85\xb9\x86\xbb\x68\xe9\x11\xef\x2f\xae\x18\xf8\x69\xb0\x02\x8c\x56\x48\xb4\x9c\xdf\xcd\x6e\x52\x2a\x49\xbb\x03\x61\xb2\x2d\x98\x4f\x1c\xfc\xf0\xc3\xf1\x9e\xa6\xe7\x07\x73\xb1\xc8\x08\x67\xe4\x29\xad\x5a\x41\x0c\x93\x22\x5d\x20\x3b\xba\xbd\xf1\xd8\x0f\xad\x31\x52\xa0\x15\xba\x49\xe7\xff\x8f\x8d\xe7\x8b\x3e\x00\x56\xa1\xf4\x7f\x63\xd8\x83\xfd\xe8\xa3\x27\x2b\xd8\x9f\x1f\xbb\xc1\xc3\x80\x25\xad\xfa\x06\x3f\x8d\xc7\xf6\x80\xcc\x27\x96\x4e\x50\x02\x0e\xdf\x4e\xf9\x8e\x79\x12\x05\xd8\x40\x48\x35\x9d\x0f\x94\xc4\x7b\x44\x96\xd9\x1e\xf3\xd6\x47\x15\xf6\xbc\x0c\xd2\x60\xde\x1b\xa3\xd8\xa6\x35\x90\xce\xc3\x8d\xe4\x39\xc0\x1b\x0e\xf3\x37\x68\x7e\xcd\xfb\xb9\x21\xa3\x1e\x7b\x3d\xf7\xd8\xb2\x93\xd8\x03\xd3\x26\xc3\x94\xa6\xf3\xc0\xf5\xd2\x6f\x1a\x9b\xc4\x15\x3f\x59\x9f\xd6\x07\x02\x15\x34\x1c\x13\xf8\x8b\x99\x3a\x8d\x80\x91\xa7\xa8\xc8\x99\x82\x9d\xdc\xc3\xda\x87\x90\xce\xa3\x46\x3d\xbf\x82\xc7\x94\x46\xe0\x90\xe0\x35\x70\x6d\x76\x3c\x9d\x4f\xbb\x3b\xa9\x61\xb8\x4b\xd1\x23\xde\x9f\xf1\x12\x9f\x05\xa3\xda\x20\x78\x87\x80\x6b\x88\x64\xe6\x05\x38\x9c\x8d\x05\x52\x60\x5a\x25\xee\x66\xcf\xa8\x9a\x14\x85\x32\xed\xab\x48\xa7\x65\x7c\x91\x8f\x57\x52\x3c\xe7\xe3\x15\xf2\x2e\xf3\xa1\x1b\x26\x86\x77\x77\x4d\x0c\xcc\x30\xb1\xcd\xb2\xec\x9c\x9e\xb1\x1f\x9c\xd8\xf1\x4f\x23\xb5\xf9\x88\x0d\x4e\xe7\x79\xd0\x8f\xce\xad\xcd\x1e\x89\x6c\x20\x34\xc3\xef\x5f\x1f\x9c\xcb\xaf\xfd\x0f\xe5\x9d\xdc\xde\x20\xab\xf0\xe1\xf6\x44\x60\x77\x66\xdc\x22\x33\x35\x88\x53\xc7\x50\xa0\x9b\xf8\xd4\x4f\x42\x8b\x5a\xd5\xaf\xf4\x85\xe8\xd4\x7f\x51\xf0\xc2\x81\x6f\xd4\x35\xbb\xb5\xa4\x2f\x19\xfa\x7e\x3d\xb5\xec\x77\xcb\x70\xd3\x80\xa0\xeb\x9a\x71\x9a\x06\x47\xcf\x81\x7e\xb6\x6b\x54\xeb\xbe\x3f\xf8\x72\x9c\x31\x76\xd1\xf5\x3f\xeb\x10\x91\xe1\xa4\x41\x5c\xc6\x5e\x39\xfe\x5f\xd4\x34\xfe\xd7\x05\xf9\xec\xf7\x6b\xba\xd7\x95\x1b\x6b\x78\x0e\x4c\x50\x79\xc8\xb8\x24\xd8\xcb\x25\x53\xc0\x25\xa6\x71\x53\x77\x61\xec\x66\x7e\x54\xe4\xc3\x95\x18\x6e\xcc\xbf\x03\x00\x00\xff\xff\x5f\x5b\x1b\x03\x41\x0c\x00\x00"
+var _templatesCommandLogHtml = "\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\x03\xad\x56\x6d\x6f\xe3\x36\x0c\xfe\x9e\x5f\x41\x78\x03\xee\x93\x6d\x6c\x2b\xf6\xd2\x73\x0c\x14\x5d\x37\x1c\xd6\xf5\x86\xeb\x75\xc3\x3e\x0d\x8a\xc5\x24\x42\x65\x29\x95\xe4\x74\x81\xe1\xff\x3e\x52\x7e\x49\xd2\xa6\xbb\xc3\xa1\x05\xea\xe8\x85\x7a\xf8\x90\x22\x29\xb6\xad\xc4\xa5\x32\x08\x89\x0f\x3b\x8d\x3e\xe9\xba\x59\x11\x87\xe5\x2c\x88\x85\xc6\xac\xb2\x75\x2d\x8c\x4c\xb5\x5d\x41\x90\x99\x70\x2b\x0f\xed\x0c\xe8\x6f\x69\x4d\x48\x97\xa2\x56\x7a\x77\x0e\xb5\x35\xd6\x6f\x44\x85\x6f\xe3\xde\xa3\x75\x32\x5d\x38\x14\xf7\xe7\x10\x7f\x52\xa1\xf5\xdb\x59\x77\x0a\xd4\x65\x0e\x7d\xa3\x43\x8a\xce\x59\xc7\x4a\x2a\x2b\x71\x50\x52\x59\x6d\xdd\x39\x7c\x25\x7e\x3a\x3b\x3b\xfb\xf6\x53\x08\x4b\xa1\x74\xe3\xf0\x05\x8c\x1f\xc5\xf7\xf2\xbb\x05\x63\x14\xf9\x60\x63\xdb\xa2\x91\x64\x73\x3b\xf9\xa1\x22\xab\xd0\x84\xe8\x08\xa9\xb6\x50\x69\xe1\xfd\x3c\x2e\x0b\x12\x70\x49\x79\xb4\xbe\x11\x2b\x4c\xd7\x28\x64\xdc\x61\x6d\xc5\xfa\x9b\xf2\xb2\x67\x07\xd7\x76\x55\xe4\x34\x2f\x7c\x4d\xf6\x97\x7f\xad\x2d\x08\x7f\x8f\x12\x7e\x17\x6e\xab\x0c\x04\x0b\xd2\xc2\xe3\x5a\x04\x62\x14\x45\x88\x1a\xa1\x33\x31\x50\x4b\xc8\x6e\x6c\x20\x8c\x15\xca\x77\x06\x9e\x30\x5a\xf8\xb4\xa2\x13\xb6\x09\xb0\x1f\xa6\xca\x2c\xed\x48\x64\x53\xfe\x6d\x1b\x30\x48\xfa\x48\x51\x21\x60\xed\x70\x39\x7f\xd3\xb6\xd9\xb5\xd8\x91\x70\x76\x1b\x84\x0b\xb7\x5a\x54\xf7\x77\x1f\xae\xbb\xee\x4d\xe9\xd5\xca\x00\xf1\x7a\x54\x61\x0d\x71\xa3\xc8\x45\xc9\xa7\xb7\x0a\x1f\x21\xac\x11\x06\xc7\x03\x39\x3e\x2b\xf2\xcd\x21\x61\xd4\x1e\x21\x3a\x93\xb9\x1b\x1b\x20\x7b\xe7\x2f\x64\xad\xbe\x88\xfb\x7b\xa3\x77\x40\x34\x1d\xd8\x47\x33\xaa\xf5\x20\xe8\x7a\xfd\x9a\x96\x32\x88\xd0\x1e\x2a\x61\xc0\x23\xee\x45\x96\xce\xd6\x80\x5b\x74\x3b\x6b\xf0\x19\x49\xe2\xce\x6c\x96\xd6\xd5\x23\x1d\x1e\x93\x76\x4d\xf7\x9b\x40\x8d\x61\x6d\xe5\x3c\xf9\xf5\xea\x63\x02\xa2\x0a\xca\x9a\x79\x92\x8f\xe0\x23\xbf\x03\x63\xe2\xe9\x95\xb3\xcd\x66\xd8\x8c\x02\x5a\x2c\x50\x53\x8e\x38\x12\x50\x3a\xa0\x4b\x1b\xcf\x31\x72\x47\xdf\x22\x8f\xbb\x07\xd2\xca\x6c\xc8\x17\x61\xb7\xc1\x79\x12\xf0\xdf\x90\x1c\x81\x73\xf0\x39\xab\x13\x50\xf2\x18\x0d\x8c\xa8\xe9\x44\x3f\xde\x0a\xdd\xd0\x84\xae\xf7\x97\x28\xc2\x9a\xba\x2e\x81\xb6\x7d\x72\x1b\x5d\x27\x95\xe7\x2c\x92\x43\xf8\x0f\x36\xf5\x3e\xfa\x52\xf3\xaa\xb5\x30\x06\x75\x52\x5e\xf6\x83\xd7\x30\x72\xc4\x1c\xec\x9c\xa6\x4f\x4d\x1d\x54\x92\xb5\xaf\x63\x0a\xd5\x8e\xa4\xfc\x10\x6b\xca\x73\x33\x3c\x6a\xac\xc2\xa7\xb9\x33\xc8\x48\x3c\x02\x4e\x10\x11\xc6\x6e\x38\xb4\x46\x53\x92\x52\x98\x5d\x91\xf7\x8b\xc7\x92\x6d\x9b\x82\x13\x66\x85\x90\x5d\x12\x8e\xe7\xf0\xfd\x1f\x24\x72\xca\x9f\x3c\x22\x67\xc4\x9b\xcf\x6e\x23\x5f\xa4\x7b\x06\x3f\x0c\xc7\x7b\x27\xd9\x1b\x22\xd8\x75\x2f\x6b\x1e\xf2\x65\x52\x96\xf7\x18\xaf\xe2\xe7\x87\xa4\xbc\x70\xab\xa6\xa6\x7a\xeb\x5f\x23\x5c\x1e\x46\x7f\x3f\x3c\x0f\x91\x5b\x14\xae\x5a\x9f\x8c\x90\x45\x13\x02\xf9\xaf\xd7\xe4\x9b\x45\xad\xf6\xba\x16\xc1\x00\xfd\xa7\xf4\x3c\x08\x8a\x86\xa4\xec\xd1\x8a\xbc\x3f\xc4\x95\x85\xe9\x94\xb3\x59\x11\x1f\xa6\xf1\x5c\x3f\x89\x5f\xa6\x2a\xd1\x78\x2a\xc3\x07\xaf\x16\xbf\x23\x81\x1f\x8e\x81\x44\x70\x25\xcd\xe9\x89\x40\x53\xe4\x34\xe0\x49\x5f\x2b\x86\xc9\x94\x56\xe3\xbc\xc7\x9a\xe6\x63\xb8\x0e\xd3\x8f\xaa\xc6\x69\xd2\x0f\x72\xd2\x31\xe3\x61\xd4\x5a\x84\x85\x95\x3b\xae\x89\x53\x78\x5d\x91\x4b\xd5\x3e\xc0\x88\xd3\x68\xce\xf0\xbe\x92\x37\x39\x04\xfb\x98\x39\xbc\xdb\x20\x29\x96\x1c\xea\x40\x5a\x29\x4c\xe9\xc5\x8f\x01\x47\xca\xe4\x53\x29\x2e\x56\xff\x50\xa9\xbd\x87\xaf\x21\xeb\x6b\xd4\x29\x31\x8e\xdc\x77\x26\xbe\x41\x5d\xd7\xb6\x43\xea\x4f\x07\xa7\x8c\xa7\x50\xa6\x67\x87\x7f\x8f\xd6\xcc\x29\xed\xa3\x35\xdc\xc4\x24\x1c\xfc\x14\x7d\xb7\x64\xb2\x59\x3d\x49\x29\xf6\x09\x17\x4d\xb2\xe5\x37\x45\x09\x90\xb8\xc6\x50\x47\x40\xb9\xbf\xa1\xc7\x66\x40\xe9\xe3\x39\x7e\x87\xa7\x8b\x10\x59\x9c\x35\xb3\x60\x39\x76\x17\x27\xa0\xb3\x2b\xa9\xfa\x9c\x7c\x11\x73\x0a\x39\x8c\xa2\x9f\x81\x79\x67\x24\xbd\x78\x9f\x85\xd9\x44\xd1\x63\xcc\x17\xfd\xd5\x97\xaf\xa3\xcb\xef\x2f\xe8\x8a\x3b\x36\x3a\xb8\x70\x63\x8b\x43\x42\xe3\x62\x3e\xae\x9c\x06\x67\xd1\x9f\x1b\x27\xb8\xec\xbc\x1c\x02\x17\x94\xb4\x6a\x8b\xd7\x74\xed\x24\x34\xb4\x30\x49\xbc\xba\x83\x8d\xa4\x2c\xd4\x54\x1d\x04\x2c\x45\x4a\xf5\x02\x9d\x11\x3a\xe5\x80\xa1\xed\x5c\x95\xdc\xca\x3c\x23\xd3\x27\x45\x2c\x74\x43\xf7\xb2\xcf\x46\xce\x57\xcd\x0e\x9a\x27\x3f\x10\x04\xd6\xe5\x8d\x3d\xe8\x32\x2c\xf9\x90\xba\x0b\x5a\x8e\x70\x07\x48\x43\x8b\x91\x0f\x19\x46\x03\xae\x03\xfb\xa6\x8e\xc8\xfd\x41\x7d\x23\x09\x51\x9f\x73\x68\xd4\xb8\xc3\x16\xbd\xd7\xd4\x53\x4e\xea\x98\x3c\x77\x32\xfb\x0e\xe6\xa0\x97\x19\x9b\x9b\x3e\x32\xfe\x03\x98\x3e\x10\x8b\xd4\x0b\x00\x00"
+
+func templatesCommandLogHtmlBytes() ([]byte, error) {
+	return bindataRead(
+		_templatesCommandLogHtml,
+		"templates/command-log.html",
+	)
+}
+
+func templatesCommandLogHtml() (*asset, error) {
+	bytes, err := templatesCommandLogHtmlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "templates/command-log.html", size: 3028, mode: os.FileMode(420), modTime: time.Unix(1792336629, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _templatesFactoidInfoHtml = "\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\x03\xbd\x57\x6d\x6f\xdb\x36\x10\xfe\xee\x5f\xc1\xaa\xc1\x24\xa3\xb5\xbc\x15\xfb\xe4\xc8\x2a\x5a\xa7\xdd\x02\x64\xc3\x90\xa4\xd8\xc7\x82\x16\xa9\x88\x8b\x2c\x6a\x24\x95\xc4\x10\xf4\xdf\x77\x7c\xd1\x6b\xec\x24\xed\x86\x05\x48\x42\x89\xf7\xc2\x7b\xee\xb9\xe3\xa9\xae\x09\x4d\x59\x41\x91\x27\xd5\x3e\xa7\xd2\x6b\x9a\x59\x64\x96\xf1\x2c\x24\x2c\x4d\x51\x3d\x43\xf0\x53\x62\x42\x58\x71\xb3\x42\x3f\x9e\xce\x1a\xb7\x23\x4b\x5c\xb8\x6d\xc2\x64\x99\xe3\xfd\x0a\x6d\x73\x9e\xdc\x9e\x4e\x54\xd0\xcf\xe5\x43\xaf\x66\xfe\x2e\x58\x21\xa9\x50\x4e\x7d\x8b\x93\xdb\x1b\xc1\xab\x82\x2c\x12\x9e\x73\xb1\x42\xaf\x49\x4a\xa6\x2a\x84\xe6\x54\xd1\xe3\x2a\x29\xb1\x2a\x19\x93\x8a\x8b\x3d\xbc\x2f\x14\x86\xd8\x04\x0a\x77\x54\x61\x14\xe2\x44\x31\x5e\x48\x67\x21\xcd\x39\x56\x2b\x24\xd8\x4d\xa6\xb4\x5e\xb4\x74\x71\xd7\x35\x2d\x08\xe0\x50\x77\xd8\x68\x4b\xb4\x50\x06\x1c\xc2\xee\x50\x92\x63\x29\xd7\x5e\xe7\xc0\x8b\x47\xef\x4b\x7c\x43\x17\x19\xc5\xc4\xec\x68\x5f\x51\xf6\x53\xfc\x19\xdc\x73\x46\x56\x28\x4a\x38\xa1\x71\x5d\x87\xee\x4d\xfb\xff\x77\xbc\xa3\x4d\x13\x2d\xcd\x36\xaa\x6b\x06\x81\xb7\x22\xe7\xf2\x02\x80\xa5\x70\xac\x88\xb5\x6e\x52\x8c\x52\xbc\xd0\x80\x7b\x71\xb4\x64\xb1\x3b\x77\xb4\x04\x67\x10\x0d\x1c\x28\x9e\x19\xef\x75\x7d\xcf\x54\xd6\x19\x83\x28\xcc\x99\xca\xf8\x02\x4b\x85\x28\x61\x00\x43\x5d\x57\x90\x90\xaf\x39\x2b\x6e\xd1\x09\x0a\xf5\xce\x17\x78\xe1\x64\xf5\x0f\x83\x64\xd7\x49\x86\x8b\x82\xe6\x23\xb9\x8d\x7d\x37\x10\xad\x6b\x41\x73\xc5\x76\xd4\x0a\x5c\xc3\x4a\x2a\xbc\x2b\x9b\x06\x2d\x50\x84\x51\x26\x68\xba\xf6\xea\x1a\x8b\x24\x63\x77\xf4\xab\x7e\x9e\x58\xb3\x0f\xbf\x51\x29\x01\xcc\xa6\xf1\xe2\xab\x1c\x32\x8e\x3e\x58\x8d\x68\x89\x63\x17\x1a\xa0\x74\x02\xb2\x7b\x5e\xa9\x70\x53\x09\x01\x79\xb2\x07\x8f\x0c\x3f\x19\x59\x7b\x3a\xc2\x45\x6a\x83\xf7\x5a\xf4\xb6\xaa\x40\xf0\x0b\xac\x4a\x71\x95\x2b\x0f\x99\xec\x03\xaa\x86\x16\x86\x15\x00\xeb\x04\xec\x92\x16\x09\xcb\x2d\xdc\xe8\x13\x98\x05\xd2\x80\x97\x1e\xfa\xd2\xe5\x5b\xb3\xc1\x19\x4c\x72\x8a\xc5\x6a\xcb\x55\xa6\xf5\xfa\xac\x44\xa5\xa0\xe6\x78\xa5\x60\x3b\x0c\x7c\x95\xbc\x12\x09\xed\x0e\xe8\x1e\xe3\x8e\x2e\x97\xf8\xfe\xca\xbc\xeb\x48\x02\xfe\x04\x6d\x81\xb0\xa4\xed\x51\x09\xcf\xa0\x68\x3e\x0b\xbe\x6b\xf3\x9d\xbd\x33\xee\x74\x2d\x79\xb1\x86\xf9\x86\x4a\x94\x82\x00\x12\xf4\x8e\x49\x28\x0d\x50\xec\xb4\xc2\xb3\xed\xf9\x19\x24\x4c\x71\x6b\x8e\xfe\x6d\x2d\x5e\x73\xb3\xd3\x53\xd3\xca\x25\x16\x7a\x38\x46\x2e\xe1\x80\x53\x8b\x4e\xab\x69\x7a\x92\xbe\x8b\x7b\x18\x46\x21\x23\x7b\xc2\x36\x6e\xa1\x0f\x6a\x7d\xb7\x49\x75\xe2\x60\x7b\xa3\x57\x9a\x1e\xb0\xfe\x03\x58\xc4\x1e\xe0\xc8\xb0\xbe\xa6\x0f\x4a\x7b\x19\x67\xe7\x49\xd0\x00\x9f\xf8\x57\xdb\x3a\xec\xe9\x66\x51\xca\xc5\x0e\x41\xef\xc8\x38\xe0\xf6\xcb\xa7\x6b\x0f\xd9\x16\xb2\xf6\x96\x8e\x4e\x72\x09\xce\xae\x12\x5e\x52\xc7\xdb\x2f\x97\x17\x4d\xb3\x3c\x56\xdc\xaf\x6d\x68\x33\xa8\xbd\x68\x5b\x29\x05\x00\xa9\x7d\x09\x24\x91\xd5\x76\xc7\xd4\x51\x72\x3e\x22\x22\x7d\x48\x4c\x02\x1d\x15\x37\x7c\x57\x62\xc0\x51\x42\x8b\x4c\x14\x25\x5d\x46\x65\xb4\xb4\x7e\x62\xc3\xcd\x61\x97\x7a\xd4\x26\x3d\xdd\xfa\x1c\xda\x0e\x88\x96\x3a\x03\x35\x17\xb8\xe3\xd8\xb9\xfc\xcc\xc5\x0d\x57\xd0\x1e\x9b\x26\x6d\x97\x0e\x57\xd7\xfc\xa6\x16\x74\x33\x1e\x6c\xb9\x5e\xd4\x8b\x0e\x53\xec\x5a\xf6\x54\x3c\xc7\x5b\x68\x11\x8a\x29\x5d\x60\x6d\xf0\x86\xca\x0a\xc2\xea\xa2\xd7\xb8\x15\x65\xa5\x1c\xc8\x02\x13\xc6\x3d\x54\x40\x2a\x20\x0e\x90\xf6\xd0\x1d\xce\x2b\x6a\x98\x64\xe9\xe9\xc5\xc6\x4c\xb4\x34\x1e\x5e\xe2\x15\xea\xe3\xc5\x3e\x15\x3f\xe8\x51\xf1\xc3\xfe\xda\x46\xf9\x5e\x1f\x69\xdd\x69\x38\x12\x99\x9b\xd1\x34\x76\x57\x7b\x5d\x53\xec\xdb\xf0\xd1\xe6\x68\xb6\x0a\x5d\x56\xba\x96\x4f\x26\xc5\x3c\x4a\xc1\x84\x8b\x66\xfd\x60\xe2\x85\x0b\xbc\x6f\xab\x04\x2b\xbc\x68\x41\x18\xc5\x37\xe5\x2e\xdc\xd9\xdc\xf1\xf6\xd2\x18\x19\x97\x69\x5b\x94\x1d\x0c\x76\x77\x14\xd9\xa5\xf3\xb3\x42\x9d\x9f\x43\x84\x1c\xc5\xb1\xab\xa0\x30\xbc\x38\xb0\x63\x04\x99\x8f\xbd\x76\xe6\xb7\xfb\xff\xed\x2e\xfc\xa1\x20\x58\x66\xa7\xff\xd9\x8d\xd8\x5d\x3f\x16\x36\x73\xd5\xf4\x15\x36\x6d\xb3\x2f\xbd\x59\x9c\xa1\x16\x27\xf7\x18\x2d\x75\x73\xec\xe6\x8c\x48\x26\x82\x95\xca\x6a\xdc\x61\x81\x98\xd4\xd7\x23\xcc\x7f\x68\x0d\x39\x87\x3b\xe1\x74\xb0\x75\x85\xef\x0e\xee\x28\xe8\xda\x50\x53\x18\x76\x8a\x2a\xcf\x4f\x67\x27\x01\xe1\x49\xb5\x03\xda\x86\x37\x54\x7d\xca\xa9\x5e\x7e\xdc\x9f\x93\x60\x7c\xa9\xcf\xe7\x61\x92\xb3\xe4\x36\x48\xab\xc2\x74\x8c\x60\xee\xa6\x3c\x6d\xf6\x44\xcb\x7e\xb4\xed\x76\x8d\x4e\x02\xff\xf5\x50\xd9\x9f\xdb\x03\x00\x7b\x82\x57\xdd\xb1\x5b\xfd\xce\x86\xbb\x9b\xac\xfe\xf8\xca\x6e\x2d\xb4\xb2\x50\x8f\xfa\xfe\xd1\xb2\x56\x20\xd4\x81\x05\x13\xa9\x82\xde\x5f\xf7\xf1\x76\x71\x26\xf0\xac\xa8\x0b\x35\xf0\x5b\x48\x86\x3e\x06\x9a\xa1\x69\x27\xa0\xef\x7c\x1e\x16\x92\x54\x7d\x50\x4a\x30\xb8\x0a\x68\xe0\xbb\x69\x56\x63\x80\xb7\x39\xf5\xdf\x22\xff\x98\xf5\xb1\x22\x60\x05\xb2\xc7\x63\x1f\x6a\x1a\xaa\x5d\xc0\x2d\x12\xc2\x67\x40\xe0\x3b\xac\x17\xda\xe9\x50\x65\x98\xf1\x5e\xbb\xdf\x6f\x01\x14\x14\xbe\x2f\x12\xfa\x27\xb4\xbb\x60\x20\x38\xb0\x34\x48\x32\x48\xef\xf8\x1d\x35\xb3\x41\xe0\x0f\x7a\x97\x7f\x44\x1e\x8e\x38\x10\x76\x01\x1e\x13\xce\xd4\x2e\x0f\xfc\x69\x53\x4b\x32\xda\xce\xe1\x08\xe8\x3d\xc2\x65\x58\x0b\x4a\x54\x8e\xf0\x0d\xd2\xa3\xd2\x80\x66\x9a\x80\x6d\x6d\xcc\xa1\xbf\xaa\x4a\x14\xa7\xb3\x47\x50\x4d\x92\x02\x9f\x5e\x3a\x8b\x64\x9a\xc6\x27\xf1\x78\x26\xc4\x31\x1e\xcf\x80\x77\x18\x0f\x59\x42\x8f\x74\xff\xcd\x6c\xd1\x22\x03\xc1\x85\x61\x38\x86\xa7\xeb\x07\x3d\x3a\xe6\x0b\x92\x4b\x75\x06\xd7\x4a\xe0\x7f\xe7\xb8\xb5\x34\x74\x7b\x8b\x60\xaa\xb9\x5f\xf5\x00\x9a\x9a\x69\xe6\xa1\xca\x68\xd1\x77\x0c\x41\x65\x39\xac\xfa\xc9\xd1\x06\xad\xea\x7b\xfa\xc2\xa0\xea\x61\x4e\x7d\xa2\xe0\xa1\xef\x1e\xd1\xdb\x40\x63\x7e\x42\x51\xf7\xed\xa9\xa6\xf5\x16\xe2\x12\x3e\x5c\xc8\x26\x63\x39\x09\x9c\xa1\xc7\x82\xfa\xad\x69\x54\x1b\xdb\x1f\x74\x3a\x46\x88\x1d\x34\xfd\x6d\x1d\x62\xa0\x38\x69\x10\x87\x65\x8f\x94\x3f\xe8\x4f\x05\x9f\x25\xe4\xa3\x4f\xb7\xa9\xaf\x23\x37\x56\xfb\x73\xcf\x60\x6c\xb9\x0f\xe1\x73\x1b\x6b\xba\xc0\x91\xe0\x43\x91\x0c\x9b\x7a\xe3\xd6\xcd\x4c\xaf\x80\x0e\xe1\x78\x46\xf2\x9f\xbe\xa4\xb6\xfd\x05\xa5\x07\xca\x79\x7f\x2d\x76\x1f\x53\xeb\x56\x2a\x24\xa6\x2c\xda\x8d\xd1\x15\xe6\x0e\x0a\x5d\x3e\x65\x62\x17\xf8\x76\xc6\xd2\x73\x6a\x67\xc7\x47\x6f\xfa\x87\x37\xc8\x7f\xef\xcf\xfb\x86\x63\xe0\xdc\x7e\x7b\x6d\x5b\x3f\xe3\xf2\xfe\xf7\x15\x6c\x21\x34\x35\xdc\xcd\x7c\xed\xea\xd9\x12\xb6\x21\x21\xfd\x3a\xfc\x4b\x6a\xc0\x5d\x86\xa6\x8a\x5b\x4e\xf6\xf3\x49\x2b\x7e\xa5\x5f\x86\xfc\x76\xda\x13\x1c\xbe\x38\x87\x73\x05\xfe\x86\x57\x39\x41\x05\x57\x6e\x20\x5e\x19\x70\x8d\xea\xce\x8e\x6a\x53\xa6\x3e\x09\xed\xa3\xd9\x78\xca\xd2\x61\x96\x2c\xd9\x5e\x46\x50\x4d\x49\xfd\x0b\x53\xaf\x9b\xd4\xdc\x40\xf7\x0f\x12\x0f\x9a\x22\x02\x14\x00\x00"
 
 func templatesFactoidInfoHtmlBytes() ([]byte, error) {
 	return bindataRead(
@@ -135,12 +157,12 @@ func templatesFactoidInfoHtml() (*asset, error) {
 		return nil, err
 	}
 
-	info := bindataFileInfo{name: "templates/factoid-info.html", size: 3137, mode: os.FileMode(420), modTime: time.Unix(1515446988, 0)}
+	info := bindataFileInfo{name: "templates/factoid-info.html", size: 5122, mode: os.FileMode(420), modTime: time.Unix(1792339942, 0)}
 	a := &asset{bytes: bytes, info: info}
 	return a, nil
 }
 
-var _templatesFactoidListHtml = "\x1f\x8b\x08\x00\x00\x09\x6e\x88\x00\xff\x9c\x55\x4d\x8f\x9c\x46\x10\xbd\xf3\x2b\xca\x23\xdb\x49\xa4\x01\xe2\x8f\xd3\x1a\xa3\x58\x96\x22\x59\x5a\xe7\x10\xdb\x8a\x72\xf2\x16\x50\x40\x67\x9a\x6e\xd2\xd5\xcc\x2c\x42\xfc\xf7\xa8\x1b\x9a\x99\xd9\x5d\x5f\x32\xa7\xa1\xba\x3e\x5e\xbd\x7e\x55\x3d\x4d\x15\xd5\x42\x11\xec\xd8\x8e\x92\x78\x37\xcf\x51\xe6\xff\xe6\xd1\x20\x93\x1a\x4b\xab\x45\x15\x4b\xc1\x16\xa6\x08\x00\xa0\xc7\xaa\x12\xaa\xb9\x81\x5f\xdf\x45\xf3\x23\x1f\x29\x56\x37\xf7\x15\xfb\x44\x37\xa0\xb4\xa2\x77\xde\xda\xa1\x69\x84\x8a\x25\xd5\xf6\x06\x5e\xf7\xf7\x57\x56\x23\x9a\x36\x98\xe7\xe8\x51\xde\x44\x22\xdb\xb8\xd3\x95\xa8\x05\x55\x6b\x99\x5a\x6a\xb4\x37\xe0\x43\x5d\x54\x96\xae\xe0\xa7\x89\x54\x35\xcf\xd1\xb9\xc1\x52\x2b\x4b\xca\xfa\x0e\x2b\x71\x84\x52\x22\xf3\x7b\x6f\x46\xa1\xc8\xec\xf2\x2b\x7b\x8f\x0d\xc5\x2d\x61\xe5\x4f\x5c\xad\xac\x7d\x95\xff\xbe\x80\xe2\x2c\x6d\x5f\xe5\x19\x77\x28\x65\xfe\x01\x4e\x38\x82\xd5\xc0\x56\x1b\x02\x54\x15\x18\xb2\x46\xd0\x91\xc0\xd2\xbd\xcd\xd2\xc5\x2f\xca\xd2\x4a\x1c\xaf\xab\x14\x1c\x97\x28\xa5\x1e\x2c\x9c\xff\xc6\x42\xd5\x7a\x2b\xfa\x36\xff\xc6\x42\x35\x70\x51\xfa\xed\x7a\xd6\xe7\x5f\x70\x84\xac\xd4\x15\xe5\xcf\x56\xbe\x14\x76\x94\xa5\xde\x04\x42\x01\xaa\x11\xca\x16\x95\x22\x09\xa7\x96\x0c\xc1\x67\x34\x47\xa1\x40\xb0\x83\x6c\x06\x05\x08\x6b\x68\x02\x9f\x6a\xb0\x2d\x85\x6f\xb0\x78\x20\x06\x34\xcd\xd0\x91\xb2\xbc\x5f\x04\x60\xf4\x51\x54\xe4\x1c\x3b\xc0\xda\x92\xf1\x31\xae\xee\x1e\xb8\xc7\x92\x62\xa6\x1e\x0d\x5a\xaa\x12\xf8\xda\x52\x00\x18\x50\x65\xd4\xe5\xdd\xc0\x36\x4b\xa9\xcb\xa1\x20\x40\xeb\x33\x1c\xc9\x8c\x50\x50\x23\x94\x72\xfd\x6a\x8f\x65\x11\x08\x31\x63\x43\x2e\x9b\x60\x87\x1c\x25\x6b\x70\x6c\x51\x05\x08\x05\xaa\x26\x0e\x3d\x64\x69\xbf\xb1\xf3\xb7\x1e\xa0\x44\xb5\xb8\x5f\xb5\x0a\xc5\x08\x8c\xa3\xab\xb3\xa0\xfb\xad\x5b\x68\x09\xe7\x0d\x59\x78\xcc\x68\x02\x1f\x18\x0a\xaa\xb5\xa1\xfd\x46\xc4\xc6\x8f\x2f\x8b\x0c\x4a\x9b\x0e\xe5\x82\xe4\xff\xde\xf9\x5f\x46\xd8\x1f\xde\xfa\x37\xf6\xf4\x3f\x80\x6e\xa8\xa3\xae\x20\x73\x89\x1b\x56\xd5\x07\xee\x4b\xdd\x75\x4e\xa0\x4e\xad\x64\x2f\xae\xfe\x92\xb6\x4f\x35\x8c\x7a\x00\x45\x54\x5d\xc9\x61\xcd\xe5\x82\xd7\xa9\x01\x45\x27\x29\x14\xf1\x1e\x2a\x0d\xb6\x15\x7c\xb3\x74\xdd\x1b\xca\x7f\x80\x8e\xee\xb1\xeb\x25\xc1\xcb\x57\xd1\xdd\xdd\x5d\xf4\x79\x90\x56\xb8\x1c\xd1\xc7\x25\xbd\xb3\xae\x70\xb3\xd4\xe5\x79\x0a\x56\xe7\xa2\x5c\x96\x2e\x84\x43\x8f\xc6\xf2\x1e\xfe\x19\xd8\x02\x56\x4e\x17\x2f\x5f\xaf\x8a\x39\x69\x73\x60\xa8\xb5\x01\x94\x32\x70\xc0\x6e\x3c\xd6\x61\x88\xa1\x18\x2c\x28\x6d\x9d\x93\xaf\x77\xa9\x29\xde\xbb\x5b\xb5\x2d\x8d\x80\xf2\x84\x23\xc3\xb0\x5e\x00\x29\x2b\x0c\x05\x7d\x3e\x3b\x93\xd8\x9a\x07\xb0\x7f\x32\x04\x2d\x1e\xdd\x95\x5a\xa3\x87\x42\x12\x9c\x84\x6d\xa1\x6f\x0d\x32\x31\x48\x71\x20\x78\x81\xa6\xe1\x17\x7e\x83\x3c\xaf\xa5\xe8\x7f\x4e\x92\xe4\x17\x28\xc8\x45\x09\x65\xc9\xf4\x86\x2c\x55\x0e\xb8\xab\xae\x07\xdb\x0f\xd6\x0d\xca\xa8\x07\x03\xb7\x03\x02\x97\x46\xf4\x76\x19\x54\xf7\x23\x61\x5b\x32\xd0\x1b\xaa\xb1\xa4\xc5\x6f\x0d\xf3\xe5\x97\x3b\x9a\x0c\x9e\xe6\x20\x11\x6d\xfc\xc2\x68\x2e\x25\x36\xc9\x01\x37\x87\x4a\x18\x2a\xad\x70\xdb\x4d\x5f\x9c\x9b\xe0\x90\x6c\xd5\xdd\xf0\x33\x95\x5a\x55\x0e\xc1\xd1\xcd\xc8\xb9\x0f\xb4\x42\xab\x75\x98\x7c\x29\x87\xdf\x25\x00\xba\xa7\x72\xb0\x4e\x54\xa7\x56\x48\x7a\x0a\x64\x25\x18\x0b\x49\x8f\xd2\x2d\x4b\x63\x6d\x31\x81\xcb\x11\x8c\xb2\xf6\x4d\xd8\xdf\x70\x2b\xdc\xfe\x69\xdf\xe4\x51\x36\xc8\x30\x98\x97\x2f\xce\xce\x3d\x21\x60\x3c\x0f\x89\xf3\x86\x79\x8e\x00\x32\x29\xf2\x0c\xa1\x35\x54\xbf\xdf\xa5\x41\x1f\xe9\x34\x89\x1a\xe8\x5f\x48\xbe\x94\xba\xa7\x8f\xeb\xbe\xdd\xed\xe6\xf9\xfb\x34\x91\x64\x9a\xe7\x69\xba\x3a\x74\x06\xff\x42\xa5\xd3\x94\xac\xa8\xfe\xc0\x8e\xe6\x79\x5d\x02\x10\xda\x9e\xe0\xf2\x1c\xe6\xc0\x81\xaf\xa9\xe8\x89\x9a\x19\xf7\xa8\x42\x53\x82\xe3\x75\xff\xc7\x5a\xc9\x71\x97\x4f\xd3\xfa\xfd\x5d\x0a\x75\x80\xe7\xf0\x00\x57\x96\xba\xf0\x7c\x85\x97\xa5\x18\xf0\xf8\x82\xc9\x27\xbe\xd5\xe5\x81\xdc\x91\x38\x13\x07\x35\xc6\x52\x97\x87\x5d\x9e\xa5\x22\xc4\x86\x3e\x2e\xe1\x5c\x3d\xe2\xbb\xdc\x7d\xc2\xf6\xa6\x17\x23\x4c\xd3\xc0\x64\x36\x68\xb7\xc8\xf6\x1b\x93\xd9\x92\x81\x13\xff\xe3\x16\x9c\xdf\xd6\x01\x4c\x93\x21\x69\x45\x47\xcb\xc1\x57\xd1\x11\x5b\xec\xfa\xad\xb9\x80\xac\x37\x14\x80\xb1\x1e\x4c\x49\xbb\x3c\xb0\x9e\xfc\x89\xa7\x2f\xde\xb6\x31\xbe\xad\xa3\x2c\x95\xc2\xeb\x83\x54\xe5\x74\x91\xa5\xc3\xf9\x79\x5f\x9b\xff\x2f\x00\x00\xff\xff\xc4\x12\x17\xcf\x5a\x09\x00\x00"
+var _templatesFactoidListHtml = "\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\x03\xed\x56\x4b\x8f\xdb\x36\x10\xbe\xfb\x57\x4c\x84\x24\x6d\x81\xb5\xdc\x4d\x72\xda\x68\xd5\x06\x41\x1f\x01\x36\x39\x64\x37\x28\x7a\x4a\x68\x89\xb6\xd8\xa5\x44\x95\xa4\xec\x18\x82\xff\x7b\x3f\x3e\x24\xcb\xf6\x2e\xd0\xf6\xd2\x4b\x0d\x1f\x44\x72\x1e\xdf\xcc\x7c\x33\x64\xdf\x97\x7c\x25\x1a\x4e\x89\xb1\x3b\xc9\x4d\xb2\xdf\xcf\x32\xff\x99\xcf\x3a\x99\xae\x58\x61\x95\x28\xe7\x52\x18\x4b\xfd\x8c\xf0\x6b\x59\x59\x8a\x66\x7d\x45\xdf\xbf\x9e\xed\xcf\x64\xa4\x88\x62\x6e\x35\xf7\x86\xae\xa8\x51\x0d\x7f\xed\x77\x6b\xa6\xd7\xa2\x99\x4b\xbe\xb2\x57\xf4\xa2\xfd\x7a\xb4\xab\xc5\xba\x1a\xb6\xf7\xb3\x33\xbb\xa9\x64\x30\x59\xab\x52\xac\x04\x2f\xa3\x9b\x95\x54\x0c\x3a\x5e\xf5\x48\xcb\x70\xa6\x8b\x2a\x4a\x45\x07\x4b\x65\xad\xaa\xaf\xe8\x92\xd7\xe7\x1e\x52\xd3\x88\xb6\xe5\xd6\x09\xdf\x3f\x18\x6b\xb6\x88\x89\xe9\x7b\xde\x94\x48\x54\x3f\x26\xaf\x50\x8d\xe5\x8d\xf5\xd9\x2b\xc5\x86\x0a\x60\x35\xd7\x7e\x9b\x41\x40\x27\xf9\xd1\x7e\xcb\xd6\x7c\x5e\x71\x56\xfa\x13\xe7\x29\xab\x2e\xf3\x9f\x03\x1c\x93\x2d\xb0\xc8\x4c\xcd\xa4\xcc\xdf\xd0\x96\xed\xc8\x2a\x32\x56\x69\x4e\xac\x29\x49\x73\xab\x05\xdf\x70\xb2\xfc\xab\x05\x26\x2f\x07\x70\xb0\x7f\xec\x65\x69\xe6\x05\xce\x54\x67\xe9\xf0\x39\x17\xcd\x4a\x8d\x4e\x5f\xe5\x9f\x0c\x02\xa4\x89\xeb\x57\xf1\xac\xcd\x6f\xe1\x39\x2b\x54\xc9\xf3\x27\x31\x53\x0d\xab\x79\xb6\xf0\x5b\x24\x1a\xa0\xd9\x51\x51\xb1\xa6\xe1\x92\xb6\x15\x07\xbe\xf7\x4c\x6f\x70\x20\x8c\x83\xac\x3b\x88\x50\x54\x4d\xe9\xdd\x8a\x6c\xc5\x87\x35\x59\x76\xcf\x0d\xa1\x30\x5d\x8d\xcc\x99\x8b\x90\x70\xad\x36\xa2\xe4\x4e\xb0\x26\xb6\xb2\x5c\x7b\x1d\xe7\xf7\x82\x4c\xcb\x0a\x8e\xc2\xb6\x4c\x33\xcb\x61\xf1\x0e\x47\x11\xe0\x80\x2a\xe3\x75\x5e\x77\x06\x79\xc1\x07\x2d\x91\x31\xeb\x2d\x6c\xb8\xde\x61\x09\x16\x34\x2e\x5e\xe5\xb1\x04\x6e\x70\x63\x50\x0e\x67\x0d\xb0\xf1\x67\xd2\x28\x72\xd9\x02\xc9\x18\x2d\x59\xb3\x9e\x0f\x31\x64\x8b\x76\xcc\xce\xef\xaa\x83\x54\x13\xc4\x8f\x42\xa5\xe5\x8e\x0c\xdb\x39\x3f\x01\xdd\x8f\x75\x48\xcb\x70\xbe\x06\xcb\xce\x33\x9a\xd2\x1b\x03\x88\x2b\xd4\xf9\x62\x4c\xc4\x98\x1f\xef\x96\x19\xf4\x92\x46\xc1\x03\x92\x7f\x5b\xf3\xdf\xb4\xb0\x8f\x56\xfd\x93\xf1\xe9\x3f\x81\xae\x79\xcd\xeb\x25\xca\x31\xc1\x4d\x91\xf5\x43\xee\x0b\x55\xd7\x8e\xa0\x8e\xad\x88\xf0\x50\xfa\x69\xda\xc0\x82\x1d\x32\xd7\x70\x64\x77\x4a\x87\x68\xcb\x29\xc7\xae\x81\xcc\x56\xa2\x79\xcc\x05\x95\x0a\xb2\xc2\x5c\x85\xa8\x5b\xcd\xf3\x47\xd0\xf1\xaf\xac\x6e\x25\xa7\xe7\x97\xb3\x2f\x5f\xbe\xcc\xde\x77\xd2\x0a\x67\x63\xf6\x36\x98\x77\xbb\x11\x2e\x6c\xc1\xce\x43\xb0\x6a\xa7\xe5\xac\xd4\x83\x3a\x06\x81\x06\x45\xe9\x0f\x30\x8b\x30\x13\x10\xdb\xf3\x17\x91\x31\x5b\xa5\xef\x0d\xa1\x6a\x60\x82\x1c\x72\x60\x5c\x7b\xc4\x66\x98\xd3\x12\xd5\x68\x94\x75\x42\xde\xdf\x94\x53\xb0\x8a\xaa\x22\x13\x3b\xe8\xa3\xd7\x0d\x75\xb1\x00\x80\x2b\xd0\x52\x91\x9f\x4f\x0e\x49\xac\xf4\x09\xec\x6f\x20\x56\xb1\x8d\x2b\xa9\xd5\xaa\x5b\x02\xfa\x56\xd8\x8a\xda\x4a\x33\x83\x26\x93\xe2\x9e\xd3\x33\x50\xc9\x3c\xf3\x13\xe4\xe9\x4a\x8a\xf6\xdb\x34\x4d\xbf\x03\xdf\x9c\x96\x40\x72\x34\xd2\x81\xae\x72\xc0\x9d\x77\xd0\xa6\x05\x6c\xe5\x1d\x68\xba\xe9\x18\x99\x42\x8b\xd6\x86\x46\x75\x3f\x0e\x1f\xc8\x39\xf4\x10\x0b\x0f\x72\x51\xcd\xbb\x0f\x35\xea\x35\xdb\xee\x07\x8a\x20\x4b\x6e\x60\xac\xa7\x14\xeb\x65\xc7\x46\x81\x12\x21\x17\x56\xb8\xe9\xa6\x26\xe7\x7a\x10\x48\x47\xef\xae\xf9\x0d\x07\x57\x4a\x87\x60\xe3\x7a\xe4\x10\x07\xb3\x42\x35\xb1\x99\xbc\x2b\x87\xdf\x19\x00\x43\x78\xd1\x59\x47\xaa\x6d\x25\x24\x7f\x08\x64\x29\x0c\x43\x0e\xcf\xcc\x85\xa1\x11\x43\x4c\x69\xda\x82\xb3\x0c\x9e\xea\xa1\x09\xdd\x37\x7a\xce\x33\xe7\xf8\x3e\x4a\x50\x4e\x5b\xa9\xf2\x3a\xf9\xe5\xa7\xbb\x84\x70\x06\xc3\xd7\xc9\x62\x20\x83\x6f\xd2\xbe\x17\x2b\x4a\xdf\x86\xc1\xba\xdf\x67\xa2\x71\x29\xb5\xbb\x96\x5f\x27\x95\x28\x4b\xde\x24\x7e\x22\xe2\x7e\x09\x32\x09\x6d\x98\xec\xb0\xee\xfb\x83\x5a\x92\x0f\xd7\x14\x98\x32\x99\x10\x1e\xdc\x1a\x34\x69\x87\x89\x20\xd9\x12\x03\x3c\x1e\x1b\x3d\x57\x8d\xdc\x25\x8e\xac\x10\x3e\x46\x9f\xdf\x86\x5b\x75\x35\x0e\x0e\xaf\x1b\xed\x4c\x71\x0e\xe1\x4e\x9d\xba\xbe\xd6\x0a\x68\x45\x79\x66\x39\x06\x34\xac\x0e\xf1\x04\x8f\x08\x87\x5a\x09\x96\x55\x4a\xe2\xd2\xbc\x4e\x4e\x80\xf8\x50\x62\x2d\xf0\x81\x86\xb3\x28\x58\x84\xd2\x2d\x6b\x61\x47\x28\x4b\x0b\x62\xd8\x66\x8e\xab\x9b\xa1\xbf\x93\x3c\x13\x23\x4a\x37\xb2\xc6\x58\xb3\x85\xc8\x29\xf8\xc9\x16\xc1\xe2\xa1\x3a\x03\xac\x8c\x9d\xda\x45\xd5\xef\x13\xaa\xd0\x14\x93\xba\x9e\x94\xf4\x87\x58\xb8\xeb\x69\xc1\x62\xb9\x90\xe4\x4a\x6d\xdd\x30\xc9\x16\x6c\xac\x61\xb6\x70\x29\x04\xd1\x8e\xdd\xcf\xb2\xea\xe5\x50\x94\x8f\xdc\x20\x1e\x37\xcc\x5f\x82\x97\x9d\x3c\x44\x75\x78\xe5\x24\xee\xf1\x42\xda\x77\x60\x1a\x15\x28\x50\x44\x8a\x1c\xc1\x9c\xe0\x5e\x78\x77\xfc\x4f\x78\x2c\x54\xcb\x23\x56\x4a\xf0\xd0\xf9\x0c\x68\xd2\x70\x87\xfb\xe8\x70\x0c\x04\xba\x69\xbc\x61\x3e\xa0\xb8\x2e\xb2\xd8\xbc\xb1\xe7\x7a\x9a\x9e\x03\x46\x6c\x40\xef\x13\xad\x73\xee\x33\xc3\x13\xa0\x19\xe2\x12\xb8\xdf\xc2\x59\x60\x2c\xf4\xe2\xfa\xb3\xab\x01\x3d\xa5\x13\x5c\x78\x2b\x41\x7d\x48\xa9\xcb\x6e\xc4\x13\x72\xfa\xce\xdc\xa8\xe2\x9e\xbb\xa3\x13\x46\x48\xec\x07\x3e\x1c\x5a\xca\xc7\x31\x85\x73\xf4\x3a\x4d\x72\xb7\xa4\xf1\xb1\x8a\x17\x41\xdf\x63\xb0\xeb\x11\xda\x0d\xce\x71\xd5\xea\xfd\x1e\x27\x9a\xe3\xa2\x41\x0a\xfc\xee\x1d\xbe\x8c\xc5\x3d\x36\x22\x1e\xdc\xb5\x63\x93\x86\xa7\xaa\x0b\x39\xfd\xf5\xee\xfd\xcd\x6d\x58\x3b\x85\x36\x74\x02\xaa\xe9\x2a\xed\x2a\x74\xa8\xef\x07\x35\x36\x0c\x5e\xb9\xb6\xa8\x80\x6c\xa8\xc5\x81\xd1\x71\xd0\x1e\x6c\x60\xc2\x7a\x06\x76\xd2\x3f\x7d\x7d\xd1\x3d\xf1\x62\xf5\xe8\x46\xb8\x07\xd7\xdf\xa5\x9d\x93\xfe\x9f\x73\xff\x2d\xe7\xc6\x5b\x14\xb7\xfd\x79\x08\x4e\x6e\x8c\xe0\x1f\xd0\x13\x77\xed\x40\x50\xbc\x05\x0a\x9e\xe4\x23\xbb\x3e\xb2\xed\xad\xdf\x1b\x33\x3e\xbe\xbf\x1e\x25\x5a\x1c\x7c\x7e\xa8\xc7\xe5\x5f\xa6\xd8\x74\x91\xaf\x0e\x00\x00"
 
 func templatesFactoidListHtmlBytes() ([]byte, error) {
 	return bindataRead(
@@ -155,7 +177,27 @@ func templatesFactoidListHtml() (*asset, error) {
 		return nil, err
 	}
 
-	info := bindataFileInfo{name: "templates/factoid-list.html", size: 2394, mode: os.FileMode(420), modTime: time.Unix(1484702025, 0)}
+	info := bindataFileInfo{name: "templates/factoid-list.html", size: 3759, mode: os.FileMode(420), modTime: time.Unix(1792339713, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _templatesHelpHtml = "\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\x03\x8d\x54\xc1\x72\xd3\x30\x10\xbd\xe7\x2b\x76\xc4\x70\xc3\x4e\x02\x3d\xb5\x8e\x87\x4e\xe9\xc0\x81\xf6\xd0\xc2\x07\x28\xf6\x26\xd6\x54\x96\x8c\x24\x27\x04\x8f\xff\x9d\x95\x2c\xd7\x09\x4d\x01\x1f\x3c\xb6\x76\xf7\xed\xee\xdb\xb7\xea\xba\x12\x37\x42\x21\x30\xeb\x0e\x12\x2d\xeb\xfb\x59\x16\x3e\xf3\x59\x5a\xe8\xba\xe6\xaa\x4c\x0c\x6e\x60\xfc\x81\x6e\x06\xf4\xd4\xdc\x6c\x85\x4a\xd6\xda\x39\x5d\x5f\xc2\x12\xeb\xab\x59\xff\x4a\xc4\xf9\x50\x89\x1b\x77\x09\xef\xff\x12\x98\x54\x28\x9b\x18\xb4\xaf\x84\xc3\xc4\x36\xbc\xc0\x4b\x68\x0c\x26\x7b\xc3\x9b\xab\x23\xbc\x4b\x58\xa4\x1f\xb0\x86\xc5\x0b\xb8\xea\x02\x0a\x5d\x62\x04\xda\x68\xe5\x12\x2b\x7e\x11\xcc\x72\xb1\x78\xeb\xbd\xb3\x79\x6c\xb8\xeb\x50\x95\x44\x40\xf7\x4c\x4a\xc4\x09\xac\x94\x62\x07\x85\xe4\xd6\xae\x9e\x8f\x41\x94\x2b\xd6\x75\xe9\xb5\x2a\x2a\x6d\xfa\x9e\xe5\x21\x47\x56\x5d\xe4\x19\x87\x8a\xb2\xaf\xd8\x9b\x13\x7b\xe6\x4b\xc9\xe9\xe8\x9e\xd7\xd8\xf7\xd9\x3c\xfc\x67\x73\x3e\x44\xfa\xa7\xeb\x12\x30\x5c\x6d\x11\xd2\x6b\x29\xb8\x45\xdb\xf7\x90\xd9\x9a\x4b\x39\x85\x1f\x85\x0e\x96\xb1\xf6\x63\x14\x41\x5c\x3e\xa0\x75\x46\x14\x0e\xcb\x80\xd2\x70\x35\x36\x21\xf9\x1a\x25\x84\x77\xb2\xe7\x46\x09\xb5\x65\x1e\xfa\x2b\xee\x50\x7a\x7c\xef\x3c\xe2\x66\x73\xea\x69\x76\x0c\xfc\x85\x86\x13\xf3\x9d\xa1\x26\xcc\x2e\xc0\x0d\x7e\xd9\x9c\x7c\x26\x80\xa9\xd6\x93\x6e\xad\xbe\x76\x9e\x18\x3b\x02\x37\x79\x6c\xdc\xdb\x80\xef\xb8\xa0\x7a\x25\x02\xb7\xf0\x3a\x15\xd9\xbc\xf9\x47\xa6\xc7\x76\x1d\xeb\xa4\x4c\x5d\xe7\xb0\x6e\x24\x77\x47\xf3\x86\xd4\x9f\x0f\xb1\xb1\xf4\x73\xe2\x50\x0e\x95\x7b\x29\x0e\xe5\x38\x39\x18\x52\xc3\xf1\x79\xc3\xb7\x48\xb4\xf0\x32\x58\x06\x9d\x2c\xf3\x9b\xb8\x1b\x0f\xb8\x41\x83\xaa\x40\x62\x7a\x39\x76\x7d\xbb\x43\x73\x70\x15\x4d\x06\x0e\xba\x85\x82\x86\xc7\xed\x13\xdc\x71\xb3\x13\x0a\x9c\x86\x52\x8f\x4d\x8f\x65\x12\x65\x11\xd2\x02\x37\x08\xa6\x55\xb0\x3e\x40\x4d\x85\x0a\xed\x67\x1c\xa3\xdf\x01\xa6\xdb\x34\xb2\xf8\xb1\x1e\x10\xc3\xc2\x6d\x78\xe1\xb4\x28\x23\xa9\x69\xa8\xf4\xbb\xc5\x73\xae\x49\x42\xa9\xa3\xa3\x2f\x67\x8b\x0e\xa8\x5c\x0b\x52\x58\x07\xe4\xc5\xe1\xd3\x5d\x1a\xe6\x91\x6d\xb4\xa9\x47\x2a\xfc\x77\x22\x94\x24\x96\x18\x95\xe6\x2a\x4d\x7b\xf4\xf9\xf6\x1b\x03\xca\x4d\x65\xae\xd8\x7c\xd0\xcf\x0b\x79\x85\xc8\xad\xd1\xed\x68\x0c\x0e\x83\x94\xc9\xb6\x62\x3e\x2e\xf9\xc1\xf2\x47\xe4\xa6\xa8\xb2\x79\x30\x1d\xb9\x0a\xd5\xb4\x54\xe4\xa1\xc1\x15\x73\xf8\xd3\xb1\x13\x64\x3f\x3b\xa3\xe5\xb0\xd8\x11\x0a\x14\x29\x72\xc5\xe8\x63\xc7\x65\x8b\x61\xe1\x07\xf4\x69\xe1\x27\x75\x67\xeb\x96\xae\x44\x15\x33\xd8\x76\x5d\x8b\x29\xc7\xda\xd1\x30\x9c\x4a\x48\x41\xbc\x95\x6e\xaa\x72\x08\x1a\x55\xeb\xd7\x6b\xcc\x40\xf7\xc8\x1f\xc1\xc4\xda\x13\x8b\x97\x4b\xa4\xe9\xb1\xd2\x7b\x08\xa3\xe0\xf9\x24\x5b\xdf\x51\x7e\xee\xde\xf2\xd7\x22\xf3\x8a\x7e\x5e\x88\x9b\xff\xde\x86\xb8\xfe\x4a\x3b\x48\xef\xb8\x2b\x2a\x0c\xc9\x68\x51\xb1\xce\xef\x35\x8c\x7b\x45\xd7\x72\x30\x7a\xe1\x1a\xb0\xa1\x1b\x52\x02\x39\x05\x39\x4c\xab\x39\xea\xf6\x74\xcb\x7e\x03\x41\x2d\xed\x13\x98\x06\x00\x00"
+
+func templatesHelpHtmlBytes() ([]byte, error) {
+	return bindataRead(
+		_templatesHelpHtml,
+		"templates/help.html",
+	)
+}
+
+func templatesHelpHtml() (*asset, error) {
+	bytes, err := templatesHelpHtmlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "templates/help.html", size: 1688, mode: os.FileMode(420), modTime: time.Unix(1792337200, 0)}
 	a := &asset{bytes: bytes, info: info}
 	return a, nil
 }
@@ -394,8 +436,10 @@ func AssetNames() []string {
 var _bindata = map[string]func() (*asset, error){
 	"layout.html":                   layoutHtml,
 	"assets/styles.css":             assetsStylesCss,
+	"templates/command-log.html":    templatesCommandLogHtml,
 	"templates/factoid-info.html":   templatesFactoidInfoHtml,
 	"templates/factoid-list.html":   templatesFactoidListHtml,
+	"templates/help.html":           templatesHelpHtml,
 	"templates/home.html":           templatesHomeHtml,
 	"templates/invite-box.html":     templatesInviteBoxHtml,
 	"templates/invite-list.html":    templatesInviteListHtml,
@@ -453,8 +497,10 @@ var _bintree = &bintree{nil, map[string]*bintree{
 	}},
 	"layout.html": &bintree{layoutHtml, map[string]*bintree{}},
 	"templates": &bintree{nil, map[string]*bintree{
+		"command-log.html":    &bintree{templatesCommandLogHtml, map[string]*bintree{}},
 		"factoid-info.html":   &bintree{templatesFactoidInfoHtml, map[string]*bintree{}},
 		"factoid-list.html":   &bintree{templatesFactoidListHtml, map[string]*bintree{}},
+		"help.html":           &bintree{templatesHelpHtml, map[string]*bintree{}},
 		"home.html":           &bintree{templatesHomeHtml, map[string]*bintree{}},
 		"invite-box.html":     &bintree{templatesInviteBoxHtml, map[string]*bintree{}},
 		"invite-list.html":    &bintree{templatesInviteListHtml, map[string]*bintree{}},
@@ -22,6 +22,8 @@ const (
 	NavSectionFactoids = "Factoids"
 	NavSectionInvite   = "Channels"
 	NavSectionLogs     = "Logs"
+	NavSectionCommands = "Commands"
+	NavSectionHelp     = "Help"
 	NavSectionUser     = "User"
 )
 
@@ -32,6 +34,8 @@ var NavbarContent = []struct {
 	{Name: NavSectionFactoids, URL: "/factoids"},
 	{Name: NavSectionInvite, URL: "/invites"},
 	{Name: NavSectionLogs, URL: "/logs"},
+	{Name: NavSectionCommands, URL: "/commands"},
+	{Name: NavSectionHelp, URL: "/help"},
 }
 
 type LayoutContent struct {
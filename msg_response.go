@@ -1,7 +1,10 @@
 package marvin
 
 import (
+	"fmt"
+
 	"github.com/riking/marvin/slack"
+	"github.com/riking/marvin/util"
 )
 
 type ReplyType int
@@ -11,19 +14,28 @@ const (
 	ReplyTypeInChannel
 	ReplyTypeLog
 	ReplyTypeFlagOmitUsername
+	// ReplyTypeEphemeral is shown in the channel, but only to the user who
+	// ran the command. It cannot be edited later.
+	ReplyTypeEphemeral
 )
 
 const (
 	ReplyTypeInvalid      ReplyType = 0
 	ReplyTypeShortProblem           = ReplyTypeInChannel | ReplyTypeLog
 	ReplyTypeLongProblem            = ReplyTypePM | ReplyTypeLog
-	ReplyTypeDestinations           = ReplyTypeInChannel | ReplyTypePM | ReplyTypeLog
+	ReplyTypeDestinations           = ReplyTypeInChannel | ReplyTypePM | ReplyTypeLog | ReplyTypeEphemeral
 )
 
 const LongReplyThreshold = 400
 const LongReplyCut = 100
 const ShortReplyThreshold = 35
 
+// LongReplyText is the replacement for a long reply that was stored
+// elsewhere, such as in a snippet or a paste.
+func LongReplyText(message, link string) string {
+	return fmt.Sprintf("%s…\nFull reply: %s", util.PreviewString(message, LongReplyCut), link)
+}
+
 type ActionSourceUserMessage struct {
 	Team Team
 	Msg  slack.SlackTextMessage
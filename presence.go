@@ -0,0 +1,32 @@
+package marvin
+
+import (
+	"time"
+
+	"github.com/riking/marvin/slack"
+)
+
+// Presence values reported by Team.UserPresence.
+const (
+	PresenceActive = "active"
+	PresenceAway   = "away"
+	// PresenceUnknown is returned for users that have opted out of presence
+	// tracking or that Slack has not reported on yet.
+	PresenceUnknown = ""
+)
+
+// LastSeen is the recorded activity of a user. Zero times mean the activity
+// was never seen.
+type LastSeen struct {
+	User slack.UserID
+	// LastActive is the last time the user was seen active, either from a
+	// presence change or from a message.
+	LastActive time.Time
+	// LastMessage is the time of the last message the user sent in a
+	// channel the bot is in, and Channel is where it was sent.
+	LastMessage time.Time
+	Channel     slack.ChannelID
+	// OptedOut is set if the user asked not to be tracked. None of the
+	// other fields are filled in.
+	OptedOut bool
+}
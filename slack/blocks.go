@@ -0,0 +1,294 @@
+package slack
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// A Block is one Block Kit layout block in a message.
+//
+// https://api.slack.com/reference/block-kit/blocks
+type Block interface {
+	BlockType() string
+	// PlainText is the text used for notifications and logs, where blocks
+	// cannot be shown.
+	PlainText() string
+}
+
+// A BlockElement is an interactive element, used in actions blocks and as
+// the accessory of a section.
+type BlockElement interface {
+	ElementType() string
+}
+
+// A ContextElement is a TextObject or an ImageElement.
+type ContextElement interface {
+	ElementType() string
+}
+
+const (
+	TextTypePlain    = "plain_text"
+	TextTypeMarkdown = "mrkdwn"
+)
+
+// TextObject is a text composition object.
+type TextObject struct {
+	Type     string `json:"type"`
+	Text     string `json:"text"`
+	Emoji    bool   `json:"emoji,omitempty"`
+	Verbatim bool   `json:"verbatim,omitempty"`
+}
+
+// PlainTextObject makes a plain_text TextObject with emoji enabled.
+func PlainTextObject(text string) *TextObject {
+	return &TextObject{Type: TextTypePlain, Text: text, Emoji: true}
+}
+
+// MarkdownObject makes a mrkdwn TextObject.
+func MarkdownObject(text string) *TextObject {
+	return &TextObject{Type: TextTypeMarkdown, Text: text}
+}
+
+func (t *TextObject) ElementType() string { return t.Type }
+
+// SectionBlock shows text, optionally with fields and an accessory.
+type SectionBlock struct {
+	BlockID   string        `json:"block_id,omitempty"`
+	Text      *TextObject   `json:"text,omitempty"`
+	Fields    []*TextObject `json:"fields,omitempty"`
+	Accessory BlockElement  `json:"accessory,omitempty"`
+}
+
+func (b *SectionBlock) BlockType() string { return "section" }
+
+func (b *SectionBlock) PlainText() string {
+	var parts []string
+	if b.Text != nil {
+		parts = append(parts, b.Text.Text)
+	}
+	for _, f := range b.Fields {
+		parts = append(parts, f.Text)
+	}
+	return strings.Join(parts, "\n")
+}
+
+func (b *SectionBlock) MarshalJSON() ([]byte, error) {
+	type plain SectionBlock
+	return marshalTyped(b.BlockType(), (*plain)(b))
+}
+
+// ContextBlock shows small text and images.
+type ContextBlock struct {
+	BlockID  string           `json:"block_id,omitempty"`
+	Elements []ContextElement `json:"elements"`
+}
+
+func (b *ContextBlock) BlockType() string { return "context" }
+
+func (b *ContextBlock) PlainText() string {
+	var parts []string
+	for _, e := range b.Elements {
+		if t, ok := e.(*TextObject); ok {
+			parts = append(parts, t.Text)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func (b *ContextBlock) MarshalJSON() ([]byte, error) {
+	type plain ContextBlock
+	return marshalTyped(b.BlockType(), (*plain)(b))
+}
+
+// DividerBlock is a horizontal rule.
+type DividerBlock struct {
+	BlockID string `json:"block_id,omitempty"`
+}
+
+func (b *DividerBlock) BlockType() string { return "divider" }
+func (b *DividerBlock) PlainText() string { return "" }
+
+func (b *DividerBlock) MarshalJSON() ([]byte, error) {
+	type plain DividerBlock
+	return marshalTyped(b.BlockType(), (*plain)(b))
+}
+
+// ImageBlock shows a single image.
+type ImageBlock struct {
+	BlockID  string      `json:"block_id,omitempty"`
+	ImageURL string      `json:"image_url"`
+	AltText  string      `json:"alt_text"`
+	Title    *TextObject `json:"title,omitempty"`
+}
+
+func (b *ImageBlock) BlockType() string { return "image" }
+
+func (b *ImageBlock) PlainText() string {
+	if b.Title != nil {
+		return b.Title.Text + " " + b.ImageURL
+	}
+	return b.ImageURL
+}
+
+func (b *ImageBlock) MarshalJSON() ([]byte, error) {
+	type plain ImageBlock
+	return marshalTyped(b.BlockType(), (*plain)(b))
+}
+
+// ActionsBlock holds interactive elements.
+type ActionsBlock struct {
+	BlockID  string         `json:"block_id,omitempty"`
+	Elements []BlockElement `json:"elements"`
+}
+
+func (b *ActionsBlock) BlockType() string { return "actions" }
+
+func (b *ActionsBlock) PlainText() string {
+	var parts []string
+	for _, e := range b.Elements {
+		if btn, ok := e.(*ButtonElement); ok && btn.URL != "" {
+			parts = append(parts, btn.Text.Text+": "+btn.URL)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+func (b *ActionsBlock) MarshalJSON() ([]byte, error) {
+	type plain ActionsBlock
+	return marshalTyped(b.BlockType(), (*plain)(b))
+}
+
+// ImageElement is an image in a context block or a section accessory.
+type ImageElement struct {
+	ImageURL string `json:"image_url"`
+	AltText  string `json:"alt_text"`
+}
+
+func (e *ImageElement) ElementType() string { return "image" }
+
+func (e *ImageElement) MarshalJSON() ([]byte, error) {
+	type plain ImageElement
+	return marshalTyped(e.ElementType(), (*plain)(e))
+}
+
+const (
+	ButtonStylePrimary = "primary"
+	ButtonStyleDanger  = "danger"
+)
+
+// ButtonElement is a button. Buttons with a URL open it; other buttons need
+// an interactivity endpoint to do anything.
+type ButtonElement struct {
+	ActionID string      `json:"action_id,omitempty"`
+	Text     *TextObject `json:"text"`
+	URL      string      `json:"url,omitempty"`
+	Value    string      `json:"value,omitempty"`
+	Style    string      `json:"style,omitempty"`
+}
+
+func (e *ButtonElement) ElementType() string { return "button" }
+
+func (e *ButtonElement) MarshalJSON() ([]byte, error) {
+	type plain ButtonElement
+	return marshalTyped(e.ElementType(), (*plain)(e))
+}
+
+// LinkButton makes a button that opens a URL.
+func LinkButton(text, url string) *ButtonElement {
+	return &ButtonElement{Text: PlainTextObject(text), URL: url}
+}
+
+// marshalTyped adds the "type" field to the JSON form of v.
+func marshalTyped(typ string, v interface{}) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]json.RawMessage
+	err = json.Unmarshal(b, &m)
+	if err != nil {
+		return nil, err
+	}
+	m["type"], _ = json.Marshal(typ)
+	return json.Marshal(m)
+}
+
+// BlocksFallback returns the plain text form of a list of blocks.
+func BlocksFallback(blocks []Block) string {
+	var parts []string
+	for _, b := range blocks {
+		if s := b.PlainText(); s != "" {
+			parts = append(parts, s)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// BlockBuilder builds a list of blocks. The methods can be chained:
+//
+//	blocks := slack.NewBlockBuilder().
+//		Section("*Build passed*").
+//		Context("riking/marvin", "master").
+//		Blocks()
+type BlockBuilder struct {
+	blocks []Block
+}
+
+func NewBlockBuilder() *BlockBuilder {
+	return &BlockBuilder{}
+}
+
+// Add appends any block.
+func (bb *BlockBuilder) Add(b Block) *BlockBuilder {
+	bb.blocks = append(bb.blocks, b)
+	return bb
+}
+
+// Section appends a section with mrkdwn text.
+func (bb *BlockBuilder) Section(text string) *BlockBuilder {
+	return bb.Add(&SectionBlock{Text: MarkdownObject(text)})
+}
+
+// SectionWithAccessory appends a section with mrkdwn text and an element
+// to the right of it.
+func (bb *BlockBuilder) SectionWithAccessory(text string, accessory BlockElement) *BlockBuilder {
+	return bb.Add(&SectionBlock{Text: MarkdownObject(text), Accessory: accessory})
+}
+
+// Fields appends a section of mrkdwn fields, shown in two columns.
+func (bb *BlockBuilder) Fields(fields ...string) *BlockBuilder {
+	b := &SectionBlock{}
+	for _, f := range fields {
+		b.Fields = append(b.Fields, MarkdownObject(f))
+	}
+	return bb.Add(b)
+}
+
+// Context appends a context block with each string as a mrkdwn element.
+func (bb *BlockBuilder) Context(texts ...string) *BlockBuilder {
+	b := &ContextBlock{}
+	for _, t := range texts {
+		b.Elements = append(b.Elements, MarkdownObject(t))
+	}
+	return bb.Add(b)
+}
+
+// Divider appends a divider.
+func (bb *BlockBuilder) Divider() *BlockBuilder {
+	return bb.Add(&DividerBlock{})
+}
+
+// Image appends an image block.
+func (bb *BlockBuilder) Image(imageURL, altText string) *BlockBuilder {
+	return bb.Add(&ImageBlock{ImageURL: imageURL, AltText: altText})
+}
+
+// Actions appends an actions block.
+func (bb *BlockBuilder) Actions(elements ...BlockElement) *BlockBuilder {
+	return bb.Add(&ActionsBlock{Elements: elements})
+}
+
+// Blocks returns the built list.
+func (bb *BlockBuilder) Blocks() []Block {
+	return bb.blocks
+}
@@ -0,0 +1,93 @@
+package slack
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// sameJSON reports whether two JSON documents are equal, ignoring key order
+// and whitespace.
+func sameJSON(t *testing.T, a, b []byte) bool {
+	var va, vb interface{}
+	if err := json.Unmarshal(a, &va); err != nil {
+		t.Fatalf("bad JSON %s: %s", a, err)
+	}
+	if err := json.Unmarshal(b, &vb); err != nil {
+		t.Fatalf("bad JSON %s: %s", b, err)
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+func TestBlocksMarshal(t *testing.T) {
+	tests := []struct {
+		name   string
+		block  Block
+		expect string
+	}{
+		{"section", &SectionBlock{Text: MarkdownObject("*hi*")},
+			`{"type": "section", "text": {"type": "mrkdwn", "text": "*hi*"}}`},
+		{"section fields", &SectionBlock{BlockID: "b1", Fields: []*TextObject{MarkdownObject("a"), PlainTextObject("b")}},
+			`{"type": "section", "block_id": "b1", "fields": [
+				{"type": "mrkdwn", "text": "a"},
+				{"type": "plain_text", "text": "b", "emoji": true}]}`},
+		{"section accessory", &SectionBlock{Text: MarkdownObject("x"), Accessory: &ImageElement{ImageURL: "https://example.com/a.png", AltText: "a"}},
+			`{"type": "section", "text": {"type": "mrkdwn", "text": "x"},
+				"accessory": {"type": "image", "image_url": "https://example.com/a.png", "alt_text": "a"}}`},
+		{"context", &ContextBlock{Elements: []ContextElement{MarkdownObject("by @a"), &ImageElement{ImageURL: "https://example.com/b.png", AltText: "b"}}},
+			`{"type": "context", "elements": [
+				{"type": "mrkdwn", "text": "by @a"},
+				{"type": "image", "image_url": "https://example.com/b.png", "alt_text": "b"}]}`},
+		{"divider", &DividerBlock{}, `{"type": "divider"}`},
+		{"image", &ImageBlock{ImageURL: "https://example.com/c.png", AltText: "c", Title: PlainTextObject("C")},
+			`{"type": "image", "image_url": "https://example.com/c.png", "alt_text": "c",
+				"title": {"type": "plain_text", "text": "C", "emoji": true}}`},
+		{"actions", &ActionsBlock{Elements: []BlockElement{
+			LinkButton("Open", "https://example.com/"),
+			&ButtonElement{ActionID: "del", Text: PlainTextObject("Delete"), Value: "5", Style: ButtonStyleDanger},
+		}},
+			`{"type": "actions", "elements": [
+				{"type": "button", "text": {"type": "plain_text", "text": "Open", "emoji": true}, "url": "https://example.com/"},
+				{"type": "button", "action_id": "del", "text": {"type": "plain_text", "text": "Delete", "emoji": true}, "value": "5", "style": "danger"}]}`},
+	}
+	for _, v := range tests {
+		b, err := json.Marshal(v.block)
+		if err != nil {
+			t.Errorf("%s: %s", v.name, err)
+			continue
+		}
+		if !sameJSON(t, b, []byte(v.expect)) {
+			t.Errorf("%s: expected\n%s\ngot\n%s", v.name, v.expect, b)
+		}
+	}
+}
+
+func TestBlockBuilder(t *testing.T) {
+	blocks := NewBlockBuilder().
+		Section("*Build passed*").
+		Fields("Branch", "master").
+		Divider().
+		Context("riking/marvin", "1 minute ago").
+		Actions(LinkButton("Logs", "https://example.com/logs")).
+		Blocks()
+
+	b, err := json.Marshal(blocks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `[
+		{"type": "section", "text": {"type": "mrkdwn", "text": "*Build passed*"}},
+		{"type": "section", "fields": [{"type": "mrkdwn", "text": "Branch"}, {"type": "mrkdwn", "text": "master"}]},
+		{"type": "divider"},
+		{"type": "context", "elements": [{"type": "mrkdwn", "text": "riking/marvin"}, {"type": "mrkdwn", "text": "1 minute ago"}]},
+		{"type": "actions", "elements": [{"type": "button", "text": {"type": "plain_text", "text": "Logs", "emoji": true}, "url": "https://example.com/logs"}]}
+	]`
+	if !sameJSON(t, b, []byte(expect)) {
+		t.Errorf("expected\n%s\ngot\n%s", expect, b)
+	}
+
+	fallback := "*Build passed*\nBranch\nmaster\nriking/marvin 1 minute ago\nLogs: https://example.com/logs"
+	if got := BlocksFallback(blocks); got != fallback {
+		t.Errorf("fallback: expected %q, got %q", fallback, got)
+	}
+}
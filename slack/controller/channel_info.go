@@ -10,83 +10,65 @@ import (
 
 	"github.com/riking/marvin"
 	"github.com/riking/marvin/slack"
+	"github.com/riking/marvin/util"
 )
 
 func (t *Team) ChannelName(channel slack.ChannelID) string {
 	if channel == "" {
 		return "#(!Empty channel ID)"
 	}
-	switch channel[0] {
-	case 'C':
-		ch, err := t.PublicChannelInfo(channel)
-		if err != nil {
-			return fmt.Sprintf("<!error getting channel name for %s>", string(channel))
-		}
-		return "#" + ch.Name
-	case 'G':
-		ch, err := t.PrivateChannelInfo(channel)
-		if err != nil {
-			return fmt.Sprintf("<!error getting channel name for %s>", string(channel))
-		}
-		if ch.IsMultiIM() {
-			var membersStr bytes.Buffer
-			for i, v := range ch.Members {
-				if i != 0 {
-					membersStr.WriteByte(' ')
-				}
-				membersStr.WriteByte('@')
-				membersStr.WriteString(t.UserName(v))
-			}
-			return fmt.Sprintf("#[MultiIM %v]", membersStr.String())
-		}
-		return "#" + ch.Name
-	case 'D':
-		otherUser, _ := t.GetIMOtherUser(channel)
-		if otherUser == "" {
-			return fmt.Sprintf("<!error getting other user for %s>", string(channel))
-		}
-		return fmt.Sprintf("#[IM @%s]", t.UserName(otherUser))
+	if channel[0] == '(' {
+		// (via web)
+		return string(channel)
+	}
+	conv, err := t.ConversationInfo(channel)
+	if err != nil {
+		return fmt.Sprintf("<!error getting channel name for %s>", string(channel))
 	}
-	return string(channel)
+	switch conv.Type() {
+	case slack.ConversationMPIM:
+		return fmt.Sprintf("#[MultiIM %v]", t.mpimMembers(conv))
+	case slack.ConversationIM:
+		return fmt.Sprintf("#[IM @%s]", t.UserName(conv.User))
+	}
+	return "#" + conv.Name
 }
 
 func (t *Team) FormatChannel(channel slack.ChannelID) string {
-	switch channel[0] {
-	case 'C':
-		ch, err := t.PublicChannelInfo(channel)
-		if err != nil {
-			return fmt.Sprintf("<!error getting channel name for %s>", string(channel))
-		}
-		return fmt.Sprintf("<#%s|%s>", channel, ch.Name)
-	case 'G':
-		ch, err := t.PrivateChannelInfo(channel)
-		if err != nil {
-			return fmt.Sprintf("<!error getting channel name for %s>", string(channel))
-		}
-		if ch.IsMultiIM() {
-			var membersStr bytes.Buffer
-			for i, v := range ch.Members {
-				if i != 0 {
-					membersStr.WriteByte(' ')
-				}
-				membersStr.WriteByte('@')
-				membersStr.WriteString(t.UserName(v))
-			}
-			return fmt.Sprintf("#[MultiIM %v]", membersStr.String())
-		}
-		return fmt.Sprintf("<#%s|%s>", channel, ch.Name)
-	case 'D':
-		otherUser, _ := t.GetIMOtherUser(channel)
-		if otherUser == "" {
-			return fmt.Sprintf("<!error getting other user for %s>", string(channel))
-		}
-		return fmt.Sprintf("#[IM @%s]", t.UserName(otherUser))
-	case '(':
+	if channel == "" {
+		return "#(!Empty channel ID)"
+	}
+	if channel[0] == '(' {
 		// (via web)
 		return string(channel)
 	}
-	return string(channel)
+	conv, err := t.ConversationInfo(channel)
+	if err != nil {
+		return fmt.Sprintf("<!error getting channel name for %s>", string(channel))
+	}
+	switch conv.Type() {
+	case slack.ConversationMPIM:
+		return fmt.Sprintf("#[MultiIM %v]", t.mpimMembers(conv))
+	case slack.ConversationIM:
+		return fmt.Sprintf("#[IM @%s]", t.UserName(conv.User))
+	}
+	return fmt.Sprintf("<#%s|%s>", channel, conv.Name)
+}
 
+func (t *Team) mpimMembers(conv *slack.Conversation) string {
+	members := conv.Members
+	if len(members) == 0 {
+		members = t.ChannelMemberList(conv.ID)
+	}
+	var membersStr bytes.Buffer
+	for i, v := range members {
+		if i != 0 {
+			membersStr.WriteByte(' ')
+		}
+		membersStr.WriteByte('@')
+		membersStr.WriteString(t.UserName(v))
+	}
+	return membersStr.String()
 }
 
 func (t *Team) UserName(user slack.UserID) string {
@@ -111,25 +93,58 @@ func (t *Team) UserLevel(user slack.UserID) marvin.AccessLevel {
 		return marvin.AccessLevelBlacklisted
 	}
 
-	val, isDefault, err := t.ModuleConfig("blacklist").GetIsDefault(string(u.ID))
-	if isDefault {
-		// user not blacklisted
-	} else if err != nil {
-		// DB error, continue
-	} else if val != "" {
-		// user is blacklisted
+	groups := t.client.UserUsergroups(user)
+	for _, v := range t.TeamConfig().Controllers {
+		if groupContains(groups, slack.UsergroupID(v)) {
+			return marvin.AccessLevelController
+		}
+	}
+
+	if t.isBlacklisted(string(u.ID)) {
 		return marvin.AccessLevelBlacklisted
 	}
+	for _, v := range groups {
+		if t.isBlacklisted(string(v)) {
+			return marvin.AccessLevelBlacklisted
+		}
+	}
 
 	if u.IsOwner || u.IsAdmin {
 		return marvin.AccessLevelAdmin
 	}
+	for _, v := range t.TeamConfig().AdminGroups {
+		if groupContains(groups, v) {
+			return marvin.AccessLevelAdmin
+		}
+	}
 	if u.IsBot {
 		return marvin.AccessLevelBlacklisted
 	}
 	return marvin.AccessLevelNormal
 }
 
+// isBlacklisted checks the blacklist for a user or user group ID.
+func (t *Team) isBlacklisted(id string) bool {
+	val, isDefault, err := t.ModuleConfig("blacklist").GetIsDefault(id)
+	if isDefault {
+		// not blacklisted
+	} else if err != nil {
+		// DB error, continue
+	} else if val != "" {
+		return true
+	}
+	return false
+}
+
+func groupContains(groups []slack.UsergroupID, group slack.UsergroupID) bool {
+	for _, v := range groups {
+		if v == group {
+			return true
+		}
+	}
+	return false
+}
+
 var rgxPlainTextChannelName = regexp.MustCompile(`^#([a-z0-9_\-]+)$`)
 
 func (t *Team) ResolveChannelName(input string) slack.ChannelID {
@@ -174,6 +189,52 @@ func (t *Team) ResolveUserName(input string) slack.UserID {
 	return ""
 }
 
+// ResolveUserNames resolves a user or a user group to a list of users. User
+// groups are expanded to their members.
+func (t *Team) ResolveUserNames(input string) []slack.UserID {
+	if group := slack.ParseUsergroupMention(input); group != "" {
+		return t.UsergroupMembers(group)
+	}
+	// Names are checked first, as a name like SAM2 looks like a group ID
+	if uID := t.ResolveUserName(input); uID != "" {
+		return []slack.UserID{uID}
+	}
+	if group := t.client.UsergroupByHandle(input); group != nil {
+		return t.UsergroupMembers(group.ID)
+	}
+	if group := slack.ParseUsergroupID(input); group != "" {
+		return t.UsergroupMembers(group)
+	}
+	return nil
+}
+
+// ResolveUsergroup parses a user group mention, handle, or ID.
+func (t *Team) ResolveUsergroup(input string) slack.UsergroupID {
+	if id := slack.ParseUsergroupMention(input); id != "" {
+		return id
+	}
+	if group := t.client.UsergroupByHandle(input); group != nil {
+		return group.ID
+	}
+	return slack.ParseUsergroupID(input)
+}
+
+func (t *Team) UsergroupInfo(group slack.UsergroupID) *slack.Usergroup {
+	return t.client.Usergroup(group)
+}
+
+func (t *Team) UsergroupMembers(group slack.UsergroupID) []slack.UserID {
+	info := t.client.Usergroup(group)
+	if info == nil {
+		return nil
+	}
+	return append([]slack.UserID(nil), info.Users...)
+}
+
+func (t *Team) UserUsergroups(user slack.UserID) []slack.UsergroupID {
+	return t.client.UserUsergroups(user)
+}
+
 func (t *Team) cachedUserInfo(user slack.UserID) *slack.User {
 	t.client.MetadataLock.RLock()
 	defer t.client.MetadataLock.RUnlock()
@@ -215,23 +276,20 @@ func (t *Team) updateUserInfo(user slack.UserID) (*slack.User, error) {
 	return response.User, nil
 }
 
-func (t *Team) UserInChannels(user slack.UserID, channels ...slack.ChannelID) map[slack.ChannelID]bool {
-	return t.client.UserInChannels(user, channels...)
+func (t *Team) UserPresence(user slack.UserID) string {
+	return t.client.Presence(user)
 }
 
-func (t *Team) cachedPublicChannelInfo(channel slack.ChannelID) *slack.Channel {
-	t.client.MetadataLock.RLock()
-	defer t.client.MetadataLock.RUnlock()
+func (t *Team) LastSeen(user slack.UserID) (marvin.LastSeen, error) {
+	return t.client.LastSeen(user)
+}
 
-	for i, v := range t.client.Channels {
-		if v.ID == channel {
-			if v.CacheTS.Before(time.Now().Add(-24 * time.Hour)) {
-				return nil
-			}
-			return t.client.Channels[i]
-		}
-	}
-	return nil
+func (t *Team) SetPresenceOptOut(user slack.UserID, optOut bool) error {
+	return t.client.SetPresenceOptOut(user, optOut)
+}
+
+func (t *Team) UserInChannels(user slack.UserID, channels ...slack.ChannelID) map[slack.ChannelID]bool {
+	return t.client.UserInChannels(user, channels...)
 }
 
 func (t *Team) ChannelIDByName(chName string) slack.ChannelID {
@@ -251,116 +309,44 @@ func (t *Team) ChannelIDByName(chName string) slack.ChannelID {
 	return ""
 }
 
-func (t *Team) PublicChannelInfo(channel slack.ChannelID) (*slack.Channel, error) {
-	result := t.cachedPublicChannelInfo(channel)
-	if result != nil {
-		return result, nil
-	}
-
-	var response struct {
-		Channel *slack.Channel `json:"channel"`
-	}
-	form := url.Values{"channel": []string{string(channel)}}
-	err := t.SlackAPIPostJSON("channels.info", form, &response)
-	if err != nil {
-		return nil, err
-	}
-
-	go t.client.ReplaceChannelObject(time.Now(), response.Channel)
-	return response.Channel, nil
-}
-
-func (t *Team) cachedPrivateChannelInfo(channel slack.ChannelID) *slack.Channel {
-	t.client.MetadataLock.RLock()
-	defer t.client.MetadataLock.RUnlock()
-
-	for i, v := range t.client.Groups {
-		if v.ID == channel {
-			if v.CacheTS.Before(time.Now().Add(-24 * time.Hour)) {
-				return nil
-			}
-			return t.client.Groups[i]
-		}
-	}
-	return nil
-}
-
-func (t *Team) PrivateChannelInfo(channel slack.ChannelID) (*slack.Channel, error) {
-	result := t.cachedPrivateChannelInfo(channel)
-	if result != nil {
-		return result, nil
-	}
-
-	var response struct {
-		Group *slack.Channel `json:"group"`
-	}
-	form := url.Values{"channel": []string{string(channel)}}
-	err := t.SlackAPIPostJSON("groups.info", form, &response)
-	if err != nil {
-		return nil, err
-	}
-
-	go t.client.ReplaceGroupObject(time.Now(), response.Group)
-	return response.Group, nil
-}
-
-func (t *Team) GetIMOtherUser(im slack.ChannelID) (slack.UserID, error) {
-	t.client.MetadataLock.RLock()
-	defer t.client.MetadataLock.RUnlock()
-
-	for _, v := range t.client.Ims {
-		if v.ID == im {
-			return v.User, nil
-		}
-	}
-	return "", nil
-}
-
-func (t *Team) cachedIMEntry(user slack.UserID) slack.ChannelID {
-	t.client.MetadataLock.RLock()
-	defer t.client.MetadataLock.RUnlock()
-
-	for _, v := range t.client.Ims {
-		if v.User == user {
-			return v.ID
-		}
-	}
-	return ""
-}
-
-func (t *Team) GetIM(user slack.UserID) (slack.ChannelID, error) {
-	result := t.cachedIMEntry(user)
-	if result != "" {
-		return result, nil
-	}
-
-	form := url.Values{"user": []string{string(user)}}
-	var response struct {
-		Channel struct {
-			ID slack.ChannelID `json:"id"`
-		} `json:"channel"`
-	}
-	err := t.SlackAPIPostJSON("im.open", form, &response)
+// isIM reports whether the channel is a DM.
+func (t *Team) isIM(channel slack.ChannelID) bool {
+	conv, err := t.ConversationInfo(channel)
 	if err != nil {
-		return "", err
+		return false
 	}
-	t.client.ReplaceIMObject(time.Now(), &slack.ChannelIM{
-		ID:   response.Channel.ID,
-		User: user,
-	})
-	return response.Channel.ID, nil
+	return conv.IsIM
 }
 
 func (t *Team) ChannelMemberCount(channel slack.ChannelID) int {
 	if channel == "" {
 		return 0
 	}
-	if channel[0] == 'D' {
+	if t.isIM(channel) {
 		return 2
 	}
+	if t.client.MemberList(channel) == nil {
+		// Not fetched yet, or a channel the bot isn't in
+		return len(t.ChannelMemberList(channel))
+	}
 	return t.client.MemberCount(channel)
 }
 
 func (t *Team) ChannelMemberList(channel slack.ChannelID) []slack.UserID {
-	return t.client.MemberList(channel)
+	if channel == "" {
+		return nil
+	}
+	if t.isIM(channel) {
+		other, _ := t.GetIMOtherUser(channel)
+		if other == "" {
+			return nil
+		}
+		return []slack.UserID{t.BotUser(), other}
+	}
+	members, err := t.client.FetchChannelMembers(channel)
+	if err != nil {
+		util.LogError(err)
+		return nil
+	}
+	return members
 }
@@ -0,0 +1,185 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/riking/marvin/slack"
+)
+
+// conversationCacheTime is how long conversation info is used before it is
+// fetched again.
+const conversationCacheTime = 24 * time.Hour
+
+func (t *Team) cachedConversation(channel slack.ChannelID) *slack.Conversation {
+	t.client.MetadataLock.RLock()
+	defer t.client.MetadataLock.RUnlock()
+
+	for _, ary := range [][]*slack.Conversation{t.client.Channels, t.client.Groups} {
+		for _, v := range ary {
+			if v.ID == channel {
+				if v.CacheTS.Before(time.Now().Add(-conversationCacheTime)) {
+					return nil
+				}
+				return v
+			}
+		}
+	}
+	for _, v := range t.client.Ims {
+		if v.ID == channel {
+			return &slack.Conversation{
+				ID:            v.ID,
+				IsIM:          true,
+				User:          v.User,
+				Created:       int(v.Created),
+				IsUserDeleted: v.IsUserDeleted,
+			}
+		}
+	}
+	return nil
+}
+
+// ConversationInfo returns information about any kind of conversation.
+func (t *Team) ConversationInfo(channel slack.ChannelID) (*slack.Conversation, error) {
+	result := t.cachedConversation(channel)
+	if result != nil {
+		return result, nil
+	}
+
+	var response struct {
+		Channel *slack.Conversation `json:"channel"`
+	}
+	form := url.Values{"channel": []string{string(channel)}}
+	err := t.SlackAPIPostJSON("conversations.info", form, &response)
+	if err != nil {
+		return nil, err
+	}
+	if response.Channel == nil {
+		return nil, errors.Errorf("conversations.info: no channel returned for %s", channel)
+	}
+
+	go t.client.ReplaceConversationObject(time.Now(), response.Channel)
+	return response.Channel, nil
+}
+
+// PublicChannelInfo returns information about a public channel. It is the
+// same as ConversationInfo, except that it fails for other conversations.
+func (t *Team) PublicChannelInfo(channel slack.ChannelID) (*slack.Channel, error) {
+	conv, err := t.ConversationInfo(channel)
+	if err != nil {
+		return nil, err
+	}
+	if !conv.IsPublicChannel() {
+		return nil, errors.Errorf("%s is not a public channel", channel)
+	}
+	return conv, nil
+}
+
+// PrivateChannelInfo returns information about a private channel or MPIM.
+// It is the same as ConversationInfo, except that it fails for other
+// conversations.
+func (t *Team) PrivateChannelInfo(channel slack.ChannelID) (*slack.Channel, error) {
+	conv, err := t.ConversationInfo(channel)
+	if err != nil {
+		return nil, err
+	}
+	if conv.IsPublicChannel() || conv.IsIM {
+		return nil, errors.Errorf("%s is not a private channel", channel)
+	}
+	return conv, nil
+}
+
+func (t *Team) GetIMOtherUser(im slack.ChannelID) (slack.UserID, error) {
+	conv, err := t.ConversationInfo(im)
+	if err != nil {
+		return "", err
+	}
+	return conv.User, nil
+}
+
+func (t *Team) cachedIMEntry(user slack.UserID) slack.ChannelID {
+	t.client.MetadataLock.RLock()
+	defer t.client.MetadataLock.RUnlock()
+
+	for _, v := range t.client.Ims {
+		if v.User == user {
+			return v.ID
+		}
+	}
+	return ""
+}
+
+// GetIM opens an IM with the user, if needed, and returns its ID.
+func (t *Team) GetIM(user slack.UserID) (slack.ChannelID, error) {
+	result := t.cachedIMEntry(user)
+	if result != "" {
+		return result, nil
+	}
+
+	form := url.Values{"users": []string{string(user)}}
+	var response struct {
+		Channel struct {
+			ID slack.ChannelID `json:"id"`
+		} `json:"channel"`
+	}
+	err := t.SlackAPIPostJSON("conversations.open", form, &response)
+	if err != nil {
+		return "", err
+	}
+	t.client.ReplaceIMObject(time.Now(), &slack.ChannelIM{
+		ID:   response.Channel.ID,
+		User: user,
+	})
+	return response.Channel.ID, nil
+}
+
+// InviteToConversation invites users to a public or private channel.
+func (t *Team) InviteToConversation(channel slack.ChannelID, users ...slack.UserID) error {
+	ids := make([]string, len(users))
+	for i, v := range users {
+		ids[i] = string(v)
+	}
+	form := url.Values{
+		"channel": []string{string(channel)},
+		"users":   []string{strings.Join(ids, ",")},
+	}
+	return t.SlackAPIPostJSON("conversations.invite", form, nil)
+}
+
+// ConversationHistory fetches up to limit messages from a conversation,
+// newest first. If oldest is set, only messages after it are returned.
+func (t *Team) ConversationHistory(channel slack.ChannelID, oldest slack.MessageTS, limit int) ([]json.RawMessage, error) {
+	form := url.Values{
+		"channel": []string{string(channel)},
+		"limit":   []string{strconv.Itoa(limit)},
+	}
+	if oldest != "" {
+		form.Set("oldest", string(oldest))
+	}
+
+	// PostRaw is used because we're unmarshalling a large response body into `json.RawMessage`s.
+	// Best to keep the json work to a minimum.
+	resp, err := t.SlackAPIPostRaw("conversations.history", form)
+	if err != nil {
+		return nil, errors.Wrap(err, "Slack API conversations.history error")
+	}
+	defer resp.Body.Close()
+
+	var response struct {
+		slack.APIResponse
+		Messages []json.RawMessage `json:"messages"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&response)
+	if err != nil {
+		return nil, errors.Wrap(err, "Slack API conversations.history json decode error")
+	}
+	if !response.OK {
+		return nil, errors.Wrap(response, "Slack API conversations.history error")
+	}
+	return response.Messages, nil
+}
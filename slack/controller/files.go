@@ -0,0 +1,181 @@
+package controller
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/riking/marvin"
+	"github.com/riking/marvin/slack"
+	"github.com/riking/marvin/util"
+)
+
+// UploadSnippet uploads text as a snippet and shares it to the channel.
+func (t *Team) UploadSnippet(channel slack.ChannelID, title, content string) (*slack.File, error) {
+	form := uploadForm(slack.FileUpload{Title: title, Filetype: "text"}, []slack.ChannelID{channel})
+	form.Set("content", content)
+	var resp struct {
+		File *slack.File `json:"file"`
+	}
+	err := t.SlackAPIPostJSON("files.upload", form, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp.File, nil
+}
+
+// UploadFile uploads a file and shares it to the channels. The file is not
+// shared if no channels are given.
+func (t *Team) UploadFile(upload slack.FileUpload, channels ...slack.ChannelID) (*slack.File, error) {
+	filename := upload.Filename
+	if filename == "" {
+		filename = "file"
+	}
+	var resp struct {
+		File *slack.File `json:"file"`
+	}
+	err := t.slackAPIPostFile("files.upload", uploadForm(upload, channels), filename, upload.Content, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp.File, nil
+}
+
+func uploadForm(upload slack.FileUpload, channels []slack.ChannelID) url.Values {
+	form := url.Values{}
+	if len(channels) > 0 {
+		chStrs := make([]string, len(channels))
+		for i, v := range channels {
+			chStrs[i] = string(v)
+		}
+		form.Set("channels", strings.Join(chStrs, ","))
+	}
+	if upload.Filename != "" {
+		form.Set("filename", upload.Filename)
+	}
+	if upload.Title != "" {
+		form.Set("title", upload.Title)
+	}
+	if upload.Filetype != "" {
+		form.Set("filetype", upload.Filetype)
+	}
+	if upload.InitialComment != "" {
+		form.Set("initial_comment", upload.InitialComment)
+	}
+	if upload.ThreadTS != "" {
+		form.Set("thread_ts", string(upload.ThreadTS))
+	}
+	return form
+}
+
+// slackAPIPostFile makes a Slack API call with a multipart body, sending the
+// content as the "file" field.
+func (t *Team) slackAPIPostFile(method string, form url.Values, filename string, content []byte, result interface{}) error {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	for k, v := range form {
+		for _, val := range v {
+			w.WriteField(k, val)
+		}
+	}
+	w.WriteField("token", t.teamConfig.UserToken)
+	fw, err := w.CreateFormFile("file", filename)
+	if err != nil {
+		return errors.Wrapf(err, "Slack API %s: build request", method)
+	}
+	fw.Write(content)
+	err = w.Close()
+	if err != nil {
+		return errors.Wrapf(err, "Slack API %s: build request", method)
+	}
+
+	req, err := http.NewRequest("POST", fmt.Sprintf("https://slack.com/api/%s", method), &buf)
+	if err != nil {
+		panic(err)
+	}
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	req.Header.Set("User-Agent", "marvin-slackbot (+https://github.com/riking/homeapi/tree/shocky)")
+	resp, err := t.apiClient.Do(req)
+	if err != nil {
+		util.LogBadf("Slack API %s error: %s", method, err)
+		return errors.Wrapf(err, "Slack API %s: connect", method)
+	}
+	return t.decodeAPIResponse(method, form, resp, result)
+}
+
+// FileInfo fetches the metadata of a file.
+func (t *Team) FileInfo(file slack.FileID) (*slack.File, error) {
+	form := url.Values{"file": []string{string(file)}}
+	var resp struct {
+		File *slack.File `json:"file"`
+	}
+	err := t.SlackAPIPostJSON("files.info", form, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp.File, nil
+}
+
+// DownloadFile fetches the content of a file, using the bot's token to
+// access private files. Files larger than maxSize are not downloaded.
+func (t *Team) DownloadFile(file *slack.File, maxSize int64) ([]byte, error) {
+	if int64(file.Size) > maxSize {
+		return nil, marvin.ErrFileTooLarge{Size: int64(file.Size), Limit: maxSize}
+	}
+	u := file.URLPrivateDownload
+	if u == "" {
+		u = file.URLPrivate
+	}
+	if !strings.HasPrefix(u, "https://files.slack.com/") {
+		// Don't send the token anywhere else
+		return nil, errors.Errorf("file %s has an unexpected download URL", file.ID)
+	}
+
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "download file")
+	}
+	req.Header.Set("Authorization", "Bearer "+t.teamConfig.UserToken)
+	req.Header.Set("User-Agent", "marvin-slackbot (+https://github.com/riking/homeapi/tree/shocky)")
+	resp, err := t.apiClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "download file")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("download file: status %s", resp.Status)
+	}
+	b, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, errors.Wrap(err, "download file")
+	}
+	if int64(len(b)) > maxSize {
+		return nil, marvin.ErrFileTooLarge{Size: int64(len(b)), Limit: maxSize}
+	}
+	return b, nil
+}
+
+// OnFileShared calls the function with the event and the file metadata for
+// every file_shared event.
+func (t *Team) OnFileShared(mod marvin.ModuleID, f func(ev *slack.FileSharedEvent, file *slack.File)) marvin.EventHandle {
+	return t.onTypedEvent(mod, func(e slack.Event) {
+		ev, ok := e.(*slack.FileSharedEvent)
+		if !ok {
+			wrongEventType(e, ev)
+			return
+		}
+		file, err := t.FileInfo(ev.FileID)
+		if err != nil {
+			util.LogError(errors.Wrapf(err, "file_shared: info for %s", ev.FileID))
+			return
+		}
+		f(ev, file)
+	}, "file_shared")
+}
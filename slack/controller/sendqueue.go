@@ -0,0 +1,199 @@
+package controller
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/riking/marvin/slack"
+	"github.com/riking/marvin/util"
+)
+
+// sendInterval is the time between two messages to the same channel. Slack
+// allows about one message per second per channel.
+const sendInterval = 1 * time.Second
+
+// sendWaitTimeout is how long SendMessage and SendComplexMessage wait for
+// their message to be sent, including the time spent in the queue.
+const sendWaitTimeout = 2 * time.Minute
+
+type sendResult struct {
+	ts  slack.MessageTS
+	raw slack.RTMRawMessage
+	err error
+}
+
+type outgoingMessage struct {
+	message slack.OutgoingSlackMessage
+	// viaRTM is set for messages from SendMessage.
+	viaRTM bool
+	// mergeable messages are plain text from QueueMessage, and can be sent
+	// together with the mergeable messages queued after them.
+	mergeable bool
+	// result is nil for messages from QueueMessage.
+	result chan sendResult
+}
+
+type channelQueue struct {
+	pending  []*outgoingMessage
+	running  bool
+	lastSend time.Time
+}
+
+// sendQueue keeps the messages for each channel in order and paces them.
+// A channel's queue is removed once it has been idle for an interval.
+type sendQueue struct {
+	lock     sync.Mutex
+	channels map[slack.ChannelID]*channelQueue
+
+	interval time.Duration
+	send     func(channel slack.ChannelID, m *outgoingMessage) sendResult
+}
+
+func (q *sendQueue) init(send func(channel slack.ChannelID, m *outgoingMessage) sendResult) {
+	q.channels = make(map[slack.ChannelID]*channelQueue)
+	q.interval = sendInterval
+	q.send = send
+}
+
+// sendQueued sends a message with sendMessageNow or sendComplexMessageNow.
+func (t *Team) sendQueued(channel slack.ChannelID, m *outgoingMessage) sendResult {
+	var res sendResult
+	if m.viaRTM {
+		res.ts, res.raw, res.err = t.sendMessageNow(channel, m.message.Text)
+	} else {
+		res.ts, res.raw, res.err = t.sendComplexMessageNow(channel, m.message)
+	}
+	return res
+}
+
+func (q *sendQueue) enqueue(channel slack.ChannelID, m *outgoingMessage) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	cq := q.channels[channel]
+	if cq == nil {
+		cq = &channelQueue{}
+		q.channels[channel] = cq
+	}
+	cq.pending = append(cq.pending, m)
+	if !cq.running {
+		cq.running = true
+		go q.run(channel, cq)
+	}
+}
+
+// wait waits for the result of a message with a result channel. If the
+// timeout passes first, a message still in the queue is removed so that it
+// is never sent.
+func (q *sendQueue) wait(channel slack.ChannelID, m *outgoingMessage, timeout time.Duration) sendResult {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case res := <-m.result:
+		return res
+	case <-timer.C:
+	}
+
+	q.lock.Lock()
+	removed := false
+	if cq := q.channels[channel]; cq != nil {
+		for i, v := range cq.pending {
+			if v == m {
+				cq.pending = append(cq.pending[:i:i], cq.pending[i+1:]...)
+				removed = true
+				break
+			}
+		}
+	}
+	q.lock.Unlock()
+
+	if removed {
+		return sendResult{err: errors.Errorf("message to %s was not sent: waited %v in the queue", channel, timeout)}
+	}
+	return sendResult{err: errors.Errorf("message to %s was sent, but no reply after %v", channel, timeout)}
+}
+
+// run sends the pending messages for a channel. It exits, removing the
+// channel's queue, when the queue has been empty for an interval.
+func (q *sendQueue) run(channel slack.ChannelID, cq *channelQueue) {
+	for {
+		q.lock.Lock()
+		if len(cq.pending) == 0 {
+			idle := cq.lastSend.Add(q.interval).Sub(time.Now())
+			if idle <= 0 {
+				cq.running = false
+				delete(q.channels, channel)
+				q.lock.Unlock()
+				return
+			}
+			// Keep the queue until the next message may be sent, so the
+			// pacing applies to messages queued in the meantime
+			q.lock.Unlock()
+			time.Sleep(idle)
+			continue
+		}
+		m := cq.pending[0]
+		cq.pending = cq.pending[1:]
+		if m.mergeable {
+			for len(cq.pending) > 0 && cq.pending[0].mergeable &&
+				len(m.message.Text)+1+len(cq.pending[0].message.Text) <= slack.MaxMessageLength {
+				m.message.Text = m.message.Text + "\n" + cq.pending[0].message.Text
+				cq.pending = cq.pending[1:]
+			}
+		}
+		wait := cq.lastSend.Add(q.interval).Sub(time.Now())
+		q.lock.Unlock()
+
+		if wait > 0 {
+			time.Sleep(wait)
+		}
+		res := q.send(channel, m)
+
+		q.lock.Lock()
+		cq.lastSend = time.Now()
+		q.lock.Unlock()
+
+		if m.result != nil {
+			m.result <- res
+		} else if res.err != nil {
+			util.LogError(res.err)
+		}
+	}
+}
+
+// depth returns the number of messages waiting to be sent to the channel.
+func (q *sendQueue) depth(channel slack.ChannelID) int {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	cq := q.channels[channel]
+	if cq == nil {
+		return 0
+	}
+	return len(cq.pending)
+}
+
+// QueueMessage sends a message to the channel without waiting for it. Plain
+// text messages may be merged with other queued messages to the same
+// channel.
+func (t *Team) QueueMessage(channel slack.ChannelID, message slack.OutgoingSlackMessage) {
+	t.sendQueue.enqueue(channel, &outgoingMessage{
+		message:   message,
+		mergeable: isPlainMessage(message),
+	})
+}
+
+// QueueDepth returns the number of messages waiting to be sent to the
+// channel.
+func (t *Team) QueueDepth(channel slack.ChannelID) int {
+	return t.sendQueue.depth(channel)
+}
+
+func isPlainMessage(m slack.OutgoingSlackMessage) bool {
+	return m.Text != "" && m.ThreadTS == "" && len(m.Blocks) == 0 && len(m.Attachments) == 0 &&
+		m.UnfurlLinks == util.TriDefault && m.UnfurlMedia == util.TriDefault &&
+		m.Parse == "" && m.LinkNames == util.TriDefault && m.Markdown == util.TriDefault
+}
@@ -0,0 +1,168 @@
+package controller
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/riking/marvin/slack"
+)
+
+// fakeSender records the messages sent through a sendQueue.
+type fakeSender struct {
+	lock  sync.Mutex
+	texts []string
+	times []time.Time
+	block chan struct{}
+}
+
+func (f *fakeSender) send(channel slack.ChannelID, m *outgoingMessage) sendResult {
+	if f.block != nil {
+		<-f.block
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.texts = append(f.texts, m.message.Text)
+	f.times = append(f.times, time.Now())
+	return sendResult{ts: slack.MessageTS(m.message.Text)}
+}
+
+func (f *fakeSender) sent() ([]string, []time.Time) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return append([]string(nil), f.texts...), append([]time.Time(nil), f.times...)
+}
+
+func newTestQueue(f *fakeSender, interval time.Duration) *sendQueue {
+	q := &sendQueue{}
+	q.init(f.send)
+	q.interval = interval
+	return q
+}
+
+// waitIdle waits for the channel's queue to be removed.
+func waitIdle(t *testing.T, q *sendQueue, channel slack.ChannelID) {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		q.lock.Lock()
+		cq := q.channels[channel]
+		q.lock.Unlock()
+		if cq == nil {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("queue was not removed")
+}
+
+func TestSendQueueMerge(t *testing.T) {
+	f := &fakeSender{block: make(chan struct{})}
+	q := newTestQueue(f, time.Millisecond)
+	ch := slack.ChannelID("C1")
+
+	plain := func(text string) *outgoingMessage {
+		return &outgoingMessage{message: slack.OutgoingSlackMessage{Text: text}, mergeable: true}
+	}
+	// "c\n" + long is exactly the length limit
+	long := strings.Repeat("x", slack.MaxMessageLength-2)
+
+	// The first message is taken right away; the rest wait behind it
+	q.enqueue(ch, plain("first"))
+	time.Sleep(20 * time.Millisecond)
+	q.enqueue(ch, plain("a"))
+	q.enqueue(ch, plain("b"))
+	reply := &outgoingMessage{message: slack.OutgoingSlackMessage{Text: "reply"}, result: make(chan sendResult, 1)}
+	q.enqueue(ch, reply)
+	q.enqueue(ch, plain("c"))
+	q.enqueue(ch, plain(long))
+	q.enqueue(ch, plain("d"))
+	if n := q.depth(ch); n != 6 {
+		t.Errorf("expected 6 queued messages, got %d", n)
+	}
+	close(f.block)
+
+	res := q.wait(ch, reply, 5*time.Second)
+	if res.err != nil || res.ts != "reply" {
+		t.Errorf("wrong result for the waiting message: %+v", res)
+	}
+	waitIdle(t, q, ch)
+
+	texts, _ := f.sent()
+	// Messages with a result are never merged, and merging stops at the
+	// length limit
+	expect := []string{"first", "a\nb", "reply", "c\n" + long, "d"}
+	if len(texts) != len(expect) {
+		t.Fatalf("expected %d messages, got %d: %q", len(expect), len(texts), texts)
+	}
+	for i := range expect {
+		if texts[i] != expect[i] {
+			t.Errorf("message %d: expected %.20q, got %.20q", i, expect[i], texts[i])
+		}
+	}
+}
+
+func TestSendQueuePacing(t *testing.T) {
+	f := &fakeSender{}
+	interval := 30 * time.Millisecond
+	q := newTestQueue(f, interval)
+
+	for _, ch := range []slack.ChannelID{"C1", "C2"} {
+		for _, text := range []string{"1", "2", "3"} {
+			q.enqueue(ch, &outgoingMessage{message: slack.OutgoingSlackMessage{Text: string(ch) + text}})
+		}
+	}
+	waitIdle(t, q, "C1")
+	waitIdle(t, q, "C2")
+
+	texts, times := f.sent()
+	if len(texts) != 6 {
+		t.Fatalf("expected 6 messages, got %q", texts)
+	}
+	last := make(map[byte]time.Time)
+	next := map[byte]byte{'1': '1', '2': '1'}
+	for i, text := range texts {
+		ch := text[1]
+		if text[2] != next[ch] {
+			t.Errorf("channel C%c: out of order: %q", ch, texts)
+		}
+		next[ch]++
+		if prev, ok := last[ch]; ok && times[i].Sub(prev) < interval {
+			t.Errorf("channel C%c: messages %v apart, expected at least %v", ch, times[i].Sub(prev), interval)
+		}
+		last[ch] = times[i]
+	}
+	// Different channels are not paced against each other
+	if d := times[1].Sub(times[0]); d >= interval {
+		t.Errorf("channels were paced together: %v apart", d)
+	}
+}
+
+func TestSendQueueTimeout(t *testing.T) {
+	f := &fakeSender{block: make(chan struct{})}
+	q := newTestQueue(f, time.Millisecond)
+	ch := slack.ChannelID("C1")
+
+	first := &outgoingMessage{message: slack.OutgoingSlackMessage{Text: "first"}, result: make(chan sendResult, 1)}
+	second := &outgoingMessage{message: slack.OutgoingSlackMessage{Text: "second"}, result: make(chan sendResult, 1)}
+	q.enqueue(ch, first)
+	q.enqueue(ch, second)
+
+	// first is being sent, second is still queued
+	if res := q.wait(ch, second, 20*time.Millisecond); res.err == nil {
+		t.Error("expected a timeout error")
+	}
+	if n := q.depth(ch); n != 0 {
+		t.Errorf("timed out message was not removed from the queue: depth %d", n)
+	}
+	if res := q.wait(ch, first, 20*time.Millisecond); res.err == nil {
+		t.Error("expected a timeout error")
+	}
+	close(f.block)
+	waitIdle(t, q, ch)
+
+	texts, _ := f.sent()
+	if len(texts) != 1 || texts[0] != "first" {
+		t.Errorf("expected only the first message to be sent, got %q", texts)
+	}
+}
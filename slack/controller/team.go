@@ -34,6 +34,12 @@ type Team struct {
 	confLock sync.Mutex
 	confMap  map[marvin.ModuleID]marvin.ModuleConfig
 
+	observersLock sync.Mutex
+	observers     []commandObserver
+
+	sendQueue sendQueue
+	apiClient marvin.HTTPDoer
+
 	outerHttp http.Handler
 	httpMux   *mux.Router
 	httpStrip string
@@ -49,6 +55,14 @@ func NewTeam(cfg *marvin.TeamConfig) (*Team, error) {
 	if err != nil {
 		return nil, err
 	}
+	err = rtm.MigrateCache(db)
+	if err != nil {
+		return nil, err
+	}
+	err = rtm.MigratePresence(db)
+	if err != nil {
+		return nil, err
+	}
 
 	t := &Team{
 		teamConfig: cfg,
@@ -58,7 +72,9 @@ func NewTeam(cfg *marvin.TeamConfig) (*Team, error) {
 		modules:    nil,
 		confMap:    make(map[marvin.ModuleID]marvin.ModuleConfig),
 		httpMux:    mux.NewRouter(),
+		apiClient:  http.DefaultClient,
 	}
+	t.sendQueue.init(t.sendQueued)
 
 	u, err := url.Parse(cfg.HTTPURL)
 	if err != nil {
@@ -78,6 +94,16 @@ func (t *Team) ConnectRTM(c *rtm.Client) {
 	t.client = c
 }
 
+// SetAPIClient replaces the HTTP client used for Slack API calls, for
+// recording or replaying them.
+func (t *Team) SetAPIClient(d marvin.HTTPDoer) {
+	t.apiClient = d
+}
+
+func (t *Team) APIClient() marvin.HTTPDoer {
+	return t.apiClient
+}
+
 func (t *Team) EnableModules() bool {
 	t.ModuleConfig("modules").(interface {
 		marvin.ModuleConfig
@@ -181,23 +207,91 @@ func (t *Team) UnregisterCommand(name string) {
 
 func (t *Team) DispatchCommand(args *marvin.CommandArguments) marvin.CommandResult {
 	var result marvin.CommandResult
+	start := time.Now()
 	err := util.PCall(func() error {
 		result = t.commands.Handle(t, args)
 		return nil
 	})
 	if err != nil {
-		return marvin.CmdError(args, err, "Runtime error")
+		result = marvin.CmdError(args, err, "Runtime error")
 	}
+	t.ReportCommand(args, result, time.Since(start))
 	return result
 }
 
+type commandObserver struct {
+	Module marvin.ModuleID
+	Cb     marvin.CommandObserver
+}
+
+func (t *Team) OnCommand(mod marvin.ModuleID, f marvin.CommandObserver) {
+	t.observersLock.Lock()
+	defer t.observersLock.Unlock()
+	t.observers = append(t.observers, commandObserver{Module: mod, Cb: f})
+}
+
+func (t *Team) ReportCommand(args *marvin.CommandArguments, result marvin.CommandResult, elapsed time.Duration) {
+	t.observersLock.Lock()
+	observers := t.observers
+	t.observersLock.Unlock()
+
+	for _, v := range observers {
+		err := util.PCall(func() error {
+			v.Cb(args, result, elapsed)
+			return nil
+		})
+		if err != nil {
+			util.LogError(errors.Wrapf(err, "command observer for %s", v.Module))
+		}
+	}
+}
+
 func (t *Team) Help(args *marvin.CommandArguments) marvin.CommandResult {
 	return t.commands.Help(t, args)
 }
 
+func (t *Team) CommandTree() []*marvin.CommandInfo {
+	return t.commands.CommandTree(t, nil)
+}
+
 // ---
 
+// SendMessage sends a message over the RTM connection. It waits for the
+// messages queued before it to be sent first, and gives up after
+// sendWaitTimeout.
 func (t *Team) SendMessage(channel slack.ChannelID, message string) (slack.MessageTS, slack.RTMRawMessage, error) {
+	m := &outgoingMessage{
+		message: slack.OutgoingSlackMessage{Text: message},
+		viaRTM:  true,
+		result:  make(chan sendResult, 1),
+	}
+	t.sendQueue.enqueue(channel, m)
+	res := t.sendQueue.wait(channel, m, sendWaitTimeout)
+	return res.ts, res.raw, res.err
+}
+
+// SendComplexMessage sends a message with chat.postMessage. It waits for the
+// messages queued before it to be sent first, and gives up after
+// sendWaitTimeout.
+func (t *Team) SendComplexMessage(channelID slack.ChannelID, message slack.OutgoingSlackMessage) (slack.MessageTS, slack.RTMRawMessage, error) {
+	m := &outgoingMessage{
+		message: message,
+		result:  make(chan sendResult, 1),
+	}
+	t.sendQueue.enqueue(channelID, m)
+	res := t.sendQueue.wait(channelID, m, sendWaitTimeout)
+	return res.ts, res.raw, res.err
+}
+
+func (t *Team) sendMessageNow(channel slack.ChannelID, message string) (slack.MessageTS, slack.RTMRawMessage, error) {
+	if len(message) > slack.MaxMessageLength {
+		var snippet *slack.File
+		message, snippet = t.shortenMessage(channel, message)
+		if snippet != nil {
+			ts := snippet.ShareTS(channel)
+			return ts, slack.RTMRawMessage{"channel": string(channel), "ts": string(ts)}, nil
+		}
+	}
 	msg, err := t.client.SendMessage(channel, message)
 	if err != nil {
 		return "", msg, err
@@ -205,7 +299,74 @@ func (t *Team) SendMessage(channel slack.ChannelID, message string) (slack.Messa
 	return msg.MessageTS(), msg, err
 }
 
-func (t *Team) SendComplexMessage(channelID slack.ChannelID, message slack.OutgoingSlackMessage) (slack.MessageTS, slack.RTMRawMessage, error) {
+func (t *Team) SendEphemeral(channelID slack.ChannelID, userID slack.UserID, message string) (slack.MessageTS, error) {
+	if len(message) > slack.MaxMessageLength {
+		message, _ = t.shortenMessage("", message)
+	}
+	form := url.Values{
+		"channel": []string{string(channelID)},
+		"user":    []string{string(userID)},
+		"text":    []string{message},
+		"as_user": []string{"true"},
+		"parse":   []string{"client"},
+	}
+	var resp struct {
+		MessageTS slack.MessageTS `json:"message_ts"`
+	}
+	err := t.SlackAPIPostJSON("chat.postEphemeral", form, &resp)
+	if err != nil {
+		return "", err
+	}
+	return resp.MessageTS, nil
+}
+
+// longReplyAPI is implemented by the paste module.
+type longReplyAPI interface {
+	StoreLongReply(channel slack.ChannelID, command, message string) (string, *slack.File, error)
+}
+
+// shortenMessage replaces a message that is too long for Slack with a
+// preview and a link to the full text. If the message was shared to the
+// channel as a snippet instead, the snippet is returned and nothing else
+// should be sent. The channel is empty if the message has to stay a
+// message.
+func (t *Team) shortenMessage(channel slack.ChannelID, message string) (string, *slack.File) {
+	ms := t.GetModuleStatus("paste")
+	if ms != nil && ms.IsEnabled() {
+		link, snippet, err := ms.Instance().(longReplyAPI).StoreLongReply(channel, "", message)
+		if err != nil {
+			util.LogError(errors.Wrap(err, "storing long message"))
+		} else if snippet != nil {
+			return "", snippet
+		} else if link != "" {
+			return marvin.LongReplyText(message, link), nil
+		}
+	}
+	return "[Message too long]\n" + util.PreviewString(message, marvin.LongReplyCut*10) + "…", nil
+}
+
+func (t *Team) sendComplexMessageNow(channelID slack.ChannelID, message slack.OutgoingSlackMessage) (slack.MessageTS, slack.RTMRawMessage, error) {
+	form, err := messageForm(channelID, message)
+	if err != nil {
+		return "", nil, err
+	}
+	if message.ThreadTS != "" {
+		form.Set("thread_ts", string(message.ThreadTS))
+	}
+
+	var resp struct {
+		TS      slack.MessageTS `json:"ts"`
+		Channel slack.ChannelID `json:"channel"`
+	}
+	err = t.SlackAPIPostJSON("chat.postMessage", form, &resp)
+	if err != nil {
+		return "", nil, err
+	}
+	return resp.TS, nil, err
+}
+
+// messageForm builds the form for chat.postMessage and chat.update.
+func messageForm(channelID slack.ChannelID, message slack.OutgoingSlackMessage) (url.Values, error) {
 	form := url.Values{
 		"channel": []string{string(channelID)},
 		"as_user": []string{"true"},
@@ -213,32 +374,41 @@ func (t *Team) SendComplexMessage(channelID slack.ChannelID, message slack.Outgo
 
 	if message.Text != "" {
 		form.Set("text", message.Text)
+	} else if len(message.Blocks) > 0 {
+		form.Set("text", slack.BlocksFallback(message.Blocks))
+	}
+	if len(message.Blocks) > 0 {
+		b, err := json.Marshal(message.Blocks)
+		if err != nil {
+			return nil, errors.Wrap(err, "building messsage")
+		}
+		form.Set("blocks", string(b))
 	}
 	if message.Attachments != nil {
 		b, err := json.Marshal(message.Attachments)
 		if err != nil {
-			return "", nil, errors.Wrap(err, "building messsage")
+			return nil, errors.Wrap(err, "building messsage")
 		}
 		form.Set("attachments", string(b))
 	}
 	if message.LinkNames != util.TriDefault {
 		b, err := message.LinkNames.MarshalJSON()
 		if err != nil {
-			return "", nil, errors.Wrap(err, "building messsage")
+			return nil, errors.Wrap(err, "building messsage")
 		}
 		form.Set("link_names", string(b))
 	}
 	if message.UnfurlLinks != util.TriDefault {
 		b, err := message.UnfurlLinks.MarshalJSON()
 		if err != nil {
-			return "", nil, errors.Wrap(err, "building messsage")
+			return nil, errors.Wrap(err, "building messsage")
 		}
 		form.Set("unfurl_links", string(b))
 	}
 	if message.UnfurlMedia != util.TriDefault {
-		b, err := message.UnfurlLinks.MarshalJSON()
+		b, err := message.UnfurlMedia.MarshalJSON()
 		if err != nil {
-			return "", nil, errors.Wrap(err, "building messsage")
+			return nil, errors.Wrap(err, "building messsage")
 		}
 		form.Set("unfurl_media", string(b))
 	}
@@ -247,28 +417,68 @@ func (t *Team) SendComplexMessage(channelID slack.ChannelID, message slack.Outgo
 	} else {
 		form.Set("parse", "client")
 	}
-	if message.ThreadTS != "" {
-		form.Set("thread_ts", string(message.ThreadTS))
+	return form, nil
+}
+
+func (t *Team) ReactMessage(msgID slack.MessageID, emojiName string) error {
+	form := url.Values{
+		"name":      []string{emojiName},
+		"channel":   []string{string(msgID.ChannelID)},
+		"timestamp": []string{string(msgID.MessageTS)},
 	}
+	return t.SlackAPIPostJSON("reactions.add", form, nil)
+}
 
-	var resp struct {
-		TS      slack.MessageTS `json:"ts"`
-		Channel slack.ChannelID `json:"channel"`
+// UpdateMessage replaces the content of a message sent by the bot. A non-nil
+// empty Blocks removes the blocks of the message.
+func (t *Team) UpdateMessage(msgID slack.MessageID, message slack.OutgoingSlackMessage) error {
+	if len(message.Text) > slack.MaxMessageLength {
+		message.Text, _ = t.shortenMessage("", message.Text)
 	}
-	err := t.SlackAPIPostJSON("chat.postMessage", form, &resp)
+	form, err := messageForm(msgID.ChannelID, message)
 	if err != nil {
-		return "", nil, err
+		return err
 	}
-	return resp.TS, nil, err
+	form.Set("ts", string(msgID.MessageTS))
+	if message.Blocks != nil && len(message.Blocks) == 0 {
+		form.Set("blocks", "[]")
+	}
+	return t.SlackAPIPostJSON("chat.update", form, nil)
 }
 
-func (t *Team) ReactMessage(msgID slack.MessageID, emojiName string) error {
+// DeleteMessage deletes a message sent by the bot.
+func (t *Team) DeleteMessage(msgID slack.MessageID) error {
+	form := url.Values{
+		"channel": []string{string(msgID.ChannelID)},
+		"ts":      []string{string(msgID.MessageTS)},
+		"as_user": []string{"true"},
+	}
+	return t.SlackAPIPostJSON("chat.delete", form, nil)
+}
+
+func (t *Team) RemoveReaction(msgID slack.MessageID, emojiName string) error {
 	form := url.Values{
 		"name":      []string{emojiName},
 		"channel":   []string{string(msgID.ChannelID)},
 		"timestamp": []string{string(msgID.MessageTS)},
 	}
-	return t.SlackAPIPostJSON("reactions.add", form, nil)
+	return t.SlackAPIPostJSON("reactions.remove", form, nil)
+}
+
+func (t *Team) PinMessage(msgID slack.MessageID) error {
+	form := url.Values{
+		"channel":   []string{string(msgID.ChannelID)},
+		"timestamp": []string{string(msgID.MessageTS)},
+	}
+	return t.SlackAPIPostJSON("pins.add", form, nil)
+}
+
+func (t *Team) UnpinMessage(msgID slack.MessageID) error {
+	form := url.Values{
+		"channel":   []string{string(msgID.ChannelID)},
+		"timestamp": []string{string(msgID.MessageTS)},
+	}
+	return t.SlackAPIPostJSON("pins.remove", form, nil)
 }
 
 func (t *Team) SlackAPIPostRaw(method string, form url.Values) (*http.Response, error) {
@@ -290,7 +500,7 @@ func (t *Team) SlackAPIPostRaw(method string, form url.Values) (*http.Response,
 	}
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	req.Header.Set("User-Agent", "marvin-slackbot (+https://github.com/riking/homeapi/tree/shocky)")
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := t.apiClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -298,15 +508,21 @@ func (t *Team) SlackAPIPostRaw(method string, form url.Values) (*http.Response,
 }
 
 func (t *Team) SlackAPIPostJSON(method string, form url.Values, result interface{}) error {
-	var rawResponse json.RawMessage
-	var slackResponse slack.APIResponse
-
 	resp, err := t.SlackAPIPostRaw(method, form)
 	if err != nil {
 		util.LogBadf("Slack API %s error: %s", method, err)
 		return errors.Wrapf(err, "Slack API %s: connect", method)
 	}
-	err = json.NewDecoder(resp.Body).Decode(&rawResponse)
+	return t.decodeAPIResponse(method, form, resp, result)
+}
+
+// decodeAPIResponse checks the response of a Slack API call for errors and
+// decodes it into result, if not nil.
+func (t *Team) decodeAPIResponse(method string, form url.Values, resp *http.Response, result interface{}) error {
+	var rawResponse json.RawMessage
+	var slackResponse slack.APIResponse
+
+	err := json.NewDecoder(resp.Body).Decode(&rawResponse)
 	resp.Body.Close()
 	if err != nil {
 		util.LogBadf("Slack API %s error: %s", method, err)
@@ -344,26 +560,124 @@ func (t *Team) SlackAPIPostJSON(method string, form url.Values, result interface
 
 // ---
 
-func (t *Team) OnEveryEvent(mod marvin.ModuleID, f func(slack.RTMRawMessage)) {
-	t.client.RegisterRawHandler(mod, f, rtm.MsgTypeAll, nil)
+func (t *Team) OnEveryEvent(mod marvin.ModuleID, f func(slack.RTMRawMessage)) marvin.EventHandle {
+	return t.client.RegisterRawHandler(mod, f, rtm.MsgTypeAll, nil)
 }
 
-func (t *Team) OnEvent(mod marvin.ModuleID, event string, f func(slack.RTMRawMessage)) {
-	t.client.RegisterRawHandler(mod, f, event, nil)
+func (t *Team) OnEvent(mod marvin.ModuleID, event string, f func(slack.RTMRawMessage)) marvin.EventHandle {
+	return t.client.RegisterRawHandler(mod, f, event, nil)
 }
 
-func (t *Team) OnSpecialMessage(mod marvin.ModuleID, msgSubtype []string, f func(slack.RTMRawMessage)) {
-	t.client.RegisterRawHandler(mod, f, "message", msgSubtype)
+func (t *Team) OnSpecialMessage(mod marvin.ModuleID, msgSubtype []string, f func(slack.RTMRawMessage)) marvin.EventHandle {
+	return t.client.RegisterRawHandler(mod, f, "message", msgSubtype)
 }
 
 var _filterNoSubgroup = []string{""}
 
-func (t *Team) OnNormalMessage(mod marvin.ModuleID, f func(slack.RTMRawMessage)) {
-	t.client.RegisterRawHandler(mod, f, "message", _filterNoSubgroup)
+func (t *Team) OnNormalMessage(mod marvin.ModuleID, f func(slack.RTMRawMessage)) marvin.EventHandle {
+	return t.client.RegisterRawHandler(mod, f, "message", _filterNoSubgroup)
+}
+
+func (t *Team) OnFilteredEvent(mod marvin.ModuleID, filter marvin.EventFilter, f marvin.EventHandlerFunc) marvin.EventHandle {
+	return t.client.RegisterHandler(mod, filter, f)
+}
+
+// onTypedEvent registers a handler that receives the decoded form of the
+// events.
+func (t *Team) onTypedEvent(mod marvin.ModuleID, f func(slack.Event), eventTypes ...string) marvin.EventHandle {
+	handles := make(marvin.EventHandles, len(eventTypes))
+	for i, eventType := range eventTypes {
+		handles[i] = t.client.RegisterRawHandler(mod, func(msg slack.RTMRawMessage) {
+			ev, err := slack.DecodeEvent(msg)
+			if err != nil {
+				util.LogError(err)
+				return
+			}
+			f(ev)
+		}, eventType, nil)
+	}
+	return handles
+}
+
+// wrongEventType logs an event that was decoded into a different type than
+// the handler expects, which happens if RegisterEventType replaced the
+// decoder for its event type.
+func wrongEventType(ev slack.Event, expect slack.Event) {
+	util.LogError(errors.Errorf("%s event decoded as %T, not %T", ev.EventType(), ev, expect))
+}
+
+func (t *Team) OnMessage(mod marvin.ModuleID, f func(*slack.MessageEvent)) marvin.EventHandle {
+	return t.onTypedEvent(mod, func(e slack.Event) {
+		if ev, ok := e.(*slack.MessageEvent); ok {
+			f(ev)
+		} else {
+			wrongEventType(e, ev)
+		}
+	}, "message")
+}
+
+func (t *Team) OnReaction(mod marvin.ModuleID, f func(*slack.ReactionEvent)) marvin.EventHandle {
+	return t.onTypedEvent(mod, func(e slack.Event) {
+		if ev, ok := e.(*slack.ReactionEvent); ok {
+			f(ev)
+		} else {
+			wrongEventType(e, ev)
+		}
+	}, "reaction_added", "reaction_removed")
+}
+
+func (t *Team) OnMemberChannel(mod marvin.ModuleID, f func(*slack.MemberChannelEvent)) marvin.EventHandle {
+	return t.onTypedEvent(mod, func(e slack.Event) {
+		if ev, ok := e.(*slack.MemberChannelEvent); ok {
+			f(ev)
+		} else {
+			wrongEventType(e, ev)
+		}
+	}, "member_joined_channel", "member_left_channel")
+}
+
+func (t *Team) OnChannelRename(mod marvin.ModuleID, f func(*slack.ChannelRenameEvent)) marvin.EventHandle {
+	return t.onTypedEvent(mod, func(e slack.Event) {
+		if ev, ok := e.(*slack.ChannelRenameEvent); ok {
+			f(ev)
+		} else {
+			wrongEventType(e, ev)
+		}
+	}, "channel_rename", "group_rename")
+}
+
+func (t *Team) OnUserChange(mod marvin.ModuleID, f func(*slack.UserChangeEvent)) marvin.EventHandle {
+	return t.onTypedEvent(mod, func(e slack.Event) {
+		if ev, ok := e.(*slack.UserChangeEvent); ok {
+			f(ev)
+		} else {
+			wrongEventType(e, ev)
+		}
+	}, "user_change")
+}
+
+func (t *Team) OnPin(mod marvin.ModuleID, f func(*slack.PinEvent)) marvin.EventHandle {
+	return t.onTypedEvent(mod, func(e slack.Event) {
+		if ev, ok := e.(*slack.PinEvent); ok {
+			f(ev)
+		} else {
+			wrongEventType(e, ev)
+		}
+	}, "pin_added", "pin_removed")
 }
 
 func (t *Team) OffAllEvents(mod marvin.ModuleID) {
 	t.client.UnregisterAllMatching(mod)
+
+	t.observersLock.Lock()
+	defer t.observersLock.Unlock()
+	newObservers := make([]commandObserver, 0, len(t.observers))
+	for _, v := range t.observers {
+		if v.Module != mod {
+			newObservers = append(newObservers, v)
+		}
+	}
+	t.observers = newObservers
 }
 
 // ---
@@ -426,30 +740,14 @@ func (t *Team) AbsoluteURL(path string) string {
 
 // ---
 
+// ArchiveURL links to a message. The channel ID works in archive links for
+// every kind of conversation, so no channel lookup is needed.
 func (t *Team) ArchiveURL(msgID slack.MessageID) string {
 	channel := msgID.ChannelID
-	if channel[0] == 'D' {
-		return slack.ArchiveURL(t.teamConfig.TeamDomain, "", msgID)
-	}
-	if channel[0] == 'G' {
-		info, err := t.PrivateChannelInfo(channel)
-		if err != nil || info.IsMultiIM() {
-			return slack.ArchiveURL(t.teamConfig.TeamDomain, "", msgID)
-		} else {
-			return slack.ArchiveURL(t.teamConfig.TeamDomain, info.Name, msgID)
-		}
-	}
-	if channel[0] == 'C' {
-		info, err := t.PublicChannelInfo(channel)
-		if err != nil {
-			panic(errors.Wrap(err, "could not get info about public channel"))
-		}
-		return slack.ArchiveURL(t.teamConfig.TeamDomain, info.Name, msgID)
-	}
-	if channel[0] == '(' {
+	if channel != "" && channel[0] == '(' {
 		return string(channel) + string(msgID.MessageTS)
 	}
-	panic(errors.Errorf("Invalid channel id '%s' passed to ArchiveURL", channel))
+	return slack.ArchiveURL(t.teamConfig.TeamDomain, "", msgID)
 }
 
 func (t *Team) ReportError(err error, source marvin.ActionSource) {
@@ -0,0 +1,222 @@
+package slack
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// An Event is a decoded RTM event. Event types embed EventBase, which keeps
+// the raw message.
+type Event interface {
+	EventType() string
+	Raw() RTMRawMessage
+	setRaw(msg RTMRawMessage)
+}
+
+// EventBase holds the fields common to all events.
+type EventBase struct {
+	Type    string    `json:"type"`
+	EventTS MessageTS `json:"event_ts"`
+
+	raw RTMRawMessage
+}
+
+func (e *EventBase) EventType() string        { return e.Type }
+func (e *EventBase) Raw() RTMRawMessage       { return e.raw }
+func (e *EventBase) setRaw(msg RTMRawMessage) { e.raw = msg }
+
+// MessageEvent is a message event. Message edits and deletions are also
+// message events, with the subtypes message_changed and message_deleted.
+//
+// https://api.slack.com/events/message
+type MessageEvent struct {
+	EventBase
+	Subtype  string    `json:"subtype"`
+	Channel  ChannelID `json:"channel"`
+	User     UserID    `json:"user"`
+	BotID    string    `json:"bot_id"`
+	Text     string    `json:"text"`
+	TS       MessageTS `json:"ts"`
+	ThreadTS MessageTS `json:"thread_ts"`
+	Hidden   bool      `json:"hidden"`
+	Edited   *struct {
+		User UserID    `json:"user"`
+		TS   MessageTS `json:"ts"`
+	} `json:"edited"`
+	Files []File `json:"files"`
+
+	// message_changed
+	Message         *MessageEvent `json:"message"`
+	PreviousMessage *MessageEvent `json:"previous_message"`
+	// message_deleted
+	DeletedTS MessageTS `json:"deleted_ts"`
+}
+
+// MessageID returns the ID of the message. For message_changed events, this
+// is the ID of the edited message.
+func (m *MessageEvent) MessageID() MessageID {
+	if m.Message != nil {
+		return MsgID(m.Channel, m.Message.TS)
+	}
+	if m.DeletedTS != "" {
+		return MsgID(m.Channel, m.DeletedTS)
+	}
+	return MsgID(m.Channel, m.TS)
+}
+
+// ReactionItem is the target of a reaction or a pin.
+type ReactionItem struct {
+	Type string `json:"type"`
+	// type = message
+	Channel ChannelID `json:"channel"`
+	TS      MessageTS `json:"ts"`
+	// type = file, file_comment
+	File        FileID        `json:"file"`
+	FileComment FileCommentID `json:"file_comment"`
+}
+
+// ReactionEvent is a reaction_added or reaction_removed event.
+//
+// https://api.slack.com/events/reaction_added
+type ReactionEvent struct {
+	EventBase
+	User     UserID       `json:"user"`
+	Reaction string       `json:"reaction"`
+	ItemUser UserID       `json:"item_user"`
+	Item     ReactionItem `json:"item"`
+}
+
+// IsRemoval reports whether the reaction was removed.
+func (r *ReactionEvent) IsRemoval() bool { return r.Type == "reaction_removed" }
+
+// MessageID returns the message reacted to, if the item is a message.
+func (r *ReactionEvent) MessageID() MessageID { return MsgID(r.Item.Channel, r.Item.TS) }
+
+// MemberChannelEvent is a member_joined_channel or member_left_channel
+// event.
+//
+// https://api.slack.com/events/member_joined_channel
+type MemberChannelEvent struct {
+	EventBase
+	User        UserID    `json:"user"`
+	Channel     ChannelID `json:"channel"`
+	ChannelType string    `json:"channel_type"`
+	Team        TeamID    `json:"team"`
+	Inviter     UserID    `json:"inviter"`
+}
+
+// IsLeave reports whether the user left the channel.
+func (m *MemberChannelEvent) IsLeave() bool { return m.Type == "member_left_channel" }
+
+// ChannelRenameEvent is a channel_rename or group_rename event.
+//
+// https://api.slack.com/events/channel_rename
+type ChannelRenameEvent struct {
+	EventBase
+	Channel struct {
+		ID      ChannelID `json:"id"`
+		Name    string    `json:"name"`
+		Created int64     `json:"created"`
+	} `json:"channel"`
+}
+
+// UserChangeEvent is a user_change event.
+//
+// https://api.slack.com/events/user_change
+type UserChangeEvent struct {
+	EventBase
+	User *User `json:"user"`
+}
+
+// PinEvent is a pin_added or pin_removed event.
+//
+// https://api.slack.com/events/pin_added
+type PinEvent struct {
+	EventBase
+	User    UserID     `json:"user"`
+	Channel ChannelID  `json:"channel_id"`
+	Item    PinnedItem `json:"item"`
+}
+
+// IsRemoval reports whether the item was unpinned.
+func (p *PinEvent) IsRemoval() bool { return p.Type == "pin_removed" }
+
+// FileSharedEvent is a file_shared event. ChannelID is only present on some
+// events.
+//
+// https://api.slack.com/events/file_shared
+type FileSharedEvent struct {
+	EventBase
+	FileID    FileID    `json:"file_id"`
+	UserID    UserID    `json:"user_id"`
+	ChannelID ChannelID `json:"channel_id"`
+}
+
+// ---
+
+var (
+	eventDecodersLock sync.RWMutex
+	eventDecoders     = map[string]func() Event{}
+)
+
+// RegisterEventType sets the type DecodeEvent uses for an event type. The
+// function returns a new, empty event.
+func RegisterEventType(eventType string, newEvent func() Event) {
+	eventDecodersLock.Lock()
+	defer eventDecodersLock.Unlock()
+	eventDecoders[eventType] = newEvent
+}
+
+func init() {
+	RegisterEventType("message", func() Event { return &MessageEvent{} })
+	RegisterEventType("reaction_added", func() Event { return &ReactionEvent{} })
+	RegisterEventType("reaction_removed", func() Event { return &ReactionEvent{} })
+	RegisterEventType("member_joined_channel", func() Event { return &MemberChannelEvent{} })
+	RegisterEventType("member_left_channel", func() Event { return &MemberChannelEvent{} })
+	RegisterEventType("channel_rename", func() Event { return &ChannelRenameEvent{} })
+	RegisterEventType("group_rename", func() Event { return &ChannelRenameEvent{} })
+	RegisterEventType("user_change", func() Event { return &UserChangeEvent{} })
+	RegisterEventType("pin_added", func() Event { return &PinEvent{} })
+	RegisterEventType("pin_removed", func() Event { return &PinEvent{} })
+	RegisterEventType("file_shared", func() Event { return &FileSharedEvent{} })
+}
+
+// ErrUnknownEvent is returned by DecodeEvent for event types that have not
+// been registered.
+type ErrUnknownEvent struct {
+	Type string
+}
+
+func (e ErrUnknownEvent) Error() string {
+	return "no decoder for event type " + e.Type
+}
+
+// DecodeEvent decodes a raw event into the type registered for its event
+// type.
+func DecodeEvent(msg RTMRawMessage) (Event, error) {
+	eventDecodersLock.RLock()
+	newEvent := eventDecoders[msg.Type()]
+	eventDecodersLock.RUnlock()
+	if newEvent == nil {
+		return nil, ErrUnknownEvent{Type: msg.Type()}
+	}
+
+	ev := newEvent()
+	b := msg.Original()
+	if b == nil {
+		// Synthetic events don't have the original bytes
+		var err error
+		b, err = json.Marshal(msg)
+		if err != nil {
+			return nil, errors.Wrapf(err, "decode %s event", msg.Type())
+		}
+	}
+	err := json.Unmarshal(b, ev)
+	if err != nil {
+		return nil, errors.Wrapf(err, "decode %s event", msg.Type())
+	}
+	ev.setRaw(msg)
+	return ev, nil
+}
@@ -0,0 +1,120 @@
+package slack
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// rawEvent makes an RTMRawMessage the way the RTM reader does.
+func rawEvent(t *testing.T, data string) RTMRawMessage {
+	var msg RTMRawMessage
+	err := json.Unmarshal([]byte(data), &msg)
+	if err != nil {
+		t.Fatalf("bad test data %s: %s", data, err)
+	}
+	msg[MsgFieldRawBytes] = []byte(data)
+	return msg
+}
+
+func TestDecodeEvent(t *testing.T) {
+	msg := rawEvent(t, `{"type": "reaction_added", "user": "U1", "reaction": "thumbsup", "item_user": "U2",
+		"item": {"type": "message", "channel": "C1", "ts": "1500000000.000100"}, "event_ts": "1500000001.000200"}`)
+	ev, err := DecodeEvent(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reaction, ok := ev.(*ReactionEvent)
+	if !ok {
+		t.Fatalf("expected a *ReactionEvent, got %T", ev)
+	}
+	if reaction.EventType() != "reaction_added" || reaction.IsRemoval() ||
+		reaction.User != "U1" || reaction.Reaction != "thumbsup" || reaction.ItemUser != "U2" ||
+		reaction.EventTS != "1500000001.000200" {
+		t.Errorf("wrong fields: %+v", reaction)
+	}
+	if reaction.MessageID() != MsgID("C1", "1500000000.000100") {
+		t.Errorf("wrong message ID: %v", reaction.MessageID())
+	}
+	if reaction.Raw().Type() != "reaction_added" {
+		t.Errorf("raw message not kept")
+	}
+}
+
+func TestDecodeEventSynthetic(t *testing.T) {
+	// Events made by the bot have no raw bytes
+	msg := RTMRawMessage{"type": "member_left_channel", "user": "U1", "channel": "C1"}
+	ev, err := DecodeEvent(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	member, ok := ev.(*MemberChannelEvent)
+	if !ok {
+		t.Fatalf("expected a *MemberChannelEvent, got %T", ev)
+	}
+	if !member.IsLeave() || member.User != "U1" || member.Channel != "C1" {
+		t.Errorf("wrong fields: %+v", member)
+	}
+}
+
+func TestDecodeEventMessageSubtypes(t *testing.T) {
+	tests := []struct {
+		data    string
+		subtype string
+		id      MessageID
+		text    string
+	}{
+		// No subtype field
+		{`{"type": "message", "channel": "C1", "user": "U1", "text": "hi", "ts": "1.1"}`,
+			"", MsgID("C1", "1.1"), "hi"},
+		{`{"type": "message", "subtype": "me_message", "channel": "C1", "user": "U1", "text": "waves", "ts": "1.2"}`,
+			"me_message", MsgID("C1", "1.2"), "waves"},
+		{`{"type": "message", "subtype": "message_changed", "channel": "C1", "ts": "1.4",
+			"message": {"type": "message", "user": "U1", "text": "edited", "ts": "1.3", "edited": {"user": "U1", "ts": "1.4"}}}`,
+			"message_changed", MsgID("C1", "1.3"), ""},
+		// message_changed without the message falls back to the event
+		{`{"type": "message", "subtype": "message_changed", "channel": "C1", "ts": "1.5"}`,
+			"message_changed", MsgID("C1", "1.5"), ""},
+		{`{"type": "message", "subtype": "message_deleted", "channel": "C1", "ts": "1.6", "deleted_ts": "1.1"}`,
+			"message_deleted", MsgID("C1", "1.1"), ""},
+	}
+	for _, v := range tests {
+		ev, err := DecodeEvent(rawEvent(t, v.data))
+		if err != nil {
+			t.Errorf("%s: %s", v.data, err)
+			continue
+		}
+		m, ok := ev.(*MessageEvent)
+		if !ok {
+			t.Errorf("%s: expected a *MessageEvent, got %T", v.data, ev)
+			continue
+		}
+		if m.Subtype != v.subtype || m.MessageID() != v.id || m.Text != v.text {
+			t.Errorf("%s: expected %q %v %q, got %q %v %q", v.data, v.subtype, v.id, v.text, m.Subtype, m.MessageID(), m.Text)
+		}
+	}
+}
+
+func TestDecodeEventErrors(t *testing.T) {
+	// Unknown and missing types
+	for _, msg := range []RTMRawMessage{
+		rawEvent(t, `{"type": "no_such_event"}`),
+		rawEvent(t, `{"text": "no type"}`),
+	} {
+		_, err := DecodeEvent(msg)
+		if _, ok := err.(ErrUnknownEvent); !ok {
+			t.Errorf("%v: expected ErrUnknownEvent, got %v", msg, err)
+		}
+	}
+
+	// Bad JSON
+	for _, v := range []struct{ eventType, data string }{
+		{"message", `{"type": "message", "ts": 12}`},
+		{"pin_added", `{"type": "pin_added", "item": "not an object"}`},
+		{"message", `{"type": "message", "text": "trunc`},
+	} {
+		msg := RTMRawMessage{"type": v.eventType, MsgFieldRawBytes: []byte(v.data)}
+		if _, err := DecodeEvent(msg); err == nil {
+			t.Errorf("%s: expected an error", v.data)
+		}
+	}
+}
@@ -0,0 +1,549 @@
+package rtm
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/riking/marvin/database"
+	"github.com/riking/marvin/slack"
+	"github.com/riking/marvin/util"
+)
+
+// The users and channels of the team are saved to the database, so that
+// names and access levels are available right after a restart instead of
+// once the Slack lists have been fetched again.
+
+// cacheRefreshInterval is how often the lists are fetched from Slack again,
+// in case an event was missed.
+const cacheRefreshInterval = 6 * time.Hour
+
+func MigrateCache(c *database.Conn) error {
+	err := c.Migrate("slack_cache", 1508342400,
+		`CREATE TABLE slack_users (
+			id         varchar(15) PRIMARY KEY, -- slack.UserID
+			data       JSONB       NOT NULL,
+			updated_at TIMESTAMPTZ NOT NULL
+		)`,
+		`CREATE TABLE slack_channels (
+			id         varchar(15) PRIMARY KEY, -- slack.ChannelID
+			data       JSONB       NOT NULL,
+			updated_at TIMESTAMPTZ NOT NULL
+		)`,
+	)
+	if err != nil {
+		return err
+	}
+	err = c.Migrate("slack_cache", 1508428800,
+		`CREATE TABLE slack_cache_refresh (
+			id           boolean     PRIMARY KEY DEFAULT TRUE CHECK (id), -- only one row
+			refreshed_at TIMESTAMPTZ NOT NULL
+		)`,
+	)
+	c.SyntaxCheck(
+		sqlCacheLoadUsers,
+		sqlCacheLoadChannels,
+		sqlCacheSaveUser,
+		sqlCacheSaveChannel,
+		sqlCacheDeleteChannel,
+		sqlCachePruneChannels,
+		sqlCacheCount,
+		sqlCacheLoadRefresh,
+		sqlCacheSaveRefresh,
+	)
+	return err
+}
+
+const (
+	sqlCacheLoadUsers = `SELECT data, updated_at FROM slack_users`
+
+	sqlCacheLoadChannels = `SELECT data, updated_at FROM slack_channels`
+
+	// $1 = id $2 = data $3 = updated_at
+	sqlCacheSaveUser = `
+	INSERT INTO slack_users (id, data, updated_at)
+	VALUES ($1, $2::jsonb, $3)
+	ON CONFLICT (id) DO UPDATE
+	SET data = excluded.data, updated_at = excluded.updated_at`
+
+	// $1 = id $2 = data $3 = updated_at
+	sqlCacheSaveChannel = `
+	INSERT INTO slack_channels (id, data, updated_at)
+	VALUES ($1, $2::jsonb, $3)
+	ON CONFLICT (id) DO UPDATE
+	SET data = excluded.data, updated_at = excluded.updated_at`
+
+	// $1 = id
+	sqlCacheDeleteChannel = `DELETE FROM slack_channels WHERE id = $1`
+
+	// $1 = refresh start time
+	sqlCachePruneChannels = `
+	DELETE FROM slack_channels
+	WHERE updated_at < $1`
+
+	sqlCacheCount = `
+	SELECT (SELECT COUNT(*) FROM slack_users), (SELECT COUNT(*) FROM slack_channels)`
+
+	sqlCacheLoadRefresh = `SELECT refreshed_at FROM slack_cache_refresh`
+
+	// $1 = refreshed_at
+	sqlCacheSaveRefresh = `
+	INSERT INTO slack_cache_refresh (id, refreshed_at)
+	VALUES (TRUE, $1)
+	ON CONFLICT (id) DO UPDATE
+	SET refreshed_at = excluded.refreshed_at`
+)
+
+// CacheStats describes the state of the user and channel cache.
+type CacheStats struct {
+	Users    int
+	Channels int
+	Groups   int
+	// StoredUsers and StoredChannels are the row counts in the database.
+	StoredUsers    int
+	StoredChannels int
+
+	// LoadedAt is when the cache was read from the database.
+	LoadedAt time.Time
+	// Updates is the number of objects changed by events or API calls
+	// since startup.
+	Updates int64
+	// WriteErrors is the number of failed database writes.
+	WriteErrors int64
+
+	Refreshing      bool
+	LastRefresh     time.Time
+	RefreshDuration time.Duration
+	RefreshError    error
+}
+
+type cacheState struct {
+	loadedAt time.Time
+	// storedRefresh is when the last successful refresh before startup
+	// started.
+	storedRefresh   time.Time
+	updates         int64
+	writeErrors     int64
+	refreshing      bool
+	lastRefresh     time.Time
+	refreshDuration time.Duration
+	refreshError    error
+}
+
+// loadCache fills the user and channel lists from the database.
+func (c *Client) loadCache() {
+	users, err := c.loadCachedUsers()
+	if err != nil {
+		util.LogError(errors.Wrap(err, "load user cache"))
+	}
+	channels, groups, err := c.loadCachedChannels()
+	if err != nil {
+		util.LogError(errors.Wrap(err, "load channel cache"))
+	}
+	refreshed, err := c.loadRefreshTime()
+	if err != nil {
+		util.LogError(errors.Wrap(err, "load cache refresh time"))
+	}
+	c.setLoadedCache(users, channels, groups, refreshed)
+	util.LogGood("Loaded", len(users), "users and", len(channels)+len(groups), "channels from the database")
+}
+
+// setLoadedCache fills the lists with the objects loaded from the
+// database. refreshed is the time of the last successful refresh that
+// saved them.
+//
+// The times of single channels can't be used instead, as channels are also
+// saved when they are created, joined, or looked up.
+func (c *Client) setLoadedCache(users []*slack.User, channels, groups []*slack.Channel, refreshed time.Time) {
+	c.MetadataLock.Lock()
+	if len(c.Users) == 0 {
+		c.Users = users
+	}
+	if len(c.Channels) == 0 {
+		c.Channels = channels
+	}
+	if len(c.Groups) == 0 {
+		c.Groups = groups
+	}
+	c.MetadataLock.Unlock()
+
+	c.membershipCh <- membershipRequest{
+		C: make(chan interface{}, 1),
+		F: c.rebuildMembershipMapFunc(append(append([]*slack.Channel(nil), channels...), groups...)),
+	}
+
+	c.cacheLock.Lock()
+	c.cache.loadedAt = time.Now()
+	if len(users) > 0 {
+		c.cache.storedRefresh = refreshed
+	}
+	c.cacheLock.Unlock()
+}
+
+// loadRefreshTime gets the time of the last successful refresh, or the
+// zero time if there wasn't one.
+func (c *Client) loadRefreshTime() (time.Time, error) {
+	var refreshed time.Time
+	err := c.team.DB().QueryRow(sqlCacheLoadRefresh).Scan(&refreshed)
+	if err == sql.ErrNoRows {
+		return time.Time{}, nil
+	} else if err != nil {
+		return time.Time{}, err
+	}
+	return refreshed, nil
+}
+
+// saveRefreshTime records a successful refresh that started at start.
+func (c *Client) saveRefreshTime(start time.Time) {
+	_, err := c.team.DB().Exec(sqlCacheSaveRefresh, start)
+	if err != nil {
+		c.cacheWriteError(err)
+	}
+}
+
+func (c *Client) loadCachedUsers() ([]*slack.User, error) {
+	rows, err := c.team.DB().Query(sqlCacheLoadUsers)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []*slack.User
+	for rows.Next() {
+		var data []byte
+		var updated time.Time
+		err = rows.Scan(&data, &updated)
+		if err != nil {
+			return users, err
+		}
+		u, err := decodeCachedUser(data, updated)
+		if err != nil {
+			util.LogError(err)
+			continue
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+// decodeCachedUser decodes a row of slack_users.
+func decodeCachedUser(data []byte, updated time.Time) (*slack.User, error) {
+	u := new(slack.User)
+	err := json.Unmarshal(data, u)
+	if err != nil {
+		return nil, errors.Wrap(err, "decode cached user")
+	}
+	u.CacheTS = updated
+	return u, nil
+}
+
+// decodeCachedChannel decodes a row of slack_channels. It returns nil for
+// rows without a channel ID.
+func decodeCachedChannel(data []byte, updated time.Time) (*slack.Channel, error) {
+	ch := new(slack.Channel)
+	err := json.Unmarshal(data, ch)
+	if err != nil {
+		return nil, errors.Wrap(err, "decode cached channel")
+	}
+	if ch.ID == "" {
+		return nil, nil
+	}
+	ch.CacheTS = updated
+	return ch, nil
+}
+
+func (c *Client) loadCachedChannels() (channels, groups []*slack.Channel, err error) {
+	rows, err := c.team.DB().Query(sqlCacheLoadChannels)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var data []byte
+		var updated time.Time
+		err = rows.Scan(&data, &updated)
+		if err != nil {
+			return channels, groups, err
+		}
+		ch, err := decodeCachedChannel(data, updated)
+		if err != nil {
+			util.LogError(err)
+			continue
+		} else if ch == nil {
+			continue
+		}
+		if ch.IsPublicChannel() {
+			channels = append(channels, ch)
+		} else {
+			groups = append(groups, ch)
+		}
+	}
+	return channels, groups, rows.Err()
+}
+
+func (c *Client) cacheWriteError(err error) {
+	util.LogError(errors.Wrap(err, "save to slack cache"))
+	c.cacheLock.Lock()
+	c.cache.writeErrors++
+	c.cacheLock.Unlock()
+}
+
+func (c *Client) saveUsers(users ...*slack.User) {
+	stmt, err := c.team.DB().Prepare(sqlCacheSaveUser)
+	if err != nil {
+		c.cacheWriteError(err)
+		return
+	}
+	defer stmt.Close()
+
+	for _, u := range users {
+		data, err := json.Marshal(u)
+		if err != nil {
+			c.cacheWriteError(err)
+			continue
+		}
+		_, err = stmt.Exec(string(u.ID), data, u.CacheTS)
+		if err != nil {
+			c.cacheWriteError(err)
+		}
+	}
+	c.cacheLock.Lock()
+	c.cache.updates += int64(len(users))
+	c.cacheLock.Unlock()
+}
+
+func (c *Client) saveChannels(channels ...*slack.Channel) {
+	stmt, err := c.team.DB().Prepare(sqlCacheSaveChannel)
+	if err != nil {
+		c.cacheWriteError(err)
+		return
+	}
+	defer stmt.Close()
+
+	for _, ch := range channels {
+		data, err := json.Marshal(ch)
+		if err != nil {
+			c.cacheWriteError(err)
+			continue
+		}
+		_, err = stmt.Exec(string(ch.ID), data, ch.CacheTS)
+		if err != nil {
+			c.cacheWriteError(err)
+		}
+	}
+	c.cacheLock.Lock()
+	c.cache.updates += int64(len(channels))
+	c.cacheLock.Unlock()
+}
+
+// pruneChannels removes the saved channels that were not seen in a refresh
+// that started at since.
+func (c *Client) pruneChannels(since time.Time) {
+	stmt, err := c.team.DB().Prepare(sqlCachePruneChannels)
+	if err != nil {
+		c.cacheWriteError(err)
+		return
+	}
+	defer stmt.Close()
+	_, err = stmt.Exec(since)
+	if err != nil {
+		c.cacheWriteError(err)
+	}
+}
+
+// updateChannel changes a channel in place and saves it. It returns false
+// if the channel is not in the cache.
+func (c *Client) updateChannel(channel slack.ChannelID, f func(ch *slack.Channel)) bool {
+	if channel == "" {
+		return false
+	}
+	var found *slack.Channel
+	var saved slack.Channel
+
+	c.MetadataLock.Lock()
+	for _, ary := range [][]*slack.Channel{c.Channels, c.Groups} {
+		for _, v := range ary {
+			if v.ID == channel {
+				f(v)
+				found = v
+				saved = *v
+				break
+			}
+		}
+	}
+	c.MetadataLock.Unlock()
+
+	if found == nil {
+		return false
+	}
+	c.saveChannels(&saved)
+	return true
+}
+
+func (c *Client) removeChannel(channel slack.ChannelID) {
+	c.MetadataLock.Lock()
+	for _, ary := range []*[]*slack.Channel{&c.Channels, &c.Groups} {
+		for i, v := range *ary {
+			if v.ID == channel {
+				*ary = append((*ary)[:i], (*ary)[i+1:]...)
+				break
+			}
+		}
+	}
+	c.MetadataLock.Unlock()
+
+	stmt, err := c.team.DB().Prepare(sqlCacheDeleteChannel)
+	if err != nil {
+		c.cacheWriteError(err)
+		return
+	}
+	defer stmt.Close()
+	_, err = stmt.Exec(string(channel))
+	if err != nil {
+		c.cacheWriteError(err)
+	}
+}
+
+func (c *Client) onChannelCreated(msg slack.RTMRawMessage) {
+	var resp struct {
+		Channel *slack.Channel `json:"channel"`
+	}
+	err := msg.ReMarshal(&resp)
+	if err != nil {
+		util.LogError(errors.Wrapf(err, "decode %s", msg.Type()))
+		return
+	}
+	if resp.Channel == nil {
+		return
+	}
+	// Only sent for public channels
+	resp.Channel.IsChannel = true
+	c.ReplaceChannelObject(time.Now(), resp.Channel)
+}
+
+func (c *Client) onChannelRename(msg slack.RTMRawMessage) {
+	var resp struct {
+		Channel struct {
+			ID   slack.ChannelID `json:"id"`
+			Name string          `json:"name"`
+		} `json:"channel"`
+	}
+	err := msg.ReMarshal(&resp)
+	if err != nil {
+		util.LogError(errors.Wrapf(err, "decode %s", msg.Type()))
+		return
+	}
+	c.updateChannel(resp.Channel.ID, func(ch *slack.Channel) {
+		ch.Name = resp.Channel.Name
+	})
+}
+
+func (c *Client) onChannelArchive(msg slack.RTMRawMessage) {
+	archived := !strings.HasSuffix(msg.Type(), "_unarchive")
+	c.updateChannel(msg.ChannelID(), func(ch *slack.Channel) {
+		ch.IsArchived = archived
+	})
+}
+
+func (c *Client) onChannelDeleted(msg slack.RTMRawMessage) {
+	c.removeChannel(msg.ChannelID())
+}
+
+func (c *Client) cacheRefresher() {
+	for range time.Tick(cacheRefreshInterval) {
+		err := c.RefreshCache()
+		if err != nil {
+			util.LogError(errors.Wrap(err, "refresh slack cache"))
+		}
+	}
+}
+
+// cacheFresh reports whether the user and channel lists, from the database
+// or the last refresh, are recent enough to skip a refresh.
+func (c *Client) cacheFresh() bool {
+	c.cacheLock.Lock()
+	defer c.cacheLock.Unlock()
+	return isCacheFresh(c.cache.storedRefresh, c.cache.lastRefresh, c.cache.refreshError, time.Now())
+}
+
+func isCacheFresh(storedRefresh, lastRefresh time.Time, refreshError error, now time.Time) bool {
+	last := storedRefresh
+	if lastRefresh.After(last) {
+		if refreshError != nil {
+			return false
+		}
+		last = lastRefresh
+	}
+	return !last.IsZero() && now.Sub(last) < cacheRefreshInterval
+}
+
+// RefreshCache fetches the user and channel lists from Slack and saves
+// them. It returns an error without doing anything if a refresh is already
+// running.
+func (c *Client) RefreshCache() error {
+	c.cacheLock.Lock()
+	if c.cache.refreshing {
+		c.cacheLock.Unlock()
+		return errors.Errorf("a refresh is already running")
+	}
+	c.cache.refreshing = true
+	c.cacheLock.Unlock()
+
+	start := time.Now()
+	err := c.fillChannelList()
+	if err == nil {
+		c.pruneChannels(start)
+	}
+	err2 := c.fillMemberships()
+	if err == nil {
+		err = err2
+	}
+	err2 = c.fillUsersList()
+	if err == nil {
+		err = err2
+	}
+
+	c.cacheLock.Lock()
+	c.cache.refreshing = false
+	c.cache.lastRefresh = start
+	c.cache.refreshDuration = time.Since(start)
+	c.cache.refreshError = err
+	c.cacheLock.Unlock()
+
+	if err == nil {
+		c.saveRefreshTime(start)
+	}
+	return err
+}
+
+// CacheStats reports the size and state of the user and channel cache.
+func (c *Client) CacheStats() (CacheStats, error) {
+	var stats CacheStats
+
+	c.MetadataLock.RLock()
+	stats.Users = len(c.Users)
+	stats.Channels = len(c.Channels)
+	stats.Groups = len(c.Groups)
+	c.MetadataLock.RUnlock()
+
+	c.cacheLock.Lock()
+	stats.LoadedAt = c.cache.loadedAt
+	stats.Updates = c.cache.updates
+	stats.WriteErrors = c.cache.writeErrors
+	stats.Refreshing = c.cache.refreshing
+	stats.LastRefresh = c.cache.lastRefresh
+	stats.RefreshDuration = c.cache.refreshDuration
+	stats.RefreshError = c.cache.refreshError
+	c.cacheLock.Unlock()
+
+	row := c.team.DB().QueryRow(sqlCacheCount)
+	err := row.Scan(&stats.StoredUsers, &stats.StoredChannels)
+	if err != nil && err != sql.ErrNoRows {
+		return stats, errors.Wrap(err, "count cached objects")
+	}
+	return stats, nil
+}
@@ -0,0 +1,148 @@
+package rtm
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/riking/marvin/slack"
+)
+
+func TestCacheUserRoundTrip(t *testing.T) {
+	updated := time.Date(2017, 10, 18, 12, 0, 0, 0, time.UTC)
+	u := &slack.User{
+		CacheTS:  time.Now(),
+		ID:       "U1234",
+		Name:     "alice",
+		RealName: "Alice",
+		IsAdmin:  true,
+	}
+	u.Profile.Email = "alice@example.com"
+
+	data, err := json.Marshal(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := decodeCachedUser(data, updated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.CacheTS.Equal(updated) {
+		t.Errorf("CacheTS: expected %v, got %v", updated, got.CacheTS)
+	}
+	got.CacheTS = u.CacheTS
+	if *got != *u {
+		t.Errorf("expected %+v, got %+v", u, got)
+	}
+
+	_, err = decodeCachedUser([]byte(`{"id": 5`), updated)
+	if err == nil {
+		t.Error("expected an error for bad JSON")
+	}
+}
+
+func TestCacheChannelRoundTrip(t *testing.T) {
+	updated := time.Date(2017, 10, 18, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		ch     slack.Channel
+		public bool
+	}{
+		{slack.Channel{ID: "C1234", Name: "general", IsChannel: true, IsMember: true, IsGeneral: true}, true},
+		{slack.Channel{ID: "C5678", Name: "secret", IsChannel: true, IsPrivate: true}, false},
+		{slack.Channel{ID: "G1234", Name: "old-group", IsGroup: true, IsPrivate: true, IsArchived: true}, false},
+		{slack.Channel{ID: "G5678", Name: "mpdm-a--b--c-1", IsMPIM: true, IsPrivate: true,
+			Members: []slack.UserID{"U1", "U2", "U3"}}, false},
+	}
+	for _, v := range tests {
+		data, err := json.Marshal(&v.ch)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := decodeCachedChannel(data, updated)
+		if err != nil {
+			t.Errorf("%s: %s", v.ch.ID, err)
+			continue
+		} else if got == nil {
+			t.Errorf("%s: channel was dropped", v.ch.ID)
+			continue
+		}
+		if !got.CacheTS.Equal(updated) {
+			t.Errorf("%s: CacheTS: expected %v, got %v", v.ch.ID, updated, got.CacheTS)
+		}
+		if got.ID != v.ch.ID || got.Name != v.ch.Name || got.IsMember != v.ch.IsMember ||
+			got.IsArchived != v.ch.IsArchived || len(got.Members) != len(v.ch.Members) {
+			t.Errorf("%s: expected %+v, got %+v", v.ch.ID, v.ch, *got)
+		}
+		if got.IsPublicChannel() != v.public {
+			t.Errorf("%s: IsPublicChannel: expected %v", v.ch.ID, v.public)
+		}
+	}
+
+	got, err := decodeCachedChannel([]byte(`{"Name": "no-id"}`), updated)
+	if err != nil || got != nil {
+		t.Errorf("channel without an ID: expected nil, got %v %v", got, err)
+	}
+	_, err = decodeCachedChannel([]byte(`not json`), updated)
+	if err == nil {
+		t.Error("expected an error for bad JSON")
+	}
+}
+
+func TestCacheFresh(t *testing.T) {
+	now := time.Date(2017, 10, 18, 12, 0, 0, 0, time.UTC)
+	recent := now.Add(-time.Hour)
+	old := now.Add(-2 * cacheRefreshInterval)
+	failed := errors.New("failed")
+
+	tests := []struct {
+		stored, last time.Time
+		err          error
+		expect       bool
+	}{
+		{time.Time{}, time.Time{}, nil, false},
+		{recent, time.Time{}, nil, true},
+		{old, time.Time{}, nil, false},
+		{old, recent, nil, true},
+		{old, recent, failed, false},
+		{recent, old, failed, true},
+	}
+	for i, v := range tests {
+		got := isCacheFresh(v.stored, v.last, v.err, now)
+		if got != v.expect {
+			t.Errorf("case %d: expected %v, got %v", i, v.expect, got)
+		}
+	}
+}
+
+func TestLoadedCacheFreshness(t *testing.T) {
+	now := time.Now()
+	old := now.Add(-3 * 24 * time.Hour)
+	users := []*slack.User{{ID: "U1", CacheTS: old}}
+	// One channel was looked up just before the restart
+	channels := []*slack.Channel{
+		{ID: "C1", Name: "general", IsChannel: true, CacheTS: old},
+		{ID: "C2", Name: "random", IsChannel: true, CacheTS: now.Add(-time.Minute)},
+	}
+	groups := []*slack.Channel{{ID: "G1", Name: "secret", IsGroup: true, CacheTS: old}}
+
+	tests := []struct {
+		name      string
+		users     []*slack.User
+		refreshed time.Time
+		expect    bool
+	}{
+		{"old refresh, one new channel", users, old, false},
+		{"never refreshed, one new channel", users, time.Time{}, false},
+		{"recent refresh", users, now.Add(-time.Hour), true},
+		{"no saved users", nil, now.Add(-time.Hour), false},
+	}
+	for _, v := range tests {
+		c := NewClient(nil)
+		c.setLoadedCache(v.users, channels, groups, v.refreshed)
+		if got := c.cacheFresh(); got != v.expect {
+			t.Errorf("%s: expected fresh=%v, got %v", v.name, v.expect, got)
+		}
+	}
+}
@@ -8,29 +8,17 @@ import (
 )
 
 func (c *Client) setTopicPurpose(channel slack.ChannelID, isTopic bool, new slack.ChannelTopicPurpose) {
-	var ary *[]*slack.Channel
-
-	c.MetadataLock.Lock()
-	defer c.MetadataLock.Unlock()
-
-	if channel[0] == 'C' {
-		ary = &c.Channels
-	} else {
-		ary = &c.Groups
-	}
-	for i, v := range *ary {
-		if v.ID == channel {
-			if isTopic {
-				(*ary)[i].Topic.Value = new.Value
-				(*ary)[i].Topic.Creator = new.Creator
-				(*ary)[i].Topic.LastSet = float64(time.Now().Unix())
-			} else {
-				(*ary)[i].Purpose.Value = new.Value
-				(*ary)[i].Purpose.Creator = new.Creator
-				(*ary)[i].Purpose.LastSet = float64(time.Now().Unix())
-			}
+	c.updateChannel(channel, func(ch *slack.Channel) {
+		if isTopic {
+			ch.Topic.Value = new.Value
+			ch.Topic.Creator = new.Creator
+			ch.Topic.LastSet = float64(time.Now().Unix())
+		} else {
+			ch.Purpose.Value = new.Value
+			ch.Purpose.Creator = new.Creator
+			ch.Purpose.LastSet = float64(time.Now().Unix())
 		}
-	}
+	})
 }
 
 func (c *Client) onTopicChange(msg slack.RTMRawMessage) {
@@ -78,7 +66,20 @@ func (c *Client) onGroupJoin(msg slack.RTMRawMessage) {
 		Channel *slack.Channel `json:"channel"`
 	}
 	msg.ReMarshal(&resp)
-	c.ReplaceGroupObject(time.Now(), resp.Channel)
+	if resp.Channel == nil {
+		return
+	}
+	if resp.Channel.IsPublicChannel() {
+		// group_joined is only sent for private channels
+		resp.Channel.IsPrivate = true
+	}
+	c.ReplaceConversationObject(time.Now(), resp.Channel)
+	go func() {
+		err := c.FillChannelMembers(resp.Channel.ID)
+		if err != nil {
+			util.LogError(err)
+		}
+	}()
 }
 
 func (c *Client) onChannelJoin(msg slack.RTMRawMessage) {
@@ -86,10 +87,24 @@ func (c *Client) onChannelJoin(msg slack.RTMRawMessage) {
 		Channel *slack.Channel `json:"channel"`
 	}
 	msg.ReMarshal(&resp)
-	c.ReplaceChannelObject(time.Now(), resp.Channel)
+	if resp.Channel == nil {
+		return
+	}
+	c.ReplaceConversationObject(time.Now(), resp.Channel)
+	go func() {
+		err := c.FillChannelMembers(resp.Channel.ID)
+		if err != nil {
+			util.LogError(err)
+		}
+	}()
 }
 
 func (c *Client) ReplaceUserObject(obj *slack.User) {
+	c.replaceUserObject(obj)
+	c.saveUsers(obj)
+}
+
+func (c *Client) replaceUserObject(obj *slack.User) {
 	c.MetadataLock.Lock()
 	defer c.MetadataLock.Unlock()
 
@@ -104,6 +119,12 @@ func (c *Client) ReplaceUserObject(obj *slack.User) {
 }
 
 func (c *Client) ReplaceManyUserObjects(objs []*slack.User) {
+	saved := append([]*slack.User(nil), objs...)
+	c.replaceManyUserObjects(objs)
+	c.saveUsers(saved...)
+}
+
+func (c *Client) replaceManyUserObjects(objs []*slack.User) {
 	c.MetadataLock.Lock()
 	defer c.MetadataLock.Unlock()
 
@@ -126,6 +147,11 @@ func (c *Client) ReplaceManyUserObjects(objs []*slack.User) {
 }
 
 func (c *Client) ReplaceChannelObject(cacheTS time.Time, obj *slack.Channel) {
+	c.replaceChannelObject(cacheTS, obj)
+	c.saveChannels(obj)
+}
+
+func (c *Client) replaceChannelObject(cacheTS time.Time, obj *slack.Channel) {
 	c.MetadataLock.Lock()
 	defer c.MetadataLock.Unlock()
 
@@ -140,6 +166,11 @@ func (c *Client) ReplaceChannelObject(cacheTS time.Time, obj *slack.Channel) {
 }
 
 func (c *Client) ReplaceGroupObject(cacheTS time.Time, obj *slack.Channel) {
+	c.replaceGroupObject(cacheTS, obj)
+	c.saveChannels(obj)
+}
+
+func (c *Client) replaceGroupObject(cacheTS time.Time, obj *slack.Channel) {
 	c.MetadataLock.Lock()
 	defer c.MetadataLock.Unlock()
 
@@ -153,6 +184,24 @@ func (c *Client) ReplaceGroupObject(cacheTS time.Time, obj *slack.Channel) {
 	c.Groups = append(c.Groups, obj)
 }
 
+// ReplaceConversationObject stores any kind of conversation in the list
+// for its type.
+func (c *Client) ReplaceConversationObject(cacheTS time.Time, obj *slack.Conversation) {
+	switch obj.Type() {
+	case slack.ConversationPublic:
+		c.ReplaceChannelObject(cacheTS, obj)
+	case slack.ConversationIM:
+		c.ReplaceIMObject(cacheTS, &slack.ChannelIM{
+			ID:            obj.ID,
+			User:          obj.User,
+			Created:       int64(obj.Created),
+			IsUserDeleted: obj.IsUserDeleted,
+		})
+	default:
+		c.ReplaceGroupObject(cacheTS, obj)
+	}
+}
+
 func (c *Client) ReplaceIMObject(cacheTS time.Time, obj *slack.ChannelIM) {
 	c.MetadataLock.Lock()
 	defer c.MetadataLock.Unlock()
@@ -0,0 +1,196 @@
+package rtm
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+
+	"github.com/riking/marvin"
+	"github.com/riking/marvin/slack"
+	"github.com/riking/marvin/util"
+)
+
+type messageHandler struct {
+	id     int32
+	Cb     marvin.EventHandlerFunc
+	Filter marvin.EventFilter
+	Module marvin.ModuleID
+}
+
+type handlerHandle struct {
+	c  *Client
+	id int32
+}
+
+func (h handlerHandle) Unregister() {
+	h.c.msgCbsLock.Lock()
+	defer h.c.msgCbsLock.Unlock()
+
+	for i, v := range h.c.msgCbs {
+		if v.id == h.id {
+			h.c.msgCbs = append(h.c.msgCbs[:i:i], h.c.msgCbs[i+1:]...)
+			return
+		}
+	}
+}
+
+// RegisterRawHandler registers a handler for events of the given type (or
+// MsgTypeAll) and subtypes.
+func (c *Client) RegisterRawHandler(
+	mod marvin.ModuleID,
+	cb func(slack.RTMRawMessage),
+	typeOnly string, subtypes []string,
+) marvin.EventHandle {
+	if typeOnly == MsgTypeAll {
+		typeOnly = ""
+	}
+	return c.RegisterHandler(mod, marvin.EventFilter{
+		Type:     typeOnly,
+		Subtypes: subtypes,
+	}, func(msg slack.RTMRawMessage) bool {
+		cb(msg)
+		return false
+	})
+}
+
+// RegisterHandler registers a handler for the events matching the filter.
+func (c *Client) RegisterHandler(mod marvin.ModuleID, filter marvin.EventFilter, cb marvin.EventHandlerFunc) marvin.EventHandle {
+	if filter.Type == "" && len(filter.Subtypes) > 0 {
+		panic("cannot specify subtypes without specifying type")
+	}
+
+	c.msgCbsLock.Lock()
+	defer c.msgCbsLock.Unlock()
+
+	id := c.handlerID.Get()
+	c.msgCbs = append(c.msgCbs, messageHandler{
+		id:     id,
+		Cb:     cb,
+		Filter: filter,
+		Module: mod,
+	})
+	return handlerHandle{c: c, id: id}
+}
+
+func (c *Client) UnregisterAllMatching(mod marvin.ModuleID) {
+	c.msgCbsLock.Lock()
+	defer c.msgCbsLock.Unlock()
+
+	newMsgCbs := make([]messageHandler, 0, len(c.msgCbs))
+	for _, v := range c.msgCbs {
+		if v.Module != mod {
+			newMsgCbs = append(newMsgCbs, v)
+		}
+	}
+	c.msgCbs = newMsgCbs
+}
+
+func isBotMessage(msg slack.RTMRawMessage) bool {
+	_, hasBotID := msg["bot_id"]
+	return hasBotID || msg.Subtype() == "bot_message"
+}
+
+func (h *messageHandler) matches(msg slack.RTMRawMessage) bool {
+	f := &h.Filter
+	if f.Type != "" && msg.Type() != f.Type {
+		return false
+	}
+	if f.Type != "" && len(f.Subtypes) != 0 {
+		msgType := msg.Subtype()
+		found := false
+		for _, v := range f.Subtypes {
+			if msgType == v {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.Channel != "" && msg.ChannelID() != f.Channel {
+		return false
+	}
+	if f.User != "" && msg.UserID() != f.User {
+		return false
+	}
+	if f.Bots == util.TriYes && !isBotMessage(msg) {
+		return false
+	} else if f.Bots == util.TriNo && isBotMessage(msg) {
+		return false
+	}
+	return true
+}
+
+func (c *Client) dispatchMessage(msg slack.RTMRawMessage) {
+	var handlers []messageHandler
+
+	c.msgCbsLock.RLock()
+	for _, v := range c.msgCbs {
+		if !v.matches(msg) {
+			continue
+		}
+		if v.Filter.Observer {
+			go dispatchOne(v, msg)
+			continue
+		}
+		handlers = append(handlers, v)
+	}
+	c.msgCbsLock.RUnlock()
+
+	if len(handlers) == 0 {
+		return
+	}
+	sort.SliceStable(handlers, func(i, j int) bool {
+		return handlers[i].Filter.Priority > handlers[j].Filter.Priority
+	})
+	if handlers[0].Filter.Priority == handlers[len(handlers)-1].Filter.Priority {
+		// Nothing can be consumed before another handler sees it
+		for _, v := range handlers {
+			go dispatchOne(v, msg)
+		}
+		return
+	}
+	go dispatchByPriority(handlers, msg)
+}
+
+// dispatchByPriority runs each priority level in turn, stopping when a
+// handler consumes the event.
+func dispatchByPriority(handlers []messageHandler, msg slack.RTMRawMessage) {
+	for len(handlers) > 0 {
+		level := handlers[0].Filter.Priority
+		n := 1
+		for n < len(handlers) && handlers[n].Filter.Priority == level {
+			n++
+		}
+
+		var wg sync.WaitGroup
+		var consumed int32
+		for _, v := range handlers[:n] {
+			wg.Add(1)
+			go func(v messageHandler) {
+				defer wg.Done()
+				if dispatchOne(v, msg) {
+					atomic.StoreInt32(&consumed, 1)
+				}
+			}(v)
+		}
+		wg.Wait()
+		if atomic.LoadInt32(&consumed) != 0 {
+			return
+		}
+		handlers = handlers[n:]
+	}
+}
+
+func dispatchOne(handler messageHandler, msg slack.RTMRawMessage) (consumed bool) {
+	defer func() {
+		if err := recover(); err != nil {
+			util.LogError(errors.Errorf("A message handler callback from %s panicked: %+v", handler.Module, err))
+		}
+	}()
+
+	return handler.Cb(msg)
+}
@@ -0,0 +1,104 @@
+package rtm
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/riking/marvin"
+	"github.com/riking/marvin/slack"
+	"github.com/riking/marvin/util"
+)
+
+func TestHandlerMatches(t *testing.T) {
+	message := slack.RTMRawMessage{"type": "message", "channel": "C1", "user": "U1", "text": "hi"}
+	edit := slack.RTMRawMessage{"type": "message", "subtype": "message_changed", "channel": "C1"}
+	botMessage := slack.RTMRawMessage{"type": "message", "subtype": "bot_message", "channel": "C1", "bot_id": "B1"}
+	botUser := slack.RTMRawMessage{"type": "message", "channel": "C2", "user": "U2", "bot_id": "B2"}
+	reaction := slack.RTMRawMessage{"type": "reaction_added", "user": "U1"}
+
+	tests := []struct {
+		name   string
+		filter marvin.EventFilter
+		msg    slack.RTMRawMessage
+		expect bool
+	}{
+		{"empty filter", marvin.EventFilter{}, reaction, true},
+		{"type", marvin.EventFilter{Type: "message"}, message, true},
+		{"other type", marvin.EventFilter{Type: "message"}, reaction, false},
+		{"type with subtype", marvin.EventFilter{Type: "message"}, edit, true},
+		{"subtype", marvin.EventFilter{Type: "message", Subtypes: []string{"message_changed"}}, edit, true},
+		{"subtype list", marvin.EventFilter{Type: "message", Subtypes: []string{"", "bot_message"}}, botMessage, true},
+		{"empty subtype", marvin.EventFilter{Type: "message", Subtypes: []string{""}}, message, true},
+		{"wrong subtype", marvin.EventFilter{Type: "message", Subtypes: []string{""}}, edit, false},
+		{"channel", marvin.EventFilter{Channel: "C1"}, message, true},
+		{"other channel", marvin.EventFilter{Channel: "C1"}, botUser, false},
+		{"user", marvin.EventFilter{User: "U1"}, reaction, true},
+		{"other user", marvin.EventFilter{User: "U1"}, botUser, false},
+		{"only bots", marvin.EventFilter{Bots: util.TriYes}, botMessage, true},
+		{"only bots, bot_id", marvin.EventFilter{Bots: util.TriYes}, botUser, true},
+		{"only bots, person", marvin.EventFilter{Bots: util.TriYes}, message, false},
+		{"no bots", marvin.EventFilter{Bots: util.TriNo}, message, true},
+		{"no bots, bot_message", marvin.EventFilter{Bots: util.TriNo}, botMessage, false},
+		{"no bots, bot_id", marvin.EventFilter{Bots: util.TriNo}, botUser, false},
+		{"all fields", marvin.EventFilter{Type: "message", Channel: "C1", User: "U1", Bots: util.TriNo}, message, true},
+	}
+	for _, v := range tests {
+		h := messageHandler{Filter: v.filter}
+		got := h.matches(v.msg)
+		if got != v.expect {
+			t.Errorf("%s: expected %v, got %v", v.name, v.expect, got)
+		}
+	}
+}
+
+func TestDispatchByPriority(t *testing.T) {
+	var lock sync.Mutex
+	var ran []string
+	handler := func(name string, priority int, consume bool) messageHandler {
+		return messageHandler{
+			Module: marvin.ModuleID(name),
+			Filter: marvin.EventFilter{Priority: priority},
+			Cb: func(msg slack.RTMRawMessage) bool {
+				lock.Lock()
+				ran = append(ran, name)
+				lock.Unlock()
+				if name == "panic" {
+					panic("handler failed")
+				}
+				return consume
+			},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		handlers []messageHandler
+		expect   []string
+	}{
+		{"nothing consumed", []messageHandler{
+			handler("a", 2, false), handler("b", 1, false), handler("c", 0, false),
+		}, []string{"a", "b", "c"}},
+		{"consumed at the top level", []messageHandler{
+			handler("a", 2, true), handler("b", 1, false), handler("c", 0, false),
+		}, []string{"a"}},
+		{"same level still runs", []messageHandler{
+			handler("a", 2, false), handler("b", 2, true), handler("c", 1, false), handler("d", 0, false),
+		}, []string{"a", "b"}},
+		{"consumed at a lower level", []messageHandler{
+			handler("a", 2, false), handler("b", 1, true), handler("c", 0, false),
+		}, []string{"a", "b"}},
+		{"panic does not consume", []messageHandler{
+			handler("panic", 1, true), handler("b", 0, false),
+		}, []string{"b", "panic"}},
+	}
+	for _, v := range tests {
+		ran = nil
+		dispatchByPriority(v.handlers, slack.RTMRawMessage{"type": "message"})
+		sort.Strings(ran)
+		if strings.Join(ran, " ") != strings.Join(v.expect, " ") {
+			t.Errorf("%s: expected %v to run, got %v", v.name, v.expect, ran)
+		}
+	}
+}
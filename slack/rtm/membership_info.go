@@ -9,6 +9,10 @@ import (
 	"github.com/riking/marvin/util"
 )
 
+// membershipFetchDelay spaces out the conversations.members calls in
+// fillMemberships to stay under the rate limit.
+const membershipFetchDelay = 700 * time.Millisecond
+
 type membershipMap map[slack.ChannelID]map[slack.UserID]bool
 
 type membershipRequest struct {
@@ -22,20 +26,36 @@ func (c *Client) membershipWorker() {
 	}
 }
 
-func (c *Client) rebuildMembershipMapFunc(groups []*slack.Channel) func(m membershipMap) interface{} {
+// rebuildMembershipMapFunc replaces the member lists of the channels that
+// came with one, like the ones in join events. Other channels are filled by
+// fillMemberships.
+func (c *Client) rebuildMembershipMapFunc(channels []*slack.Channel) func(m membershipMap) interface{} {
 	return func(m membershipMap) interface{} {
-		// Only groups are relevant
-		for _, v := range groups {
-			m := make(map[slack.UserID]bool)
+		for _, v := range channels {
+			if v.Members == nil {
+				continue
+			}
+			chMap := make(map[slack.UserID]bool)
 			for _, userID := range v.Members {
-				m[userID] = true
+				chMap[userID] = true
 			}
-			c.channelMembers[v.ID] = m
+			m[v.ID] = chMap
 		}
 		return nil
 	}
 }
 
+func setChannelMembers(channel slack.ChannelID, users []slack.UserID) func(m membershipMap) interface{} {
+	return func(m membershipMap) interface{} {
+		chMap := make(map[slack.UserID]bool, len(users))
+		for _, userID := range users {
+			chMap[userID] = true
+		}
+		m[channel] = chMap
+		return nil
+	}
+}
+
 func userInChannelList(user slack.UserID, channels ...slack.ChannelID) func(m membershipMap) interface{} {
 	r := make(map[slack.ChannelID]bool)
 	return func(m membershipMap) interface{} {
@@ -56,7 +76,11 @@ func userJoinChannel(user slack.UserID, channel slack.ChannelID, join bool) func
 			chMap = make(map[slack.UserID]bool)
 			m[channel] = chMap
 		}
-		chMap[user] = join
+		if join {
+			chMap[user] = true
+		} else {
+			delete(chMap, user)
+		}
 		return nil
 	}
 }
@@ -89,7 +113,7 @@ func (c *Client) MemberList(channel slack.ChannelID) []slack.UserID {
 		F: func(m membershipMap) interface{} {
 			chMap, ok := m[channel]
 			if !ok {
-				return int(0)
+				return []slack.UserID(nil)
 			}
 			users := make([]slack.UserID, 0, len(chMap))
 			for k := range chMap {
@@ -102,6 +126,16 @@ func (c *Client) MemberList(channel slack.ChannelID) []slack.UserID {
 }
 
 func (c *Client) onUserJoinChannel(msg slack.RTMRawMessage) {
+	if msg.UserID() == c.Self.ID {
+		c.updateChannel(msg.ChannelID(), func(ch *slack.Channel) {
+			ch.IsMember = true
+		})
+		// Events for this channel start now, so get the current list
+		go func() {
+			util.LogIfError(c.FillChannelMembers(msg.ChannelID()))
+		}()
+		return
+	}
 	ch := make(chan interface{}, 1)
 	c.membershipCh <- membershipRequest{C: ch,
 		F: userJoinChannel(msg.UserID(), msg.ChannelID(), true),
@@ -110,11 +144,145 @@ func (c *Client) onUserJoinChannel(msg slack.RTMRawMessage) {
 
 func (c *Client) onUserLeaveChannel(msg slack.RTMRawMessage) {
 	ch := make(chan interface{}, 1)
+	if msg.UserID() == c.Self.ID {
+		c.updateChannel(msg.ChannelID(), func(ch *slack.Channel) {
+			ch.IsMember = false
+		})
+		// No more events for this channel, so the list would go stale
+		channel := msg.ChannelID()
+		c.membershipCh <- membershipRequest{C: ch,
+			F: func(m membershipMap) interface{} {
+				delete(m, channel)
+				return nil
+			},
+		}
+		return
+	}
 	c.membershipCh <- membershipRequest{C: ch,
 		F: userJoinChannel(msg.UserID(), msg.ChannelID(), false),
 	}
 }
 
+// fetchMembers lists the members of any kind of conversation.
+func (c *Client) fetchMembers(channel slack.ChannelID) ([]slack.UserID, error) {
+	var response struct {
+		slack.APIResponse
+		Members  []slack.UserID `json:"members"`
+		PageInfo struct {
+			NextCursor string `json:"next_cursor"`
+		} `json:"response_metadata"`
+	}
+	var form = url.Values{
+		"channel": []string{string(channel)},
+		"limit":   []string{"500"},
+	}
+
+	var members []slack.UserID
+	for {
+		err := c.team.SlackAPIPostJSON("conversations.members", form, &response)
+		if err != nil {
+			return nil, errors.Wrapf(err, "list members of %s", channel)
+		}
+		members = append(members, response.Members...)
+		if response.PageInfo.NextCursor == "" {
+			return members, nil
+		}
+		form.Set("cursor", response.PageInfo.NextCursor)
+		response.PageInfo.NextCursor = ""
+	}
+}
+
+// FetchChannelMembers returns the member list of a channel. The lists of
+// channels the bot is in are kept up to date by events. Other channels are
+// fetched from Slack on every call, as nothing would keep a saved list
+// current.
+func (c *Client) FetchChannelMembers(channel slack.ChannelID) ([]slack.UserID, error) {
+	if !c.isMember(channel) {
+		return c.fetchMembers(channel)
+	}
+	members := c.MemberList(channel)
+	if members != nil {
+		return members, nil
+	}
+	err := c.FillChannelMembers(channel)
+	if err != nil {
+		return nil, err
+	}
+	return c.MemberList(channel), nil
+}
+
+// isMember reports whether the bot is in a channel.
+func (c *Client) isMember(channel slack.ChannelID) bool {
+	c.MetadataLock.RLock()
+	defer c.MetadataLock.RUnlock()
+	for _, ary := range [][]*slack.Channel{c.Channels, c.Groups} {
+		for _, v := range ary {
+			if v.ID == channel {
+				return v.IsMember && !v.IsArchived
+			}
+		}
+	}
+	return false
+}
+
+// FillChannelMembers fetches the member list of one channel and stores it.
+// It should only be used for channels the bot is in.
+func (c *Client) FillChannelMembers(channel slack.ChannelID) error {
+	members, err := c.fetchMembers(channel)
+	if err != nil {
+		return err
+	}
+	c.membershipCh <- membershipRequest{
+		C: make(chan interface{}, 1),
+		F: setChannelMembers(channel, members),
+	}
+	return nil
+}
+
+// fillMemberships fetches the member lists of the channels the bot is in.
+// The lists are then kept up to date by join and leave events, which only
+// arrive for those channels.
+//
+// Lists of other channels would go stale, so any stored for channels the
+// bot has left are dropped. FetchChannelMembers gets them from Slack
+// instead.
+func (c *Client) fillMemberships() error {
+	var channels []slack.ChannelID
+	joined := make(map[slack.ChannelID]bool)
+	c.MetadataLock.RLock()
+	for _, ary := range [][]*slack.Channel{c.Channels, c.Groups} {
+		for _, v := range ary {
+			if v.IsMember && !v.IsArchived {
+				channels = append(channels, v.ID)
+				joined[v.ID] = true
+			}
+		}
+	}
+	c.MetadataLock.RUnlock()
+
+	c.membershipCh <- membershipRequest{
+		C: make(chan interface{}, 1),
+		F: func(m membershipMap) interface{} {
+			for k := range m {
+				if !joined[k] {
+					delete(m, k)
+				}
+			}
+			return nil
+		},
+	}
+
+	var firstErr error
+	for _, v := range channels {
+		err := c.FillChannelMembers(v)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		time.Sleep(membershipFetchDelay)
+	}
+	return firstErr
+}
+
 func (c *Client) ListPublicChannels() []*slack.Channel {
 	c.MetadataLock.RLock()
 	defer c.MetadataLock.RUnlock()
@@ -139,15 +307,35 @@ func (c *Client) ListIMs() []*slack.ChannelIM {
 	return c.Ims
 }
 
+// fetchTeamInfo loads the saved users and channels on the first
+// connection. The current lists are only fetched from Slack if the saved
+// ones are out of date; otherwise cacheRefresher and the events keep them
+// current.
 func (c *Client) fetchTeamInfo() {
-	go c.fillGroupList()
-	go c.fillUsersList()
-
-	// TODO(kyork): list normal channels too
-	// TODO(kyork): use the listChannels() from logger module
+	firstLoad := false
+	c.cacheLoad.Do(func() {
+		firstLoad = true
+		c.loadCache()
+		c.loadPresenceOptOuts()
+	})
+	if c.cacheFresh() {
+		if firstLoad {
+			// Member lists are not saved
+			go func() {
+				util.LogIfError(c.fillMemberships())
+			}()
+		}
+		return
+	}
+	go func() {
+		err := c.RefreshCache()
+		if err != nil {
+			util.LogError(errors.Wrapf(err, "[%s] Could not refresh team info", c.Team.Domain))
+		}
+	}()
 }
 
-func (c *Client) fillUsersList() {
+func (c *Client) fillUsersList() error {
 	var response struct {
 		slack.APIResponse
 		Members  []*slack.User
@@ -160,41 +348,65 @@ func (c *Client) fillUsersList() {
 		"limit":    []string{"200"},
 	}
 
-	err := c.team.SlackAPIPostJSON("users.list", form, &response)
-	if err != nil {
-		util.LogError(errors.Wrapf(err, "[%s] Could not retrieve users list", c.Team.Domain))
-	}
-
-	for response.PageInfo.NextCursor != "" {
-		c.ReplaceManyUserObjects(response.Members)
-		time.Sleep(10 * time.Second)
-
-		form.Set("cursor", response.PageInfo.NextCursor)
+	for {
 		err := c.team.SlackAPIPostJSON("users.list", form, &response)
 		if err != nil {
-			util.LogError(errors.Wrapf(err, "[%s] Could not retrieve users list", c.Team.Domain))
-			break
+			return errors.Wrapf(err, "[%s] Could not retrieve users list", c.Team.Domain)
 		}
+		c.ReplaceManyUserObjects(response.Members)
+		if response.PageInfo.NextCursor == "" {
+			return nil
+		}
+		form.Set("cursor", response.PageInfo.NextCursor)
+		response.PageInfo.NextCursor = ""
+		time.Sleep(10 * time.Second)
 	}
 }
 
-func (c *Client) fillGroupList() {
+// fillChannelList lists the public channels, and the private channels and
+// MPIMs the bot is in.
+func (c *Client) fillChannelList() error {
 	var response struct {
 		slack.APIResponse
-		Groups []*slack.Channel
+		Channels []*slack.Conversation
+		PageInfo struct {
+			NextCursor string `json:"next_cursor"`
+		} `json:"response_metadata"`
 	}
-	err := c.team.SlackAPIPostJSON("groups.list", url.Values{}, &response)
-	if err != nil {
-		util.LogError(errors.Wrapf(err, "[%s] Could not retrieve groups list", c.Team.Domain))
-		return
+	var form = url.Values{
+		"types": []string{"public_channel,private_channel,mpim"},
+		"limit": []string{"200"},
 	}
 
+	var all []*slack.Conversation
+	for {
+		err := c.team.SlackAPIPostJSON("conversations.list", form, &response)
+		if err != nil {
+			return errors.Wrapf(err, "[%s] Could not retrieve channels list", c.Team.Domain)
+		}
+		all = append(all, response.Channels...)
+		if response.PageInfo.NextCursor == "" {
+			break
+		}
+		form.Set("cursor", response.PageInfo.NextCursor)
+		response.PageInfo.NextCursor = ""
+	}
+
+	now := time.Now()
+	var channels, groups []*slack.Conversation
+	for _, v := range all {
+		v.CacheTS = now
+		if v.IsPublicChannel() {
+			channels = append(channels, v)
+		} else {
+			groups = append(groups, v)
+		}
+	}
 	c.MetadataLock.Lock()
-	c.Groups = response.Groups
+	c.Channels = channels
+	c.Groups = groups
 	c.MetadataLock.Unlock()
 
-	c.membershipCh <- membershipRequest{
-		C: make(chan interface{}, 1),
-		F: c.rebuildMembershipMapFunc(response.Groups),
-	}
+	c.saveChannels(all...)
+	return nil
 }
@@ -0,0 +1,45 @@
+package rtm
+
+import (
+	"testing"
+
+	"github.com/riking/marvin/slack"
+)
+
+func TestFetchChannelMembersStored(t *testing.T) {
+	c := NewClient(nil)
+	c.Channels = []*slack.Channel{
+		{ID: "C1", IsChannel: true, IsMember: true},
+		{ID: "C2", IsChannel: true},
+		{ID: "C3", IsChannel: true, IsMember: true, IsArchived: true},
+	}
+	c.Groups = []*slack.Channel{{ID: "G1", IsGroup: true, IsPrivate: true, IsMember: true}}
+
+	tests := []struct {
+		channel slack.ChannelID
+		expect  bool
+	}{
+		{"C1", true},
+		{"C2", false},
+		{"C3", false},
+		{"G1", true},
+		{"C9", false},
+	}
+	for _, v := range tests {
+		if got := c.isMember(v.channel); got != v.expect {
+			t.Errorf("isMember(%s): expected %v, got %v", v.channel, v.expect, got)
+		}
+	}
+
+	// A stored list is used without asking Slack
+	ch := make(chan interface{}, 1)
+	c.membershipCh <- membershipRequest{C: ch, F: setChannelMembers("C1", []slack.UserID{"U1", "U2"})}
+	<-ch
+	members, err := c.FetchChannelMembers("C1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(members) != 2 {
+		t.Errorf("expected the stored list, got %v", members)
+	}
+}
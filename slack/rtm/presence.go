@@ -0,0 +1,411 @@
+package rtm
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+
+	"github.com/riking/marvin"
+	"github.com/riking/marvin/database"
+	"github.com/riking/marvin/slack"
+	"github.com/riking/marvin/util"
+)
+
+const (
+	// presenceSubLimit is the most users kept in the presence_sub list. The
+	// users asked about least recently are dropped first.
+	presenceSubLimit = 500
+	// presenceSubDelay batches presence_sub updates.
+	presenceSubDelay = 5 * time.Second
+	// presenceFlushInterval is how often recorded activity is saved.
+	presenceFlushInterval = 1 * time.Minute
+)
+
+func MigratePresence(c *database.Conn) error {
+	err := c.Migrate("slack_presence", 1508428800,
+		`CREATE TABLE slack_presence (
+			user_id      varchar(15) PRIMARY KEY, -- slack.UserID
+			last_active  TIMESTAMPTZ DEFAULT NULL,
+			last_message TIMESTAMPTZ DEFAULT NULL,
+			last_channel varchar(15) DEFAULT NULL, -- slack.ChannelID
+			opted_out    boolean     NOT NULL DEFAULT FALSE
+		)`,
+	)
+	c.SyntaxCheck(
+		sqlPresenceSave,
+		sqlPresenceGet,
+		sqlPresenceSetOptOut,
+		sqlPresenceListOptOut,
+	)
+	return err
+}
+
+const (
+	// $1 = user $2 = last_active $3 = last_message $4 = last_channel
+	sqlPresenceSave = `
+	INSERT INTO slack_presence (user_id, last_active, last_message, last_channel)
+	VALUES ($1, $2, $3, $4)
+	ON CONFLICT (user_id) DO UPDATE
+	SET last_active = GREATEST(slack_presence.last_active, excluded.last_active),
+		last_message = GREATEST(slack_presence.last_message, excluded.last_message),
+		last_channel = CASE
+			WHEN excluded.last_message IS NULL THEN slack_presence.last_channel
+			WHEN slack_presence.last_message > excluded.last_message THEN slack_presence.last_channel
+			ELSE excluded.last_channel END
+	WHERE NOT slack_presence.opted_out`
+
+	// $1 = user
+	sqlPresenceGet = `
+	SELECT last_active, last_message, last_channel, opted_out
+	FROM slack_presence
+	WHERE user_id = $1`
+
+	// $1 = user $2 = opted_out
+	sqlPresenceSetOptOut = `
+	INSERT INTO slack_presence (user_id, opted_out)
+	VALUES ($1, $2)
+	ON CONFLICT (user_id) DO UPDATE
+	SET opted_out = excluded.opted_out,
+		last_active = NULL, last_message = NULL, last_channel = NULL`
+
+	sqlPresenceListOptOut = `SELECT user_id FROM slack_presence WHERE opted_out`
+)
+
+type presenceState struct {
+	presence    string
+	lastActive  time.Time
+	lastMessage time.Time
+	channel     slack.ChannelID
+	dirty       bool
+}
+
+type presenceTracker struct {
+	lock      sync.Mutex
+	users     map[slack.UserID]*presenceState
+	optedOut  map[slack.UserID]bool
+	interest  map[slack.UserID]time.Time
+	subSent   bool
+	subNeeded chan struct{}
+}
+
+func (p *presenceTracker) init() {
+	p.users = make(map[slack.UserID]*presenceState)
+	p.optedOut = make(map[slack.UserID]bool)
+	p.interest = make(map[slack.UserID]time.Time)
+	p.subNeeded = make(chan struct{}, 1)
+}
+
+// get returns the state for a user, creating it if needed. Called with the
+// lock held.
+func (p *presenceTracker) get(user slack.UserID) *presenceState {
+	st := p.users[user]
+	if st == nil {
+		st = &presenceState{}
+		p.users[user] = st
+	}
+	return st
+}
+
+func (c *Client) loadPresenceOptOuts() {
+	rows, err := c.team.DB().Query(sqlPresenceListOptOut)
+	if err != nil {
+		util.LogError(errors.Wrap(err, "load presence opt-outs"))
+		return
+	}
+	defer rows.Close()
+
+	c.presence.lock.Lock()
+	defer c.presence.lock.Unlock()
+	for rows.Next() {
+		var user string
+		err = rows.Scan(&user)
+		if err != nil {
+			util.LogError(errors.Wrap(err, "load presence opt-outs"))
+			return
+		}
+		c.presence.optedOut[slack.UserID(user)] = true
+	}
+}
+
+// addInterest adds a user to the presence_sub list.
+func (c *Client) addInterest(user slack.UserID) {
+	c.presence.lock.Lock()
+	_, ok := c.presence.interest[user]
+	if !c.presence.optedOut[user] {
+		c.presence.interest[user] = time.Now()
+	}
+	c.presence.lock.Unlock()
+
+	if !ok {
+		select {
+		case c.presence.subNeeded <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (c *Client) presenceSubWorker() {
+	for range c.presence.subNeeded {
+		time.Sleep(presenceSubDelay)
+		c.sendPresenceSub()
+	}
+}
+
+// sendPresenceSub sends the current presence_sub list. It replaces the
+// previous list on the Slack side.
+func (c *Client) sendPresenceSub() {
+	c.presence.lock.Lock()
+	ids := make([]slack.UserID, 0, len(c.presence.interest))
+	for k := range c.presence.interest {
+		ids = append(ids, k)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return c.presence.interest[ids[i]].After(c.presence.interest[ids[j]])
+	})
+	if len(ids) > presenceSubLimit {
+		for _, v := range ids[presenceSubLimit:] {
+			delete(c.presence.interest, v)
+		}
+		ids = ids[:presenceSubLimit]
+	}
+	c.presence.subSent = true
+	c.presence.lock.Unlock()
+
+	b, err := json.Marshal(map[string]interface{}{
+		"type": "presence_sub",
+		"ids":  ids,
+	})
+	if err != nil {
+		util.LogError(errors.Wrap(err, "presence_sub"))
+		return
+	}
+	c.sendChan <- b
+}
+
+func (c *Client) onPresenceHello(msg slack.RTMRawMessage) {
+	// Subscriptions don't survive a reconnect
+	c.presence.lock.Lock()
+	sent := c.presence.subSent
+	c.presence.lock.Unlock()
+	if sent {
+		go c.sendPresenceSub()
+	}
+}
+
+func (c *Client) onPresenceChange(msg slack.RTMRawMessage) {
+	var resp struct {
+		User     slack.UserID   `json:"user"`
+		Users    []slack.UserID `json:"users"`
+		Presence string         `json:"presence"`
+	}
+	err := msg.ReMarshal(&resp)
+	if err != nil {
+		util.LogError(errors.Wrap(err, "decode presence_change"))
+		return
+	}
+	if resp.User != "" {
+		resp.Users = append(resp.Users, resp.User)
+	}
+
+	now := time.Now()
+	c.presence.lock.Lock()
+	defer c.presence.lock.Unlock()
+	for _, v := range resp.Users {
+		if c.presence.optedOut[v] {
+			continue
+		}
+		st := c.presence.get(v)
+		st.presence = resp.Presence
+		if resp.Presence == marvin.PresenceActive {
+			st.lastActive = now
+			st.dirty = true
+		}
+	}
+}
+
+func (c *Client) onPresenceMessage(msg slack.RTMRawMessage) {
+	user := msg.UserID()
+	channel := msg.ChannelID()
+	if user == "" || channel == "" || msg.Subtype() != "" {
+		return
+	}
+	c.MetadataLock.RLock()
+	self := c.Self.ID
+	c.MetadataLock.RUnlock()
+	if user == self {
+		return
+	}
+
+	now := time.Now()
+	c.presence.lock.Lock()
+	if c.presence.optedOut[user] {
+		c.presence.lock.Unlock()
+		return
+	}
+	st := c.presence.get(user)
+	st.lastActive = now
+	st.lastMessage = now
+	st.channel = channel
+	st.dirty = true
+	c.presence.lock.Unlock()
+
+	c.addInterest(user)
+}
+
+func (c *Client) presenceFlusher() {
+	for range time.Tick(presenceFlushInterval) {
+		c.flushPresence()
+	}
+}
+
+// flushPresence saves the activity recorded since the last flush.
+func (c *Client) flushPresence() {
+	type entry struct {
+		user slack.UserID
+		st   presenceState
+	}
+	var list []entry
+	c.presence.lock.Lock()
+	for k, v := range c.presence.users {
+		if v.dirty {
+			list = append(list, entry{k, *v})
+			v.dirty = false
+		}
+	}
+	c.presence.lock.Unlock()
+	if len(list) == 0 {
+		return
+	}
+
+	stmt, err := c.team.DB().Prepare(sqlPresenceSave)
+	if err != nil {
+		util.LogError(errors.Wrap(err, "save presence"))
+		return
+	}
+	defer stmt.Close()
+	for _, v := range list {
+		_, err = stmt.Exec(string(v.user), nullTime(v.st.lastActive), nullTime(v.st.lastMessage), nullString(string(v.st.channel)))
+		if err != nil {
+			util.LogError(errors.Wrap(err, "save presence"))
+		}
+	}
+}
+
+func nullTime(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t
+}
+
+func nullString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// Presence returns the presence of the user, asking Slack if it is not
+// known yet. Users asked about are added to the presence_sub list.
+func (c *Client) Presence(user slack.UserID) string {
+	c.presence.lock.Lock()
+	if c.presence.optedOut[user] {
+		c.presence.lock.Unlock()
+		return marvin.PresenceUnknown
+	}
+	presence := ""
+	if st := c.presence.users[user]; st != nil {
+		presence = st.presence
+	}
+	c.presence.lock.Unlock()
+
+	c.addInterest(user)
+	if presence != "" {
+		return presence
+	}
+
+	var response struct {
+		Presence string `json:"presence"`
+	}
+	err := c.team.SlackAPIPostJSON("users.getPresence", url.Values{"user": []string{string(user)}}, &response)
+	if err != nil {
+		util.LogError(errors.Wrapf(err, "get presence of %s", user))
+		return marvin.PresenceUnknown
+	}
+
+	c.presence.lock.Lock()
+	defer c.presence.lock.Unlock()
+	st := c.presence.get(user)
+	if st.presence == "" {
+		st.presence = response.Presence
+	}
+	return st.presence
+}
+
+// LastSeen returns the recorded activity of the user, including activity
+// that hasn't been saved yet.
+func (c *Client) LastSeen(user slack.UserID) (marvin.LastSeen, error) {
+	seen := marvin.LastSeen{User: user}
+
+	var lastActive, lastMessage pq.NullTime
+	var channel sql.NullString
+	row := c.team.DB().QueryRow(sqlPresenceGet, string(user))
+	err := row.Scan(&lastActive, &lastMessage, &channel, &seen.OptedOut)
+	if err != nil && err != sql.ErrNoRows {
+		return seen, errors.Wrap(err, "get last seen")
+	}
+	if seen.OptedOut {
+		return seen, nil
+	}
+	seen.LastActive = lastActive.Time
+	seen.LastMessage = lastMessage.Time
+	seen.Channel = slack.ChannelID(channel.String)
+
+	c.presence.lock.Lock()
+	if st := c.presence.users[user]; st != nil {
+		if st.lastActive.After(seen.LastActive) {
+			seen.LastActive = st.lastActive
+		}
+		if st.lastMessage.After(seen.LastMessage) {
+			seen.LastMessage = st.lastMessage
+			seen.Channel = st.channel
+		}
+	}
+	c.presence.lock.Unlock()
+	return seen, nil
+}
+
+// SetPresenceOptOut stops or resumes tracking a user. Opting out deletes the
+// recorded activity of the user.
+func (c *Client) SetPresenceOptOut(user slack.UserID, optOut bool) error {
+	stmt, err := c.team.DB().Prepare(sqlPresenceSetOptOut)
+	if err != nil {
+		return errors.Wrap(err, "prepare")
+	}
+	defer stmt.Close()
+	_, err = stmt.Exec(string(user), optOut)
+	if err != nil {
+		return errors.Wrap(err, "set presence opt-out")
+	}
+
+	c.presence.lock.Lock()
+	if optOut {
+		c.presence.optedOut[user] = true
+		delete(c.presence.users, user)
+		delete(c.presence.interest, user)
+	} else {
+		delete(c.presence.optedOut, user)
+	}
+	c.presence.lock.Unlock()
+
+	if optOut {
+		go c.sendPresenceSub()
+	}
+	return nil
+}
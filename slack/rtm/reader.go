@@ -76,6 +76,10 @@ func (c *Client) pump() {
 		}
 
 		c.resetPingTimer()
+		if c.recorder != nil {
+			c.recorder.recordFrame(RecordReceive, msg.Original())
+		}
+		c.trackEvent(msg)
 		if _, ok := msg["reply_to"]; ok {
 			replyToId := msg.ReplyTo()
 			c.sendCbsLock.Lock()
@@ -93,6 +97,9 @@ func (c *Client) pump() {
 
 func (c *Client) pumpSend() {
 	for bytes := range c.sendChan {
+		if c.recorder != nil {
+			c.recorder.recordFrame(RecordSend, bytes)
+		}
 		c.connLock.L.Lock()
 		for {
 			if c.conn == nil {
@@ -147,38 +154,3 @@ func (c *Client) resetPingTimer() {
 	c.pingTimer.Reset(pingOnIdleTime)
 	c.connLock.L.Unlock()
 }
-
-func (c *Client) dispatchMessage(msg slack.RTMRawMessage) {
-	c.msgCbsLock.RLock()
-	defer c.msgCbsLock.RUnlock()
-
-	for _, v := range c.msgCbs {
-		if v.MsgType != MsgTypeAll && msg.Type() != v.MsgType {
-			continue
-		}
-		if v.MsgType != MsgTypeAll && len(v.SubtypesOnly) != 0 {
-			msgType := msg.Subtype()
-			found := false
-			for _, v := range v.SubtypesOnly {
-				if msgType == v {
-					found = true
-					break
-				}
-			}
-			if !found {
-				continue
-			}
-		}
-		go dispatchOne(v, msg)
-	}
-}
-
-func dispatchOne(handler messageHandler, msg slack.RTMRawMessage) {
-	defer func() {
-		if err := recover(); err != nil {
-			util.LogError(errors.Errorf("A message handler callback panicked: %+v", err))
-		}
-	}()
-
-	handler.Cb(msg)
-}
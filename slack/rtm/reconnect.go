@@ -0,0 +1,129 @@
+package rtm
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/riking/marvin/slack"
+	"github.com/riking/marvin/util"
+)
+
+// MsgTypeResumed is the type of the synthetic event sent after the client
+// reconnects. Its "last_event_ts" field is the timestamp of the last event
+// received before the disconnect, and "disconnected_at" is the Unix time the
+// connection was lost. Handlers can use it to catch up on missed events.
+const MsgTypeResumed = "resumed"
+
+const (
+	reconnectMinDelay = 1 * time.Second
+	reconnectMaxDelay = 5 * time.Minute
+)
+
+// reconnectDelay returns the time to wait before the given reconnect
+// attempt (starting at 0), with up to 50% jitter.
+func reconnectDelay(rng *rand.Rand, attempt int) time.Duration {
+	delay := reconnectMinDelay
+	for i := 0; i < attempt && delay < reconnectMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > reconnectMaxDelay {
+		delay = reconnectMaxDelay
+	}
+	return delay/2 + time.Duration(rng.Int63n(int64(delay/2)+1))
+}
+
+func (c *Client) reconnectWorker() {
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	doReconnect := func() {
+		c.connLock.L.Lock()
+		if c.conn != nil {
+			c.conn.Close()
+			c.disconnectedAt = time.Now()
+		}
+		c.conn = nil
+		c.connLock.L.Unlock()
+		util.LogWarn("Disconnected.")
+
+		for attempt := 0; ; attempt++ {
+			util.LogWarn("Reconnecting...")
+			err := c.Connect()
+			if err != nil {
+				delay := reconnectDelay(rng, attempt)
+				util.LogBad("Could not reconnect, retrying in", delay, err)
+				time.Sleep(delay)
+				continue
+			}
+			break
+		}
+		c.connLock.Broadcast()
+	}
+
+	for range c.needReconn {
+		doReconnect()
+	}
+}
+
+// onGoodbye reconnects right away when Slack says it is about to close the
+// connection.
+func (c *Client) onGoodbye(msg slack.RTMRawMessage) {
+	util.LogWarn("Slack sent goodbye, reconnecting")
+	c.connLock.L.Lock()
+	c.reconnect()
+	c.connLock.L.Unlock()
+}
+
+func (c *Client) onReconnectURL(msg slack.RTMRawMessage) {
+	u := msg.StringField("url")
+	if u == "" {
+		return
+	}
+	c.connLock.L.Lock()
+	c.reconnectURL = u
+	c.connLock.L.Unlock()
+}
+
+// trackEvent remembers the timestamp of the latest event.
+func (c *Client) trackEvent(msg slack.RTMRawMessage) {
+	ts := slack.MessageTS(msg.StringField("event_ts"))
+	if ts == "" {
+		ts = msg.MessageTS()
+	}
+	if ts == "" {
+		return
+	}
+	c.eventLock.Lock()
+	if ts > c.lastEventTS {
+		c.lastEventTS = ts
+	}
+	c.lastEventTime = time.Now()
+	c.eventLock.Unlock()
+}
+
+// LastEvent returns the timestamp of the newest event received, and when it
+// was received.
+func (c *Client) LastEvent() (slack.MessageTS, time.Time) {
+	c.eventLock.Lock()
+	defer c.eventLock.Unlock()
+	return c.lastEventTS, c.lastEventTime
+}
+
+// sendResumed dispatches a MsgTypeResumed event if this connection replaced
+// one that was lost.
+func (c *Client) sendResumed() {
+	c.connLock.L.Lock()
+	disconnectedAt := c.disconnectedAt
+	c.disconnectedAt = time.Time{}
+	c.connLock.L.Unlock()
+	if disconnectedAt.IsZero() {
+		return
+	}
+
+	lastTS, _ := c.LastEvent()
+	msg := slack.RTMRawMessage{
+		"type":            MsgTypeResumed,
+		"last_event_ts":   string(lastTS),
+		"disconnected_at": float64(disconnectedAt.Unix()),
+	}
+	util.LogGood("Resumed after", time.Since(disconnectedAt).Round(time.Second), "- last event", lastTS)
+	c.dispatchMessage(msg)
+}
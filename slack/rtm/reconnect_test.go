@@ -0,0 +1,37 @@
+package rtm
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestReconnectDelay(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	tests := []struct {
+		attempt int
+		max     time.Duration
+	}{
+		{0, reconnectMinDelay},
+		{1, 2 * reconnectMinDelay},
+		{2, 4 * reconnectMinDelay},
+		{5, 32 * reconnectMinDelay},
+		{8, 256 * reconnectMinDelay},
+		{9, reconnectMaxDelay},
+		{20, reconnectMaxDelay},
+		{1000, reconnectMaxDelay},
+	}
+	for _, v := range tests {
+		seen := make(map[time.Duration]bool)
+		for i := 0; i < 50; i++ {
+			got := reconnectDelay(rng, v.attempt)
+			if got < v.max/2 || got > v.max {
+				t.Errorf("attempt %d: expected a delay in [%v, %v], got %v", v.attempt, v.max/2, v.max, got)
+			}
+			seen[got] = true
+		}
+		if len(seen) < 2 {
+			t.Errorf("attempt %d: expected jitter, got the same delay every time", v.attempt)
+		}
+	}
+}
@@ -0,0 +1,320 @@
+package rtm
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/riking/marvin"
+	"github.com/riking/marvin/slack"
+	"github.com/riking/marvin/util"
+)
+
+// Kinds of recorded entries.
+const (
+	// RecordReceive is a frame received over the RTM websocket.
+	RecordReceive = "recv"
+	// RecordSend is a frame sent over the RTM websocket.
+	RecordSend = "send"
+	// RecordAPI is a Slack Web API call and its response.
+	RecordAPI = "api"
+)
+
+// A RecordEntry is one line of a recording.
+type RecordEntry struct {
+	Time time.Time `json:"time"`
+	Kind string    `json:"kind"`
+	// Method and Form are only set for API calls. The token is removed
+	// from the form.
+	Method string          `json:"method,omitempty"`
+	Form   url.Values      `json:"form,omitempty"`
+	Data   json.RawMessage `json:"data"`
+}
+
+// A Recorder writes the RTM frames and API calls of a client to a JSONL
+// file, so they can be replayed later with Client.Replay.
+type Recorder struct {
+	lock sync.Mutex
+	f    *os.File
+	w    *bufio.Writer
+}
+
+// NewRecorder starts a recording, appending to the file if it exists.
+func NewRecorder(path string) (*Recorder, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, errors.Wrap(err, "open recording")
+	}
+	return &Recorder{f: f, w: bufio.NewWriter(f)}, nil
+}
+
+func (r *Recorder) Close() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	err := r.w.Flush()
+	if err != nil {
+		r.f.Close()
+		return err
+	}
+	return r.f.Close()
+}
+
+func (r *Recorder) record(e RecordEntry) {
+	e.Time = time.Now()
+	b, err := json.Marshal(e)
+	if err != nil {
+		util.LogError(errors.Wrap(err, "recording"))
+		return
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.w.Write(b)
+	r.w.WriteByte('\n')
+	r.w.Flush()
+}
+
+func (r *Recorder) recordFrame(kind string, data []byte) {
+	if len(data) == 0 || !json.Valid(data) {
+		return
+	}
+	r.record(RecordEntry{Kind: kind, Data: json.RawMessage(data)})
+}
+
+type recordingDoer struct {
+	r    *Recorder
+	next marvin.HTTPDoer
+}
+
+// WrapHTTP returns an HTTPDoer that records the Slack API calls made
+// through it.
+func (r *Recorder) WrapHTTP(next marvin.HTTPDoer) marvin.HTTPDoer {
+	return &recordingDoer{r: r, next: next}
+}
+
+func (d *recordingDoer) Do(req *http.Request) (*http.Response, error) {
+	var form url.Values
+	if req.Body != nil && req.Header.Get("Content-Type") == "application/x-www-form-urlencoded" {
+		body, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		form, _ = url.ParseQuery(string(body))
+		form.Del("token")
+	}
+
+	resp, err := d.next.Do(req)
+	if err != nil {
+		return resp, err
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if json.Valid(body) {
+		d.r.record(RecordEntry{
+			Kind:   RecordAPI,
+			Method: apiMethodName(req.URL),
+			Form:   form,
+			Data:   json.RawMessage(body),
+		})
+	}
+	return resp, nil
+}
+
+func apiMethodName(u *url.URL) string {
+	return strings.TrimPrefix(u.Path, "/api/")
+}
+
+// SetRecorder starts recording every frame the client sends and receives.
+// The recorder should also wrap the team's API client to record API calls.
+func (c *Client) SetRecorder(r *Recorder) {
+	c.recorder = r
+}
+
+// ReadRecording loads a recording made by a Recorder.
+func ReadRecording(path string) ([]RecordEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "open recording")
+	}
+	defer f.Close()
+
+	var entries []RecordEntry
+	dec := json.NewDecoder(f)
+	for dec.More() {
+		var e RecordEntry
+		err = dec.Decode(&e)
+		if err != nil {
+			return nil, errors.Wrapf(err, "read recording entry %d", len(entries)+1)
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// ReplayAPI is an HTTPDoer that answers Slack API calls with the responses
+// from a recording. Each method's responses are used in order, and the last
+// one is repeated when they run out. Methods that were never recorded get
+// {"ok": true}.
+type ReplayAPI struct {
+	lock      sync.Mutex
+	responses map[string][]json.RawMessage
+	calls     []RecordEntry
+}
+
+func NewReplayAPI(entries []RecordEntry) *ReplayAPI {
+	api := &ReplayAPI{responses: make(map[string][]json.RawMessage)}
+	for _, e := range entries {
+		if e.Kind == RecordAPI {
+			api.responses[e.Method] = append(api.responses[e.Method], e.Data)
+		}
+	}
+	return api
+}
+
+func (api *ReplayAPI) Do(req *http.Request) (*http.Response, error) {
+	var form url.Values
+	if req.Body != nil {
+		body, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		form, _ = url.ParseQuery(string(body))
+		form.Del("token")
+	}
+	method := apiMethodName(req.URL)
+
+	api.lock.Lock()
+	resp := json.RawMessage(`{"ok":true}`)
+	if list := api.responses[method]; len(list) > 0 {
+		resp = list[0]
+		if len(list) > 1 {
+			api.responses[method] = list[1:]
+		}
+	}
+	api.calls = append(api.calls, RecordEntry{Time: time.Now(), Kind: RecordAPI, Method: method, Form: form, Data: resp})
+	api.lock.Unlock()
+
+	return &http.Response{
+		Status:     "200 OK",
+		StatusCode: http.StatusOK,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       ioutil.NopCloser(bytes.NewReader(resp)),
+		Request:    req,
+	}, nil
+}
+
+// Calls returns the API calls made during the replay, for comparing against
+// the recording.
+func (api *ReplayAPI) Calls() []RecordEntry {
+	api.lock.Lock()
+	defer api.lock.Unlock()
+	return append([]RecordEntry(nil), api.calls...)
+}
+
+// Replay feeds the received frames of a recording to the event handlers,
+// instead of connecting to Slack. The team's API client should be a
+// ReplayAPI made from the same recording.
+//
+// speed scales the delays between frames; 0 replays them as fast as
+// possible. Replay returns after the last frame has been dispatched.
+func (c *Client) Replay(entries []RecordEntry, speed float64) error {
+	c.registerInternalHandlers()
+	go c.replaySend()
+
+	var start connectResponse
+	for _, e := range entries {
+		if e.Kind == RecordAPI && e.Method == "rtm.connect" {
+			err := json.Unmarshal(e.Data, &start)
+			if err != nil {
+				return errors.Wrap(err, "replay: decode rtm.connect")
+			}
+			break
+		}
+	}
+	c.MetadataLock.Lock()
+	c.Self = start.Self
+	c.Team = start.Team
+	c.MetadataLock.Unlock()
+	c.fetchTeamInfo()
+
+	return c.replayFrames(entries, speed)
+}
+
+// replayFrames dispatches the received frames of a recording.
+func (c *Client) replayFrames(entries []RecordEntry, speed float64) error {
+	var last time.Time
+	for i, e := range entries {
+		if e.Kind != RecordReceive {
+			continue
+		}
+		if speed > 0 && !last.IsZero() {
+			time.Sleep(time.Duration(float64(e.Time.Sub(last)) / speed))
+		}
+		last = e.Time
+
+		msg := make(slack.RTMRawMessage)
+		err := json.Unmarshal(e.Data, &msg)
+		if err != nil {
+			return errors.Wrapf(err, "replay: decode entry %d", i+1)
+		}
+		msg[slack.MsgFieldRawBytes] = []byte(e.Data)
+		if _, ok := msg["reply_to"]; ok {
+			// Replies are made up by replaySend
+			continue
+		}
+		c.dispatchMessage(msg)
+	}
+	return nil
+}
+
+// replaySend answers frames sent during a replay as if Slack had accepted
+// them.
+func (c *Client) replaySend() {
+	var counter int64
+	for b := range c.sendChan {
+		var out slack.RTMRawMessage
+		err := json.Unmarshal(b, &out)
+		if err != nil {
+			util.LogError(errors.Wrap(err, "replay: decode sent frame"))
+			continue
+		}
+		util.LogDebug("[replay] send", string(b))
+
+		id, _ := out["id"].(float64)
+		counter++
+		reply := slack.RTMRawMessage{
+			"ok":       true,
+			"reply_to": id,
+			"ts":       fmt.Sprintf("%d.%06d", time.Now().Unix(), counter%1000000),
+			"text":     out["text"],
+		}
+		c.sendCbsLock.Lock()
+		ch, ok := c.sendCbs[int(id)]
+		delete(c.sendCbs, int(id))
+		c.sendCbsLock.Unlock()
+		if ok {
+			ch <- reply
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package rtm
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/riking/marvin"
+	"github.com/riking/marvin/slack"
+	"github.com/riking/marvin/util"
+)
+
+const testRecording = "testdata/replay.jsonl"
+
+func TestReplayFrames(t *testing.T) {
+	entries, err := ReadRecording(testRecording)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewClient(nil)
+
+	all := make(chan string, 10)
+	c.RegisterRawHandler("test", func(msg slack.RTMRawMessage) {
+		all <- msg.Type()
+	}, MsgTypeAll, nil)
+	people := make(chan string, 10)
+	c.RegisterHandler("test", marvin.EventFilter{Type: "message", Bots: util.TriNo}, func(msg slack.RTMRawMessage) bool {
+		people <- msg.Text()
+		return false
+	})
+	reactions := make(chan slack.MessageID, 10)
+	c.RegisterRawHandler("test", func(msg slack.RTMRawMessage) {
+		ev, err := slack.DecodeEvent(msg)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if r, ok := ev.(*slack.ReactionEvent); ok {
+			reactions <- r.MessageID()
+		}
+	}, "reaction_added", nil)
+
+	err = c.replayFrames(entries, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Replies to sent frames are not dispatched
+	var types []string
+	for i := 0; i < 4; i++ {
+		types = append(types, receive(t, all))
+	}
+	sort.Strings(types)
+	if strings.Join(types, " ") != "hello message message reaction_added" {
+		t.Errorf("wrong events dispatched: %v", types)
+	}
+	if text := receive(t, people); text != "hello" {
+		t.Errorf("wrong message from a person: %q", text)
+	}
+	select {
+	case r := <-reactions:
+		if r != slack.MsgID("C1", "1508328003.000200") {
+			t.Errorf("wrong reaction target: %v", r)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("reaction was not dispatched")
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	if len(all) != 0 || len(people) != 0 {
+		t.Errorf("extra events dispatched: %d %d", len(all), len(people))
+	}
+}
+
+func receive(t *testing.T, ch chan string) string {
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(5 * time.Second):
+		t.Fatal("event was not dispatched")
+		return ""
+	}
+}
+
+func TestReplayAPI(t *testing.T) {
+	entries, err := ReadRecording(testRecording)
+	if err != nil {
+		t.Fatal(err)
+	}
+	api := NewReplayAPI(entries)
+
+	call := func(method string, form url.Values) json.RawMessage {
+		req, err := http.NewRequest("POST", "https://slack.com/api/"+method, strings.NewReader(form.Encode()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		resp, err := api.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	userName := func(b json.RawMessage) string {
+		var resp struct {
+			User slack.User `json:"user"`
+		}
+		err := json.Unmarshal(b, &resp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.User.Name
+	}
+
+	form := url.Values{"token": []string{"xoxb-secret"}, "user": []string{"U1"}}
+	// The last response is repeated
+	for i, expect := range []string{"alice", "alice2", "alice2"} {
+		if got := userName(call("users.info", form)); got != expect {
+			t.Errorf("users.info call %d: expected %s, got %s", i+1, expect, got)
+		}
+	}
+	if got := string(call("chat.postMessage", nil)); got != `{"ok":true}` {
+		t.Errorf("unrecorded method: expected ok, got %s", got)
+	}
+
+	calls := api.Calls()
+	if len(calls) != 4 {
+		t.Fatalf("expected 4 calls, got %d", len(calls))
+	}
+	if calls[0].Method != "users.info" || calls[0].Form.Get("user") != "U1" || calls[0].Form.Get("token") != "" {
+		t.Errorf("wrong call recorded: %+v", calls[0])
+	}
+	if calls[3].Method != "chat.postMessage" {
+		t.Errorf("wrong call recorded: %+v", calls[3])
+	}
+}
@@ -74,18 +74,48 @@ type Client struct {
 	msgCbsLock sync.RWMutex
 	started    bool // if false, no need to lock
 	msgCbs     []messageHandler
+	handlerID  uniqueID
 
 	sendCbsLock sync.Mutex
 	sendCbs     map[int]chan slack.RTMRawMessage
 
 	rtmMsgId uniqueID
+
+	recorder *Recorder
+
+	cacheLoad sync.Once
+	cacheLock sync.Mutex
+	cache     cacheState
+
+	presence presenceTracker
+
+	usergroups usergroupState
+
+	// Protected by connLock
+	reconnectURL   string
+	disconnectedAt time.Time
+	// Protected by eventLock
+	eventLock     sync.Mutex
+	lastEventTS   slack.MessageTS
+	lastEventTime time.Time
 }
 
-type messageHandler struct {
-	Cb           func(slack.RTMRawMessage)
-	MsgType      string
-	SubtypesOnly []string
-	Module       marvin.ModuleID
+// connectResponse is the part of the rtm.connect response the client uses.
+type connectResponse struct {
+	URL            string
+	CacheVersion   string `json:"cache_version"`
+	CacheTsVersion string `json:"cache_ts_version"`
+	Team           struct {
+		ID             slack.TeamID
+		Name           string
+		Domain         string
+		EnterpriseID   slack.EnterpriseID `json:"enterprise_id"`
+		EnterpriseName string             `json:"enterprise_name"`
+	}
+	Self struct {
+		ID   slack.UserID
+		Name string
+	}
 }
 
 // Dial tries to connect to the Slack RTM API. The caller should register
@@ -104,6 +134,8 @@ func NewClient(team marvin.Team) *Client {
 	c.sendCbs = make(map[int]chan slack.RTMRawMessage)
 	c.sendChan = make(chan []byte)
 
+	c.presence.init()
+	c.usergroups.groups = make(map[slack.UsergroupID]*slack.Usergroup)
 	c.channelMembers = make(membershipMap)
 	c.membershipCh = make(chan membershipRequest, 8)
 
@@ -113,25 +145,25 @@ func NewClient(team marvin.Team) *Client {
 }
 
 func (c *Client) Connect() error {
+	// Try the URL from the last reconnect_url event first, which skips
+	// calling rtm.connect again.
+	c.connLock.L.Lock()
+	reconnectURL := c.reconnectURL
+	c.reconnectURL = ""
+	c.connLock.L.Unlock()
+	if reconnectURL != "" {
+		err := c.dial(reconnectURL)
+		if err == nil {
+			util.LogGood("Reconnected to Slack using reconnect_url")
+			return nil
+		}
+		util.LogWarn("reconnect_url failed, using rtm.connect:", err)
+	}
+
 	data := url.Values{}
 	data.Set("token", c.team.TeamConfig().UserToken)
 	data.Set("presence_sub", "true") // Only get user presence when requested
-	var startResponse struct {
-		URL            string
-		CacheVersion   string `json:"cache_version"`
-		CacheTsVersion string `json:"cache_ts_version"`
-		Team           struct {
-			ID             slack.TeamID
-			Name           string
-			Domain         string
-			EnterpriseID   slack.EnterpriseID `json:"enterprise_id"`
-			EnterpriseName string             `json:"enterprise_name"`
-		}
-		Self struct {
-			ID   slack.UserID
-			Name string
-		}
-	}
+	var startResponse connectResponse
 	err := c.team.SlackAPIPostJSON("rtm.connect", data, &startResponse)
 	if err != nil {
 		return err
@@ -142,7 +174,33 @@ func (c *Client) Connect() error {
 	//if startResponse.CacheVersion != "v16-giraffe" {
 	//	panic(errors.Errorf("Unexpected CacheVersion %s", startResponse.CacheVersion))
 	//}
-	wsURL, err := url.Parse(startResponse.URL)
+
+	c.MetadataLock.Lock()
+	c.Self = startResponse.Self
+	c.Team = startResponse.Team
+	//c.Users = startResponse.Client.Users
+	//c.AboutTeam = startResponse.Client.AboutTeam
+	//c.Channels = startResponse.Client.Channels
+	//c.Groups = startResponse.Client.Groups
+	//c.Mpims = startResponse.Client.Mpims
+	//c.Ims = startResponse.Client.Ims
+	//c.Bots = startResponse.Client.Bots
+	//c.LatestEventTs = startResponse.Client.LatestEventTs
+	c.MetadataLock.Unlock()
+
+	go c.fetchTeamInfo()
+
+	err = c.dial(startResponse.URL)
+	if err != nil {
+		return err
+	}
+	util.LogGood("Connected to Slack", startResponse.CacheVersion)
+	return nil
+}
+
+// dial connects to the RTM websocket and waits for the hello message.
+func (c *Client) dial(rawURL string) error {
+	wsURL, err := url.Parse(rawURL)
 	if err != nil {
 		return errors.Wrap(err, "start RTM - could not parse URL")
 	}
@@ -160,27 +218,16 @@ func (c *Client) Connect() error {
 		return errors.Wrap(err, "connect slack websocket")
 	}
 
-	c.MetadataLock.Lock()
-	c.Self = startResponse.Self
-	c.Team = startResponse.Team
-	//c.Users = startResponse.Client.Users
-	//c.AboutTeam = startResponse.Client.AboutTeam
-	//c.Channels = startResponse.Client.Channels
-	//c.Groups = startResponse.Client.Groups
-	//c.Mpims = startResponse.Client.Mpims
-	//c.Ims = startResponse.Client.Ims
-	//c.Bots = startResponse.Client.Bots
-	//c.LatestEventTs = startResponse.Client.LatestEventTs
-	c.MetadataLock.Unlock()
-
-	go c.fetchTeamInfo()
-
 	var msg slack.RTMRawMessage
 	err = c.codec.Receive(conn, &msg)
 	if err != nil {
 		return errors.Wrap(err, "receive first message from Slack")
 	}
+	if c.recorder != nil {
+		c.recorder.recordFrame(RecordReceive, msg.Original())
+	}
 	if msg.Type() != "hello" {
+		conn.Close()
 		return errors.Errorf("Wrong type for first message, expected 'hello' got %s: %v", msg.Type(), msg)
 	}
 
@@ -190,105 +237,81 @@ func (c *Client) Connect() error {
 	c.connLock.L.Unlock()
 
 	c.dispatchMessage(msg)
-
-	util.LogGood("Connected to Slack", startResponse.CacheVersion)
+	c.sendResumed()
 	return nil
 }
 
 func (c *Client) Start() {
-	c.RegisterRawHandler("__internal", c.onChannelJoin, "channel_joined", nil)
-	c.RegisterRawHandler("__internal", c.onGroupJoin, "group_joined", nil)
-	c.RegisterRawHandler("__internal", c.onIMCreate, "im_created", nil)
-	c.RegisterRawHandler("__internal", c.onTopicChange, "message", []string{"channel_topic", "group_topic"})
-	c.RegisterRawHandler("__internal", c.onPurposeChange, "message", []string{"channel_purpose", "group_purpose"})
-
-	c.RegisterRawHandler("__internal", c.onUserChange, "user_change", nil)
-	c.RegisterRawHandler("__internal", c.onUserChange, "team_join", nil)
-
-	c.RegisterRawHandler("__internal", c.onUserJoinChannel, "message", []string{"channel_join", "group_join"})
-	c.RegisterRawHandler("__internal", c.onUserLeaveChannel, "message", []string{"channel_leave", "group_leave"})
-
+	c.registerInternalHandlers()
 	c.started = true
 	go c.pump()
 	go c.pumpSend()
 	go c.pinger()
+	go c.cacheRefresher()
+	go c.presenceSubWorker()
+	go c.presenceFlusher()
 	c.reconnect()
 }
 
-func (c *Client) reconnect() {
-	// called holding c.connLock
-	select {
-	case c.needReconn <- struct{}{}:
-	default:
-	}
-}
-
-func (c *Client) reconnectWorker() {
-	doReconnect := func() {
-		c.connLock.L.Lock()
-		if c.conn != nil {
-			c.conn.Close()
-		}
-		c.conn = nil
-		c.connLock.L.Unlock()
-		util.LogWarn("Disconnected.")
-
-		for {
-			util.LogWarn("Reconnecting...")
-			err := c.Connect()
-			if err != nil {
-				util.LogBad("Could not reconnect", err)
-				time.Sleep(30 * time.Second)
-				continue
-			}
-			break
-		}
-		c.connLock.Broadcast()
-	}
-
-	for range c.needReconn {
-		doReconnect()
-	}
+func (c *Client) registerInternalHandlers() {
+	c.onInternalEvent(c.onChannelJoin, "channel_joined", nil)
+	c.onInternalEvent(c.onGroupJoin, "group_joined", nil)
+	c.onInternalEvent(c.onIMCreate, "im_created", nil)
+	c.onInternalEvent(c.onGoodbye, "goodbye", nil)
+	c.onInternalEvent(c.onReconnectURL, "reconnect_url", nil)
+	c.onInternalEvent(c.onTopicChange, "message", []string{"channel_topic", "group_topic"})
+	c.onInternalEvent(c.onPurposeChange, "message", []string{"channel_purpose", "group_purpose"})
+
+	c.onInternalEvent(c.onUserChange, "user_change", nil)
+	c.onInternalEvent(c.onUserChange, "team_join", nil)
+	c.onInternalEvent(c.onChannelCreated, "channel_created", nil)
+	c.onInternalEvent(c.onChannelRename, "channel_rename", nil)
+	c.onInternalEvent(c.onChannelRename, "group_rename", nil)
+	c.onInternalEvent(c.onChannelArchive, "channel_archive", nil)
+	c.onInternalEvent(c.onChannelArchive, "channel_unarchive", nil)
+	c.onInternalEvent(c.onChannelArchive, "group_archive", nil)
+	c.onInternalEvent(c.onChannelArchive, "group_unarchive", nil)
+	c.onInternalEvent(c.onChannelDeleted, "channel_deleted", nil)
+
+	c.onInternalEvent(c.onUserJoinChannel, "message", []string{"channel_join", "group_join"})
+	c.onInternalEvent(c.onUserLeaveChannel, "message", []string{"channel_leave", "group_leave"})
+	c.onInternalEvent(c.onPresenceHello, "hello", nil)
+	c.onInternalEvent(c.onPresenceChange, "presence_change", nil)
+	c.onInternalEvent(c.onPresenceMessage, "message", nil)
+	c.onInternalEvent(c.onUserJoinChannel, "member_joined_channel", nil)
+	c.onInternalEvent(c.onUserLeaveChannel, "member_left_channel", nil)
+	c.onInternalEvent(c.onSubteamUpdated, "subteam_created", nil)
+	c.onInternalEvent(c.onSubteamUpdated, "subteam_updated", nil)
+	c.onInternalEvent(c.onSubteamMembersChanged, "subteam_members_changed", nil)
 }
 
-func (c *Client) RegisterRawHandler(
-	mod marvin.ModuleID,
-	cb func(slack.RTMRawMessage),
-	typeOnly string, subtypes []string,
-) {
-	if typeOnly == "" && len(subtypes) > 0 {
-		panic("cannot specify subtypes without specifying type")
-	}
-
-	c.msgCbsLock.Lock()
-	defer c.msgCbsLock.Unlock()
-
-	c.msgCbs = append(c.msgCbs, messageHandler{
-		Cb:           cb,
-		MsgType:      typeOnly,
-		SubtypesOnly: subtypes,
+// onInternalEvent registers a handler used to keep the client's metadata up
+// to date. It sees every event, even ones consumed by modules.
+func (c *Client) onInternalEvent(cb func(slack.RTMRawMessage), typeOnly string, subtypes []string) {
+	c.RegisterHandler("__internal", marvin.EventFilter{
+		Type:     typeOnly,
+		Subtypes: subtypes,
+		Observer: true,
+	}, func(msg slack.RTMRawMessage) bool {
+		cb(msg)
+		return false
 	})
 }
 
-func (c *Client) UnregisterAllMatching(mod marvin.ModuleID) {
-	c.msgCbsLock.Lock()
-	defer c.msgCbsLock.Unlock()
-
-	newMsgCbs := make([]messageHandler, 0, len(c.msgCbs))
-	for _, v := range c.msgCbs {
-		if v.Module != mod {
-			newMsgCbs = append(newMsgCbs, v)
-		}
+func (c *Client) reconnect() {
+	// called holding c.connLock
+	select {
+	case c.needReconn <- struct{}{}:
+	default:
 	}
-	c.msgCbs = newMsgCbs
-	return
 }
 
-// SendMessage sends a simple message over the RTM api.
+// SendMessage sends a simple message over the RTM api. Messages longer than
+// slack.MaxMessageLength are rejected; Team.SendMessage uploads those instead.
 // When the Slack API returns an error, the error will be of type slack.CodedError.
 func (c *Client) SendMessage(channelID slack.ChannelID, message string) (slack.RTMRawMessage, error) {
-	if len(message) > 4000 {
-		message = fmt.Sprintf("[TRUNCATED/MESSAGE TOO LONG]\n%s", message[:4100])
+	if len(message) > slack.MaxMessageLength {
+		return nil, errors.Errorf("message too long (%d > %d bytes)", len(message), slack.MaxMessageLength)
 	}
 	outgoing := make(slack.RTMRawMessage)
 	outgoing["channel"] = string(channelID)
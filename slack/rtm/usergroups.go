@@ -0,0 +1,173 @@
+package rtm
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/riking/marvin/slack"
+	"github.com/riking/marvin/util"
+)
+
+// usergroupRefreshInterval is how long the user group list is used before
+// it is fetched again.
+const usergroupRefreshInterval = 1 * time.Hour
+
+// usergroupState holds the user groups of the team. The Usergroup objects
+// are replaced, never modified, so they can be used without the lock.
+type usergroupState struct {
+	lock       sync.RWMutex
+	load       sync.Once
+	groups     map[slack.UsergroupID]*slack.Usergroup
+	fetched    time.Time
+	refreshing bool
+}
+
+// fetchUsergroups replaces the user group list with usergroups.list.
+func (c *Client) fetchUsergroups() error {
+	form := url.Values{
+		"include_users":    []string{"true"},
+		"include_disabled": []string{"false"},
+	}
+	var response struct {
+		Usergroups []*slack.Usergroup `json:"usergroups"`
+	}
+	err := c.team.SlackAPIPostJSON("usergroups.list", form, &response)
+
+	c.usergroups.lock.Lock()
+	defer c.usergroups.lock.Unlock()
+	// Don't retry until the next refresh, even on error, because teams
+	// without user groups get an error every time
+	c.usergroups.fetched = time.Now()
+	c.usergroups.refreshing = false
+	if err != nil {
+		return errors.Wrap(err, "fetch user groups")
+	}
+	groups := make(map[slack.UsergroupID]*slack.Usergroup, len(response.Usergroups))
+	for _, v := range response.Usergroups {
+		groups[v.ID] = v
+	}
+	c.usergroups.groups = groups
+	return nil
+}
+
+// ensureUsergroups fetches the user groups the first time it is called, and
+// starts a refresh in the background if they are out of date.
+func (c *Client) ensureUsergroups() {
+	c.usergroups.load.Do(func() {
+		util.LogIfError(c.fetchUsergroups())
+	})
+
+	c.usergroups.lock.Lock()
+	stale := !c.usergroups.refreshing && time.Since(c.usergroups.fetched) > usergroupRefreshInterval
+	if stale {
+		c.usergroups.refreshing = true
+	}
+	c.usergroups.lock.Unlock()
+	if stale {
+		go func() {
+			util.LogIfError(c.fetchUsergroups())
+		}()
+	}
+}
+
+// Usergroup returns the user group with the ID, or nil if it is not known.
+// The returned object must not be modified.
+func (c *Client) Usergroup(id slack.UsergroupID) *slack.Usergroup {
+	c.ensureUsergroups()
+
+	c.usergroups.lock.RLock()
+	defer c.usergroups.lock.RUnlock()
+	return c.usergroups.groups[id]
+}
+
+// UsergroupByHandle returns the user group with the handle or name, or nil.
+func (c *Client) UsergroupByHandle(handle string) *slack.Usergroup {
+	c.ensureUsergroups()
+
+	handle = strings.TrimPrefix(handle, "@")
+	c.usergroups.lock.RLock()
+	defer c.usergroups.lock.RUnlock()
+	for _, v := range c.usergroups.groups {
+		if v.Handle == handle {
+			return v
+		}
+	}
+	for _, v := range c.usergroups.groups {
+		if v.Name == handle {
+			return v
+		}
+	}
+	return nil
+}
+
+// UserUsergroups returns the IDs of the user groups the user is in.
+func (c *Client) UserUsergroups(user slack.UserID) []slack.UsergroupID {
+	c.ensureUsergroups()
+
+	var result []slack.UsergroupID
+	c.usergroups.lock.RLock()
+	defer c.usergroups.lock.RUnlock()
+	for id, v := range c.usergroups.groups {
+		for _, u := range v.Users {
+			if u == user {
+				result = append(result, id)
+				break
+			}
+		}
+	}
+	return result
+}
+
+func (c *Client) replaceUsergroup(group *slack.Usergroup) {
+	c.usergroups.lock.Lock()
+	defer c.usergroups.lock.Unlock()
+	if group.IsDisabled() {
+		delete(c.usergroups.groups, group.ID)
+	} else {
+		c.usergroups.groups[group.ID] = group
+	}
+}
+
+func (c *Client) onSubteamUpdated(msg slack.RTMRawMessage) {
+	var resp struct {
+		Subteam *slack.Usergroup `json:"subteam"`
+	}
+	err := msg.ReMarshal(&resp)
+	if err != nil {
+		util.LogError(errors.Wrapf(err, "decode %s", msg.Type()))
+		return
+	}
+	if resp.Subteam == nil {
+		return
+	}
+	c.replaceUsergroup(resp.Subteam)
+}
+
+func (c *Client) onSubteamMembersChanged(msg slack.RTMRawMessage) {
+	id := slack.UsergroupID(msg.StringField("subteam_id"))
+	c.usergroups.lock.RLock()
+	group := c.usergroups.groups[id]
+	c.usergroups.lock.RUnlock()
+	if group == nil {
+		return
+	}
+	go func() {
+		form := url.Values{"usergroup": []string{string(id)}}
+		var response struct {
+			Users []slack.UserID `json:"users"`
+		}
+		err := c.team.SlackAPIPostJSON("usergroups.users.list", form, &response)
+		if err != nil {
+			util.LogError(errors.Wrapf(err, "fetch members of user group %s", id))
+			return
+		}
+		updated := *group
+		updated.Users = response.Users
+		updated.UserCount = len(response.Users)
+		c.replaceUsergroup(&updated)
+	}()
+}
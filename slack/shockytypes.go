@@ -120,8 +120,11 @@ const (
 )
 
 type OutgoingSlackMessage struct {
+	// Text is the fallback for notifications when Blocks are set. If it is
+	// empty, the fallback is built from the blocks.
 	Text        string        `json:"text,omitempty"`
 	ThreadTS    MessageTS     `json:"thread_ts,omitempty"`
+	Blocks      []Block       `json:"blocks,omitempty"`
 	Attachments []Attachment  `json:"attachments,omitempty"`
 	UnfurlLinks util.TriValue `json:"unfurl_links,omitempty"`
 	UnfurlMedia util.TriValue `json:"unfurl_media,omitempty"`
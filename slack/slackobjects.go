@@ -11,6 +11,7 @@ type UserID string
 type ChannelID string
 type FileID string
 type FileCommentID string
+type UsergroupID string
 type MessageTS string
 
 const MessageTSCharsAfterDot = 6
@@ -160,13 +161,32 @@ func (u *User) Avatar(size int) string {
 	return bestURL
 }
 
+// Usergroup is a user group, also called a subteam.
+type Usergroup struct {
+	ID          UsergroupID `json:"id"`
+	TeamID      TeamID      `json:"team_id"`
+	Name        string      `json:"name"`
+	Handle      string      `json:"handle"`
+	Description string      `json:"description"`
+	DateDelete  int64       `json:"date_delete"`
+	Users       []UserID    `json:"users"`
+	UserCount   int         `json:"user_count"`
+}
+
+// IsDisabled reports whether the user group was disabled.
+func (g *Usergroup) IsDisabled() bool { return g.DateDelete != 0 }
+
 type ChannelTopicPurpose struct {
 	Value   string
 	Creator UserID
 	LastSet float64 `json:"last_set"`
 }
 
-type Channel struct {
+// A Conversation is a public channel, private channel, multi-person IM or
+// IM, as returned by the conversations.* API methods. Private channels may
+// have IDs starting with either C or G, so use the methods below instead of
+// the ID to tell them apart.
+type Conversation struct {
 	CacheTS  time.Time `json:"-"`
 	NotExist bool      `json:"-"`
 
@@ -175,6 +195,10 @@ type Channel struct {
 	IsChannel  bool        `json:"is_channel"`
 	IsGroup    interface{} `json:"is_group"`
 	IsMPIM     interface{} `json:"is_mpim"`
+	IsIM       bool        `json:"is_im"`
+	IsPrivate  bool        `json:"is_private"`
+	IsMember   bool        `json:"is_member"`
+	IsShared   bool        `json:"is_shared"`
 	Created    int         // unix millis
 	Creator    UserID
 	IsArchived bool `json:"is_archived"`
@@ -187,13 +211,26 @@ type Channel struct {
 	NumMembers int      `json:"num_members"`
 
 	// IM only
-	IsUserDeleted bool `json:"is_user_deleted"`
-	IsOpen        bool `json:"is_open"`
+	User          UserID `json:"user"`
+	IsUserDeleted bool   `json:"is_user_deleted"`
+	IsOpen        bool   `json:"is_open"`
 
 	Topic   ChannelTopicPurpose
 	Purpose ChannelTopicPurpose
 }
 
+// Channel is the older name of Conversation, from when each kind of
+// conversation had its own API methods.
+type Channel = Conversation
+
+// Kinds of conversations returned by Conversation.Type.
+const (
+	ConversationPublic  = "public"
+	ConversationPrivate = "private"
+	ConversationMPIM    = "mpim"
+	ConversationIM      = "im"
+)
+
 type ChannelIM struct {
 	ID            ChannelID `json:"id"`
 	User          UserID    `json:"user"`
@@ -201,28 +238,39 @@ type ChannelIM struct {
 	IsUserDeleted bool      `json:"is_user_deleted"`
 }
 
-func (c *Channel) IsPublicChannel() bool {
-	return c.IsChannel
+func (c *Conversation) IsPublicChannel() bool {
+	return c.Type() == ConversationPublic
 }
 
-func (c *Channel) IsPrivateChannel() bool {
-	str, ok := c.IsGroup.(string)
-	if ok {
-		return str == "true"
-	}
-	b, ok := c.IsGroup.(bool)
-	if ok {
-		return b
+func (c *Conversation) IsPrivateChannel() bool {
+	return c.Type() == ConversationPrivate
+}
+
+func (c *Conversation) IsMultiIM() bool {
+	return flagValue(c.IsMPIM)
+}
+
+// Type returns one of ConversationPublic, ConversationPrivate,
+// ConversationMPIM or ConversationIM.
+func (c *Conversation) Type() string {
+	switch {
+	case c.IsIM:
+		return ConversationIM
+	case c.IsMultiIM():
+		return ConversationMPIM
+	case c.IsPrivate || flagValue(c.IsGroup):
+		return ConversationPrivate
 	}
-	return false
+	return ConversationPublic
 }
 
-func (c *Channel) IsMultiIM() bool {
-	str, ok := c.IsMPIM.(string)
+// flagValue reads a boolean field that older API methods sent as a string.
+func flagValue(v interface{}) bool {
+	str, ok := v.(string)
 	if ok {
 		return str == "true"
 	}
-	b, ok := c.IsMPIM.(bool)
+	b, ok := v.(bool)
 	if ok {
 		return b
 	}
@@ -249,6 +297,57 @@ type PinnedItem struct {
 	} `json:"comment"`
 }
 
+// File is a file uploaded to Slack, such as a snippet.
+type File struct {
+	ID                 FileID `json:"id"`
+	Created            int64  `json:"created"`
+	Name               string `json:"name"`
+	Title              string `json:"title"`
+	Mimetype           string `json:"mimetype"`
+	Filetype           string `json:"filetype"`
+	User               UserID `json:"user"`
+	Size               int    `json:"size"`
+	URLPrivate         string `json:"url_private"`
+	URLPrivateDownload string `json:"url_private_download"`
+	Permalink          string `json:"permalink"`
+	Shares             struct {
+		Public  map[ChannelID][]FileShare `json:"public"`
+		Private map[ChannelID][]FileShare `json:"private"`
+	} `json:"shares"`
+}
+
+// FileShare is a message that shared a file.
+type FileShare struct {
+	TS MessageTS `json:"ts"`
+}
+
+// ShareTS returns the timestamp of the message that shared the file to the
+// channel, if it is known.
+func (f *File) ShareTS(channel ChannelID) MessageTS {
+	for _, shares := range []map[ChannelID][]FileShare{f.Shares.Public, f.Shares.Private} {
+		if list := shares[channel]; len(list) > 0 {
+			return list[0].TS
+		}
+	}
+	return ""
+}
+
+// FileUpload describes a file to upload with files.upload.
+type FileUpload struct {
+	Filename string
+	Title    string
+	// Filetype is a Slack file type such as "text" or "png". Slack guesses
+	// the type if it is empty.
+	Filetype       string
+	InitialComment string
+	// ThreadTS shares the file as a reply in the thread.
+	ThreadTS MessageTS
+	Content  []byte
+}
+
+// MaxMessageLength is the longest message text Slack will accept.
+const MaxMessageLength = 4000
+
 type SelfPrefs struct {
 	MutedChannels string `json:"muted_channels"`
 
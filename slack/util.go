@@ -15,6 +15,8 @@ var (
 	channelIDRgx      = regexp.MustCompile(`C[A-Z0-9]+`)
 	groupIDRgx        = regexp.MustCompile(`G[A-Z0-9]+`)
 	dmIDRgx           = regexp.MustCompile(`D[A-Z0-9]+`)
+	subteamMentionRgx = regexp.MustCompile(`<!subteam\^(S[A-Z0-9]+)(?:\|[^>]*)?>`)
+	usergroupIDRgx    = regexp.MustCompile(`^S[A-Z0-9]+$`)
 )
 
 func ParseUserMention(arg string) UserID {
@@ -29,6 +31,26 @@ func ParseUserMention(arg string) UserID {
 	return ""
 }
 
+// ParseUsergroupMention returns the ID of the user group mentioned in the
+// argument as <!subteam^S…>. Bare IDs are not accepted, as they can't be
+// told apart from user names; see ParseUsergroupID.
+func ParseUsergroupMention(arg string) UsergroupID {
+	match := subteamMentionRgx.FindStringSubmatch(arg)
+	if match != nil {
+		return UsergroupID(match[1])
+	}
+	return ""
+}
+
+// ParseUsergroupID returns the argument if it has the form of a user group
+// ID.
+func ParseUsergroupID(arg string) UsergroupID {
+	if usergroupIDRgx.MatchString(arg) {
+		return UsergroupID(arg)
+	}
+	return ""
+}
+
 func ParseChannelID(arg string) ChannelID {
 	match := channelMentionRgx.FindStringSubmatch(arg)
 	if match != nil {
@@ -56,27 +78,19 @@ func IsDMChannel(channel ChannelID) bool {
 	return channel[0] == 'D'
 }
 
+// ArchiveURL links to a message. The channel name is optional, and the
+// channel ID is used in the link when it is empty.
 func ArchiveURL(teamDomain string, channelName string, msgID MessageID) string {
 	splitTS := strings.Split(string(msgID.MessageTS), ".")
-	stripTS := "p" + splitTS[0] + splitTS[1]
+	stripTS := "p" + strings.Join(splitTS, "")
 
-	channel := msgID.ChannelID
-	if channel[0] == 'D' {
-		return fmt.Sprintf("https://%s.slack.com/archives/%s/%s",
-			teamDomain, channel, stripTS)
-	}
-	if channel[0] == 'G' {
-		if channelName != "" {
-			return fmt.Sprintf("https://%s.slack.com/archives/%s/%s",
-				teamDomain, channelName, stripTS)
-		} else {
-			return fmt.Sprintf("https://%s.slack.com/archives/%s/%s",
-				teamDomain, msgID.ChannelID, stripTS)
-		}
+	channel := channelName
+	if channel == "" {
+		channel = string(msgID.ChannelID)
 	}
-	if channel[0] == 'C' {
-		return fmt.Sprintf("https://%s.slack.com/archives/%s/%s",
-			teamDomain, channelName, stripTS)
+	if channel == "" {
+		panic(errors.Errorf("Empty channel passed to ArchiveURL"))
 	}
-	panic(errors.Errorf("Invalid channel id '%s' passed to ArchiveURL", channel))
+	return fmt.Sprintf("https://%s.slack.com/archives/%s/%s",
+		teamDomain, channel, stripTS)
 }
@@ -0,0 +1,30 @@
+package slack
+
+import "testing"
+
+func TestParseUsergroupMention(t *testing.T) {
+	tests := []struct {
+		input   string
+		mention UsergroupID
+		id      UsergroupID
+	}{
+		{"<!subteam^S1234ABCD>", "S1234ABCD", ""},
+		{"<!subteam^S1234ABCD|@admins>", "S1234ABCD", ""},
+		{"S1234ABCD", "", "S1234ABCD"},
+		// A user name that looks like a group ID
+		{"SAM2", "", "SAM2"},
+		{"sam2", "", ""},
+		{"@admins", "", ""},
+		{"<@U1234>", "", ""},
+		{"xS1234", "", ""},
+		{"", "", ""},
+	}
+	for _, v := range tests {
+		if got := ParseUsergroupMention(v.input); got != v.mention {
+			t.Errorf("ParseUsergroupMention(%q): expected %q, got %q", v.input, v.mention, got)
+		}
+		if got := ParseUsergroupID(v.input); got != v.id {
+			t.Errorf("ParseUsergroupID(%q): expected %q, got %q", v.input, v.id, got)
+		}
+	}
+}
@@ -1,9 +1,12 @@
 package marvin
 
 import (
+	"fmt"
 	"sort"
 	"strings"
 	"sync"
+
+	"github.com/riking/marvin/util"
 )
 
 type subCommandWithHelp struct {
@@ -11,14 +14,49 @@ type subCommandWithHelp struct {
 	help string
 }
 
-func (sc subCommandWithHelp) Handle(t Team, args *CommandArguments) CommandResult {
+// CommandFunc makes a SubCommand from a function and its help text.
+func CommandFunc(f SubCommandFunc, help string) SubCommand {
+	return &subCommandWithHelp{f: f, help: help}
+}
+
+func (sc *subCommandWithHelp) Handle(t Team, args *CommandArguments) CommandResult {
 	return sc.f(t, args)
 }
 
-func (sc subCommandWithHelp) Help(t Team, args *CommandArguments) CommandResult {
+func (sc *subCommandWithHelp) Help(t Team, args *CommandArguments) CommandResult {
 	return CmdHelpf(args, sc.help)
 }
 
+// LeveledCommand is implemented by commands that declare the access level
+// needed to run them.
+type LeveledCommand interface {
+	SubCommand
+	RequiredLevel() AccessLevel
+}
+
+type levelCommand struct {
+	SubCommand
+	level AccessLevel
+}
+
+// RequireLevel wraps a command so that it can only be run by users with at
+// least the given access level. The level is shown in the command reference.
+func RequireLevel(level AccessLevel, c SubCommand) SubCommand {
+	return &levelCommand{SubCommand: c, level: level}
+}
+
+func (lc *levelCommand) RequiredLevel() AccessLevel {
+	return lc.level
+}
+
+func (lc *levelCommand) Handle(t Team, args *CommandArguments) CommandResult {
+	if args.Source != nil && args.Source.AccessLevel() < lc.level {
+		return CmdFailuref(args, "Sorry, %v, `%s` is restricted to %ss.",
+			args.Source.UserID(), strings.Join(args.PreArgs(), " "), lc.level)
+	}
+	return lc.SubCommand.Handle(t, args)
+}
+
 type ParentCommand struct {
 	extraHelp string
 	lock      sync.Mutex
@@ -47,7 +85,7 @@ func (pc *ParentCommand) RegisterCommandFunc(name string, f SubCommandFunc, help
 	pc.lock.Lock()
 	defer pc.lock.Unlock()
 
-	sc := subCommandWithHelp{f: f, help: help}
+	sc := CommandFunc(f, help)
 	pc.nameMap[name] = sc
 	return sc
 }
@@ -72,7 +110,7 @@ func (pc *ParentCommand) Help(t Team, args *CommandArguments) CommandResult {
 	if !ok {
 		cmdErr := CmdFailuref(args, "help: No such command `%s`", strings.Join(args.PreArgs(), " "))
 		cmdErr.Code = CmdResultNoSuchCommand
-		return cmdErr
+		return cmdErr.withSuggestions(pc.suggest(args))
 	}
 	return subC.Help(t, args)
 }
@@ -92,7 +130,8 @@ func (pc *ParentCommand) helpListCommands(t Team, args *CommandArguments) Comman
 		}
 		return CmdHelpf(args, "Subcommands of `%s`:\n`%s`", strings.Join(preArgs[1:], " "), strings.Join(subNames, "` `"))
 	}
-	return CmdHelpf(args, "Available commands:\n`%s`", strings.Join(subNames, "` `"))
+	return CmdHelpf(args, "Available commands:\n`%s`\nUse `help --all` for a summary of every command, or see %s",
+		strings.Join(subNames, "` `"), t.AbsoluteURL("/help"))
 }
 
 func (pc *ParentCommand) Handle(t Team, args *CommandArguments) CommandResult {
@@ -104,6 +143,11 @@ func (pc *ParentCommand) Handle(t Team, args *CommandArguments) CommandResult {
 	if args.Command == "help" {
 		if len(args.Arguments) == 0 {
 			return pc.Help(t, args)
+		} else if args.Arguments[0] == "--all" {
+			args.Pop()
+			preArgs := args.PreArgs()
+			tree := pc.CommandTree(t, preArgs[:len(preArgs)-2])
+			return CmdHelpf(args, "%s", FormatCommandTree(tree)).WithReplyType(ReplyTypePM)
 		} else {
 			args.Command = args.Pop()
 
@@ -114,7 +158,7 @@ func (pc *ParentCommand) Handle(t Team, args *CommandArguments) CommandResult {
 			if !ok {
 				cmdErr := CmdFailuref(args, "help: No such command '%s'", args.Command)
 				cmdErr.Code = CmdResultNoSuchCommand
-				return cmdErr
+				return cmdErr.withSuggestions(pc.suggest(args))
 			}
 			return subC.Help(t, args)
 		}
@@ -127,8 +171,166 @@ func (pc *ParentCommand) Handle(t Team, args *CommandArguments) CommandResult {
 	if !ok {
 		cmdErr := CmdFailuref(args, "No such subcommand '%s'", args.Command)
 		cmdErr.Code = CmdResultNoSuchCommand
-		return cmdErr
+		return cmdErr.withSuggestions(pc.suggest(args))
 	}
 
 	return subC.Handle(t, args)
 }
+
+// A CommandSuggestion is a possible correction for a mistyped command.
+type CommandSuggestion struct {
+	// Name is the corrected command, e.g. "factoid search".
+	Name string
+	// Arguments is the full corrected argument list, including the
+	// arguments after the mistyped word.
+	Arguments []string
+}
+
+// MaxSuggestions is the number of suggestions attached to a
+// CmdResultNoSuchCommand result.
+const MaxSuggestions = 3
+
+// SuggestionText formats a list of suggestions for display to a user.
+func SuggestionText(suggestions []CommandSuggestion) string {
+	if len(suggestions) == 0 {
+		return ""
+	}
+	names := make([]string, len(suggestions))
+	for i, v := range suggestions {
+		names[i] = v.Name
+	}
+	return fmt.Sprintf("Did you mean `%s`?", strings.Join(names, "`, `"))
+}
+
+func (r CommandResult) withSuggestions(suggestions []CommandSuggestion) CommandResult {
+	r.Suggestions = suggestions
+	if len(suggestions) > 0 {
+		r.Message = r.Message + "\n" + SuggestionText(suggestions)
+	}
+	return r
+}
+
+// suggestionScore decides whether name is a plausible correction for typo.
+// Lower scores are better.
+func suggestionScore(typo, name string) (int, bool) {
+	if len(typo) >= 2 && strings.HasPrefix(name, typo) {
+		return 1, true
+	}
+	limit := len(name) / 3
+	if limit < 1 {
+		limit = 1
+	}
+	dist := util.EditDistance(typo, name)
+	if dist <= limit {
+		return dist, true
+	}
+	return 0, false
+}
+
+// suggestionKey identifies a command registered under several names, so
+// that its aliases are only suggested once.
+func suggestionKey(name string, c SubCommand) interface{} {
+	if key := commandKey(c); key != nil {
+		return key
+	}
+	return name
+}
+
+// suggest finds likely corrections for args.Command, which was not found in
+// this ParentCommand. The subcommands of nested ParentCommands are considered
+// too, so `@marvin serach` can suggest `factoid search`. Each command is
+// suggested once, under its best matching name.
+func (pc *ParentCommand) suggest(args *CommandArguments) []CommandSuggestion {
+	type candidate struct {
+		path  []string
+		score int
+		key   interface{}
+	}
+	typo := strings.ToLower(args.Command)
+	if typo == "" {
+		return nil
+	}
+
+	pc.lock.Lock()
+	var names []string
+	commands := make(map[string]SubCommand)
+	children := make(map[string]*ParentCommand)
+	for k, v := range pc.nameMap {
+		names = append(names, k)
+		commands[k] = v
+		if child, ok := v.(*ParentCommand); ok && child != pc {
+			children[k] = child
+		}
+	}
+	pc.lock.Unlock()
+	// Longest first, so the canonical name wins over short aliases
+	sort.Slice(names, func(i, j int) bool {
+		if len(names[i]) != len(names[j]) {
+			return len(names[i]) > len(names[j])
+		}
+		return names[i] < names[j]
+	})
+
+	var candidates []candidate
+	seen := make(map[*ParentCommand]bool)
+	for _, name := range names {
+		if score, ok := suggestionScore(typo, name); ok {
+			candidates = append(candidates, candidate{path: []string{name}, score: score, key: suggestionKey(name, commands[name])})
+		}
+		child := children[name]
+		if child == nil || seen[child] {
+			continue
+		}
+		seen[child] = true
+		child.lock.Lock()
+		for sub, c := range child.nameMap {
+			if score, ok := suggestionScore(typo, sub); ok {
+				// Prefer a match at this level
+				candidates = append(candidates, candidate{path: []string{name, sub}, score: score + 1,
+					key: suggestionKey(name+" "+sub, c)})
+			}
+		}
+		child.lock.Unlock()
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].score != candidates[j].score {
+			return candidates[i].score < candidates[j].score
+		}
+		// Prefer the canonical name over short aliases
+		a, b := candidates[i].path, candidates[j].path
+		if len(a[len(a)-1]) != len(b[len(b)-1]) {
+			return len(a[len(a)-1]) > len(b[len(b)-1])
+		}
+		return strings.Join(a, " ") < strings.Join(b, " ")
+	})
+	seenKeys := make(map[interface{}]bool)
+	unique := candidates[:0]
+	for _, c := range candidates {
+		if seenKeys[c.key] {
+			continue
+		}
+		seenKeys[c.key] = true
+		unique = append(unique, c)
+	}
+	candidates = unique
+	if len(candidates) > MaxSuggestions {
+		candidates = candidates[:MaxSuggestions]
+	}
+
+	var prefix []string
+	if preArgs := args.PreArgs(); len(preArgs) > 0 {
+		prefix = preArgs[:len(preArgs)-1]
+	}
+	result := make([]CommandSuggestion, len(candidates))
+	for i, c := range candidates {
+		var fullArgs []string
+		fullArgs = append(fullArgs, prefix...)
+		fullArgs = append(fullArgs, c.path...)
+		fullArgs = append(fullArgs, args.Arguments...)
+		result[i] = CommandSuggestion{
+			Name:      strings.Join(append(append([]string(nil), prefix...), c.path...), " "),
+			Arguments: fullArgs,
+		}
+	}
+	return result
+}
@@ -0,0 +1,106 @@
+package marvin
+
+import (
+	"strings"
+	"testing"
+)
+
+func testCommandTree() *ParentCommand {
+	noop := func(t Team, args *CommandArguments) CommandResult { return CmdSuccess(args, "") }
+
+	factoid := NewParentCommand()
+	remember := factoid.RegisterCommandFunc("remember", noop, "")
+	forget := factoid.RegisterCommandFunc("forget", noop, "")
+	factoid.RegisterCommand("rem", remember)
+	factoid.RegisterCommand("r", remember)
+	factoid.RegisterCommand("fg", forget)
+	factoid.RegisterCommandFunc("search", noop, "")
+	factoid.RegisterCommandFunc("source", noop, "")
+
+	root := NewParentCommand()
+	root.RegisterCommand("factoid", factoid)
+	root.RegisterCommand("f", factoid)
+	root.RegisterCommand("remember", remember)
+	root.RegisterCommand("rem", remember)
+	root.RegisterCommand("r", remember)
+	root.RegisterCommandFunc("help", noop, "")
+	root.RegisterCommandFunc("whoami", noop, "")
+	return root
+}
+
+func TestSuggest(t *testing.T) {
+	root := testCommandTree()
+	tests := []struct {
+		typo   string
+		expect []string
+	}{
+		// The top-level alias of remember is the same command as factoid
+		// remember, so it is only suggested once
+		{"remembr", []string{"remember"}},
+		{"reme", []string{"remember"}},
+		{"serach", []string{"factoid search"}},
+		{"factiod", []string{"factoid"}},
+		{"whoamii", []string{"whoami"}},
+		{"sou", []string{"factoid source"}},
+		{"zzzzzz", nil},
+		{"", nil},
+	}
+	for _, v := range tests {
+		args := &CommandArguments{
+			OriginalArguments: []string{v.typo, "arg"},
+			Arguments:         []string{"arg"},
+			Command:           v.typo,
+		}
+		got := root.suggest(args)
+		var names []string
+		for _, s := range got {
+			names = append(names, s.Name)
+		}
+		if strings.Join(names, ",") != strings.Join(v.expect, ",") {
+			t.Errorf("%q: expected %v, got %v", v.typo, v.expect, names)
+			continue
+		}
+		for _, s := range got {
+			expectArgs := append(strings.Split(s.Name, " "), "arg")
+			if strings.Join(s.Arguments, " ") != strings.Join(expectArgs, " ") {
+				t.Errorf("%q: expected arguments %v, got %v", v.typo, expectArgs, s.Arguments)
+			}
+		}
+	}
+}
+
+func TestSuggestNested(t *testing.T) {
+	root := testCommandTree()
+	factoid := root.nameMap["factoid"].(*ParentCommand)
+	// @marvin factoid forgt x
+	args := &CommandArguments{
+		OriginalArguments: []string{"factoid", "forgt", "x"},
+		Arguments:         []string{"x"},
+		Command:           "forgt",
+	}
+	got := factoid.suggest(args)
+	if len(got) != 1 || got[0].Name != "factoid forget" || strings.Join(got[0].Arguments, " ") != "factoid forget x" {
+		t.Errorf("expected [factoid forget], got %+v", got)
+	}
+}
+
+func TestSuggestionScore(t *testing.T) {
+	tests := []struct {
+		typo, name string
+		score      int
+		ok         bool
+	}{
+		{"sea", "search", 1, true},
+		{"s", "search", 0, false},
+		{"serach", "search", 2, true},
+		{"hepl", "help", 0, false},
+		{"hlp", "help", 1, true},
+		{"xyz", "search", 0, false},
+	}
+	for _, v := range tests {
+		score, ok := suggestionScore(v.typo, v.name)
+		if ok != v.ok || (ok && score != v.score) {
+			t.Errorf("suggestionScore(%q, %q): expected %d %v, got %d %v", v.typo, v.name, v.score, v.ok, score, ok)
+		}
+	}
+}
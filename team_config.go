@@ -27,6 +27,7 @@ type TeamConfig struct {
 	HTTPListen      string
 	HTTPURL         string
 	Controllers     []slack.UserID
+	AdminGroups     []slack.UsergroupID
 	IsDevelopment   bool
 }
 
@@ -52,6 +53,12 @@ func LoadTeamConfig(sec *ini.Section) *TeamConfig {
 		c.Controllers[uid] = slack.UserID(split[uid])
 	}
 
+	if groupsKey := sec.Key("AdminGroups").String(); groupsKey != "" {
+		for _, v := range strings.Split(groupsKey, ",") {
+			c.AdminGroups = append(c.AdminGroups, slack.UsergroupID(strings.TrimSpace(v)))
+		}
+	}
+
 	if c.HTTPURL == "__auto" {
 		hostname, err := os.Hostname()
 		if err != nil {
@@ -0,0 +1,32 @@
+package util
+
+import "testing"
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b   string
+		expect int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"", "abc", 3},
+		{"search", "search", 0},
+		{"serach", "search", 2},
+		{"factiod", "factoid", 2},
+		{"remembr", "remember", 1},
+		{"forgett", "forget", 1},
+		{"kitten", "sitting", 3},
+		{"flaw", "lawn", 2},
+		// Counted in runes, not bytes
+		{"café", "cafe", 1},
+		{"日本語", "日本", 1},
+	}
+	for _, v := range tests {
+		if got := EditDistance(v.a, v.b); got != v.expect {
+			t.Errorf("EditDistance(%q, %q): expected %d, got %d", v.a, v.b, v.expect, got)
+		}
+		if got := EditDistance(v.b, v.a); got != v.expect {
+			t.Errorf("EditDistance(%q, %q): expected %d, got %d", v.b, v.a, v.expect, got)
+		}
+	}
+}